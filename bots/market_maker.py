@@ -10,6 +10,7 @@ Usage:
 
 Environment variables:
     API_URL: Backend API URL (default: http://localhost:8080)
+    ADMIN_TOKEN: Operator token, required to register as a market maker
 """
 
 import os
@@ -21,6 +22,7 @@ from typing import Optional
 from dataclasses import dataclass
 
 API_URL = os.getenv("API_URL", "http://localhost:8080")
+ADMIN_TOKEN = os.getenv("ADMIN_TOKEN", "")
 
 @dataclass
 class MarketMaker:
@@ -119,7 +121,8 @@ def register_bot() -> tuple[str, str]:
             "username": username,
             "password": "bot_password",
             "type": "market_maker"
-        }
+        },
+        headers={"X-Admin-Token": ADMIN_TOKEN}
     )
     resp.raise_for_status()
     data = resp.json()
@@ -1,26 +1,87 @@
 package main
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/thatreguy/trade.re/internal/api"
+	"github.com/thatreguy/trade.re/internal/auth"
 	"github.com/thatreguy/trade.re/internal/config"
 	"github.com/thatreguy/trade.re/internal/db"
 	"github.com/thatreguy/trade.re/internal/domain"
 	"github.com/thatreguy/trade.re/internal/engine"
+	"github.com/thatreguy/trade.re/internal/events"
+	"github.com/thatreguy/trade.re/internal/funding"
+	"github.com/thatreguy/trade.re/internal/index"
 	"github.com/thatreguy/trade.re/internal/liquidation"
 	"github.com/thatreguy/trade.re/internal/ws"
 )
 
+// endpoints lists the API routes logged at startup, as "METHOD path"
+var endpoints = []string{
+	"GET /health",
+	"GET /ws (WebSocket, ?token= for private channels)",
+	"GET /api/v1/config",
+	"GET /api/v1/export",
+	"POST /api/v1/auth/register",
+	"POST /api/v1/auth/login",
+	"GET /api/v1/instruments",
+	"GET /api/v1/traders",
+	"GET /api/v1/traders/{id}",
+	"GET /api/v1/traders/{id}/positions",
+	"GET /api/v1/traders/{id}/position-lifecycle",
+	"GET /api/v1/traders/{id}/exposure",
+	"GET /api/v1/market/orderbook",
+	"GET /api/v1/market/positions",
+	"GET /api/v1/market/oi/history",
+	"GET /api/v1/market/oi/at",
+	"GET /api/v1/market/concentration",
+	"GET /api/v1/market/insurance-fund",
+	"GET /api/v1/market/insurance-fund/history",
+	"GET /api/v1/market/trades",
+	"GET /api/v1/market/stats",
+	"GET /api/v1/market/liquidity",
+	"GET /api/v1/market/degen-index",
+	"GET /api/v1/market/liquidations/largest",
+	"GET /api/v1/market/cascade-sim",
+	"GET /api/v1/market/liquidation-rates",
+	"GET /api/v1/market/candles",
+	"GET /api/v1/history/trades",
+	"GET /api/v1/leaderboard",
+	"GET /api/v1/leaderboard/period",
+	"GET /api/v1/history/candles",
+	"GET /api/v1/history/candles.csv",
+	"GET /api/v1/admin/debug/state (admin)",
+	"GET /api/v1/admin/config/effective (admin)",
+	"PUT /api/v1/admin/market-state (admin)",
+	"POST /api/v1/admin/insurance-fund/adjust (admin)",
+	"POST /api/v1/traders/me/flatten (auth)",
+	"GET|PUT /api/v1/traders/me/mmp (auth)",
+	"POST /api/v1/traders/me/mmp/reset (auth)",
+	"POST /api/v1/orders",
+	"POST /api/v1/orders/batch",
+	"POST /api/v1/orders/oco",
+	"POST /api/v1/orders/{id}/replace",
+	"PATCH /api/v1/orders/{id}/reduce",
+	"PUT /api/v1/orders/{id}",
+	"DELETE /api/v1/orders/{id}",
+}
+
 func main() {
 	// Load configuration
 	cfg := config.LoadOrDefault("config/config.yaml")
+	slog.SetDefault(newLogger(cfg.Log))
 
 	// Get database path from env or default to ./data/tradere.db
 	dbPath := os.Getenv("DATABASE_PATH")
@@ -31,16 +92,17 @@ func main() {
 	// Ensure data directory exists
 	dbDir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dbDir, 0755); err != nil {
-		log.Fatalf("Failed to create data directory: %v", err)
+		fatal("Failed to create data directory", err)
 	}
 
 	// Initialize SQLite database
-	log.Printf("Opening database: %s", dbPath)
+	slog.Info("Opening database", "path", dbPath)
 	database, err := db.NewSQLite(dbPath)
 	if err != nil {
-		log.Fatalf("Failed to open database: %v", err)
+		fatal("Failed to open database", err)
 	}
-	defer database.Close()
+	// Closed by eng.Shutdown after the final snapshot, which flushes queued writes
+	database.SetWriteBatching(cfg.Database.WriteBatchInterval(), cfg.Database.WriteBatchRows)
 
 	// Initialize matching engine
 	eng := engine.NewMatchingEngine()
@@ -51,28 +113,52 @@ func main() {
 	// Set liquidation config for margin calculations
 	eng.SetLiquidationConfig(&cfg.Liquidation)
 
-	// Register R.index - the only tradeable instrument
-	eng.RegisterInstrument("R.index")
+	// Instrument settings (lot size, tick size, limits)
+	eng.SetInstrumentConfig(&cfg.RIndex)
+
+	// Bound the work a single order may do under the engine lock
+	eng.SetEngineConfig(&cfg.Engine)
+	eng.SetSLOConfig(&cfg.SLO)
+	eng.SetFeeConfig(&cfg.Fees)
+	eng.SetGameConfig(&cfg.Game)
+	eng.SetDegenIndexConfig(&cfg.DegenIndex)
+
+	// Register the tradeable instruments. Loading, liquidation checks and book
+	// snapshots cover whatever is registered here.
+	eng.RegisterInstrument(domain.RIndexSymbol)
 
 	// Load existing data from database
 	if err := eng.LoadFromDatabase(); err != nil {
-		log.Fatalf("Failed to load data from database: %v", err)
+		fatal("Failed to load data from database", err)
 	}
 
 	// Initialize WebSocket hub
 	hub := ws.NewHub()
+	hub.SetHeartbeatInterval(time.Duration(cfg.Server.WSHeartbeatSeconds) * time.Second)
+	hub.SetFillBroadcastDelay(cfg.Simulation.FillBroadcastDelay())
 	go hub.Run()
 
-	// Wire up trade broadcasts
+	// Wire up trade broadcasts. Trade handlers run under the engine lock, so
+	// the post-trade book snapshot is only requested here and built by the
+	// snapshot loop; a sweep's trades coalesce into one snapshot.
+	bookRefresh := make(map[string]chan struct{})
+	for _, instrument := range eng.Instruments() {
+		bookRefresh[instrument] = make(chan struct{}, 1)
+	}
 	eng.OnTrade(func(trade *domain.Trade) {
 		hub.BroadcastTrade(trade)
-		log.Printf("Trade: %s %s @ %s (buyer: %s, seller: %s)",
-			trade.Size.String(),
-			trade.Instrument,
-			trade.Price.String(),
-			trade.BuyerID.String()[:8],
-			trade.SellerID.String()[:8],
-		)
+		select {
+		case bookRefresh[trade.Instrument] <- struct{}{}:
+		default:
+		}
+		hub.BroadcastTraderActivity(trade.BuyerID.String(), ws.TypeTrade, trade)
+		hub.BroadcastTraderActivity(trade.SellerID.String(), ws.TypeTrade, trade)
+		hub.SendFill(trade.BuyerID.String(), ws.TypeTrade, trade)
+		hub.SendFill(trade.SellerID.String(), ws.TypeTrade, trade)
+	})
+
+	eng.OnPositionUpdate(func(pos *domain.Position) {
+		hub.BroadcastTraderActivity(pos.TraderID.String(), ws.TypePosition, pos)
 	})
 
 	eng.OnOrderUpdate(func(order *domain.Order) {
@@ -80,10 +166,38 @@ func main() {
 			Type: ws.TypeOrder,
 			Data: order,
 		})
+		hub.SendFill(order.TraderID.String(), ws.TypeOrder, order)
+	})
+
+	eng.OnMarketStateChange(func(change *domain.MarketStateChange) {
+		hub.BroadcastMarketState(change)
+	})
+
+	// Stream order book level changes; subscribers start from a snapshot
+	eng.OnBookLevelChange(func(update *domain.BookLevelUpdate) {
+		hub.BroadcastOrderBookDelta(update.Instrument, update)
+	})
+	hub.SetOrderBookSnapshot(func(instrument string) (interface{}, error) {
+		return eng.GetOrderBook(instrument, cfg.Server.WSOrderBookDepth)
+	})
+
+	// Tell market makers when their protection pulls their quotes
+	eng.OnMMPTrigger(func(status *domain.MMPStatus) {
+		hub.SendToTrader(status.TraderID.String(), ws.TypeMMPTriggered, status)
 	})
 
 	// Initialize and start liquidation engine
 	liqEngine := liquidation.NewEngine(cfg.Liquidation, eng, eng)
+	eng.SetInsuranceFund(liqEngine)
+	liqEngine.OnInsuranceFundChange(eng.RecordInsuranceFundEvent)
+
+	// Resume the fund from its last recorded balance rather than the initial one
+	if balance, ok, err := eng.LastInsuranceFundBalance(); err != nil {
+		fatal("Failed to load insurance fund", err)
+	} else if ok {
+		liqEngine.RestoreInsuranceFund(balance)
+		slog.Info("Restored insurance fund balance", "balance", balance)
+	}
 	liqEngine.OnLiquidation(func(liq *domain.Liquidation) {
 		// Add to matching engine history and broadcast
 		eng.AddLiquidation(liq)
@@ -91,12 +205,95 @@ func main() {
 			Type: ws.TypeLiquidation,
 			Data: liq,
 		})
+		hub.BroadcastTraderActivity(liq.TraderID.String(), ws.TypeLiquidation, liq)
+		hub.SendFill(liq.TraderID.String(), ws.TypeLiquidation, liq)
 	})
 	liqEngine.Start()
-	defer liqEngine.Stop()
+
+	// Track a reference index apart from the traded price if one is configured
+	indexSource, err := index.New(cfg.Index, cfg.RIndex)
+	if err != nil {
+		fatal("Failed to create index source", err)
+	}
+	if indexSource != nil {
+		eng.SetIndexSource(indexSource)
+		slog.Info("Index price source configured", "source", cfg.Index.Source)
+	}
+
+	// Settle funding between longs and shorts if enabled
+	var fundingEngine *funding.Engine
+	if cfg.Funding.Enabled {
+		fundingEngine = funding.NewEngine(cfg.Funding, eng, eng)
+		fundingEngine.SetMarketPhaser(eng)
+		fundingEngine.OnFreezeScheduled(func(freeze *domain.FundingFreeze) {
+			hub.BroadcastFundingFreeze(freeze)
+		})
+		eng.SetFundingSource(fundingEngine)
+		fundingEngine.Start()
+	}
+
+	// Fan engine events out to an external pipeline if one is configured
+	eventSink, err := events.New(cfg.Events)
+	if err != nil {
+		fatal("Failed to create event sink", err)
+	}
+	if eventSink != nil {
+		eng.SetEventSink(eventSink)
+		slog.Info("Publishing engine events", "sink", cfg.Events.Sink)
+	}
+
+	// Periodically snapshot open interest for the OI history endpoint
+	eng.StartSnapshots()
+
+	// Warn (and publish to the event sink) when match latency breaches its SLO
+	eng.StartSLOWatcher()
+
+	// Keep open positions' unrealized P&L current between fills
+	eng.StartPnLRefresh()
+
+	// Broadcast full order book snapshots to WebSocket subscribers on a timer
+	// and after trades
+	bookStop := make(chan struct{})
+	if cfg.Server.WSOrderBookSnapshotMs > 0 {
+		for _, instrument := range eng.Instruments() {
+			go hub.RunOrderBookSnapshots(instrument,
+				time.Duration(cfg.Server.WSOrderBookSnapshotMs)*time.Millisecond,
+				func() (interface{}, error) { return eng.GetOrderBook(instrument, cfg.Server.WSOrderBookDepth) },
+				bookRefresh[instrument], bookStop)
+		}
+	}
+
+	// Sign login tokens. Without a configured secret, tokens only last until
+	// the next restart.
+	jwtSecret := cfg.Auth.JWTSecret
+	if jwtSecret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			fatal("Failed to generate JWT secret", err)
+		}
+		jwtSecret = hex.EncodeToString(secret)
+		slog.Warn("auth.jwt_secret not set - using a random secret, tokens will not survive a restart")
+	}
+	authn := auth.New(jwtSecret, cfg.Auth.TokenExpiryHours, cfg.Auth.APIKeyLength)
 
 	// Create API server
-	server := api.NewServer(eng, hub, cfg.Server.Timezone)
+	server := api.NewServer(eng, hub, authn, cfg.Server.Timezone)
+	hub.SetAuthenticator(func(token string) (string, error) {
+		traderID, err := server.TraderForToken(token)
+		if err != nil {
+			return "", err
+		}
+		return traderID.String(), nil
+	})
+	server.SetAdminToken(cfg.Auth.AdminToken)
+	server.SetMaxInFlightOrders(cfg.Server.MaxInFlightOrders)
+	server.SetExportSource(database, cfg.Server.PublicExport)
+	server.SetCandleSource(database)
+	server.SetOrderAckDelay(cfg.Simulation.OrderAckDelay())
+	if cfg.Simulation.SimulateLatency {
+		slog.Warn("Simulated latency enabled - not for production",
+			"order_ack_delay", cfg.Simulation.OrderAckDelay(), "fill_broadcast_delay", cfg.Simulation.FillBroadcastDelay())
+	}
 
 	// Setup router
 	r := chi.NewRouter()
@@ -116,44 +313,70 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("=================================")
-	log.Printf("  Trade.re Server Starting")
-	log.Printf("  Port: %s", port)
-	log.Printf("  Database: %s", dbPath)
-	log.Printf("  Instrument: R.index")
-	log.Printf("=================================")
-	log.Printf("")
-	log.Printf("Endpoints:")
-	log.Printf("  GET  /health")
-	log.Printf("  GET  /ws (WebSocket)")
-	log.Printf("  GET  /api/v1/config")
-	log.Printf("  GET  /api/v1/auth/register")
-	log.Printf("  GET  /api/v1/auth/login")
-	log.Printf("  GET  /api/v1/traders")
-	log.Printf("  GET  /api/v1/traders/{id}")
-	log.Printf("  GET  /api/v1/traders/{id}/positions")
-	log.Printf("  GET  /api/v1/market/orderbook")
-	log.Printf("  GET  /api/v1/market/positions")
-	log.Printf("  GET  /api/v1/market/trades")
-	log.Printf("  GET  /api/v1/market/stats")
-	log.Printf("  GET  /api/v1/market/candles")
-	log.Printf("  GET  /api/v1/history/trades")
-	log.Printf("  GET  /api/v1/history/candles")
-	log.Printf("  POST /api/v1/orders")
-	log.Printf("  DELETE /api/v1/orders/{id}")
-	log.Printf("")
-
-	if err := http.ListenAndServe(":"+port, r); err != nil {
-		log.Fatal(err)
+	slog.Info("Trade.re server starting",
+		"port", port,
+		"database", dbPath,
+		"instruments", strings.Join(eng.Instruments(), ", "),
+	)
+	for _, endpoint := range endpoints {
+		slog.Debug("Endpoint", "route", endpoint)
 	}
+
+	httpServer := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("HTTP server failed", err)
+		}
+	}()
+
+	// Wait for a shutdown signal, then stop intake before flushing state
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	slog.Info("Shutdown signal received")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Error shutting down HTTP server", "error", err)
+	}
+	liqEngine.Stop()
+	if fundingEngine != nil {
+		fundingEngine.Stop()
+	}
+	close(bookStop)
+	hub.Close()
+	if err := eng.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Error shutting down engine", "error", err)
+	}
+	if eventSink != nil {
+		eventSink.Close()
+	}
+	slog.Info("Shutdown complete")
+}
+
+// newLogger builds the structured logger, writing to stderr
+func newLogger(cfg config.LogConfig) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.SlogLevel()}
+	if cfg.Format == "text" {
+		return slog.New(slog.NewTextHandler(os.Stderr, opts))
+	}
+	return slog.New(slog.NewJSONHandler(os.Stderr, opts))
+}
+
+// fatal logs an error that stops startup and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
 
 // corsMiddleware adds CORS headers
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Admin-Token")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
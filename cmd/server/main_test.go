@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/thatreguy/trade.re/internal/api"
+	"github.com/thatreguy/trade.re/internal/auth"
+	"github.com/thatreguy/trade.re/internal/engine"
+	"github.com/thatreguy/trade.re/internal/ws"
+)
+
+var urlParam = regexp.MustCompile(`\{[^}]+\}`)
+
+func TestLoggedEndpointsAreRouted(t *testing.T) {
+	r := chi.NewRouter()
+	api.NewServer(engine.NewMatchingEngine(), ws.NewHub(), auth.New("secret", 1, 32), "UTC").RegisterRoutes(r)
+
+	routed := make(map[string]bool)
+	err := chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		// A subrouter's own route walks as its prefix with a trailing slash
+		route = strings.TrimSuffix(route, "/")
+		routed[method+" "+urlParam.ReplaceAllString(route, "{}")] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, endpoint := range endpoints {
+		fields := strings.Fields(endpoint)
+		path := urlParam.ReplaceAllString(fields[1], "{}")
+		for _, method := range strings.Split(fields[0], "|") {
+			if !routed[method+" "+path] {
+				t.Errorf("%q is logged but %s %s is not routed", endpoint, method, fields[1])
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/thatreguy/trade.re/internal/auth"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+	"github.com/thatreguy/trade.re/internal/ws"
+)
+
+// newAuthRouter serves the API over a fresh test engine
+func newAuthRouter(t *testing.T) (http.Handler, *enginetest.Harness) {
+	t.Helper()
+	h := enginetest.NewTestEngine()
+	s := NewServer(h.Engine, ws.NewHub(), auth.New("test-secret", 1, 32), "UTC")
+	r := chi.NewRouter()
+	s.RegisterRoutes(r)
+	return r, h
+}
+
+func post(router http.Handler, path string, body interface{}) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload)))
+	return rec
+}
+
+func TestRegisterRejectsTakenUsername(t *testing.T) {
+	router, h := newAuthRouter(t)
+
+	first := map[string]string{"username": "alice", "password": "first"}
+	if rec := post(router, "/api/v1/auth/register", first); rec.Code != http.StatusCreated {
+		t.Fatalf("register = %d, want 201: %s", rec.Code, rec.Body)
+	}
+	second := map[string]string{"username": "alice", "password": "second"}
+	if rec := post(router, "/api/v1/auth/register", second); rec.Code != http.StatusConflict {
+		t.Fatalf("second register = %d, want 409: %s", rec.Code, rec.Body)
+	}
+
+	// The first account keeps its password
+	if rec := post(router, "/api/v1/auth/login", first); rec.Code != http.StatusOK {
+		t.Fatalf("login = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if rec := post(router, "/api/v1/auth/login", second); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("login with the rejected password = %d, want 401", rec.Code)
+	}
+	if n := len(h.Engine.GetAllTraders()); n != 1 {
+		t.Fatalf("traders = %d, want 1", n)
+	}
+}
+
+func TestConcurrentRegisterCreatesOneTrader(t *testing.T) {
+	router, h := newAuthRouter(t)
+
+	var wg sync.WaitGroup
+	codes := make([]int, 8)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = post(router, "/api/v1/auth/register", map[string]string{"username": "bob", "password": "pw"}).Code
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusConflict:
+		default:
+			t.Fatalf("register = %d, want 201 or 409", code)
+		}
+	}
+	if created != 1 || len(h.Engine.GetAllTraders()) != 1 {
+		t.Fatalf("created %d traders (%d registered), want 1", created, len(h.Engine.GetAllTraders()))
+	}
+}
+
+func TestCreateTraderRejectsTakenUsername(t *testing.T) {
+	router, h := newAuthRouter(t)
+	h.AddTrader("carol")
+
+	if rec := post(router, "/api/v1/traders", map[string]string{"username": "carol"}); rec.Code != http.StatusConflict {
+		t.Fatalf("create = %d, want 409: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestNewTraderTypeIsValidatedAndPrivilegedNeedsAdmin(t *testing.T) {
+	router, _ := newAdminRouter(t)
+	for p, path := range []string{"/api/v1/auth/register", "/api/v1/traders"} {
+		for i, c := range []struct {
+			traderType string
+			admin      bool
+			code       int
+		}{
+			{"", false, http.StatusCreated},
+			{"bot", false, http.StatusCreated},
+			{"whale", false, http.StatusBadRequest},
+			{"market_maker", false, http.StatusForbidden},
+			{"market_maker", true, http.StatusCreated},
+		} {
+			body, _ := json.Marshal(map[string]string{
+				"username": fmt.Sprintf("trader-%d-%d", p, i),
+				"password": "secret",
+				"type":     c.traderType,
+			})
+			rec := adminRequest(router, http.MethodPost, path, bytes.NewReader(body), c.admin)
+			if rec.Code != c.code {
+				t.Errorf("%s as %q (admin %v) = %d, want %d: %s", path, c.traderType, c.admin, rec.Code, c.code, rec.Body)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/auth"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+	"github.com/thatreguy/trade.re/internal/ws"
+)
+
+// adminToken enables the admin API in tests
+const adminToken = "admin-token"
+
+// newAdminRouter serves the API over a fresh test engine with the admin API
+// enabled for adminToken
+func newAdminRouter(t *testing.T) (http.Handler, *enginetest.Harness) {
+	t.Helper()
+	h := enginetest.NewTestEngine()
+	s := NewServer(h.Engine, ws.NewHub(), auth.New("test-secret", 1, 32), "UTC")
+	s.SetAdminToken(adminToken)
+	r := chi.NewRouter()
+	s.RegisterRoutes(r)
+	return r, h
+}
+
+// adminRequest sends an admin API request, with the admin token unless token is false
+func adminRequest(router http.Handler, method, path string, body io.Reader, token bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	if token {
+		req.Header.Set("X-Admin-Token", adminToken)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDebugStateReflectsEngine(t *testing.T) {
+	router, h := newAdminRouter(t)
+
+	// Two bids, one of them left over from a partial fill, one ask and a
+	// long/short pair from the fill
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+	h.MustLimit(maker, domain.SideBuy, "999", "2")
+	h.MustLimit(maker, domain.SideBuy, "998", "1")
+	h.MustLimit(maker, domain.SideSell, "1005", "1")
+	h.MustMarket(taker, domain.SideSell, "0.5")
+
+	if rec := adminRequest(router, http.MethodGet, "/api/v1/admin/debug/state", nil, false); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("debug state without the admin token = %d, want 401", rec.Code)
+	}
+
+	rec := adminRequest(router, http.MethodGet, "/api/v1/admin/debug/state", nil, true)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("debug state = %d: %s", rec.Code, rec.Body)
+	}
+	var state engine.DebugState
+	if err := json.Unmarshal(rec.Body.Bytes(), &state); err != nil {
+		t.Fatal(err)
+	}
+
+	if state.BookCount != 1 || len(state.Books) != 1 {
+		t.Fatalf("books = %d (%d listed), want 1", state.BookCount, len(state.Books))
+	}
+	book := state.Books[0]
+	if book.BidLevels != 2 || book.AskLevels != 1 || !book.BestBid.Equal(decimal.NewFromInt(999)) || !book.BestAsk.Equal(decimal.NewFromInt(1005)) {
+		t.Fatalf("book = %+v, want 2 bid levels from 999 and 1 ask at 1005", book)
+	}
+	if state.TotalBidOrders != 2 || state.TotalAskOrders != 1 {
+		t.Fatalf("resting orders = %d bids, %d asks, want 2 and 1", state.TotalBidOrders, state.TotalAskOrders)
+	}
+	if state.TraderCount != 2 || state.OpenPositionCount != 2 || !state.NetPosition.IsZero() {
+		t.Fatalf("traders %d, open positions %d, net %s, want 2, 2 and 0", state.TraderCount, state.OpenPositionCount, state.NetPosition)
+	}
+	if state.RecentTradesLength != 1 || state.MarketState != domain.MarketStateOpen {
+		t.Fatalf("recent trades %d, market %s, want 1 and open", state.RecentTradesLength, state.MarketState)
+	}
+	if state.Goroutines <= 0 || state.WriteQueue != nil {
+		t.Fatalf("goroutines %d, write queue %+v, want some and none without a database", state.Goroutines, state.WriteQueue)
+	}
+}
+
+func TestEffectiveConfigReflectsHalt(t *testing.T) {
+	router, h := newAdminRouter(t)
+
+	if rec := adminRequest(router, http.MethodGet, "/api/v1/admin/config/effective", nil, false); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("effective config without the admin token = %d, want 401", rec.Code)
+	}
+
+	halt := strings.NewReader(`{"state": "halted", "reason": "maintenance"}`)
+	if rec := adminRequest(router, http.MethodPut, "/api/v1/admin/market-state", halt, true); rec.Code != http.StatusOK {
+		t.Fatalf("halt = %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := adminRequest(router, http.MethodGet, "/api/v1/admin/config/effective", nil, true)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("effective config = %d: %s", rec.Code, rec.Body)
+	}
+	var cfg engine.EffectiveConfig
+	if err := json.Unmarshal(rec.Body.Bytes(), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MarketState != domain.MarketStateHalted || cfg.MarketStateReason != "maintenance" || cfg.AcceptingOrders {
+		t.Fatalf("effective state = %s (%q), accepting %v, want halted for maintenance", cfg.MarketState, cfg.MarketStateReason, cfg.AcceptingOrders)
+	}
+	if cfg.Instrument == nil || !cfg.Instrument.TickSize.Equal(h.Config.RIndex.TickSize) {
+		t.Fatalf("effective instrument = %+v, want the configured tick", cfg.Instrument)
+	}
+}
@@ -1,17 +1,26 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/auth"
 	"github.com/thatreguy/trade.re/internal/domain"
 	"github.com/thatreguy/trade.re/internal/engine"
+	"github.com/thatreguy/trade.re/internal/export"
 	"github.com/thatreguy/trade.re/internal/ws"
 )
 
@@ -19,19 +28,33 @@ import (
 type Server struct {
 	engine   *engine.MatchingEngine
 	hub      *ws.Hub
+	auth     *auth.Auth
 	upgrader websocket.Upgrader
 	timezone string
+
+	adminToken string        // Empty disables the admin API
+	ackDelay   time.Duration // Simulated order acknowledgement latency; zero disables
+
+	inFlightMu  sync.Mutex
+	inFlight    map[uuid.UUID]int // Order submissions being processed, by trader
+	maxInFlight int               // Zero = unlimited
+
+	exportSource export.Source       // Nil disables the dataset export
+	publicExport bool                // Export without the admin token
+	candleSource export.CandleSource // Nil disables the candle CSV
 }
 
 // NewServer creates a new API server
-func NewServer(eng *engine.MatchingEngine, hub *ws.Hub, timezone string) *Server {
+func NewServer(eng *engine.MatchingEngine, hub *ws.Hub, authn *auth.Auth, timezone string) *Server {
 	if timezone == "" {
 		timezone = "Asia/Kolkata"
 	}
 	return &Server{
 		engine:   eng,
 		hub:      hub,
+		auth:     authn,
 		timezone: timezone,
+		inFlight: make(map[uuid.UUID]int),
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
@@ -42,11 +65,90 @@ func NewServer(eng *engine.MatchingEngine, hub *ws.Hub, timezone string) *Server
 	}
 }
 
+// SetAdminToken enables the admin API, authenticated by the X-Admin-Token header
+func (s *Server) SetAdminToken(token string) {
+	s.adminToken = token
+}
+
+// SetOrderAckDelay holds every order submission response back by d, to
+// simulate latency for client testing. Never set in production.
+func (s *Server) SetOrderAckDelay(d time.Duration) {
+	s.ackDelay = d
+}
+
+// SetMaxInFlightOrders caps how many order submissions one trader may have
+// being processed at once. Zero removes the cap.
+func (s *Server) SetMaxInFlightOrders(n int) {
+	s.maxInFlight = n
+}
+
+// acquireInFlight reserves an in-flight order slot for a trader, reporting
+// false if the trader is at the cap. A successful call must be paired with
+// releaseInFlight once the submission has been acknowledged.
+func (s *Server) acquireInFlight(traderID uuid.UUID) bool {
+	s.inFlightMu.Lock()
+	defer s.inFlightMu.Unlock()
+	if s.maxInFlight > 0 && s.inFlight[traderID] >= s.maxInFlight {
+		return false
+	}
+	s.inFlight[traderID]++
+	return true
+}
+
+// releaseInFlight frees a slot taken by acquireInFlight
+func (s *Server) releaseInFlight(traderID uuid.UUID) {
+	s.inFlightMu.Lock()
+	defer s.inFlightMu.Unlock()
+	if s.inFlight[traderID] <= 1 {
+		delete(s.inFlight, traderID)
+	} else {
+		s.inFlight[traderID]--
+	}
+}
+
+// SetExportSource enables the dataset export, served to anyone when public is
+// set and otherwise only to admins
+func (s *Server) SetExportSource(src export.Source, public bool) {
+	s.exportSource = src
+	s.publicExport = public
+}
+
+// SetCandleSource enables the public candle CSV export
+func (s *Server) SetCandleSource(src export.CandleSource) {
+	s.candleSource = src
+}
+
+// simulateAckLatency waits out the simulated acknowledgement latency, giving
+// up early if the client goes away
+func (s *Server) simulateAckLatency(r *http.Request) {
+	if s.ackDelay <= 0 {
+		return
+	}
+	select {
+	case <-time.After(s.ackDelay):
+	case <-r.Context().Done():
+	}
+}
+
 // Response helpers
+
+// respondJSON encodes data before writing the status, so a value that fails to
+// marshal becomes a 500 rather than a truncated response under the original status
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(data); err != nil {
+		slog.Error("Error encoding response", "type", fmt.Sprintf("%T", data), "status", status, "error", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"failed to encode response"}` + "\n"))
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		slog.Error("Error writing response", "type", fmt.Sprintf("%T", data), "error", err)
+	}
 }
 
 func respondError(w http.ResponseWriter, status int, message string) {
@@ -66,17 +168,30 @@ func (s *Server) RegisterRoutes(r chi.Router) {
 		// Config (public settings)
 		r.Get("/config", s.handleGetConfig)
 
+		// Bulk dataset export for research
+		r.With(s.requireExportAccess).Get("/export", s.handleExport)
+
 		// Traders (public - transparency!)
 		r.Route("/traders", func(r chi.Router) {
 			r.Get("/", s.handleGetTraders)
 			r.Post("/", s.handleCreateTrader)
+			r.With(s.requireTrader).Post("/me/flatten", s.handleFlattenTrader)
+			r.With(s.requireTrader).Get("/me/mmp", s.handleGetMMP)
+			r.With(s.requireTrader).Put("/me/mmp", s.handleSetMMP)
+			r.With(s.requireTrader).Post("/me/mmp/reset", s.handleResetMMP)
+			r.With(s.requireTrader).Put("/me/margin-mode", s.handleSetMarginMode)
 			r.Get("/{traderID}", s.handleGetTrader)
 			r.Get("/{traderID}/positions", s.handleGetTraderPositions)
 			r.Get("/{traderID}/trades", s.handleGetTraderTrades)
+			r.Get("/{traderID}/orders", s.handleGetTraderOrders)
+			r.Get("/{traderID}/maker-stats", s.handleGetTraderMakerStats)
+			r.Get("/{traderID}/position-lifecycle", s.handleGetPositionLifecycle)
+			r.Get("/{traderID}/exposure", s.handleGetTraderExposure)
 		})
 
 		// Instruments
 		r.Route("/instruments", func(r chi.Router) {
+			r.Get("/", s.handleGetInstruments)
 			r.Get("/{symbol}/orderbook", s.handleGetOrderBook)
 			r.Get("/{symbol}/positions", s.handleGetPositions)
 			r.Get("/{symbol}/oi", s.handleGetOpenInterest)
@@ -87,32 +202,153 @@ func (s *Server) RegisterRoutes(r chi.Router) {
 			r.Get("/orderbook", s.handleGetMarketOrderBook)
 			r.Get("/positions", s.handleGetMarketPositions)
 			r.Get("/oi", s.handleGetMarketOpenInterest)
+			r.Get("/oi/history", s.handleGetMarketOIHistory)
+			r.Get("/oi/at", s.handleGetMarketHistoricalOI)
+			r.Get("/concentration", s.handleGetMarketConcentration)
+			r.Get("/insurance-fund", s.handleGetInsuranceFund)
+			r.Get("/insurance-fund/history", s.handleGetInsuranceFundHistory)
 			r.Get("/trades", s.handleGetMarketTrades)
 			r.Get("/liquidations", s.handleGetMarketLiquidations)
+			r.Get("/liquidations/largest", s.handleGetLargestLiquidations)
+			r.Get("/adl-queue", s.handleGetADLQueue)
+			r.Get("/cascade-sim", s.handleSimulateCascade)
+			r.Get("/liquidation-risk", s.handleGetLiquidationRisk)
+			r.Get("/liquidation-map", s.handleGetLiquidationMap)
+			r.Get("/liquidation-rates", s.handleGetLiquidationRates)
 			r.Get("/stats", s.handleGetMarketStats)
+			r.Get("/liquidity", s.handleGetMarketLiquidity)
+			r.Get("/degen-index", s.handleGetMarketDegenIndex)
 			r.Get("/candles", s.handleGetMarketCandles)
 		})
 
+		// Leaderboards
+		r.Get("/leaderboard", s.handleGetLeaderboard)
+		r.Get("/leaderboard/period", s.handleGetPeriodLeaderboard)
+
 		// Historical data API
 		r.Route("/history", func(r chi.Router) {
 			r.Get("/trades", s.handleGetHistoricalTrades)
 			r.Get("/candles", s.handleGetHistoricalCandles)
+			r.Get("/candles.csv", s.handleExportCandles)
 		})
 
 		// Auth (simplified for now)
 		r.Route("/auth", func(r chi.Router) {
 			r.Post("/register", s.handleRegister)
 			r.Post("/login", s.handleLogin)
+			r.With(s.requireTrader).Post("/apikey", s.handleCreateAPIKey)
+		})
+
+		// Admin (operator only)
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(s.requireAdmin)
+			r.Get("/debug/state", s.handleGetDebugState)
+			r.Get("/config/effective", s.handleGetEffectiveConfig)
+			r.Put("/market-state", s.handleSetMarketState)
+			r.Post("/insurance-fund/adjust", s.handleAdjustInsuranceFund)
 		})
 
-		// Orders
+		// Orders (placed as the trader the bearer token was issued to)
 		r.Route("/orders", func(r chi.Router) {
+			r.Use(s.requireTrader)
 			r.Post("/", s.handleSubmitOrder)
+			r.Get("/{orderID}", s.handleGetOrder)
+			r.Put("/{orderID}", s.handleAmendOrder)
 			r.Delete("/{orderID}", s.handleCancelOrder)
+			r.Post("/oco", s.handlePlaceOCO)
+			r.Post("/batch", s.handleSubmitOrders)
+			r.Post("/simulate", s.handleSimulateOrder)
+			r.Post("/{orderID}/replace", s.handleReplaceOrder)
+			r.Patch("/{orderID}/reduce", s.handleReduceOrder)
 		})
 	})
 }
 
+// requireAdmin rejects requests without the configured admin token
+func (s *Server) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.adminToken == "" {
+			respondError(w, http.StatusForbidden, "admin API disabled")
+			return
+		}
+		if !s.isAdmin(r) {
+			respondError(w, http.StatusUnauthorized, "invalid admin token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAdmin reports whether the request carries the configured admin token
+func (s *Server) isAdmin(r *http.Request) bool {
+	token := r.Header.Get("X-Admin-Token")
+	return s.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1
+}
+
+// requireExportAccess applies requireAdmin unless the export is public
+func (s *Server) requireExportAccess(next http.Handler) http.Handler {
+	if s.publicExport {
+		return next
+	}
+	return s.requireAdmin(next)
+}
+
+type contextKey string
+
+const traderIDKey contextKey = "trader_id"
+
+// requireTrader authenticates the caller by an X-API-Key header or the JWT
+// bearer token issued at register/login, and stores their trader ID in the
+// request context
+func (s *Server) requireTrader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var traderID uuid.UUID
+		if key := auth.ExtractAPIKey(r); key != "" {
+			trader, err := s.engine.GetTraderByAPIKey(s.auth.HashAPIKey(key))
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if trader == nil {
+				respondError(w, http.StatusUnauthorized, "invalid API key")
+				return
+			}
+			traderID = trader.ID
+		} else {
+			claims, err := s.auth.ValidateToken(auth.ExtractToken(r))
+			if err != nil || s.engine.GetTrader(claims.TraderID) == nil {
+				respondError(w, http.StatusUnauthorized, "authentication required")
+				return
+			}
+			traderID = claims.TraderID
+		}
+		ctx := context.WithValue(r.Context(), traderIDKey, traderID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// TraderForToken resolves a JWT issued at register/login, or failing that an
+// API key, to the trader it belongs to
+func (s *Server) TraderForToken(token string) (uuid.UUID, error) {
+	if claims, err := s.auth.ValidateToken(token); err == nil && s.engine.GetTrader(claims.TraderID) != nil {
+		return claims.TraderID, nil
+	}
+	trader, err := s.engine.GetTraderByAPIKey(s.auth.HashAPIKey(token))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if trader == nil {
+		return uuid.Nil, fmt.Errorf("invalid token")
+	}
+	return trader.ID, nil
+}
+
+// authenticatedTrader returns the trader ID set by requireTrader
+func authenticatedTrader(r *http.Request) uuid.UUID {
+	traderID, _ := r.Context().Value(traderIDKey).(uuid.UUID)
+	return traderID
+}
+
 // handleHealth returns server health status
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{
@@ -123,23 +359,67 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 
 // handleGetConfig returns public configuration
 func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	traderTypes := make(map[domain.TraderType]interface{})
+	for _, t := range []domain.TraderType{domain.TraderTypeHuman, domain.TraderTypeBot, domain.TraderTypeMarketMaker} {
+		traderTypes[t] = map[string]interface{}{
+			"starting_balance": s.engine.StartingBalance(t),
+			"max_leverage":     s.engine.MaxLeverage(t),
+		}
+	}
+
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"timezone":     s.timezone,
-		"max_leverage": 150,
+		"max_leverage": s.engine.MaxLeverage(domain.TraderTypeHuman),
 		"instrument":   "R.index",
+		"trader_types": traderTypes,
 	})
 }
 
-// handleWebSocket upgrades to WebSocket connection
+// handleWebSocket upgrades to WebSocket connection. ?encoding=msgpack
+// switches server messages to msgpack binary frames.
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	encoding, err := ws.ParseEncoding(r.URL.Query().Get("encoding"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// A token (JWT or API key) on the upgrade authenticates the connection
+	// up front; otherwise the client may send an auth message later
+	var traderID string
+	if token := r.URL.Query().Get("token"); token != "" {
+		id, err := s.TraderForToken(token)
+		if err != nil {
+			respondError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		traderID = id.String()
+	}
+
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
 
-	client := ws.NewClient(s.hub, conn)
+	client := ws.NewClientWithEncoding(s.hub, conn, encoding)
+	if traderID != "" {
+		client.SetTraderID(traderID)
+	}
 	s.hub.Register(client)
 
+	// Tell the client what phase the market is in before any other traffic
+	state, reason := s.engine.GetMarketState()
+	client.Send(ws.Message{
+		Type: ws.TypeWelcome,
+		Data: map[string]interface{}{
+			"market_state":          state,
+			"reason":                reason,
+			"seq":                   s.hub.Seq(),
+			"heartbeat_interval_ms": s.hub.HeartbeatInterval().Milliseconds(),
+			"trader_id":             traderID, // Empty unless authenticated
+		},
+	})
+
 	go client.WritePump()
 	go client.ReadPump()
 }
@@ -167,22 +447,46 @@ func (s *Server) handleCreateTrader(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Type == "" {
-		req.Type = domain.TraderTypeHuman
+	traderType, ok := s.newTraderType(w, r, req.Type)
+	if !ok {
+		return
 	}
 
 	trader := &domain.Trader{
 		ID:        uuid.New(),
 		Username:  req.Username,
-		Type:      req.Type,
+		Type:      traderType,
+		Balance:   s.engine.StartingBalance(traderType),
 		CreatedAt: time.Now(),
 		TotalPnL:  decimal.Zero,
 	}
 
-	s.engine.RegisterTrader(trader)
+	if err := s.engine.RegisterTrader(trader); err != nil {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
 	respondJSON(w, http.StatusCreated, trader)
 }
 
+// newTraderType returns the type a new trader asked for, human if none. It
+// responds 400 and returns false for an unknown type, and 403 for a
+// privileged type without the admin token, so nobody can grant themselves a
+// market maker's balance and leverage.
+func (s *Server) newTraderType(w http.ResponseWriter, r *http.Request, requested domain.TraderType) (domain.TraderType, bool) {
+	if requested == "" {
+		return domain.TraderTypeHuman, true
+	}
+	if !requested.IsValid() {
+		respondError(w, http.StatusBadRequest, "invalid trader type: "+string(requested))
+		return "", false
+	}
+	if requested.Privileged() && !s.isAdmin(r) {
+		respondError(w, http.StatusForbidden, "trader type "+string(requested)+" requires the admin token")
+		return "", false
+	}
+	return requested, true
+}
+
 // handleGetTrader returns a single trader (public)
 func (s *Server) handleGetTrader(w http.ResponseWriter, r *http.Request) {
 	traderIDStr := chi.URLParam(r, "traderID")
@@ -201,6 +505,103 @@ func (s *Server) handleGetTrader(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, trader)
 }
 
+// handleFlattenTrader cancels all of the caller's orders and closes their positions
+func (s *Server) handleFlattenTrader(w http.ResponseWriter, r *http.Request) {
+	result, err := s.engine.FlattenTrader(authenticatedTrader(r))
+	if result == nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	for _, trade := range result.Trades {
+		s.hub.BroadcastTrade(trade)
+	}
+
+	if err != nil {
+		// Partially flattened - report what was done alongside the error
+		respondJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":  err.Error(),
+			"result": result,
+		})
+		return
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// handleGetMMP returns the caller's market-maker protection state
+func (s *Server) handleGetMMP(w http.ResponseWriter, r *http.Request) {
+	status := s.engine.GetMMPStatus(authenticatedTrader(r))
+	if status == nil {
+		respondError(w, http.StatusNotFound, "market-maker protection not configured")
+		return
+	}
+	respondJSON(w, http.StatusOK, status)
+}
+
+// handleSetMMP configures the caller's market-maker protection thresholds
+func (s *Server) handleSetMMP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		WindowMs int64  `json:"window_ms"`
+		MaxFills int    `json:"max_fills"`
+		MaxSize  string `json:"max_size"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	maxSize := decimal.Zero
+	if req.MaxSize != "" {
+		var err error
+		if maxSize, err = decimal.NewFromString(req.MaxSize); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid max_size")
+			return
+		}
+	}
+
+	status, err := s.engine.SetMMPConfig(authenticatedTrader(r), domain.MMPConfig{
+		WindowMs: req.WindowMs,
+		MaxFills: req.MaxFills,
+		MaxSize:  maxSize,
+	})
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, status)
+}
+
+// handleSetMarginMode switches the caller between isolated and cross margin
+func (s *Server) handleSetMarginMode(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MarginMode domain.MarginMode `json:"margin_mode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	traderID := authenticatedTrader(r)
+	if err := s.engine.SetMarginMode(traderID, req.MarginMode); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"trader_id":   traderID,
+		"margin_mode": req.MarginMode,
+	})
+}
+
+// handleResetMMP unfreezes the caller after a protection trip
+func (s *Server) handleResetMMP(w http.ResponseWriter, r *http.Request) {
+	status, err := s.engine.ResetMMP(authenticatedTrader(r))
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, status)
+}
+
 // handleGetTraderPositions returns a trader's positions (public - transparency!)
 func (s *Server) handleGetTraderPositions(w http.ResponseWriter, r *http.Request) {
 	traderIDStr := chi.URLParam(r, "traderID")
@@ -217,7 +618,22 @@ func (s *Server) handleGetTraderPositions(w http.ResponseWriter, r *http.Request
 		positions = append(positions, pos)
 	}
 
-	respondJSON(w, http.StatusOK, positions)
+	respondJSON(w, http.StatusOK, s.withHealth(positions))
+}
+
+// withHealth fills in each position's margin ratio and distance to
+// liquidation at its instrument's mark price
+func (s *Server) withHealth(positions []*domain.Position) []*domain.Position {
+	marks := make(map[string]decimal.Decimal)
+	for _, pos := range positions {
+		mark, ok := marks[pos.Instrument]
+		if !ok {
+			mark = s.engine.GetMarkPrice(pos.Instrument)
+			marks[pos.Instrument] = mark
+		}
+		pos.ComputeHealth(mark)
+	}
+	return positions
 }
 
 // handleGetTraderTrades returns a trader's trade history (public - transparency!)
@@ -241,6 +657,97 @@ func (s *Server) handleGetTraderTrades(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, trades)
 }
 
+// handleGetTraderOrders returns a trader's open orders (public). With
+// ?status= it returns only orders in that status; filled and cancelled
+// orders come from history, newest first.
+func (s *Server) handleGetTraderOrders(w http.ResponseWriter, r *http.Request) {
+	traderID, err := uuid.Parse(chi.URLParam(r, "traderID"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid trader ID")
+		return
+	}
+
+	status := domain.OrderStatus(r.URL.Query().Get("status"))
+	if status == "" {
+		respondJSON(w, http.StatusOK, s.engine.GetOpenOrders(traderID, "R.index"))
+		return
+	}
+	if !status.IsValid() {
+		respondError(w, http.StatusBadRequest, "invalid status")
+		return
+	}
+
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	orders, err := s.engine.GetTraderOrders(traderID, "R.index", status, limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, orders)
+}
+
+// handleGetTraderMakerStats returns fill rate and queue time for a trader's resting orders (public)
+func (s *Server) handleGetTraderMakerStats(w http.ResponseWriter, r *http.Request) {
+	traderIDStr := chi.URLParam(r, "traderID")
+	traderID, err := uuid.Parse(traderIDStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid trader ID")
+		return
+	}
+
+	stats, err := s.engine.GetMakerStats(traderID, "R.index")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, stats)
+}
+
+// handleGetTraderExposure aggregates a trader's positions across all instruments
+func (s *Server) handleGetTraderExposure(w http.ResponseWriter, r *http.Request) {
+	traderID, err := uuid.Parse(chi.URLParam(r, "traderID"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid trader ID")
+		return
+	}
+
+	exposure := s.engine.GetTraderExposure(traderID)
+	if exposure == nil {
+		respondError(w, http.StatusNotFound, "trader not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, exposure)
+}
+
+// handleGetPositionLifecycle reconstructs the first position a trader opened
+// since ?from= (default: 24 hours ago), trade by trade (public - transparency!)
+func (s *Server) handleGetPositionLifecycle(w http.ResponseWriter, r *http.Request) {
+	traderID, err := uuid.Parse(chi.URLParam(r, "traderID"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid trader ID")
+		return
+	}
+
+	from := parseTimeParam(r, "from", time.Now().Add(-24*time.Hour))
+	lifecycle, err := s.engine.GetPositionLifecycle(traderID, "R.index", from)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if lifecycle == nil {
+		respondError(w, http.StatusNotFound, "no position opened since "+from.Format(time.RFC3339))
+		return
+	}
+
+	respondJSON(w, http.StatusOK, lifecycle)
+}
+
 // handleGetOrderBook returns the order book (public)
 func (s *Server) handleGetOrderBook(w http.ResponseWriter, r *http.Request) {
 	symbol := chi.URLParam(r, "symbol")
@@ -262,98 +769,459 @@ func (s *Server) handleGetOrderBook(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, book)
 }
 
+// handleGetInstruments lists the registered instruments (public)
+func (s *Server) handleGetInstruments(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, s.engine.GetInstruments())
+}
+
+// instrumentParam returns the {symbol} URL parameter, responding 404 and
+// returning false if no such instrument is registered
+func (s *Server) instrumentParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	symbol := chi.URLParam(r, "symbol")
+	if !s.engine.HasInstrument(symbol) {
+		respondError(w, http.StatusNotFound, "unknown instrument: "+symbol)
+		return "", false
+	}
+	return symbol, true
+}
+
 // handleGetPositions returns all positions for an instrument (public - transparency!)
 func (s *Server) handleGetPositions(w http.ResponseWriter, r *http.Request) {
-	symbol := chi.URLParam(r, "symbol")
+	symbol, ok := s.instrumentParam(w, r)
+	if !ok {
+		return
+	}
 	positions := s.engine.GetAllPositions(symbol)
-	respondJSON(w, http.StatusOK, positions)
+	respondJSON(w, http.StatusOK, s.withHealth(positions))
 }
 
 // handleGetOpenInterest returns OI breakdown (the key transparency feature!)
 func (s *Server) handleGetOpenInterest(w http.ResponseWriter, r *http.Request) {
-	symbol := chi.URLParam(r, "symbol")
+	symbol, ok := s.instrumentParam(w, r)
+	if !ok {
+		return
+	}
 	oi := s.engine.GetOpenInterestBreakdown(symbol)
 	respondJSON(w, http.StatusOK, oi)
 }
 
 // handleSubmitOrder submits a new order
 func (s *Server) handleSubmitOrder(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		TraderID   string `json:"trader_id"`
-		Instrument string `json:"instrument"`
-		Side       string `json:"side"`
-		Type       string `json:"type"`
-		Price      string `json:"price"`
-		Size       string `json:"size"`
-		Leverage   int    `json:"leverage"`
+	order, msg := decodeOrderRequest(r, authenticatedTrader(r))
+	if msg != "" {
+		respondError(w, http.StatusBadRequest, msg)
+		return
 	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "invalid request body")
+	if !s.acquireInFlight(order.TraderID) {
+		respondError(w, http.StatusTooManyRequests, "too many orders in flight")
 		return
 	}
+	defer s.releaseInFlight(order.TraderID)
 
-	traderID, err := uuid.Parse(req.TraderID)
+	trades, err := s.engine.SubmitOrder(order)
 	if err != nil {
-		respondError(w, http.StatusBadRequest, "invalid trader_id")
+		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	price, err := decimal.NewFromString(req.Price)
-	if err != nil && req.Type == "limit" {
-		respondError(w, http.StatusBadRequest, "invalid price")
+	// Broadcast trades via WebSocket
+	for _, trade := range trades {
+		s.hub.BroadcastTrade(trade)
+	}
+
+	s.simulateAckLatency(r)
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"order":  order,
+		"trades": trades,
+	})
+}
+
+// handleSimulateOrder previews what an order would do without submitting it
+func (s *Server) handleSimulateOrder(w http.ResponseWriter, r *http.Request) {
+	order, msg := decodeOrderRequest(r, authenticatedTrader(r))
+	if msg != "" {
+		respondError(w, http.StatusBadRequest, msg)
 		return
 	}
 
-	size, err := decimal.NewFromString(req.Size)
-	if err != nil || size.LessThanOrEqual(decimal.Zero) {
-		respondError(w, http.StatusBadRequest, "invalid size")
+	sim, err := s.engine.SimulateOrder(order)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	respondJSON(w, http.StatusOK, sim)
+}
 
-	order := &domain.Order{
-		TraderID:   traderID,
-		Instrument: req.Instrument,
-		Side:       domain.Side(req.Side),
-		Type:       domain.OrderType(req.Type),
-		Price:      price,
-		Size:       size,
-		Leverage:   req.Leverage,
+// handleReplaceOrder atomically cancels a resting order and submits a new one
+func (s *Server) handleReplaceOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "orderID"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid order ID")
+		return
 	}
 
-	trades, err := s.engine.SubmitOrder(order)
+	order, msg := decodeOrderRequest(r, authenticatedTrader(r))
+	if msg != "" {
+		respondError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if !s.acquireInFlight(order.TraderID) {
+		respondError(w, http.StatusTooManyRequests, "too many orders in flight")
+		return
+	}
+	defer s.releaseInFlight(order.TraderID)
+
+	trades, err := s.engine.CancelReplace(orderID, order)
 	if err != nil {
 		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Broadcast trades via WebSocket
 	for _, trade := range trades {
 		s.hub.BroadcastTrade(trade)
 	}
 
+	s.simulateAckLatency(r)
 	respondJSON(w, http.StatusCreated, map[string]interface{}{
-		"order":  order,
-		"trades": trades,
+		"replaced_order_id": orderID,
+		"order":             order,
+		"trades":            trades,
 	})
 }
 
-// handleCancelOrder cancels an existing order
-func (s *Server) handleCancelOrder(w http.ResponseWriter, r *http.Request) {
-	orderIDStr := chi.URLParam(r, "orderID")
-	orderID, err := uuid.Parse(orderIDStr)
+// handleReduceOrder shrinks a resting order while keeping its queue priority
+func (s *Server) handleReduceOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "orderID"))
 	if err != nil {
 		respondError(w, http.StatusBadRequest, "invalid order ID")
 		return
 	}
 
-	instrument := r.URL.Query().Get("instrument")
-	if instrument == "" {
+	var req struct {
+		Instrument string `json:"instrument"`
+		ReduceBy   string `json:"reduce_by"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Instrument == "" {
 		respondError(w, http.StatusBadRequest, "instrument is required")
 		return
 	}
 
-	if err := s.engine.CancelOrder(orderID, instrument); err != nil {
+	reduceBy, err := decimal.NewFromString(req.ReduceBy)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid reduce_by")
+		return
+	}
+
+	order, err := s.engine.ReduceOrder(authenticatedTrader(r), orderID, req.Instrument, reduceBy)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, order)
+}
+
+// handleAmendOrder changes a resting order's price and/or size. A size
+// decrease alone keeps queue priority; anything else re-queues the order.
+func (s *Server) handleAmendOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "orderID"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid order ID")
+		return
+	}
+
+	var req struct {
+		Instrument string `json:"instrument"`
+		Price      string `json:"price"` // Optional: unchanged if empty
+		Size       string `json:"size"`  // Optional: new total size including what has filled
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Instrument == "" {
+		req.Instrument = domain.RIndexSymbol
+	}
+	if req.Price == "" && req.Size == "" {
+		respondError(w, http.StatusBadRequest, "price or size is required")
+		return
+	}
+
+	var price, size decimal.Decimal
+	if req.Price != "" {
+		if price, err = decimal.NewFromString(req.Price); err != nil || !price.IsPositive() {
+			respondError(w, http.StatusBadRequest, "invalid price")
+			return
+		}
+	}
+	if req.Size != "" {
+		if size, err = decimal.NewFromString(req.Size); err != nil || !size.IsPositive() {
+			respondError(w, http.StatusBadRequest, "invalid size")
+			return
+		}
+	}
+
+	traderID := authenticatedTrader(r)
+	if !s.acquireInFlight(traderID) {
+		respondError(w, http.StatusTooManyRequests, "too many orders in flight")
+		return
+	}
+	defer s.releaseInFlight(traderID)
+
+	order, trades, err := s.engine.AmendOrder(traderID, orderID, req.Instrument, price, size)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	for _, trade := range trades {
+		s.hub.BroadcastTrade(trade)
+	}
+
+	s.simulateAckLatency(r)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"order":  order,
+		"trades": trades,
+	})
+}
+
+// orderRequest is the request body describing a new order. Orders are
+// always placed for the authenticated trader; a trader_id in the body is
+// ignored.
+type orderRequest struct {
+	Instrument  string `json:"instrument"`
+	Side        string `json:"side"`
+	Type        string `json:"type"`
+	Price       string `json:"price"`
+	StopPrice   string `json:"stop_price"`
+	Size        string `json:"size"`
+	DisplaySize string `json:"display_size"`          // Optional iceberg slice shown on the book
+	PostOnly    bool   `json:"post_only"`             // Reject instead of taking liquidity
+	ReduceOnly  bool   `json:"reduce_only"`           // Only shrink the trader's position
+	TimeInForce string `json:"time_in_force"`         // GTC (default), IOC or FOK
+	STP         string `json:"self_trade_prevention"` // Optional override of the engine's self-trade mode
+	Slippage    int    `json:"max_slippage_bps"`      // Optional market/stop price band, overriding the engine default
+	Leverage    int    `json:"leverage"`
+	SentAt      int64  `json:"sent_at"` // Optional client send time (Unix ms) for the stale market order check
+}
+
+// toOrder validates the request into an order for traderID, returning a
+// client error message on failure
+func (req *orderRequest) toOrder(traderID uuid.UUID) (*domain.Order, string) {
+	price, err := decimal.NewFromString(req.Price)
+	if err != nil && req.Type == "limit" {
+		return nil, "invalid price"
+	}
+
+	stopPrice, err := decimal.NewFromString(req.StopPrice)
+	if (err != nil || !stopPrice.IsPositive()) && req.Type == "stop" {
+		return nil, "invalid stop_price"
+	}
+
+	size, err := decimal.NewFromString(req.Size)
+	if err != nil || size.LessThanOrEqual(decimal.Zero) {
+		return nil, "invalid size"
+	}
+
+	var displaySize decimal.Decimal
+	if req.DisplaySize != "" {
+		if displaySize, err = decimal.NewFromString(req.DisplaySize); err != nil {
+			return nil, "invalid display_size"
+		}
+	}
+
+	order := &domain.Order{
+		TraderID:            traderID,
+		Instrument:          req.Instrument,
+		Side:                domain.Side(req.Side),
+		Type:                domain.OrderType(req.Type),
+		Price:               price,
+		StopPrice:           stopPrice,
+		Size:                size,
+		DisplaySize:         displaySize,
+		PostOnly:            req.PostOnly,
+		ReduceOnly:          req.ReduceOnly,
+		TimeInForce:         domain.TimeInForce(req.TimeInForce),
+		SelfTradePrevention: domain.SelfTradePrevention(req.STP),
+		MaxSlippageBps:      req.Slippage,
+		Leverage:            req.Leverage,
+	}
+	if req.SentAt > 0 {
+		sentAt := time.UnixMilli(req.SentAt)
+		order.SentAt = &sentAt
+	}
+	return order, ""
+}
+
+// decodeOrderRequest parses an order body, returning a client error message on failure
+func decodeOrderRequest(r *http.Request, traderID uuid.UUID) (*domain.Order, string) {
+	var req orderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, "invalid request body"
+	}
+	return req.toOrder(traderID)
+}
+
+// handlePlaceOCO submits two orders as a one-cancels-other pair
+func (s *Server) handlePlaceOCO(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Orders []orderRequest `json:"orders"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(req.Orders) != 2 {
+		respondError(w, http.StatusBadRequest, "an OCO pair requires exactly two orders")
+		return
+	}
+
+	legs := make([]*domain.Order, 2)
+	for i := range req.Orders {
+		order, msg := req.Orders[i].toOrder(authenticatedTrader(r))
+		if msg != "" {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("orders[%d]: %s", i, msg))
+			return
+		}
+		legs[i] = order
+	}
+	if !s.acquireInFlight(legs[0].TraderID) {
+		respondError(w, http.StatusTooManyRequests, "too many orders in flight")
+		return
+	}
+	defer s.releaseInFlight(legs[0].TraderID)
+
+	trades, err := s.engine.SubmitOCO(legs[0], legs[1])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	for _, trade := range trades {
+		s.hub.BroadcastTrade(trade)
+	}
+
+	s.simulateAckLatency(r)
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"group_id": legs[0].OCOGroupID,
+		"orders":   legs,
+		"trades":   trades,
+	})
+}
+
+// maxBatchOrders is the most orders one batch submission may carry
+const maxBatchOrders = 100
+
+// batchOrderResult is one entry of a batch submission's response
+type batchOrderResult struct {
+	Order  *domain.Order   `json:"order,omitempty"`
+	Trades []*domain.Trade `json:"trades,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// handleSubmitOrders submits a batch of orders in one engine pass, so a
+// market maker can lay down a quote ladder against a consistent book.
+// Results come back in request order; one order failing does not fail the
+// batch.
+func (s *Server) handleSubmitOrders(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Orders []orderRequest `json:"orders"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(req.Orders) == 0 || len(req.Orders) > maxBatchOrders {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("a batch requires 1 to %d orders", maxBatchOrders))
+		return
+	}
+
+	traderID := authenticatedTrader(r)
+	results := make([]batchOrderResult, len(req.Orders))
+	var orders []*domain.Order
+	var index []int // Position in the request of each order passed to the engine
+	for i := range req.Orders {
+		order, msg := req.Orders[i].toOrder(traderID)
+		if msg != "" {
+			results[i].Error = msg
+			continue
+		}
+		orders = append(orders, order)
+		index = append(index, i)
+	}
+
+	if !s.acquireInFlight(traderID) {
+		respondError(w, http.StatusTooManyRequests, "too many orders in flight")
+		return
+	}
+	defer s.releaseInFlight(traderID)
+
+	for j, result := range s.engine.SubmitOrders(orders) {
+		i := index[j]
+		if result.Err != nil {
+			results[i].Error = result.Err.Error()
+			continue
+		}
+		results[i].Order = result.Order
+		results[i].Trades = result.Trades
+		for _, trade := range result.Trades {
+			s.hub.BroadcastTrade(trade)
+		}
+	}
+
+	s.simulateAckLatency(r)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"results": results,
+	})
+}
+
+// handleGetOrder returns an order's fill state and status. The instrument
+// defaults to R.index.
+func (s *Server) handleGetOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "orderID"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid order ID")
+		return
+	}
+
+	instrument := r.URL.Query().Get("instrument")
+	if instrument == "" {
+		instrument = domain.RIndexSymbol
+	}
+
+	order, err := s.engine.GetOrder(orderID, instrument)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if order == nil {
+		respondError(w, http.StatusNotFound, "order not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, order)
+}
+
+// handleCancelOrder cancels an existing order
+func (s *Server) handleCancelOrder(w http.ResponseWriter, r *http.Request) {
+	orderIDStr := chi.URLParam(r, "orderID")
+	orderID, err := uuid.Parse(orderIDStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid order ID")
+		return
+	}
+
+	instrument := r.URL.Query().Get("instrument")
+	if instrument == "" {
+		respondError(w, http.StatusBadRequest, "instrument is required")
+		return
+	}
+
+	if err := s.engine.CancelOrder(authenticatedTrader(r), orderID, instrument); err != nil {
 		respondError(w, http.StatusNotFound, err.Error())
 		return
 	}
@@ -383,7 +1251,7 @@ func (s *Server) handleGetMarketOrderBook(w http.ResponseWriter, r *http.Request
 
 func (s *Server) handleGetMarketPositions(w http.ResponseWriter, r *http.Request) {
 	positions := s.engine.GetAllPositions("R.index")
-	respondJSON(w, http.StatusOK, positions)
+	respondJSON(w, http.StatusOK, s.withHealth(positions))
 }
 
 func (s *Server) handleGetMarketOpenInterest(w http.ResponseWriter, r *http.Request) {
@@ -391,6 +1259,292 @@ func (s *Server) handleGetMarketOpenInterest(w http.ResponseWriter, r *http.Requ
 	respondJSON(w, http.StatusOK, oi)
 }
 
+// handleGetMarketConcentration reports how concentrated open positions are.
+// Optional query param: top (holders counted in top_share, default 10)
+func (s *Server) handleGetMarketConcentration(w http.ResponseWriter, r *http.Request) {
+	top := 10
+	if topStr := r.URL.Query().Get("top"); topStr != "" {
+		t, err := strconv.Atoi(topStr)
+		if err != nil || t < 1 || t > 100 {
+			respondError(w, http.StatusBadRequest, "top must be 1-100")
+			return
+		}
+		top = t
+	}
+
+	respondJSON(w, http.StatusOK, s.engine.GetConcentration("R.index", top))
+}
+
+func (s *Server) handleGetMarketOIHistory(w http.ResponseWriter, r *http.Request) {
+	interval := domain.CandleInterval1h
+	if intervalStr := r.URL.Query().Get("interval"); intervalStr != "" {
+		interval = domain.CandleInterval(intervalStr)
+		if !interval.IsValid() {
+			respondError(w, http.StatusBadRequest, "invalid interval")
+			return
+		}
+	}
+
+	// Default: last 24 hours
+	startTime := parseTimeParam(r, "start", time.Now().Add(-24*time.Hour))
+	endTime := parseTimeParam(r, "end", time.Now())
+
+	history, err := s.engine.GetOIHistory("R.index", interval, startTime, endTime)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, history)
+}
+
+// handleGetMarketHistoricalOI returns open interest as it stood at a past time
+func (s *Server) handleGetMarketHistoricalOI(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("time") == "" {
+		respondError(w, http.StatusBadRequest, "time is required")
+		return
+	}
+	at := parseTimeParam(r, "time", time.Time{})
+	if at.IsZero() {
+		respondError(w, http.StatusBadRequest, "invalid time")
+		return
+	}
+	if at.After(time.Now()) {
+		respondError(w, http.StatusBadRequest, "time must not be in the future")
+		return
+	}
+
+	snap, err := s.engine.GetHistoricalOI("R.index", at)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, snap)
+}
+
+// handleSimulateCascade reports what would be liquidated if the price moved
+// to ?price=, optionally with each liquidation's book impact (?impact=true)
+func (s *Server) handleSimulateCascade(w http.ResponseWriter, r *http.Request) {
+	price, err := decimal.NewFromString(r.URL.Query().Get("price"))
+	if err != nil || !price.IsPositive() {
+		respondError(w, http.StatusBadRequest, "price must be a positive number")
+		return
+	}
+	impact := r.URL.Query().Get("impact") == "true"
+
+	sim, err := s.engine.SimulateCascade("R.index", price, impact)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, sim)
+}
+
+// handleGetLiquidationRisk returns the positions whose liquidation price is
+// within ?threshold= (a fraction of the mark, default 0.02) of the mark, closest first
+func (s *Server) handleGetLiquidationRisk(w http.ResponseWriter, r *http.Request) {
+	threshold := decimal.NewFromFloat(0.02)
+	if v := r.URL.Query().Get("threshold"); v != "" {
+		t, err := decimal.NewFromString(v)
+		if err != nil || !t.IsPositive() || t.GreaterThan(decimal.NewFromInt(1)) {
+			respondError(w, http.StatusBadRequest, "threshold must be a fraction between 0 and 1")
+			return
+		}
+		threshold = t
+	}
+
+	positions, err := s.engine.GetLiquidationRisk("R.index", threshold)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, positions)
+}
+
+// handleGetLiquidationMap returns open interest bucketed by liquidation price
+// within ?band= percent of the mark (default 10), in ?bucket_size= buckets
+// (default: the band split 50 ways)
+func (s *Server) handleGetLiquidationMap(w http.ResponseWriter, r *http.Request) {
+	band := decimal.NewFromInt(10)
+	if v := r.URL.Query().Get("band"); v != "" {
+		b, err := decimal.NewFromString(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid band")
+			return
+		}
+		band = b
+	}
+	bucketSize := decimal.Zero
+	if v := r.URL.Query().Get("bucket_size"); v != "" {
+		b, err := decimal.NewFromString(v)
+		if err != nil || !b.IsPositive() {
+			respondError(w, http.StatusBadRequest, "bucket_size must be a positive number")
+			return
+		}
+		bucketSize = b
+	}
+
+	liqMap, err := s.engine.GetLiquidationMap("R.index", bucketSize, band)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, liqMap)
+}
+
+// handleGetLiquidationRates returns the share of positions opened at each
+// leverage tier that ended in liquidation (?window=, default 168h)
+func (s *Server) handleGetLiquidationRates(w http.ResponseWriter, r *http.Request) {
+	window := 7 * 24 * time.Hour
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			respondError(w, http.StatusBadRequest, "window must be a positive duration such as 24h")
+			return
+		}
+		window = d
+	}
+
+	rates, err := s.engine.GetLiquidationRatesByTier("R.index", window)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, rates)
+}
+
+// handleExport streams traders, trades, liquidations and positions for a
+// time range as a ZIP of CSV files
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	if s.exportSource == nil {
+		respondError(w, http.StatusServiceUnavailable, "export requires a database")
+		return
+	}
+
+	// Default: last 24 hours
+	startTime := parseTimeParam(r, "start", time.Now().Add(-24*time.Hour))
+	endTime := parseTimeParam(r, "end", time.Now())
+
+	const stamp = "20060102T150405Z"
+	filename := fmt.Sprintf("tradere-export-%s-%s.zip", startTime.UTC().Format(stamp), endTime.UTC().Format(stamp))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Cache-Control", "no-store")
+
+	// The archive is streamed, so a failure part way through can only truncate it
+	if err := export.WriteZIP(w, s.exportSource, startTime, endTime); err != nil {
+		slog.Error("Error writing dataset export", "error", err)
+	}
+}
+
+// handleExportCandles streams saved R.index candles for a range and interval
+// as CSV, for backtesting (public)
+func (s *Server) handleExportCandles(w http.ResponseWriter, r *http.Request) {
+	if s.candleSource == nil {
+		respondError(w, http.StatusServiceUnavailable, "candle export requires a database")
+		return
+	}
+	interval, ok := parseCandleInterval(r, domain.CandleInterval1h)
+	if !ok {
+		respondError(w, http.StatusBadRequest, "unknown interval: use 1m, 5m, 15m, 1h, 4h or 1d")
+		return
+	}
+
+	// Default: last 30 days
+	startTime := parseTimeParam(r, "start", time.Now().Add(-30*24*time.Hour))
+	endTime := parseTimeParam(r, "end", time.Now())
+	if endTime.Before(startTime) {
+		respondError(w, http.StatusBadRequest, "end must not be before start")
+		return
+	}
+
+	limit := 10000
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 50000 {
+			limit = l
+		}
+	}
+
+	filename := fmt.Sprintf("%s-%s-candles.csv", domain.RIndexSymbol, interval)
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	// Rows are streamed, so a failure part way through can only truncate the file
+	if err := export.WriteCandlesCSV(w, s.candleSource, domain.RIndexSymbol, interval, startTime, endTime, limit); err != nil {
+		slog.Error("Error writing candle CSV", "error", err)
+	}
+}
+
+// handleGetLeaderboard ranks all traders by an all-time metric
+func (s *Server) handleGetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	by := domain.LeaderboardSortPnL
+	if sortStr := r.URL.Query().Get("sort"); sortStr != "" {
+		by = domain.LeaderboardSort(sortStr)
+		if !by.IsValid() {
+			respondError(w, http.StatusBadRequest, "sort must be pnl, roi, volume or max_leverage")
+			return
+		}
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 500 {
+			limit = l
+		}
+	}
+
+	board, err := s.engine.GetLeaderboard(by, limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, board)
+}
+
+// handleGetPeriodLeaderboard ranks traders by P&L realized within a time range
+func (s *Server) handleGetPeriodLeaderboard(w http.ResponseWriter, r *http.Request) {
+	// Default: last 7 days
+	startTime := parseTimeParam(r, "start", time.Now().Add(-7*24*time.Hour))
+	endTime := parseTimeParam(r, "end", time.Now())
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 500 {
+			limit = l
+		}
+	}
+
+	board, err := s.engine.GetPnLLeaderboard(startTime, endTime, limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, board)
+}
+
+// handleGetInsuranceFund returns the fund balance, its target and fee diversion policy
+func (s *Server) handleGetInsuranceFund(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, s.engine.GetInsuranceFundStatus())
+}
+
+// handleGetInsuranceFundHistory returns every insurance fund balance change in a time range
+func (s *Server) handleGetInsuranceFundHistory(w http.ResponseWriter, r *http.Request) {
+	// Default: last 24 hours
+	startTime := parseTimeParam(r, "start", time.Now().Add(-24*time.Hour))
+	endTime := parseTimeParam(r, "end", time.Now())
+
+	history, err := s.engine.GetInsuranceFundHistory(startTime, endTime)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, history)
+}
+
 func (s *Server) handleGetMarketTrades(w http.ResponseWriter, r *http.Request) {
 	limitStr := r.URL.Query().Get("limit")
 	limit := 50
@@ -400,8 +1554,34 @@ func (s *Server) handleGetMarketTrades(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	trades := s.engine.GetRecentTrades("R.index", limit)
-	respondJSON(w, http.StatusOK, trades)
+	cursor, forward, paged, err := parseCursor(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !paged {
+		trades := s.engine.GetRecentTrades("R.index", limit)
+		respondJSON(w, http.StatusOK, trades)
+		return
+	}
+
+	var trades []*domain.Trade
+	if forward {
+		trades, err = s.engine.GetTradesAfter("R.index", cursor.timestamp, cursor.id, limit)
+	} else {
+		trades, err = s.engine.GetTradesBefore("R.index", cursor.timestamp, cursor.id, limit)
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	page := tradePage{Trades: trades}
+	if len(trades) == limit {
+		last := trades[len(trades)-1]
+		page.NextCursor = formatCursor(last.Timestamp, last.ID)
+	}
+	respondJSON(w, http.StatusOK, page)
 }
 
 func (s *Server) handleGetMarketLiquidations(w http.ResponseWriter, r *http.Request) {
@@ -413,8 +1593,135 @@ func (s *Server) handleGetMarketLiquidations(w http.ResponseWriter, r *http.Requ
 		}
 	}
 
-	liquidations := s.engine.GetRecentLiquidations("R.index", limit)
-	respondJSON(w, http.StatusOK, liquidations)
+	cursor, forward, paged, err := parseCursor(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !paged {
+		liquidations := s.engine.GetRecentLiquidations("R.index", limit)
+		respondJSON(w, http.StatusOK, liquidations)
+		return
+	}
+
+	var liquidations []*domain.Liquidation
+	if forward {
+		liquidations, err = s.engine.GetLiquidationsAfter("R.index", cursor.timestamp, cursor.id, limit)
+	} else {
+		liquidations, err = s.engine.GetLiquidationsBefore("R.index", cursor.timestamp, cursor.id, limit)
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	page := liquidationPage{Liquidations: liquidations}
+	if len(liquidations) == limit {
+		last := liquidations[len(liquidations)-1]
+		page.NextCursor = formatCursor(last.Timestamp, last.ID)
+	}
+	respondJSON(w, http.StatusOK, page)
+}
+
+// tradePage is one page of trade history. NextCursor is empty on the last page.
+type tradePage struct {
+	Trades     []*domain.Trade `json:"trades"`
+	NextCursor string          `json:"next_cursor"`
+}
+
+// liquidationPage is one page of liquidation history
+type liquidationPage struct {
+	Liquidations []*domain.Liquidation `json:"liquidations"`
+	NextCursor   string                `json:"next_cursor"`
+}
+
+// historyCursor is a position in trade or liquidation history. The id
+// breaks ties between entries sharing a timestamp.
+type historyCursor struct {
+	timestamp time.Time
+	id        uuid.UUID
+}
+
+// parseCursor reads a before or after history cursor: a "timestamp,id" pair
+// (as returned in next_cursor), or a bare RFC3339 time or Unix milliseconds
+// that excludes every entry at that instant. An empty before, or a limit
+// with neither param, starts from the newest entry. paged is false when none
+// of before, after or limit is given.
+func parseCursor(r *http.Request) (cursor historyCursor, forward, paged bool, err error) {
+	q := r.URL.Query()
+	name := "before"
+	switch {
+	case q.Has("before") && q.Has("after"):
+		return historyCursor{}, false, false, fmt.Errorf("use before or after, not both")
+	case q.Has("after"):
+		name, forward = "after", true
+	case !q.Has("before") && !q.Has("limit"):
+		return historyCursor{}, false, false, nil
+	}
+
+	str := q.Get(name)
+	if str == "" && !forward {
+		return historyCursor{timestamp: time.Now().Add(time.Nanosecond)}, false, true, nil
+	}
+	if forward {
+		cursor.id = uuid.Max
+	}
+	if ts, idStr, ok := strings.Cut(str, ","); ok {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return historyCursor{}, false, false, fmt.Errorf("invalid %s: bad cursor id", name)
+		}
+		str, cursor.id = ts, id
+	}
+	if t, err := time.Parse(time.RFC3339, str); err == nil {
+		cursor.timestamp = t
+		return cursor, forward, true, nil
+	}
+	if ms, err := strconv.ParseInt(str, 10, 64); err == nil {
+		cursor.timestamp = time.UnixMilli(ms)
+		return cursor, forward, true, nil
+	}
+	return historyCursor{}, false, false, fmt.Errorf("invalid %s: use next_cursor, RFC3339 or Unix milliseconds", name)
+}
+
+// formatCursor encodes a history cursor at full precision, with the id of
+// the entry it points at
+func formatCursor(t time.Time, id uuid.UUID) string {
+	return t.UTC().Format(time.RFC3339Nano) + "," + id.String()
+}
+
+// handleGetLargestLiquidations ranks the biggest liquidations ever, by loss
+// (default) or notional
+func (s *Server) handleGetLargestLiquidations(w http.ResponseWriter, r *http.Request) {
+	by := domain.LiquidationRankingLoss
+	if byStr := r.URL.Query().Get("by"); byStr != "" {
+		by = domain.LiquidationRanking(byStr)
+		if !by.IsValid() {
+			respondError(w, http.StatusBadRequest, "by must be loss or notional")
+			return
+		}
+	}
+
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	largest, err := s.engine.GetLargestLiquidations("R.index", by, limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, largest)
+}
+
+// handleGetADLQueue returns the auto-deleveraging ranking of profitable
+// positions, first to be closed first
+func (s *Server) handleGetADLQueue(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, s.engine.GetADLQueue("R.index"))
 }
 
 func (s *Server) handleGetMarketStats(w http.ResponseWriter, r *http.Request) {
@@ -422,23 +1729,26 @@ func (s *Server) handleGetMarketStats(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, stats)
 }
 
+// handleGetMarketLiquidity returns the composite liquidity score and its components
+func (s *Server) handleGetMarketLiquidity(w http.ResponseWriter, r *http.Request) {
+	score, err := s.engine.GetLiquidityScore("R.index")
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, score)
+}
+
+// handleGetMarketDegenIndex returns the 0-100 degen gauge and its components
+func (s *Server) handleGetMarketDegenIndex(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, s.engine.GetDegenIndex("R.index"))
+}
+
 func (s *Server) handleGetMarketCandles(w http.ResponseWriter, r *http.Request) {
-	// Parse interval (default: 1m)
-	intervalStr := r.URL.Query().Get("interval")
-	interval := domain.CandleInterval1m
-	switch intervalStr {
-	case "1m":
-		interval = domain.CandleInterval1m
-	case "5m":
-		interval = domain.CandleInterval5m
-	case "15m":
-		interval = domain.CandleInterval15m
-	case "1h":
-		interval = domain.CandleInterval1h
-	case "4h":
-		interval = domain.CandleInterval4h
-	case "1d":
-		interval = domain.CandleInterval1d
+	interval, ok := parseCandleInterval(r, domain.CandleInterval1m)
+	if !ok {
+		respondError(w, http.StatusBadRequest, "unknown interval: use 1m, 5m, 15m, 1h, 4h or 1d")
+		return
 	}
 
 	// Parse limit (default: 100)
@@ -454,8 +1764,97 @@ func (s *Server) handleGetMarketCandles(w http.ResponseWriter, r *http.Request)
 	respondJSON(w, http.StatusOK, candles)
 }
 
+// Admin endpoints
+
+// handleGetDebugState dumps engine internals for diagnostics (read-only)
+func (s *Server) handleGetDebugState(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, s.engine.GetDebugState())
+}
+
+// handleGetEffectiveConfig returns the configuration and runtime state the engine is using
+func (s *Server) handleGetEffectiveConfig(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, s.engine.GetEffectiveConfig())
+}
+
+// handleSetMarketState moves the market to a new phase (e.g. halts trading)
+func (s *Server) handleSetMarketState(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		State  domain.MarketState `json:"state"`
+		Reason string             `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = "operator override"
+	}
+
+	if err := s.engine.SetMarketState(req.State, req.Reason); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	state, reason := s.engine.GetMarketState()
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"market_state": state,
+		"reason":       reason,
+	})
+}
+
+// handleAdjustInsuranceFund tops up or withdraws from the insurance fund
+func (s *Server) handleAdjustInsuranceFund(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Delta string `json:"delta"`
+		Note  string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	delta, err := decimal.NewFromString(req.Delta)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid delta")
+		return
+	}
+
+	event, err := s.engine.AdjustInsuranceFund(delta, req.Note)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, event)
+}
+
 // Historical data endpoints
 
+// parseCandleInterval reads the interval query param, returning def if it is
+// absent and false if it is not a known interval
+func parseCandleInterval(r *http.Request, def domain.CandleInterval) (domain.CandleInterval, bool) {
+	interval := domain.CandleInterval(r.URL.Query().Get("interval"))
+	if interval == "" {
+		return def, true
+	}
+	return interval, interval.IsValid()
+}
+
+// parseTimeParam reads an RFC3339 or unix-millisecond query param, returning def if absent or invalid
+func parseTimeParam(r *http.Request, name string, def time.Time) time.Time {
+	str := r.URL.Query().Get(name)
+	if str == "" {
+		return def
+	}
+	if t, err := time.Parse(time.RFC3339, str); err == nil {
+		return t
+	}
+	if ts, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return time.UnixMilli(ts)
+	}
+	return def
+}
+
 func (s *Server) handleGetHistoricalTrades(w http.ResponseWriter, r *http.Request) {
 	// Parse time range
 	startStr := r.URL.Query().Get("start")
@@ -484,6 +1883,10 @@ func (s *Server) handleGetHistoricalTrades(w http.ResponseWriter, r *http.Reques
 	if endTime.IsZero() {
 		endTime = time.Now()
 	}
+	if endTime.Before(startTime) {
+		respondError(w, http.StatusBadRequest, "end must not be before start")
+		return
+	}
 
 	// Parse limit
 	limitStr := r.URL.Query().Get("limit")
@@ -494,27 +1897,46 @@ func (s *Server) handleGetHistoricalTrades(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
+	// Optional price band
+	minStr, maxStr := r.URL.Query().Get("min_price"), r.URL.Query().Get("max_price")
+	if minStr != "" || maxStr != "" {
+		minPrice, maxPrice := decimal.Zero, decimal.New(1, 18)
+		var err error
+		if minStr != "" {
+			if minPrice, err = decimal.NewFromString(minStr); err != nil {
+				respondError(w, http.StatusBadRequest, "invalid min_price")
+				return
+			}
+		}
+		if maxStr != "" {
+			if maxPrice, err = decimal.NewFromString(maxStr); err != nil {
+				respondError(w, http.StatusBadRequest, "invalid max_price")
+				return
+			}
+		}
+		if minPrice.GreaterThan(maxPrice) {
+			respondError(w, http.StatusBadRequest, "min_price must not exceed max_price")
+			return
+		}
+
+		trades, err := s.engine.GetTradesByPriceRange("R.index", minPrice, maxPrice, startTime, endTime, limit)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondJSON(w, http.StatusOK, trades)
+		return
+	}
+
 	trades := s.engine.GetHistoricalTrades("R.index", startTime, endTime, limit)
 	respondJSON(w, http.StatusOK, trades)
 }
 
 func (s *Server) handleGetHistoricalCandles(w http.ResponseWriter, r *http.Request) {
-	// Parse interval
-	intervalStr := r.URL.Query().Get("interval")
-	interval := domain.CandleInterval1h
-	switch intervalStr {
-	case "1m":
-		interval = domain.CandleInterval1m
-	case "5m":
-		interval = domain.CandleInterval5m
-	case "15m":
-		interval = domain.CandleInterval15m
-	case "1h":
-		interval = domain.CandleInterval1h
-	case "4h":
-		interval = domain.CandleInterval4h
-	case "1d":
-		interval = domain.CandleInterval1d
+	interval, ok := parseCandleInterval(r, domain.CandleInterval1h)
+	if !ok {
+		respondError(w, http.StatusBadRequest, "unknown interval: use 1m, 5m, 15m, 1h, 4h or 1d")
+		return
 	}
 
 	// Parse time range
@@ -544,6 +1966,10 @@ func (s *Server) handleGetHistoricalCandles(w http.ResponseWriter, r *http.Reque
 	if endTime.IsZero() {
 		endTime = time.Now()
 	}
+	if endTime.Before(startTime) {
+		respondError(w, http.StatusBadRequest, "end must not be before start")
+		return
+	}
 
 	// Parse limit
 	limitStr := r.URL.Query().Get("limit")
@@ -554,11 +1980,15 @@ func (s *Server) handleGetHistoricalCandles(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
-	candles := s.engine.GetHistoricalCandles("R.index", interval, startTime, endTime, limit)
+	candles, err := s.engine.GetHistoricalCandles("R.index", interval, startTime, endTime, limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 	respondJSON(w, http.StatusOK, candles)
 }
 
-// Auth handlers (simplified - no real auth for now)
+// Auth handlers
 
 func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 	var req struct {
@@ -577,25 +2007,48 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Type == "" {
-		req.Type = domain.TraderTypeHuman
+	traderType, ok := s.newTraderType(w, r, req.Type)
+	if !ok {
+		return
+	}
+
+	if s.engine.GetTraderByUsername(req.Username) != nil {
+		respondError(w, http.StatusConflict, engine.ErrUsernameTaken.Error())
+		return
+	}
+
+	hash, err := s.auth.HashPassword(req.Password)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
 
 	trader := &domain.Trader{
-		ID:        uuid.New(),
-		Username:  req.Username,
-		Type:      req.Type,
-		Balance:   decimal.NewFromInt(10000), // Starting balance
-		CreatedAt: time.Now(),
-		TotalPnL:  decimal.Zero,
+		ID:           uuid.New(),
+		Username:     req.Username,
+		Type:         traderType,
+		PasswordHash: hash,
+		Balance:      s.engine.StartingBalance(traderType),
+		CreatedAt:    time.Now(),
+		TotalPnL:     decimal.Zero,
 	}
 
-	s.engine.RegisterTrader(trader)
+	// Registering checks the username again, in case another request took it
+	// while the password was hashed
+	if err := s.engine.RegisterTrader(trader); err != nil {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	token, err := s.auth.GenerateToken(trader.ID, trader.Username)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
-	// Return trader with a simple token (trader ID as token for simplicity)
 	respondJSON(w, http.StatusCreated, map[string]interface{}{
 		"trader": trader,
-		"token":  trader.ID.String(),
+		"token":  token,
 	})
 }
 
@@ -610,17 +2063,36 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Find trader by username
-	traders := s.engine.GetAllTraders()
-	for _, trader := range traders {
-		if trader.Username == req.Username {
-			respondJSON(w, http.StatusOK, map[string]interface{}{
-				"trader": trader,
-				"token":  trader.ID.String(),
-			})
-			return
-		}
+	// Traders created without a password (e.g. bots added via POST /traders)
+	// have no hash, so they never match
+	trader := s.engine.GetTraderByUsername(req.Username)
+	if trader == nil || trader.PasswordHash == "" || !s.auth.VerifyPassword(req.Password, trader.PasswordHash) {
+		respondError(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+
+	token, err := s.auth.GenerateToken(trader.ID, trader.Username)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"trader": trader,
+		"token":  token,
+	})
+}
+
+// handleCreateAPIKey issues the caller a new long-lived API key, revoking any
+// previous one. The plaintext key is only ever returned here.
+func (s *Server) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	key := s.auth.GenerateAPIKey()
+	if err := s.engine.SetTraderAPIKey(authenticatedTrader(r), s.auth.HashAPIKey(key)); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
 
-	respondError(w, http.StatusUnauthorized, "invalid credentials")
+	respondJSON(w, http.StatusCreated, map[string]string{
+		"api_key": key,
+	})
 }
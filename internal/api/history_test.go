@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+func TestMarketTradesPageWhenLimitGiven(t *testing.T) {
+	router, h := newAdminRouter(t)
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+	for _, price := range []string{"1000", "1001", "1002"} {
+		h.MustLimit(maker, domain.SideSell, price, "1")
+	}
+	// One sweep fills all three asks
+	h.MustMarket(taker, domain.SideBuy, "3")
+
+	rec := adminRequest(router, http.MethodGet, "/api/v1/market/trades", nil, false)
+	var recent []*domain.Trade
+	if err := json.Unmarshal(rec.Body.Bytes(), &recent); rec.Code != http.StatusOK || err != nil || len(recent) != 3 {
+		t.Fatalf("unpaged trades = %d %s, want a bare list of 3", rec.Code, rec.Body)
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	path := "/api/v1/market/trades?limit=2"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("paging did not end")
+		}
+		rec := adminRequest(router, http.MethodGet, path, nil, false)
+		var page tradePage
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); rec.Code != http.StatusOK || err != nil {
+			t.Fatalf("GET %s = %d %s, want a page", path, rec.Code, rec.Body)
+		}
+		for _, trade := range page.Trades {
+			if seen[trade.ID] {
+				t.Fatalf("trade %s returned twice", trade.ID)
+			}
+			seen[trade.ID] = true
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		path = "/api/v1/market/trades?limit=2&before=" + url.QueryEscape(page.NextCursor)
+	}
+	if len(seen) != 3 {
+		t.Fatalf("paged through %d trades, want 3", len(seen))
+	}
+
+	for _, query := range []string{"before=2024-01-01T00:00:00Z,not-an-id", "before=yesterday", "before=1&after=2"} {
+		if rec := adminRequest(router, http.MethodGet, "/api/v1/market/trades?"+query, nil, false); rec.Code != http.StatusBadRequest {
+			t.Fatalf("GET ?%s = %d, want 400", query, rec.Code)
+		}
+	}
+}
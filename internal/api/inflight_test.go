@@ -0,0 +1,75 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/thatreguy/trade.re/internal/auth"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+	"github.com/thatreguy/trade.re/internal/ws"
+)
+
+func TestInFlightOrdersCapped(t *testing.T) {
+	const limit, concurrent = 3, 8
+	h := enginetest.NewTestEngine()
+	authn := auth.New("test-secret", 1, 32)
+	s := NewServer(h.Engine, ws.NewHub(), authn, "UTC")
+	s.SetMaxInFlightOrders(limit)
+	// Hold each submission in flight long enough for all of them to arrive
+	s.SetOrderAckDelay(300 * time.Millisecond)
+	router := chi.NewRouter()
+	s.RegisterRoutes(router)
+
+	// submitter returns a function posting one small bid for a new trader
+	submitter := func(username string) func() int {
+		trader := h.AddTrader(username)
+		token, err := authn.GenerateToken(trader.ID, trader.Username)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return func() int {
+			body := []byte(`{"instrument": "R.index", "side": "buy", "type": "limit", "price": "900", "size": "0.1", "leverage": 1}`)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", bytes.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			return rec.Code
+		}
+	}
+	busy := submitter("busy")
+	other := submitter("other")
+
+	codes := make(map[int]int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < concurrent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code := busy()
+			mu.Lock()
+			codes[code]++
+			mu.Unlock()
+		}()
+	}
+
+	// The cap is per trader, so another trader is unaffected
+	time.Sleep(100 * time.Millisecond)
+	if code := other(); code != http.StatusCreated {
+		t.Errorf("other trader's order = %d while the first is at the cap, want 201", code)
+	}
+	wg.Wait()
+
+	if codes[http.StatusCreated] != limit || codes[http.StatusTooManyRequests] != concurrent-limit {
+		t.Fatalf("responses = %v, want %d accepted and %d rejected with 429", codes, limit, concurrent-limit)
+	}
+	// Completed submissions free their slots
+	if code := busy(); code != http.StatusCreated {
+		t.Fatalf("order after the others completed = %d, want 201", code)
+	}
+}
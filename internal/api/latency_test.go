@@ -0,0 +1,61 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/thatreguy/trade.re/internal/auth"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+	"github.com/thatreguy/trade.re/internal/ws"
+)
+
+func TestOrderAckDelay(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	for _, tc := range []struct {
+		name  string
+		delay time.Duration
+	}{
+		{"disabled", 0},
+		{"enabled", delay},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := enginetest.NewTestEngine()
+			authn := auth.New("test-secret", 1, 32)
+			s := NewServer(h.Engine, ws.NewHub(), authn, "UTC")
+			s.SetOrderAckDelay(tc.delay)
+			router := chi.NewRouter()
+			s.RegisterRoutes(router)
+
+			trader := h.AddTrader("trader")
+			token, err := authn.GenerateToken(trader.ID, trader.Username)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := []byte(`{"instrument": "R.index", "side": "buy", "type": "limit", "price": "999", "size": "1", "leverage": 1}`)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", bytes.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+
+			started := time.Now()
+			router.ServeHTTP(rec, req)
+			elapsed := time.Since(started)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("submit = %d: %s", rec.Code, rec.Body)
+			}
+			if tc.delay > 0 && elapsed < tc.delay {
+				t.Fatalf("acknowledged after %s, want at least %s", elapsed, tc.delay)
+			}
+			if tc.delay == 0 && elapsed >= delay {
+				t.Fatalf("acknowledged after %s with the delay off", elapsed)
+			}
+			// The order is on the book before the delayed ack
+			if bids := h.Bids(); len(bids) != 1 {
+				t.Fatalf("bids = %+v, want the order resting", bids)
+			}
+		})
+	}
+}
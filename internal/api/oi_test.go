@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/db"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+func TestOIHistoryBucketsRecordedSnapshots(t *testing.T) {
+	router, h := newAdminRouter(t)
+	database, err := db.NewSQLite(filepath.Join(t.TempDir(), "oi.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	h.Engine.SetDatabase(database)
+
+	get := func(query url.Values) []*domain.OISnapshot {
+		t.Helper()
+		rec := adminRequest(router, http.MethodGet, "/api/v1/market/oi/history?"+query.Encode(), nil, false)
+		var buckets []*domain.OISnapshot
+		if err := json.Unmarshal(rec.Body.Bytes(), &buckets); rec.Code != http.StatusOK || err != nil {
+			t.Fatalf("GET oi/history?%s = %d %s", query.Encode(), rec.Code, rec.Body)
+		}
+		return buckets
+	}
+
+	// Older snapshots at known times: two in the 10:00 bucket, one at 11:00,
+	// and one either side of the requested range
+	base := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	for _, s := range []struct {
+		at time.Duration
+		oi int64
+	}{
+		{-10 * time.Minute, 9},
+		{5 * time.Minute, 1},
+		{40 * time.Minute, 2},
+		{70 * time.Minute, 3},
+		{150 * time.Minute, 9},
+	} {
+		snap := &domain.OISnapshot{Instrument: h.Instrument, Timestamp: base.Add(s.at), OpenInterest: decimal.NewFromInt(s.oi), Price: decimal.NewFromInt(1000)}
+		if err := database.SaveOISnapshot(snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buckets := get(url.Values{"interval": {"1h"}, "start": {base.Format(time.RFC3339)}, "end": {base.Add(2 * time.Hour).Format(time.RFC3339)}})
+	want := []struct {
+		at time.Time
+		oi string
+	}{
+		{base, "2"}, // The later snapshot in the hour closes the bucket
+		{base.Add(time.Hour), "3"},
+	}
+	if len(buckets) != len(want) {
+		t.Fatalf("%d buckets, want %d: %+v", len(buckets), len(want), buckets)
+	}
+	for i, w := range want {
+		if !buckets[i].Timestamp.Equal(w.at) || buckets[i].OpenInterest.String() != w.oi {
+			t.Fatalf("bucket %d = %s OI %s, want %s OI %s", i, buckets[i].Timestamp, buckets[i].OpenInterest, w.at, w.oi)
+		}
+	}
+
+	// A snapshot recorded from live positions
+	a := h.AddTrader("a")
+	b := h.AddTrader("b")
+	h.MustLimit(b, domain.SideSell, "1000", "2")
+	h.MustMarket(a, domain.SideBuy, "2")
+	if err := h.Engine.RecordOISnapshot(h.Instrument); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	buckets = get(url.Values{"interval": {"1m"}, "start": {now.Add(-time.Minute).Format(time.RFC3339)}, "end": {now.Add(time.Minute).Format(time.RFC3339)}})
+	if len(buckets) != 1 {
+		t.Fatalf("%d buckets around now, want the recorded one: %+v", len(buckets), buckets)
+	}
+	got := buckets[0]
+	if !got.OpenInterest.Equal(decimal.NewFromInt(2)) || got.LongPositions != 1 || got.ShortPositions != 1 || !got.Price.Equal(decimal.NewFromInt(1000)) {
+		t.Fatalf("recorded bucket = %+v, want OI 2 across one long and one short at 1000", got)
+	}
+	if got.Timestamp.Second() != 0 || got.Timestamp.Nanosecond() != 0 {
+		t.Fatalf("bucket starts at %s, want a whole minute", got.Timestamp)
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// unmarshalable fails to encode
+type unmarshalable struct{}
+
+func (unmarshalable) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("cannot encode")
+}
+
+func TestRespondJSONEncodingFailureReturns500(t *testing.T) {
+	rec := httptest.NewRecorder()
+	respondJSON(rec, http.StatusOK, map[string]interface{}{"ok": true, "bad": unmarshalable{}})
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %q is not JSON: %v", rec.Body, err)
+	}
+	if body["error"] == "" || strings.Contains(rec.Body.String(), "ok") {
+		t.Fatalf("body = %q, want only an error", rec.Body)
+	}
+}
+
+func TestRespondJSONWritesStatusAndBody(t *testing.T) {
+	rec := httptest.NewRecorder()
+	respondJSON(rec, http.StatusCreated, map[string]int{"n": 1})
+
+	if rec.Code != http.StatusCreated || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, content type %q, want 201 JSON", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"n":1}` {
+		t.Fatalf("body = %s", got)
+	}
+}
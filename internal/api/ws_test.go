@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"github.com/thatreguy/trade.re/internal/auth"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+	"github.com/thatreguy/trade.re/internal/ws"
+)
+
+// newWSServer serves the API and WebSocket over a running hub, wired to the
+// engine's market state changes as the server binary does
+func newWSServer(t *testing.T) (*httptest.Server, *enginetest.Harness) {
+	t.Helper()
+	h := enginetest.NewTestEngine()
+	hub := ws.NewHub()
+	go hub.Run()
+	h.Engine.OnMarketStateChange(func(change *domain.MarketStateChange) {
+		hub.BroadcastMarketState(change)
+	})
+
+	r := chi.NewRouter()
+	NewServer(h.Engine, hub, auth.New("test-secret", 1, 32), "UTC").RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server, h
+}
+
+func dialWS(t *testing.T, server *httptest.Server) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readType reads messages until one of the given type arrives
+func readType(t *testing.T, conn *websocket.Conn, msgType ws.MessageType) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg struct {
+			Type ws.MessageType         `json:"type"`
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("waiting for %s: %v", msgType, err)
+		}
+		if msg.Type == msgType {
+			return msg.Data
+		}
+	}
+}
+
+func TestHaltBroadcastsMarketState(t *testing.T) {
+	server, h := newWSServer(t)
+	conn := dialWS(t, server)
+
+	if welcome := readType(t, conn, ws.TypeWelcome); welcome["market_state"] != string(domain.MarketStateOpen) {
+		t.Fatalf("welcome = %v, want market_state open", welcome)
+	}
+
+	if err := h.Engine.SetMarketState(domain.MarketStateHalted, "circuit breaker"); err != nil {
+		t.Fatal(err)
+	}
+	change := readType(t, conn, ws.TypeMarketState)
+	if change["state"] != string(domain.MarketStateHalted) || change["previous_state"] != string(domain.MarketStateOpen) || change["reason"] != "circuit breaker" {
+		t.Fatalf("market_state = %v, want open to halted for the circuit breaker", change)
+	}
+
+	// Clients connecting during the halt are told of it up front
+	late := dialWS(t, server)
+	if welcome := readType(t, late, ws.TypeWelcome); welcome["market_state"] != string(domain.MarketStateHalted) || welcome["reason"] != "circuit breaker" {
+		t.Fatalf("welcome during halt = %v, want halted", welcome)
+	}
+
+	resp, err := http.Get(server.URL + "/api/v1/market/stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var stats struct {
+		MarketState domain.MarketState `json:"market_state"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.MarketState != domain.MarketStateHalted {
+		t.Fatalf("market stats state = %q, want halted", stats.MarketState)
+	}
+}
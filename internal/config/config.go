@@ -2,8 +2,12 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/shopspring/decimal"
 	"gopkg.in/yaml.v3"
@@ -16,7 +20,16 @@ type Config struct {
 	RIndex      RIndexConfig      `yaml:"rindex"`
 	Auth        AuthConfig        `yaml:"auth"`
 	Liquidation LiquidationConfig `yaml:"liquidation"`
+	Funding     FundingConfig     `yaml:"funding"`
 	Game        GameConfig        `yaml:"game"`
+	Engine      EngineConfig      `yaml:"engine"`
+	Fees        FeeConfig         `yaml:"fees"`
+	Simulation  SimulationConfig  `yaml:"simulation"`
+	Events      EventsConfig      `yaml:"events"`
+	Index       IndexConfig       `yaml:"index"`
+	DegenIndex  DegenIndexConfig  `yaml:"degen_index"`
+	SLO         SLOConfig         `yaml:"slo"`
+	Log         LogConfig         `yaml:"log"`
 }
 
 // ServerConfig holds HTTP server settings
@@ -24,9 +37,17 @@ type ServerConfig struct {
 	Port     int    `yaml:"port"`
 	Host     string `yaml:"host"`
 	Timezone string `yaml:"timezone"`
+
+	WSHeartbeatSeconds int  `yaml:"ws_heartbeat_seconds"` // 0 disables WebSocket heartbeats
+	MaxInFlightOrders  int  `yaml:"max_in_flight_orders"` // Per-trader concurrent order submissions (0 = unlimited)
+	PublicExport       bool `yaml:"public_export"`        // Serve /api/v1/export without the admin token
+
+	// Full order book snapshots broadcast on the orderbook channel
+	WSOrderBookSnapshotMs int `yaml:"ws_orderbook_snapshot_ms"` // 0 disables
+	WSOrderBookDepth      int `yaml:"ws_orderbook_depth"`       // Price levels per side
 }
 
-// DatabaseConfig holds PostgreSQL connection settings
+// DatabaseConfig holds PostgreSQL connection settings and SQLite write batching
 type DatabaseConfig struct {
 	Host           string `yaml:"host"`
 	Port           int    `yaml:"port"`
@@ -34,6 +55,15 @@ type DatabaseConfig struct {
 	User           string `yaml:"user"`
 	Password       string `yaml:"password"`
 	MaxConnections int    `yaml:"max_connections"`
+
+	// SQLite writes are committed in batches from a background writer
+	WriteBatchMs   int `yaml:"write_batch_ms"`   // Commit queued writes this often (0 = write synchronously)
+	WriteBatchRows int `yaml:"write_batch_rows"` // Commit early once this many are queued (0 = 500)
+}
+
+// WriteBatchInterval returns how often batched writes are committed (zero = synchronous writes)
+func (d DatabaseConfig) WriteBatchInterval() time.Duration {
+	return time.Duration(d.WriteBatchMs) * time.Millisecond
 }
 
 // ConnectionString returns the PostgreSQL connection string
@@ -49,14 +79,37 @@ type RIndexConfig struct {
 	StartingPrice decimal.Decimal `yaml:"starting_price"`
 	TickSize      decimal.Decimal `yaml:"tick_size"`
 	MinOrderSize  decimal.Decimal `yaml:"min_order_size"`
+	LotSize       decimal.Decimal `yaml:"lot_size"` // Sizes are rounded down to a multiple of this
 	MaxLeverage   int             `yaml:"max_leverage"`
 }
 
+// RoundToLot rounds a size down to the nearest lot (unchanged if no lot size is set)
+func (c RIndexConfig) RoundToLot(size decimal.Decimal) decimal.Decimal {
+	if !c.LotSize.IsPositive() {
+		return size
+	}
+	return size.Div(c.LotSize).Floor().Mul(c.LotSize)
+}
+
+// RoundToTick rounds a price to the nearest tick (unchanged if no tick size is set)
+func (c RIndexConfig) RoundToTick(price decimal.Decimal) decimal.Decimal {
+	if !c.TickSize.IsPositive() {
+		return price
+	}
+	return price.Div(c.TickSize).Round(0).Mul(c.TickSize)
+}
+
+// OnTick reports whether a price is a whole number of ticks
+func (c RIndexConfig) OnTick(price decimal.Decimal) bool {
+	return c.RoundToTick(price).Equal(price)
+}
+
 // AuthConfig holds authentication settings
 type AuthConfig struct {
 	JWTSecret        string `yaml:"jwt_secret"`
 	TokenExpiryHours int    `yaml:"token_expiry_hours"`
 	APIKeyLength     int    `yaml:"api_key_length"`
+	AdminToken       string `yaml:"admin_token"` // Enables /api/v1/admin/* when set
 }
 
 // LiquidationConfig holds liquidation engine settings
@@ -64,6 +117,49 @@ type LiquidationConfig struct {
 	CheckIntervalMs      int                `yaml:"check_interval_ms"`
 	InsuranceFundInitial decimal.Decimal    `yaml:"insurance_fund_initial"`
 	MaintenanceMargins   MaintenanceMargins `yaml:"maintenance_margins"`
+
+	// Notional brackets, smallest first; empty disables tiered margin
+	RiskTiers []RiskTier `yaml:"risk_tiers"`
+
+	// Cancel all of a trader's resting and stop orders when they are liquidated
+	CancelOrdersOnLiquidation bool `yaml:"cancel_orders_on_liquidation"`
+}
+
+// FundingConfig schedules periodic funding payments between longs and
+// shorts. R.index has no external index, so the mark price is compared to a
+// time-weighted average of recent trade prices.
+type FundingConfig struct {
+	Enabled             bool            `yaml:"enabled"`
+	IntervalMinutes     int             `yaml:"interval_minutes"`      // Time between settlements, aligned to the UTC day
+	AnchorWindowMinutes int             `yaml:"anchor_window_minutes"` // Trade TWAP window the mark is compared to
+	MaxRate             decimal.Decimal `yaml:"max_rate"`              // Cap on the rate per interval, either direction
+
+	// Seconds before each settlement in which only orders reducing a
+	// position are accepted (0 = no freeze)
+	FreezeSeconds int `yaml:"freeze_seconds"`
+}
+
+// RiskTier caps leverage and raises maintenance margin for positions up to a
+// notional size. The last tier may leave MaxNotional unset to cover the rest.
+type RiskTier struct {
+	MaxNotional       decimal.Decimal `yaml:"max_notional"`
+	MaxLeverage       int             `yaml:"max_leverage"`
+	MaintenanceMargin decimal.Decimal `yaml:"maintenance_margin"`
+}
+
+// RiskTierFor returns the tier covering a position notional, or nil if no
+// tiers are configured. Notionals beyond the last bracket use the last tier.
+func (c LiquidationConfig) RiskTierFor(notional decimal.Decimal) *RiskTier {
+	for i := range c.RiskTiers {
+		tier := &c.RiskTiers[i]
+		if !tier.MaxNotional.IsPositive() || notional.LessThanOrEqual(tier.MaxNotional) {
+			return tier
+		}
+	}
+	if n := len(c.RiskTiers); n > 0 {
+		return &c.RiskTiers[n-1]
+	}
+	return nil
 }
 
 // MaintenanceMargins by leverage tier
@@ -92,6 +188,175 @@ func (m MaintenanceMargins) GetMarginForLeverage(leverage int) decimal.Decimal {
 type GameConfig struct {
 	StartingBalance decimal.Decimal `yaml:"starting_balance"`
 	CurrencySymbol  string          `yaml:"currency_symbol"`
+
+	// Per trader type overrides, keyed by type ("human", "bot", "market_maker")
+	TraderTypes map[string]TraderTypeConfig `yaml:"trader_types"`
+}
+
+// TraderTypeConfig overrides the global limits for one kind of participant.
+// Zero values fall back to game.starting_balance and rindex.max_leverage.
+type TraderTypeConfig struct {
+	StartingBalance decimal.Decimal `yaml:"starting_balance"`
+	MaxLeverage     int             `yaml:"max_leverage"`
+}
+
+// traderTypes lists the valid keys for game.trader_types
+var traderTypes = []string{"human", "bot", "market_maker"}
+
+// FeeConfig holds trading fee rates (fractions of notional).
+// Taker rates depend on the position effect, so reducing risk is cheaper
+// than adding to it.
+type FeeConfig struct {
+	MakerRate       decimal.Decimal `yaml:"maker_rate"`
+	TakerOpenRate   decimal.Decimal `yaml:"taker_open_rate"`
+	TakerCloseRate  decimal.Decimal `yaml:"taker_close_rate"`
+	LiquidationRate decimal.Decimal `yaml:"liquidation_rate"` // Charged on positions closed by the liquidation engine
+
+	// Fee diversion into the insurance fund; the rest is kept as exchange revenue
+	InsuranceFundShare  decimal.Decimal `yaml:"insurance_fund_share"`  // Fraction of fees diverted, 0-1
+	InsuranceFundTarget decimal.Decimal `yaml:"insurance_fund_target"` // Diversion stops at this balance (0 = no target)
+}
+
+// DegenIndexConfig weights the components of the market degen index. Weights
+// are relative: they are normalized by their sum.
+type DegenIndexConfig struct {
+	WindowMinutes      int             `yaml:"window_minutes"` // Lookback for fills, liquidations and orders
+	LeverageWeight     decimal.Decimal `yaml:"leverage_weight"`
+	HighLeverageWeight decimal.Decimal `yaml:"high_leverage_weight"`
+	LiquidationWeight  decimal.Decimal `yaml:"liquidation_weight"`
+	ChurnWeight        decimal.Decimal `yaml:"churn_weight"`
+}
+
+// SLOConfig sets the match latency objective the engine watches. An alert is
+// raised once when the rolling p99 stays above the threshold for the sustain
+// period, and re-armed when it recovers.
+type SLOConfig struct {
+	MatchLatencyP99Ms int `yaml:"match_latency_p99_ms"` // 0 disables the watcher
+	SustainSeconds    int `yaml:"sustain_seconds"`      // How long p99 must stay above the threshold
+	SampleWindow      int `yaml:"sample_window"`        // Most recent submissions the p99 is taken over
+}
+
+// EventsConfig selects where engine events are published for downstream pipelines
+type EventsConfig struct {
+	Sink       string `yaml:"sink"` // "none" (default) or "webhook"
+	WebhookURL string `yaml:"webhook_url"`
+	BufferSize int    `yaml:"buffer_size"` // Events queued for publishing before new ones are dropped
+}
+
+// IndexConfig selects the reference index behind index_price and the funding anchor
+type IndexConfig struct {
+	Source      string          `yaml:"source"`       // "none" (default: index follows the mark) or "random_walk"
+	Seed        int64           `yaml:"seed"`         // Random walk: same seed, same path from each start
+	StepSeconds int             `yaml:"step_seconds"` // Random walk: time between steps
+	Volatility  decimal.Decimal `yaml:"volatility"`   // Random walk: largest move per step, as a fraction
+}
+
+// LogConfig holds structured logging settings
+type LogConfig struct {
+	Level  string `yaml:"level"`  // "debug", "info" (default), "warn" or "error"
+	Format string `yaml:"format"` // "json" (default) or "text"
+}
+
+// SlogLevel returns the configured level, info if unset
+func (c LogConfig) SlogLevel() slog.Level {
+	switch c.Level {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// SimulationConfig holds client-testing aids that must stay off in production.
+// Nothing here has any effect unless SimulateLatency is set.
+type SimulationConfig struct {
+	SimulateLatency      bool `yaml:"simulate_latency"`
+	OrderAckDelayMs      int  `yaml:"order_ack_delay_ms"`      // Added before responding to order submissions
+	FillBroadcastDelayMs int  `yaml:"fill_broadcast_delay_ms"` // Added to the WebSocket feed; all broadcasts are delayed so ordering holds
+}
+
+// OrderAckDelay returns the artificial order acknowledgement delay (zero unless enabled)
+func (c SimulationConfig) OrderAckDelay() time.Duration {
+	if !c.SimulateLatency {
+		return 0
+	}
+	return time.Duration(c.OrderAckDelayMs) * time.Millisecond
+}
+
+// FillBroadcastDelay returns the artificial WebSocket feed delay (zero unless enabled)
+func (c SimulationConfig) FillBroadcastDelay() time.Duration {
+	if !c.SimulateLatency {
+		return 0
+	}
+	return time.Duration(c.FillBroadcastDelayMs) * time.Millisecond
+}
+
+// EngineConfig holds matching engine settings
+type EngineConfig struct {
+	MaxMatchLevels int `yaml:"max_match_levels"` // Price levels walked per submission (0 = unlimited)
+	MaxMatchOrders int `yaml:"max_match_orders"` // Resting orders visited per submission (0 = unlimited)
+
+	// Market orders sent with a client timestamp older than this are rejected (0 = no check)
+	MaxMarketOrderAgeMs int `yaml:"max_market_order_age_ms"`
+
+	// How far, in basis points of the best opposite price on arrival, a market
+	// order may walk the book before its remainder is cancelled (0 = no band).
+	// Orders may set their own max_slippage_bps.
+	MaxMarketSlippageBps int `yaml:"max_market_slippage_bps"`
+
+	SnapshotIntervalSeconds int `yaml:"snapshot_interval_seconds"` // How often periodic state snapshots (e.g. OI) are taken
+
+	// How often open positions' unrealized P&L is re-marked, saved and, when
+	// it moved materially, broadcast (0 = only on fills)
+	PnLRefreshIntervalMs int `yaml:"pnl_refresh_interval_ms"`
+
+	// Trades and liquidations kept in memory, across all instruments. Queries
+	// reaching back past them read the database.
+	MaxRecentTrades       int `yaml:"max_recent_trades"`       // 0 = 1000
+	MaxRecentLiquidations int `yaml:"max_recent_liquidations"` // 0 = 100
+
+	// Recent-trades ring saved with each snapshot and at shutdown, for fast warm restarts
+	TradeRingFile          string `yaml:"trade_ring_file"`            // Empty disables
+	TradeRingMaxAgeSeconds int    `yaml:"trade_ring_max_age_seconds"` // Older files are ignored (0 = no limit)
+
+	// Decimal places kept by every decimal division (entry price averaging,
+	// margin, liquidation prices). Must cover the tick and lot precision.
+	DivisionPrecision int `yaml:"division_precision"`
+
+	// How the mark price used for liquidations and funding is derived:
+	// "last" trade (default), "average" of the last mark_price_trades trades,
+	// or "mid" of the best bid and ask, held within mark_price_band (a
+	// fraction) of the last trade
+	MarkPriceMethod string          `yaml:"mark_price_method"`
+	MarkPriceTrades int             `yaml:"mark_price_trades"`
+	MarkPriceBand   decimal.Decimal `yaml:"mark_price_band"`
+
+	// What happens when an order meets its own trader's resting order:
+	// "skip" (default), "cancel-resting" or "cancel-aggressor". Orders may override it.
+	SelfTradePrevention string `yaml:"self_trade_prevention"`
+}
+
+// DefaultDivisionPrecision is shopspring/decimal's own default, used when
+// engine.division_precision is unset
+const DefaultDivisionPrecision = 16
+
+// MinDivisionPrecision is the fewest decimal places a division may keep
+// without losing precision the instrument can express: enough for a price on
+// the tick grid times a size on the lot grid
+func (c RIndexConfig) MinDivisionPrecision() int {
+	return decimalPlaces(c.TickSize) + decimalPlaces(c.LotSize)
+}
+
+// decimalPlaces counts the digits after the decimal point in d
+func decimalPlaces(d decimal.Decimal) int {
+	if exp := d.Exponent(); exp < 0 {
+		return int(-exp)
+	}
+	return 0
 }
 
 // Load reads configuration from a YAML file
@@ -116,6 +381,9 @@ func Load(path string) (*Config, error) {
 	if secret := os.Getenv("JWT_SECRET"); secret != "" {
 		cfg.Auth.JWTSecret = secret
 	}
+	if token := os.Getenv("ADMIN_TOKEN"); token != "" {
+		cfg.Auth.AdminToken = token
+	}
 
 	// Validate
 	if err := cfg.Validate(); err != nil {
@@ -133,14 +401,236 @@ func (c *Config) Validate() error {
 		errs = append(errs, "server.port must be 1-65535")
 	}
 
+	if c.Server.WSHeartbeatSeconds < 0 {
+		errs = append(errs, "server.ws_heartbeat_seconds must not be negative")
+	}
+	if c.Server.WSOrderBookSnapshotMs < 0 {
+		errs = append(errs, "server.ws_orderbook_snapshot_ms must not be negative")
+	}
+	if c.Server.WSOrderBookDepth < 1 || c.Server.WSOrderBookDepth > 100 {
+		errs = append(errs, "server.ws_orderbook_depth must be between 1 and 100")
+	}
+	if c.Server.MaxInFlightOrders < 0 {
+		errs = append(errs, "server.max_in_flight_orders must not be negative")
+	}
+
+	feeRates := []struct {
+		name string
+		rate decimal.Decimal
+	}{
+		{"taker_open_rate", c.Fees.TakerOpenRate},
+		{"taker_close_rate", c.Fees.TakerCloseRate},
+		{"liquidation_rate", c.Fees.LiquidationRate},
+	}
+	for _, f := range feeRates {
+		if f.rate.IsNegative() || f.rate.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+			errs = append(errs, fmt.Sprintf("fees.%s must be between 0 and 1", f.name))
+		}
+	}
+	// A negative maker rate is a rebate
+	if c.Fees.MakerRate.LessThanOrEqual(decimal.NewFromInt(-1)) || c.Fees.MakerRate.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+		errs = append(errs, "fees.maker_rate must be between -1 and 1")
+	}
+
+	if c.Fees.InsuranceFundShare.IsNegative() || c.Fees.InsuranceFundShare.GreaterThan(decimal.NewFromInt(1)) {
+		errs = append(errs, "fees.insurance_fund_share must be between 0 and 1")
+	}
+	if c.Fees.InsuranceFundTarget.IsNegative() {
+		errs = append(errs, "fees.insurance_fund_target must not be negative")
+	}
+
+	if c.DegenIndex.WindowMinutes < 0 {
+		errs = append(errs, "degen_index.window_minutes must not be negative")
+	}
+	degenWeights := []decimal.Decimal{c.DegenIndex.LeverageWeight, c.DegenIndex.HighLeverageWeight, c.DegenIndex.LiquidationWeight, c.DegenIndex.ChurnWeight}
+	for _, w := range degenWeights {
+		if w.IsNegative() {
+			errs = append(errs, "degen_index weights must not be negative")
+			break
+		}
+	}
+
+	switch c.Events.Sink {
+	case "", "none":
+	case "webhook":
+		if c.Events.WebhookURL == "" {
+			errs = append(errs, "events.webhook_url is required for the webhook sink")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("events.sink %q must be none or webhook", c.Events.Sink))
+	}
+	if c.Events.BufferSize < 0 {
+		errs = append(errs, "events.buffer_size must not be negative")
+	}
+	switch c.Index.Source {
+	case "", "none":
+	case "random_walk":
+		if c.Index.StepSeconds <= 0 {
+			errs = append(errs, "index.step_seconds must be positive")
+		}
+		if !c.Index.Volatility.IsPositive() || c.Index.Volatility.GreaterThan(decimal.NewFromFloat(0.1)) {
+			errs = append(errs, "index.volatility must be in (0, 0.1]")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("index.source %q must be none or random_walk", c.Index.Source))
+	}
+	switch c.Log.Level {
+	case "", "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Sprintf("log.level %q must be debug, info, warn or error", c.Log.Level))
+	}
+	switch c.Log.Format {
+	case "", "json", "text":
+	default:
+		errs = append(errs, fmt.Sprintf("log.format %q must be json or text", c.Log.Format))
+	}
+	if c.Database.WriteBatchMs < 0 || c.Database.WriteBatchRows < 0 {
+		errs = append(errs, "database.write_batch_ms and write_batch_rows must not be negative")
+	}
+
+	for _, d := range []struct {
+		name string
+		ms   int
+	}{
+		{"order_ack_delay_ms", c.Simulation.OrderAckDelayMs},
+		{"fill_broadcast_delay_ms", c.Simulation.FillBroadcastDelayMs},
+	} {
+		if d.ms < 0 || d.ms > 10000 {
+			errs = append(errs, fmt.Sprintf("simulation.%s must be 0-10000", d.name))
+		}
+	}
+
 	if c.RIndex.MaxLeverage < 1 || c.RIndex.MaxLeverage > 150 {
 		errs = append(errs, "rindex.max_leverage must be 1-150")
 	}
 
+	for i, tier := range c.Liquidation.RiskTiers {
+		name := fmt.Sprintf("liquidation.risk_tiers[%d]", i)
+		last := i == len(c.Liquidation.RiskTiers)-1
+		if !tier.MaxNotional.IsPositive() && !last {
+			errs = append(errs, name+".max_notional must be positive (only the last tier may be unbounded)")
+		}
+		if tier.MaxLeverage < 1 || tier.MaxLeverage > 150 {
+			errs = append(errs, name+".max_leverage must be 1-150")
+		}
+		if !tier.MaintenanceMargin.IsPositive() || tier.MaintenanceMargin.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+			errs = append(errs, name+".maintenance_margin must be between 0 and 1")
+		}
+		if i > 0 {
+			prev := c.Liquidation.RiskTiers[i-1]
+			if tier.MaxNotional.IsPositive() && tier.MaxNotional.LessThanOrEqual(prev.MaxNotional) {
+				errs = append(errs, name+".max_notional must be larger than the previous tier's")
+			}
+			if tier.MaxLeverage > prev.MaxLeverage {
+				errs = append(errs, name+".max_leverage must not exceed the previous tier's")
+			}
+		}
+	}
+
+	names := make([]string, 0, len(c.Game.TraderTypes))
+	for name := range c.Game.TraderTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		tt := c.Game.TraderTypes[name]
+		if !slices.Contains(traderTypes, name) {
+			errs = append(errs, fmt.Sprintf("game.trader_types.%s: unknown trader type (want one of %s)", name, strings.Join(traderTypes, ", ")))
+		}
+		if tt.StartingBalance.IsNegative() {
+			errs = append(errs, fmt.Sprintf("game.trader_types.%s.starting_balance must not be negative", name))
+		}
+		if tt.MaxLeverage < 0 || tt.MaxLeverage > 150 {
+			errs = append(errs, fmt.Sprintf("game.trader_types.%s.max_leverage must be 0-150", name))
+		}
+	}
+
 	if c.RIndex.StartingPrice.LessThanOrEqual(decimal.Zero) {
 		errs = append(errs, "rindex.starting_price must be positive")
 	}
 
+	if c.RIndex.TickSize.IsNegative() {
+		errs = append(errs, "rindex.tick_size must not be negative")
+	} else if c.RIndex.TickSize.IsPositive() && !c.RIndex.OnTick(c.RIndex.StartingPrice) {
+		errs = append(errs, "rindex.starting_price must be a multiple of tick_size")
+	}
+
+	if c.RIndex.LotSize.IsNegative() {
+		errs = append(errs, "rindex.lot_size must not be negative")
+	}
+	if c.RIndex.MinOrderSize.IsNegative() {
+		errs = append(errs, "rindex.min_order_size must not be negative")
+	}
+
+	if c.Engine.MaxMatchLevels < 0 || c.Engine.MaxMatchOrders < 0 {
+		errs = append(errs, "engine match limits must not be negative")
+	}
+	if c.Engine.TradeRingMaxAgeSeconds < 0 {
+		errs = append(errs, "engine.trade_ring_max_age_seconds must not be negative")
+	}
+	if c.Engine.MaxMarketOrderAgeMs < 0 {
+		errs = append(errs, "engine.max_market_order_age_ms must not be negative")
+	}
+	if c.Engine.MaxMarketSlippageBps < 0 || c.Engine.MaxMarketSlippageBps > 10000 {
+		errs = append(errs, "engine.max_market_slippage_bps must be between 0 and 10000")
+	}
+	if c.Engine.PnLRefreshIntervalMs < 0 {
+		errs = append(errs, "engine.pnl_refresh_interval_ms must not be negative")
+	}
+	if c.Engine.MaxRecentTrades < 0 || c.Engine.MaxRecentLiquidations < 0 {
+		errs = append(errs, "engine recent history limits must not be negative")
+	}
+
+	if p := c.Engine.DivisionPrecision; p != 0 && (p < c.RIndex.MinDivisionPrecision() || p > 64) {
+		errs = append(errs, fmt.Sprintf("engine.division_precision must be between %d (tick and lot precision) and 64", c.RIndex.MinDivisionPrecision()))
+	}
+
+	switch c.Engine.MarkPriceMethod {
+	case "", "last":
+	case "average":
+		if c.Engine.MarkPriceTrades < 1 {
+			errs = append(errs, "engine.mark_price_trades must be at least 1 for the average mark price")
+		}
+	case "mid":
+		if !c.Engine.MarkPriceBand.IsPositive() || c.Engine.MarkPriceBand.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+			errs = append(errs, "engine.mark_price_band must be in (0, 1) for the mid mark price")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("engine.mark_price_method %q must be last, average or mid", c.Engine.MarkPriceMethod))
+	}
+
+	switch c.Engine.SelfTradePrevention {
+	case "", "skip", "cancel-resting", "cancel-aggressor":
+	default:
+		errs = append(errs, fmt.Sprintf("engine.self_trade_prevention %q must be skip, cancel-resting or cancel-aggressor", c.Engine.SelfTradePrevention))
+	}
+
+	if c.Funding.Enabled {
+		if c.Funding.IntervalMinutes <= 0 || (24*60)%c.Funding.IntervalMinutes != 0 {
+			errs = append(errs, "funding.interval_minutes must be positive and divide the day evenly")
+		}
+		if c.Funding.AnchorWindowMinutes <= 0 {
+			errs = append(errs, "funding.anchor_window_minutes must be positive")
+		}
+		if !c.Funding.MaxRate.IsPositive() || c.Funding.MaxRate.GreaterThan(decimal.NewFromFloat(0.1)) {
+			errs = append(errs, "funding.max_rate must be in (0, 0.1]")
+		}
+		if c.Funding.FreezeSeconds < 0 || (c.Funding.IntervalMinutes > 0 && c.Funding.FreezeSeconds >= c.Funding.IntervalMinutes*60) {
+			errs = append(errs, "funding.freeze_seconds must be between 0 and the funding interval")
+		}
+	}
+
+	if c.SLO.MatchLatencyP99Ms < 0 || c.SLO.SustainSeconds < 0 {
+		errs = append(errs, "slo thresholds must not be negative")
+	}
+	if c.SLO.MatchLatencyP99Ms > 0 && (c.SLO.SampleWindow < 100 || c.SLO.SampleWindow > 100000) {
+		errs = append(errs, "slo.sample_window must be between 100 and 100000")
+	}
+
+	if c.Engine.SnapshotIntervalSeconds < 0 {
+		errs = append(errs, "engine.snapshot_interval_seconds must not be negative")
+	}
+
 	if len(c.Auth.JWTSecret) > 0 && len(c.Auth.JWTSecret) < 32 {
 		errs = append(errs, "auth.jwt_secret must be at least 32 characters")
 	}
@@ -156,45 +646,116 @@ func (c *Config) Validate() error {
 func LoadOrDefault(path string) *Config {
 	cfg, err := Load(path)
 	if err != nil {
-		// Return sensible defaults for development
-		return &Config{
-			Server: ServerConfig{
-				Port: 8080,
-				Host:     "0.0.0.0",
-			Timezone: "Asia/Kolkata",
-			},
-			Database: DatabaseConfig{
-				Host:           "localhost",
-				Port:           5432,
-				Name:           "tradere",
-				User:           "tradere",
-				MaxConnections: 25,
-			},
-			RIndex: RIndexConfig{
-				StartingPrice: decimal.NewFromInt(1000),
-				TickSize:      decimal.NewFromFloat(0.01),
-				MinOrderSize:  decimal.NewFromFloat(0.001),
-				MaxLeverage:   150,
-			},
-			Auth: AuthConfig{
-				TokenExpiryHours: 24,
-				APIKeyLength:     32,
-			},
-			Liquidation: LiquidationConfig{
-				CheckIntervalMs:      100,
-				InsuranceFundInitial: decimal.NewFromInt(1000000),
-				MaintenanceMargins: MaintenanceMargins{
-					Conservative: decimal.NewFromFloat(0.005),
-					Moderate:     decimal.NewFromFloat(0.01),
-					Aggressive:   decimal.NewFromFloat(0.02),
-					Degen:        decimal.NewFromFloat(0.05),
-				},
-			},
-			Game: GameConfig{
-				StartingBalance: decimal.NewFromInt(10000),
-				CurrencySymbol:  "$",
-			},
-		}
+		return Default()
 	}
 	return cfg
 }
+
+// Default returns sensible defaults for development
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:               8080,
+			Host:               "0.0.0.0",
+			Timezone:           "Asia/Kolkata",
+			WSHeartbeatSeconds: 15,
+			MaxInFlightOrders:  16,
+
+			WSOrderBookSnapshotMs: 250,
+			WSOrderBookDepth:      20,
+		},
+		Database: DatabaseConfig{
+			Host:           "localhost",
+			Port:           5432,
+			Name:           "tradere",
+			User:           "tradere",
+			MaxConnections: 25,
+			WriteBatchRows: 500,
+		},
+		RIndex: RIndexConfig{
+			StartingPrice: decimal.NewFromInt(1000),
+			TickSize:      decimal.NewFromFloat(0.01),
+			MinOrderSize:  decimal.NewFromFloat(0.001),
+			LotSize:       decimal.NewFromFloat(0.001),
+			MaxLeverage:   150,
+		},
+		Auth: AuthConfig{
+			TokenExpiryHours: 24,
+			APIKeyLength:     32,
+		},
+		Liquidation: LiquidationConfig{
+			CheckIntervalMs:      100,
+			InsuranceFundInitial: decimal.NewFromInt(1000000),
+			MaintenanceMargins: MaintenanceMargins{
+				Conservative: decimal.NewFromFloat(0.005),
+				Moderate:     decimal.NewFromFloat(0.01),
+				Aggressive:   decimal.NewFromFloat(0.02),
+				Degen:        decimal.NewFromFloat(0.05),
+			},
+			CancelOrdersOnLiquidation: true,
+		},
+		Game: GameConfig{
+			StartingBalance: decimal.NewFromInt(10000),
+			CurrencySymbol:  "$",
+		},
+		Engine: EngineConfig{
+			MaxMatchLevels: 500,
+			MaxMatchOrders: 5000,
+
+			MaxMarketOrderAgeMs:  2000,
+			MaxMarketSlippageBps: 1000,
+
+			SnapshotIntervalSeconds: 60,
+
+			PnLRefreshIntervalMs: 1000,
+
+			MaxRecentTrades:       1000,
+			MaxRecentLiquidations: 100,
+
+			DivisionPrecision: DefaultDivisionPrecision,
+
+			MarkPriceMethod: "last",
+			MarkPriceTrades: 20,
+			MarkPriceBand:   decimal.NewFromFloat(0.01),
+
+			SelfTradePrevention: "skip",
+		},
+		Fees: FeeConfig{
+			MakerRate:       decimal.NewFromFloat(0.0002),
+			TakerOpenRate:   decimal.NewFromFloat(0.0006),
+			TakerCloseRate:  decimal.NewFromFloat(0.0004),
+			LiquidationRate: decimal.NewFromFloat(0.005),
+
+			InsuranceFundShare:  decimal.NewFromFloat(0.5),
+			InsuranceFundTarget: decimal.NewFromInt(5000000),
+		},
+		DegenIndex: DegenIndexConfig{
+			WindowMinutes:      60,
+			LeverageWeight:     decimal.NewFromFloat(0.3),
+			HighLeverageWeight: decimal.NewFromFloat(0.3),
+			LiquidationWeight:  decimal.NewFromFloat(0.25),
+			ChurnWeight:        decimal.NewFromFloat(0.15),
+		},
+		Funding: FundingConfig{
+			Enabled:             false,
+			IntervalMinutes:     480,
+			AnchorWindowMinutes: 60,
+			MaxRate:             decimal.NewFromFloat(0.0075),
+		},
+		Index: IndexConfig{
+			Source:      "none",
+			Seed:        1,
+			StepSeconds: 1,
+			Volatility:  decimal.NewFromFloat(0.0005),
+		},
+		SLO: SLOConfig{
+			MatchLatencyP99Ms: 50,
+			SustainSeconds:    30,
+			SampleWindow:      1024,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+	}
+}
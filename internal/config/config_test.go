@@ -0,0 +1,110 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestShippedConfigMatchesDefaultInstrument(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-at-least-32-characters")
+	cfg, err := Load("../../config/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, want := cfg.RIndex, Default().RIndex
+	for _, field := range []struct {
+		name      string
+		got, want string
+	}{
+		{"starting_price", got.StartingPrice.String(), want.StartingPrice.String()},
+		{"tick_size", got.TickSize.String(), want.TickSize.String()},
+		{"min_order_size", got.MinOrderSize.String(), want.MinOrderSize.String()},
+		{"lot_size", got.LotSize.String(), want.LotSize.String()},
+	} {
+		if field.got != field.want {
+			t.Errorf("config.yaml rindex.%s = %s, default is %s", field.name, field.got, field.want)
+		}
+	}
+	if got.MaxLeverage != want.MaxLeverage {
+		t.Errorf("config.yaml rindex.max_leverage = %d, default is %d", got.MaxLeverage, want.MaxLeverage)
+	}
+}
+
+func TestRoundToLot(t *testing.T) {
+	c := RIndexConfig{LotSize: decimal.RequireFromString("0.001")}
+	for _, tc := range []struct{ size, want string }{
+		{"1", "1"},
+		{"0.001", "0.001"},
+		{"0.0013754", "0.001"},
+		{"2.9999", "2.999"},
+		{"0.0009", "0"},
+	} {
+		if got := c.RoundToLot(decimal.RequireFromString(tc.size)); !got.Equal(decimal.RequireFromString(tc.want)) {
+			t.Errorf("RoundToLot(%s) = %s, want %s", tc.size, got, tc.want)
+		}
+	}
+
+	// No lot size leaves sizes alone
+	size := decimal.RequireFromString("0.0013754")
+	if got := (RIndexConfig{}).RoundToLot(size); !got.Equal(size) {
+		t.Errorf("RoundToLot without a lot size = %s, want %s", got, size)
+	}
+}
+
+func TestSimulatedLatencyOnlyWhenEnabled(t *testing.T) {
+	c := SimulationConfig{OrderAckDelayMs: 50, FillBroadcastDelayMs: 20}
+	if c.OrderAckDelay() != 0 || c.FillBroadcastDelay() != 0 {
+		t.Fatalf("delays = %s, %s with simulate_latency off, want none", c.OrderAckDelay(), c.FillBroadcastDelay())
+	}
+	c.SimulateLatency = true
+	if c.OrderAckDelay() != 50*time.Millisecond || c.FillBroadcastDelay() != 20*time.Millisecond {
+		t.Fatalf("delays = %s, %s, want 50ms and 20ms", c.OrderAckDelay(), c.FillBroadcastDelay())
+	}
+}
+
+func TestRoundToTick(t *testing.T) {
+	c := RIndexConfig{TickSize: decimal.RequireFromString("0.05")}
+	for _, tc := range []struct{ price, want string }{
+		{"1000", "1000"},
+		{"1000.02", "1000"},
+		{"1000.025", "1000.05"}, // Half a tick rounds up
+		{"1000.07", "1000.05"},
+	} {
+		price := decimal.RequireFromString(tc.price)
+		if got := c.RoundToTick(price); !got.Equal(decimal.RequireFromString(tc.want)) {
+			t.Errorf("RoundToTick(%s) = %s, want %s", tc.price, got, tc.want)
+		}
+		if on := c.OnTick(price); on != (tc.price == tc.want) {
+			t.Errorf("OnTick(%s) = %v", tc.price, on)
+		}
+	}
+}
+
+func TestDivisionPrecisionCoversTickAndLot(t *testing.T) {
+	c := RIndexConfig{TickSize: decimal.RequireFromString("0.01"), LotSize: decimal.RequireFromString("0.001")}
+	if got := c.MinDivisionPrecision(); got != 5 {
+		t.Fatalf("MinDivisionPrecision = %d, want 5 for a 0.01 tick and 0.001 lot", got)
+	}
+
+	for _, tc := range []struct {
+		precision int
+		ok        bool
+	}{
+		{0, true}, // Unset uses the default
+		{4, false},
+		{5, true},
+		{64, true},
+		{65, false},
+	} {
+		cfg := Default()
+		cfg.Engine.DivisionPrecision = tc.precision
+		err := cfg.Validate()
+		if rejected := err != nil && strings.Contains(err.Error(), "division_precision"); rejected == tc.ok {
+			t.Errorf("division_precision %d: err = %v, want ok %v", tc.precision, err, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultWriteBatchRows is the batch size used when none is configured
+const defaultWriteBatchRows = 500
+
+// errWriteQueueClosed is returned for writes made after Close
+var errWriteQueueClosed = errors.New("database closed")
+
+// queuedWrite is one statement waiting to be committed, or a flush marker
+type queuedWrite struct {
+	query   string
+	args    []interface{}
+	flushed chan struct{} // Set on a flush marker; closed once everything queued before it is committed
+}
+
+// writeQueue commits writes in batches from a background goroutine, in the
+// order they were queued
+type writeQueue struct {
+	mu       sync.RWMutex // Held for writing only to close writes
+	closed   bool
+	writes   chan queuedWrite
+	done     chan struct{}
+	interval time.Duration
+	maxRows  int
+
+	pending atomic.Int64 // Writes taken off the channel but not yet committed
+	statsMu sync.Mutex
+	stats   WriteQueueStats
+}
+
+// flushTrigger is why a batch was committed
+type flushTrigger int
+
+const (
+	flushOnSize flushTrigger = iota
+	flushOnTime
+	flushOnRequest // Flush or shutdown
+)
+
+// WriteQueueStats describes the batched writer, for tuning the batch
+// interval and size against how much is at risk on a crash
+type WriteQueueStats struct {
+	QueueDepth    int     `json:"queue_depth"` // Writes queued and not yet committed
+	Batches       uint64  `json:"batches"`
+	Writes        uint64  `json:"writes"`       // Writes committed across all batches
+	SizeFlushes   uint64  `json:"size_flushes"` // Batches committed because write_batch_rows were waiting
+	TimeFlushes   uint64  `json:"time_flushes"` // Batches committed when write_batch_ms elapsed
+	LastBatchSize int     `json:"last_batch_size"`
+	MaxBatchSize  int     `json:"max_batch_size"`
+	LastFlushMs   float64 `json:"last_flush_ms"` // Time taken to commit the last batch
+	MaxFlushMs    float64 `json:"max_flush_ms"`
+	TotalFlushMs  float64 `json:"total_flush_ms"`
+}
+
+// SetWriteBatching queues writes instead of committing each one as it is
+// made. A background writer commits the queue in one transaction every
+// interval, or as soon as maxRows writes are waiting (0 = 500). Writes keep
+// their order, and a full queue blocks the writer's caller rather than
+// dropping anything. Lookups of a single order or API key wait for the queue
+// to drain first; other reads may lag by up to the interval. Must be called
+// before the database is used; a zero interval leaves writes synchronous.
+func (s *SQLiteDB) SetWriteBatching(interval time.Duration, maxRows int) {
+	if interval <= 0 || s.queue != nil {
+		return
+	}
+	if maxRows <= 0 {
+		maxRows = defaultWriteBatchRows
+	}
+	s.queue = &writeQueue{
+		writes:   make(chan queuedWrite, 4*maxRows),
+		done:     make(chan struct{}),
+		interval: interval,
+		maxRows:  maxRows,
+	}
+	go s.queue.run(s.db)
+}
+
+// exec runs a write, or queues it when write batching is on
+func (s *SQLiteDB) exec(query string, args ...interface{}) error {
+	if s.queue != nil {
+		return s.queue.push(queuedWrite{query: query, args: args})
+	}
+	_, err := s.db.Exec(query, args...)
+	return err
+}
+
+// Flush waits until every write queued so far has been committed. Without
+// write batching there is nothing to wait for.
+func (s *SQLiteDB) Flush() error {
+	if s.queue == nil {
+		return nil
+	}
+	marker := queuedWrite{flushed: make(chan struct{})}
+	if err := s.queue.push(marker); err != nil {
+		return err
+	}
+	<-marker.flushed
+	return nil
+}
+
+// CloseContext closes the database like Close, but waits for queued writes
+// to be committed only until ctx expires, logging how many were. On timeout
+// the connection is left open for the writer still using it and ctx's error
+// is returned.
+func (s *SQLiteDB) CloseContext(ctx context.Context) error {
+	if s.queue != nil {
+		queued := len(s.queue.writes) + int(s.queue.pending.Load())
+		drained, err := s.queue.close(ctx)
+		if err != nil {
+			slog.Warn("Write queue not drained before the deadline", "committed", drained, "queued", queued)
+			return fmt.Errorf("draining write queue: %w", err)
+		}
+		slog.Info("Write queue drained", "committed", drained)
+	}
+	return s.db.Close()
+}
+
+// WriteQueueStats returns the batched writer's queue depth, batch sizes and
+// flush latency, or nil without write batching
+func (s *SQLiteDB) WriteQueueStats() *WriteQueueStats {
+	if s.queue == nil {
+		return nil
+	}
+	q := s.queue
+	q.statsMu.Lock()
+	stats := q.stats
+	q.statsMu.Unlock()
+	stats.QueueDepth = len(q.writes) + int(q.pending.Load())
+	return &stats
+}
+
+// push adds a write to the queue, blocking while it is full
+func (q *writeQueue) push(w queuedWrite) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return errWriteQueueClosed
+	}
+	q.writes <- w
+	return nil
+}
+
+// close stops taking writes and waits, until ctx expires, for the queued
+// ones to be committed. It returns how many were committed while it waited.
+func (q *writeQueue) close(ctx context.Context) (int, error) {
+	before := q.committed()
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.writes)
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-q.done:
+		return int(q.committed() - before), nil
+	case <-ctx.Done():
+		return int(q.committed() - before), ctx.Err()
+	}
+}
+
+// committed is how many writes have been committed so far
+func (q *writeQueue) committed() uint64 {
+	q.statsMu.Lock()
+	defer q.statsMu.Unlock()
+	return q.stats.Writes
+}
+
+// run commits queued writes until the queue is closed and drained
+func (q *writeQueue) run(db *sql.DB) {
+	defer close(q.done)
+
+	ticker := time.NewTicker(q.interval)
+	defer ticker.Stop()
+
+	pending := make([]queuedWrite, 0, q.maxRows)
+	for {
+		select {
+		case w, ok := <-q.writes:
+			switch {
+			case !ok:
+				q.commit(db, pending, flushOnRequest)
+				return
+			case w.flushed != nil:
+				q.commit(db, pending, flushOnRequest)
+				pending = pending[:0]
+				close(w.flushed)
+			default:
+				pending = append(pending, w)
+				q.pending.Add(1)
+				if len(pending) >= q.maxRows {
+					q.commit(db, pending, flushOnSize)
+					pending = pending[:0]
+				}
+			}
+		case <-ticker.C:
+			q.commit(db, pending, flushOnTime)
+			pending = pending[:0]
+		}
+	}
+}
+
+// commit commits a batch and records its size and how long it took
+func (q *writeQueue) commit(db *sql.DB, writes []queuedWrite, trigger flushTrigger) {
+	if len(writes) == 0 {
+		return
+	}
+
+	start := time.Now()
+	commitWrites(db, writes)
+	elapsed := float64(time.Since(start).Microseconds()) / 1000
+	q.pending.Add(-int64(len(writes)))
+
+	q.statsMu.Lock()
+	defer q.statsMu.Unlock()
+	q.stats.Batches++
+	q.stats.Writes += uint64(len(writes))
+	switch trigger {
+	case flushOnSize:
+		q.stats.SizeFlushes++
+	case flushOnTime:
+		q.stats.TimeFlushes++
+	}
+	q.stats.LastBatchSize = len(writes)
+	q.stats.MaxBatchSize = max(q.stats.MaxBatchSize, len(writes))
+	q.stats.LastFlushMs = elapsed
+	q.stats.MaxFlushMs = max(q.stats.MaxFlushMs, elapsed)
+	q.stats.TotalFlushMs += elapsed
+	if elapsed > float64(q.interval.Milliseconds()) {
+		slog.Warn("Write batch took longer than the batch interval",
+			"writes", len(writes), "flush_ms", elapsed, "interval_ms", q.interval.Milliseconds())
+	}
+}
+
+// commitWrites runs a batch of writes in one transaction. A statement that
+// fails is undone on its own and logged; the rest of the batch still commits.
+func commitWrites(db *sql.DB, writes []queuedWrite) {
+	if len(writes) == 0 {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		slog.Error("Error starting write batch, writes lost", "writes", len(writes), "error", err)
+		return
+	}
+	for _, w := range writes {
+		if _, err := tx.Exec(w.query, w.args...); err != nil {
+			slog.Error("Error in batched write", "error", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing write batch, writes lost", "writes", len(writes), "error", err)
+	}
+}
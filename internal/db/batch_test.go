@@ -0,0 +1,169 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// openBatched opens a fresh database with write batching on
+func openBatched(t *testing.T, path string, interval time.Duration, maxRows int) *SQLiteDB {
+	t.Helper()
+	s, err := NewSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetWriteBatching(interval, maxRows)
+	return s
+}
+
+// saveTraders queues n trader writes
+func saveTraders(t *testing.T, s *SQLiteDB, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		trader := &domain.Trader{
+			ID:        uuid.New(),
+			Username:  fmt.Sprintf("trader-%s", uuid.NewString()[:8]),
+			Type:      domain.TraderTypeBot,
+			Balance:   decimal.NewFromInt(10000),
+			CreatedAt: time.Now(),
+		}
+		if err := s.SaveTrader(trader); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func countTraders(t *testing.T, s *SQLiteDB) int {
+	t.Helper()
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM traders`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// waitFor polls until cond holds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWriteBatchCommitsOnSize(t *testing.T) {
+	s := openBatched(t, filepath.Join(t.TempDir(), "size.db"), time.Hour, 3)
+	defer s.Close()
+
+	saveTraders(t, s, 7)
+	waitFor(t, "two full batches", func() bool { return s.WriteQueueStats().SizeFlushes == 2 })
+
+	if n := countTraders(t, s); n != 6 {
+		t.Fatalf("committed %d traders, want 6 with the seventh still queued", n)
+	}
+	stats := s.WriteQueueStats()
+	if stats.TimeFlushes != 0 || stats.MaxBatchSize != 3 || stats.QueueDepth != 1 {
+		t.Fatalf("stats = %+v, want two batches of 3 and 1 queued", stats)
+	}
+}
+
+func TestWriteBatchCommitsOnInterval(t *testing.T) {
+	s := openBatched(t, filepath.Join(t.TempDir(), "time.db"), 20*time.Millisecond, 1000)
+	defer s.Close()
+
+	saveTraders(t, s, 2)
+	waitFor(t, "the interval flush", func() bool { return s.WriteQueueStats().Writes == 2 })
+
+	if n := countTraders(t, s); n != 2 {
+		t.Fatalf("committed %d traders, want 2", n)
+	}
+	// A tick may fall between the two writes, committing them separately
+	stats := s.WriteQueueStats()
+	if stats.SizeFlushes != 0 || stats.TimeFlushes != stats.Batches || stats.QueueDepth != 0 {
+		t.Fatalf("stats = %+v, want only interval batches", stats)
+	}
+}
+
+func TestWriteBatchDrainsOnClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "drain.db")
+	s := openBatched(t, path, time.Hour, 1000)
+
+	saveTraders(t, s, 5)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if stats := s.WriteQueueStats(); stats.Writes != 5 || stats.QueueDepth != 0 {
+		t.Fatalf("stats = %+v after close, want 5 writes committed", stats)
+	}
+	if err := s.SaveTrader(&domain.Trader{ID: uuid.New(), Username: "late"}); err != errWriteQueueClosed {
+		t.Fatalf("write after close = %v, want %v", err, errWriteQueueClosed)
+	}
+
+	reopened, err := NewSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if n := countTraders(t, reopened); n != 5 {
+		t.Fatalf("%d traders after reopening, want 5", n)
+	}
+}
+
+func TestCloseContextBoundsTheDrain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bounded.db")
+	s := openBatched(t, path, time.Hour, 1000)
+	saveTraders(t, s, 4)
+
+	// Hold the only connection so the writer cannot commit
+	s.db.SetMaxOpenConns(1)
+	conn, err := s.db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.CloseContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("close with a stalled writer = %v, want the deadline", err)
+	}
+	if !strings.Contains(logs.String(), "queued=4") {
+		t.Fatalf("timeout did not log the queued writes:\n%s", logs.String())
+	}
+
+	// Once the writer can commit, closing again finishes the drain
+	conn.Close()
+	logs.Reset()
+	if err := s.CloseContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), "committed=4") {
+		t.Fatalf("drain did not log the committed writes:\n%s", logs.String())
+	}
+
+	reopened, err := NewSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if n := countTraders(t, reopened); n != 4 {
+		t.Fatalf("%d traders after reopening, want 4", n)
+	}
+}
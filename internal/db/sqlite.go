@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -13,7 +14,8 @@ import (
 
 // SQLiteDB wraps the SQLite connection
 type SQLiteDB struct {
-	db *sql.DB
+	db    *sql.DB
+	queue *writeQueue // Nil unless write batching is on
 }
 
 // NewSQLite creates a new SQLite database connection
@@ -84,6 +86,8 @@ func (s *SQLiteDB) createTables() error {
 		filled_size TEXT NOT NULL DEFAULT '0',
 		status TEXT NOT NULL DEFAULT 'open',
 		leverage INTEGER NOT NULL DEFAULT 1,
+		stop_price TEXT NOT NULL DEFAULT '0',
+		oco_group_id TEXT NOT NULL DEFAULT '',
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (trader_id) REFERENCES traders(id)
@@ -101,6 +105,16 @@ func (s *SQLiteDB) createTables() error {
 		buyer_effect TEXT NOT NULL DEFAULT 'open',
 		seller_effect TEXT NOT NULL DEFAULT 'open',
 		aggressor_side TEXT NOT NULL,
+		buyer_fee TEXT NOT NULL DEFAULT '0',
+		seller_fee TEXT NOT NULL DEFAULT '0',
+		buyer_fee_rate TEXT NOT NULL DEFAULT '0',
+		seller_fee_rate TEXT NOT NULL DEFAULT '0',
+		buyer_realized_pnl TEXT NOT NULL DEFAULT '0',
+		seller_realized_pnl TEXT NOT NULL DEFAULT '0',
+		buyer_order_id TEXT NOT NULL DEFAULT '',
+		seller_order_id TEXT NOT NULL DEFAULT '',
+		buyer_new_position TEXT NOT NULL DEFAULT '0',
+		seller_new_position TEXT NOT NULL DEFAULT '0',
 		timestamp DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (buyer_id) REFERENCES traders(id),
 		FOREIGN KEY (seller_id) REFERENCES traders(id)
@@ -134,23 +148,139 @@ func (s *SQLiteDB) createTables() error {
 		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 	);
 
+	CREATE TABLE IF NOT EXISTS oi_history (
+		instrument TEXT NOT NULL,
+		timestamp DATETIME NOT NULL,
+		open_interest TEXT NOT NULL,
+		long_oi TEXT NOT NULL,
+		short_oi TEXT NOT NULL,
+		long_positions INTEGER NOT NULL DEFAULT 0,
+		short_positions INTEGER NOT NULL DEFAULT 0,
+		price TEXT NOT NULL,
+		PRIMARY KEY(instrument, timestamp)
+	);
+
+	CREATE TABLE IF NOT EXISTS candles (
+		instrument TEXT NOT NULL,
+		interval TEXT NOT NULL,
+		open_time DATETIME NOT NULL,
+		close_time DATETIME NOT NULL,
+		open TEXT NOT NULL,
+		high TEXT NOT NULL,
+		low TEXT NOT NULL,
+		close TEXT NOT NULL,
+		volume TEXT NOT NULL,
+		trade_count INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY(instrument, interval, open_time)
+	);
+
+	CREATE TABLE IF NOT EXISTS insurance_fund_history (
+		id TEXT PRIMARY KEY,
+		timestamp DATETIME NOT NULL,
+		cause TEXT NOT NULL,
+		delta TEXT NOT NULL,
+		balance TEXT NOT NULL,
+		liquidation_id TEXT NOT NULL DEFAULT '',
+		trade_id TEXT NOT NULL DEFAULT '',
+		note TEXT NOT NULL DEFAULT ''
+	);
+
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id TEXT PRIMARY KEY,
+		timestamp DATETIME NOT NULL,
+		trader_id TEXT NOT NULL,
+		action TEXT NOT NULL,
+		details TEXT NOT NULL DEFAULT ''
+	);
+
 	CREATE INDEX IF NOT EXISTS idx_positions_trader ON positions(trader_id);
 	CREATE INDEX IF NOT EXISTS idx_orders_trader ON orders(trader_id);
 	CREATE INDEX IF NOT EXISTS idx_orders_instrument_status ON orders(instrument, status);
 	CREATE INDEX IF NOT EXISTS idx_trades_instrument ON trades(instrument);
 	CREATE INDEX IF NOT EXISTS idx_trades_timestamp ON trades(timestamp DESC);
+	CREATE INDEX IF NOT EXISTS idx_trades_instrument_timestamp ON trades(instrument, timestamp);
 	CREATE INDEX IF NOT EXISTS idx_trades_buyer ON trades(buyer_id);
 	CREATE INDEX IF NOT EXISTS idx_trades_seller ON trades(seller_id);
 	CREATE INDEX IF NOT EXISTS idx_liquidations_instrument ON liquidations(instrument);
+	CREATE INDEX IF NOT EXISTS idx_liquidations_instrument_timestamp ON liquidations(instrument, timestamp);
+	CREATE INDEX IF NOT EXISTS idx_liquidations_trader ON liquidations(trader_id, timestamp);
+	CREATE INDEX IF NOT EXISTS idx_liquidations_loss ON liquidations(instrument, CAST(loss AS REAL) DESC);
+	CREATE INDEX IF NOT EXISTS idx_liquidations_notional ON liquidations(instrument, (CAST(size AS REAL) * CAST(mark_price AS REAL)) DESC);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_trader ON audit_log(trader_id, timestamp);
+	CREATE INDEX IF NOT EXISTS idx_insurance_fund_history_timestamp ON insurance_fund_history(timestamp);
 	`
 
-	_, err := s.db.Exec(schema)
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+	return s.migrate()
+}
+
+// migrate adds columns introduced after a table was first created
+func (s *SQLiteDB) migrate() error {
+	columns := []struct{ table, column, definition string }{
+		{"trades", "buyer_fee", "TEXT NOT NULL DEFAULT '0'"},
+		{"trades", "seller_fee", "TEXT NOT NULL DEFAULT '0'"},
+		{"trades", "buyer_fee_rate", "TEXT NOT NULL DEFAULT '0'"},
+		{"trades", "seller_fee_rate", "TEXT NOT NULL DEFAULT '0'"},
+		{"trades", "buyer_realized_pnl", "TEXT NOT NULL DEFAULT '0'"},
+		{"trades", "seller_realized_pnl", "TEXT NOT NULL DEFAULT '0'"},
+		{"trades", "buyer_order_id", "TEXT NOT NULL DEFAULT ''"},
+		{"trades", "seller_order_id", "TEXT NOT NULL DEFAULT ''"},
+		{"trades", "buyer_new_position", "TEXT NOT NULL DEFAULT '0'"},
+		{"trades", "seller_new_position", "TEXT NOT NULL DEFAULT '0'"},
+		{"orders", "stop_price", "TEXT NOT NULL DEFAULT '0'"},
+		{"orders", "oco_group_id", "TEXT NOT NULL DEFAULT ''"},
+		{"orders", "display_size", "TEXT NOT NULL DEFAULT '0'"},
+		{"orders", "reduce_only", "INTEGER NOT NULL DEFAULT 0"},
+		{"traders", "api_key_hash", "TEXT NOT NULL DEFAULT ''"},
+		{"traders", "margin_mode", "TEXT NOT NULL DEFAULT ''"},
+		{"liquidations", "effect", "TEXT NOT NULL DEFAULT 'liquidation'"},
+		{"candles", "vwap", "TEXT NOT NULL DEFAULT '0'"},
+	}
+	for _, c := range columns {
+		if err := s.addColumnIfMissing(c.table, c.column, c.definition); err != nil {
+			return fmt.Errorf("adding %s.%s: %w", c.table, c.column, err)
+		}
+	}
+
+	// Indexes on migrated columns
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_traders_api_key ON traders(api_key_hash)`); err != nil {
+		return fmt.Errorf("indexing traders.api_key_hash: %w", err)
+	}
+	return nil
+}
+
+// addColumnIfMissing adds a column to an existing table unless it is already there
+func (s *SQLiteDB) addColumnIfMissing(table, column, definition string) error {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
 	return err
 }
 
-// Close closes the database connection
+// Close closes the database connection, first committing any queued writes
 func (s *SQLiteDB) Close() error {
-	return s.db.Close()
+	return s.CloseContext(context.Background())
 }
 
 // === Trader Operations ===
@@ -158,24 +288,27 @@ func (s *SQLiteDB) Close() error {
 // SaveTrader inserts or updates a trader
 func (s *SQLiteDB) SaveTrader(trader *domain.Trader) error {
 	query := `
-	INSERT INTO traders (id, username, password_hash, type, balance, total_pnl, trade_count, max_leverage_used, created_at)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	INSERT INTO traders (id, username, password_hash, api_key_hash, type, balance, total_pnl, trade_count, max_leverage_used, margin_mode, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	ON CONFLICT(id) DO UPDATE SET
 		username = excluded.username,
 		balance = excluded.balance,
 		total_pnl = excluded.total_pnl,
 		trade_count = excluded.trade_count,
-		max_leverage_used = excluded.max_leverage_used
+		max_leverage_used = excluded.max_leverage_used,
+		margin_mode = excluded.margin_mode
 	`
-	_, err := s.db.Exec(query,
+	err := s.exec(query,
 		trader.ID.String(),
 		trader.Username,
 		trader.PasswordHash,
+		trader.APIKeyHash,
 		string(trader.Type),
 		trader.Balance.String(),
 		trader.TotalPnL.String(),
 		trader.TradeCount,
 		trader.MaxLeverageUsed,
+		string(trader.MarginMode),
 		trader.CreatedAt,
 	)
 	return err
@@ -183,12 +316,12 @@ func (s *SQLiteDB) SaveTrader(trader *domain.Trader) error {
 
 // GetTrader retrieves a trader by ID
 func (s *SQLiteDB) GetTrader(id uuid.UUID) (*domain.Trader, error) {
-	query := `SELECT id, username, password_hash, type, balance, total_pnl, trade_count, max_leverage_used, created_at FROM traders WHERE id = ?`
+	query := `SELECT id, username, password_hash, api_key_hash, type, balance, total_pnl, trade_count, max_leverage_used, margin_mode, created_at FROM traders WHERE id = ?`
 	row := s.db.QueryRow(query, id.String())
 
 	var trader domain.Trader
-	var idStr, typeStr, balanceStr, pnlStr string
-	err := row.Scan(&idStr, &trader.Username, &trader.PasswordHash, &typeStr, &balanceStr, &pnlStr, &trader.TradeCount, &trader.MaxLeverageUsed, &trader.CreatedAt)
+	var idStr, typeStr, balanceStr, pnlStr, modeStr string
+	err := row.Scan(&idStr, &trader.Username, &trader.PasswordHash, &trader.APIKeyHash, &typeStr, &balanceStr, &pnlStr, &trader.TradeCount, &trader.MaxLeverageUsed, &modeStr, &trader.CreatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -200,18 +333,47 @@ func (s *SQLiteDB) GetTrader(id uuid.UUID) (*domain.Trader, error) {
 	trader.Type = domain.TraderType(typeStr)
 	trader.Balance, _ = decimal.NewFromString(balanceStr)
 	trader.TotalPnL, _ = decimal.NewFromString(pnlStr)
+	trader.MarginMode = domain.MarginMode(modeStr)
 
 	return &trader, nil
 }
 
 // GetTraderByUsername retrieves a trader by username
 func (s *SQLiteDB) GetTraderByUsername(username string) (*domain.Trader, error) {
-	query := `SELECT id, username, password_hash, type, balance, total_pnl, trade_count, max_leverage_used, created_at FROM traders WHERE username = ?`
+	query := `SELECT id, username, password_hash, api_key_hash, type, balance, total_pnl, trade_count, max_leverage_used, margin_mode, created_at FROM traders WHERE username = ?`
 	row := s.db.QueryRow(query, username)
 
 	var trader domain.Trader
-	var idStr, typeStr, balanceStr, pnlStr string
-	err := row.Scan(&idStr, &trader.Username, &trader.PasswordHash, &typeStr, &balanceStr, &pnlStr, &trader.TradeCount, &trader.MaxLeverageUsed, &trader.CreatedAt)
+	var idStr, typeStr, balanceStr, pnlStr, modeStr string
+	err := row.Scan(&idStr, &trader.Username, &trader.PasswordHash, &trader.APIKeyHash, &typeStr, &balanceStr, &pnlStr, &trader.TradeCount, &trader.MaxLeverageUsed, &modeStr, &trader.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	trader.ID, _ = uuid.Parse(idStr)
+	trader.Type = domain.TraderType(typeStr)
+	trader.Balance, _ = decimal.NewFromString(balanceStr)
+	trader.TotalPnL, _ = decimal.NewFromString(pnlStr)
+	trader.MarginMode = domain.MarginMode(modeStr)
+
+	return &trader, nil
+}
+
+// GetTraderByAPIKey retrieves a trader by API key hash
+func (s *SQLiteDB) GetTraderByAPIKey(apiKeyHash string) (*domain.Trader, error) {
+	// A key just issued may still be queued
+	if err := s.Flush(); err != nil {
+		return nil, err
+	}
+	query := `SELECT id, username, password_hash, api_key_hash, type, balance, total_pnl, trade_count, max_leverage_used, margin_mode, created_at FROM traders WHERE api_key_hash = ?`
+	row := s.db.QueryRow(query, apiKeyHash)
+
+	var trader domain.Trader
+	var idStr, typeStr, balanceStr, pnlStr, modeStr string
+	err := row.Scan(&idStr, &trader.Username, &trader.PasswordHash, &trader.APIKeyHash, &typeStr, &balanceStr, &pnlStr, &trader.TradeCount, &trader.MaxLeverageUsed, &modeStr, &trader.CreatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -223,13 +385,20 @@ func (s *SQLiteDB) GetTraderByUsername(username string) (*domain.Trader, error)
 	trader.Type = domain.TraderType(typeStr)
 	trader.Balance, _ = decimal.NewFromString(balanceStr)
 	trader.TotalPnL, _ = decimal.NewFromString(pnlStr)
+	trader.MarginMode = domain.MarginMode(modeStr)
 
 	return &trader, nil
 }
 
+// UpdateTraderAPIKey replaces a trader's API key hash
+func (s *SQLiteDB) UpdateTraderAPIKey(id uuid.UUID, apiKeyHash string) error {
+	err := s.exec(`UPDATE traders SET api_key_hash = ? WHERE id = ?`, apiKeyHash, id.String())
+	return err
+}
+
 // GetAllTraders retrieves all traders
 func (s *SQLiteDB) GetAllTraders() ([]*domain.Trader, error) {
-	query := `SELECT id, username, password_hash, type, balance, total_pnl, trade_count, max_leverage_used, created_at FROM traders ORDER BY created_at DESC`
+	query := `SELECT id, username, password_hash, api_key_hash, type, balance, total_pnl, trade_count, max_leverage_used, margin_mode, created_at FROM traders ORDER BY created_at DESC`
 	rows, err := s.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -239,14 +408,15 @@ func (s *SQLiteDB) GetAllTraders() ([]*domain.Trader, error) {
 	var traders []*domain.Trader
 	for rows.Next() {
 		var trader domain.Trader
-		var idStr, typeStr, balanceStr, pnlStr string
-		if err := rows.Scan(&idStr, &trader.Username, &trader.PasswordHash, &typeStr, &balanceStr, &pnlStr, &trader.TradeCount, &trader.MaxLeverageUsed, &trader.CreatedAt); err != nil {
+		var idStr, typeStr, balanceStr, pnlStr, modeStr string
+		if err := rows.Scan(&idStr, &trader.Username, &trader.PasswordHash, &trader.APIKeyHash, &typeStr, &balanceStr, &pnlStr, &trader.TradeCount, &trader.MaxLeverageUsed, &modeStr, &trader.CreatedAt); err != nil {
 			return nil, err
 		}
 		trader.ID, _ = uuid.Parse(idStr)
 		trader.Type = domain.TraderType(typeStr)
 		trader.Balance, _ = decimal.NewFromString(balanceStr)
 		trader.TotalPnL, _ = decimal.NewFromString(pnlStr)
+		trader.MarginMode = domain.MarginMode(modeStr)
 		traders = append(traders, &trader)
 	}
 
@@ -270,7 +440,7 @@ func (s *SQLiteDB) SavePosition(pos *domain.Position) error {
 		liquidation_price = excluded.liquidation_price,
 		updated_at = excluded.updated_at
 	`
-	_, err := s.db.Exec(query,
+	err := s.exec(query,
 		pos.TraderID.String(),
 		pos.Instrument,
 		pos.Size.String(),
@@ -280,14 +450,14 @@ func (s *SQLiteDB) SavePosition(pos *domain.Position) error {
 		pos.UnrealizedPnL.String(),
 		pos.RealizedPnL.String(),
 		pos.LiquidationPrice.String(),
-		time.Now(),
+		time.Now().UTC(),
 	)
 	return err
 }
 
 // DeletePosition removes a position (when closed)
 func (s *SQLiteDB) DeletePosition(traderID uuid.UUID, instrument string) error {
-	_, err := s.db.Exec("DELETE FROM positions WHERE trader_id = ? AND instrument = ?", traderID.String(), instrument)
+	err := s.exec("DELETE FROM positions WHERE trader_id = ? AND instrument = ?", traderID.String(), instrument)
 	return err
 }
 
@@ -319,7 +489,7 @@ func (s *SQLiteDB) GetPosition(traderID uuid.UUID, instrument string) (*domain.P
 
 // GetAllPositions retrieves all positions for an instrument
 func (s *SQLiteDB) GetAllPositions(instrument string) ([]*domain.Position, error) {
-	query := `SELECT trader_id, instrument, size, entry_price, leverage, margin, unrealized_pnl, realized_pnl, liquidation_price, updated_at FROM positions WHERE instrument = ?`
+	query := "SELECT " + positionColumns + " FROM positions WHERE instrument = ?"
 	rows, err := s.db.Query(query, instrument)
 	if err != nil {
 		return nil, err
@@ -328,37 +498,57 @@ func (s *SQLiteDB) GetAllPositions(instrument string) ([]*domain.Position, error
 
 	var positions []*domain.Position
 	for rows.Next() {
-		var pos domain.Position
-		var traderIDStr, sizeStr, entryStr, marginStr, unrealizedStr, realizedStr, liqStr string
-		if err := rows.Scan(&traderIDStr, &pos.Instrument, &sizeStr, &entryStr, &pos.Leverage, &marginStr, &unrealizedStr, &realizedStr, &liqStr, &pos.UpdatedAt); err != nil {
+		pos, err := scanPosition(rows)
+		if err != nil {
 			return nil, err
 		}
-		pos.TraderID, _ = uuid.Parse(traderIDStr)
-		pos.Size, _ = decimal.NewFromString(sizeStr)
-		pos.EntryPrice, _ = decimal.NewFromString(entryStr)
-		pos.Margin, _ = decimal.NewFromString(marginStr)
-		pos.UnrealizedPnL, _ = decimal.NewFromString(unrealizedStr)
-		pos.RealizedPnL, _ = decimal.NewFromString(realizedStr)
-		pos.LiquidationPrice, _ = decimal.NewFromString(liqStr)
-		positions = append(positions, &pos)
+		positions = append(positions, pos)
 	}
 
 	return positions, nil
 }
 
+// positionColumns is the column list scanPosition expects
+const positionColumns = "trader_id, instrument, size, entry_price, leverage, margin, unrealized_pnl, realized_pnl, liquidation_price, updated_at"
+
+// scanPosition reads the current row of a positions query selected with
+// positionColumns
+func scanPosition(rows *sql.Rows) (*domain.Position, error) {
+	var pos domain.Position
+	var traderIDStr, sizeStr, entryStr, marginStr, unrealizedStr, realizedStr, liqStr string
+	if err := rows.Scan(&traderIDStr, &pos.Instrument, &sizeStr, &entryStr, &pos.Leverage, &marginStr, &unrealizedStr, &realizedStr, &liqStr, &pos.UpdatedAt); err != nil {
+		return nil, err
+	}
+	pos.TraderID, _ = uuid.Parse(traderIDStr)
+	pos.Size, _ = decimal.NewFromString(sizeStr)
+	pos.EntryPrice, _ = decimal.NewFromString(entryStr)
+	pos.Margin, _ = decimal.NewFromString(marginStr)
+	pos.UnrealizedPnL, _ = decimal.NewFromString(unrealizedStr)
+	pos.RealizedPnL, _ = decimal.NewFromString(realizedStr)
+	pos.LiquidationPrice, _ = decimal.NewFromString(liqStr)
+	return &pos, nil
+}
+
 // === Order Operations ===
 
 // SaveOrder inserts or updates an order
 func (s *SQLiteDB) SaveOrder(order *domain.Order) error {
 	query := `
-	INSERT INTO orders (id, trader_id, instrument, side, type, price, size, filled_size, status, leverage, created_at, updated_at)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	INSERT INTO orders (id, trader_id, instrument, side, type, price, size, filled_size, status, leverage, stop_price, oco_group_id, display_size, reduce_only, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	ON CONFLICT(id) DO UPDATE SET
+		price = excluded.price,
+		size = excluded.size,
 		filled_size = excluded.filled_size,
 		status = excluded.status,
+		oco_group_id = excluded.oco_group_id,
 		updated_at = excluded.updated_at
 	`
-	_, err := s.db.Exec(query,
+	ocoGroupID := ""
+	if order.OCOGroupID != nil {
+		ocoGroupID = order.OCOGroupID.String()
+	}
+	err := s.exec(query,
 		order.ID.String(),
 		order.TraderID.String(),
 		order.Instrument,
@@ -369,6 +559,10 @@ func (s *SQLiteDB) SaveOrder(order *domain.Order) error {
 		order.FilledSize.String(),
 		string(order.Status),
 		order.Leverage,
+		order.StopPrice.String(),
+		ocoGroupID,
+		order.DisplaySize.String(),
+		order.ReduceOnly,
 		order.CreatedAt,
 		order.UpdatedAt,
 	)
@@ -377,13 +571,13 @@ func (s *SQLiteDB) SaveOrder(order *domain.Order) error {
 
 // DeleteOrder removes an order
 func (s *SQLiteDB) DeleteOrder(orderID uuid.UUID) error {
-	_, err := s.db.Exec("DELETE FROM orders WHERE id = ?", orderID.String())
+	err := s.exec("DELETE FROM orders WHERE id = ?", orderID.String())
 	return err
 }
 
 // GetOpenOrders retrieves open orders for an instrument
 func (s *SQLiteDB) GetOpenOrders(instrument string) ([]*domain.Order, error) {
-	query := `SELECT id, trader_id, instrument, side, type, price, size, filled_size, status, leverage, created_at, updated_at FROM orders WHERE instrument = ? AND status = 'open' ORDER BY created_at`
+	query := "SELECT " + orderColumns + " FROM orders WHERE instrument = ? AND status IN ('pending', 'partial') ORDER BY created_at"
 	rows, err := s.db.Query(query, instrument)
 	if err != nil {
 		return nil, err
@@ -392,16 +586,99 @@ func (s *SQLiteDB) GetOpenOrders(instrument string) ([]*domain.Order, error) {
 
 	var orders []*domain.Order
 	for rows.Next() {
-		var order domain.Order
-		var idStr, traderIDStr, sideStr, typeStr, priceStr, sizeStr, filledStr, statusStr string
-		if err := rows.Scan(&idStr, &traderIDStr, &order.Instrument, &sideStr, &typeStr, &priceStr, &sizeStr, &filledStr, &statusStr, &order.Leverage, &order.CreatedAt, &order.UpdatedAt); err != nil {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// GetOrder retrieves an order by ID, or nil if it was never persisted
+func (s *SQLiteDB) GetOrder(id uuid.UUID) (*domain.Order, error) {
+	// An order that just left the book may still be queued
+	if err := s.Flush(); err != nil {
+		return nil, err
+	}
+	query := "SELECT " + orderColumns + " FROM orders WHERE id = ?"
+	rows, err := s.db.Query(query, id.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	return scanOrder(rows)
+}
+
+// GetOrdersByTrader retrieves a trader's orders in one status, newest first
+func (s *SQLiteDB) GetOrdersByTrader(traderID uuid.UUID, instrument string, status domain.OrderStatus, limit int) ([]*domain.Order, error) {
+	query := "SELECT " + orderColumns + " FROM orders WHERE trader_id = ? AND instrument = ? AND status = ? ORDER BY updated_at DESC LIMIT ?"
+	rows, err := s.db.Query(query, traderID.String(), instrument, string(status), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*domain.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// orderColumns is the column list scanOrder expects
+const orderColumns = "id, trader_id, instrument, side, type, price, size, filled_size, status, leverage, stop_price, oco_group_id, display_size, reduce_only, created_at, updated_at"
+
+// scanOrder reads the current row of an orders query selected with
+// orderColumns
+func scanOrder(rows *sql.Rows) (*domain.Order, error) {
+	var order domain.Order
+	var idStr, traderIDStr, sideStr, typeStr, priceStr, sizeStr, filledStr, statusStr, stopStr, ocoStr, displayStr string
+	if err := rows.Scan(&idStr, &traderIDStr, &order.Instrument, &sideStr, &typeStr, &priceStr, &sizeStr, &filledStr, &statusStr, &order.Leverage, &stopStr, &ocoStr, &displayStr, &order.ReduceOnly, &order.CreatedAt, &order.UpdatedAt); err != nil {
+		return nil, err
+	}
+	order.StopPrice, _ = decimal.NewFromString(stopStr)
+	order.DisplaySize, _ = decimal.NewFromString(displayStr)
+	if groupID, err := uuid.Parse(ocoStr); err == nil {
+		order.OCOGroupID = &groupID
+	}
+	order.ID, _ = uuid.Parse(idStr)
+	order.TraderID, _ = uuid.Parse(traderIDStr)
+	order.Side = domain.Side(sideStr)
+	order.Type = domain.OrderType(typeStr)
+	order.Price, _ = decimal.NewFromString(priceStr)
+	order.Size, _ = decimal.NewFromString(sizeStr)
+	order.FilledSize, _ = decimal.NewFromString(filledStr)
+	order.Status = domain.OrderStatus(statusStr)
+	return &order, nil
+}
+
+// GetTraderOrderLifecycles retrieves size, fill and timing data for a trader's resting orders
+func (s *SQLiteDB) GetTraderOrderLifecycles(traderID uuid.UUID, instrument string) ([]*domain.Order, error) {
+	query := `SELECT size, filled_size, status, created_at, updated_at FROM orders WHERE trader_id = ? AND instrument = ?`
+	rows, err := s.db.Query(query, traderID.String(), instrument)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*domain.Order
+	for rows.Next() {
+		order := domain.Order{TraderID: traderID, Instrument: instrument}
+		var sizeStr, filledStr, statusStr string
+		if err := rows.Scan(&sizeStr, &filledStr, &statusStr, &order.CreatedAt, &order.UpdatedAt); err != nil {
 			return nil, err
 		}
-		order.ID, _ = uuid.Parse(idStr)
-		order.TraderID, _ = uuid.Parse(traderIDStr)
-		order.Side = domain.Side(sideStr)
-		order.Type = domain.OrderType(typeStr)
-		order.Price, _ = decimal.NewFromString(priceStr)
 		order.Size, _ = decimal.NewFromString(sizeStr)
 		order.FilledSize, _ = decimal.NewFromString(filledStr)
 		order.Status = domain.OrderStatus(statusStr)
@@ -416,10 +693,10 @@ func (s *SQLiteDB) GetOpenOrders(instrument string) ([]*domain.Order, error) {
 // SaveTrade inserts a trade
 func (s *SQLiteDB) SaveTrade(trade *domain.Trade) error {
 	query := `
-	INSERT INTO trades (id, instrument, price, size, buyer_id, seller_id, buyer_leverage, seller_leverage, buyer_effect, seller_effect, aggressor_side, timestamp)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	INSERT INTO trades (id, instrument, price, size, buyer_id, seller_id, buyer_leverage, seller_leverage, buyer_effect, seller_effect, aggressor_side, buyer_fee, seller_fee, buyer_fee_rate, seller_fee_rate, buyer_realized_pnl, seller_realized_pnl, buyer_order_id, seller_order_id, buyer_new_position, seller_new_position, timestamp)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	_, err := s.db.Exec(query,
+	err := s.exec(query,
 		trade.ID.String(),
 		trade.Instrument,
 		trade.Price.String(),
@@ -431,69 +708,264 @@ func (s *SQLiteDB) SaveTrade(trade *domain.Trade) error {
 		string(trade.BuyerEffect),
 		string(trade.SellerEffect),
 		string(trade.AggressorSide),
-		trade.Timestamp,
+		trade.BuyerFee.String(),
+		trade.SellerFee.String(),
+		trade.BuyerFeeRate.String(),
+		trade.SellerFeeRate.String(),
+		trade.BuyerRealizedPnL.String(),
+		trade.SellerRealizedPnL.String(),
+		trade.BuyerOrderID.String(),
+		trade.SellerOrderID.String(),
+		trade.BuyerNewPosition.String(),
+		trade.SellerNewPosition.String(),
+		trade.Timestamp.UTC(),
 	)
 	return err
 }
 
 // GetRecentTrades retrieves recent trades for an instrument
 func (s *SQLiteDB) GetRecentTrades(instrument string, limit int) ([]*domain.Trade, error) {
-	query := `SELECT id, instrument, price, size, buyer_id, seller_id, buyer_leverage, seller_leverage, buyer_effect, seller_effect, aggressor_side, timestamp FROM trades WHERE instrument = ? ORDER BY timestamp DESC LIMIT ?`
+	query := "SELECT " + tradeColumns + " FROM trades WHERE instrument = ? ORDER BY timestamp DESC LIMIT ?"
 	rows, err := s.db.Query(query, instrument, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	return scanTrades(rows)
+}
+
+// GetTradesBefore retrieves trades ordered before a (timestamp, id) cursor,
+// newest first, for paging back through history. The id breaks ties between
+// trades sharing a timestamp; uuid.Nil keeps every trade at that timestamp out.
+func (s *SQLiteDB) GetTradesBefore(instrument string, before time.Time, beforeID uuid.UUID, limit int) ([]*domain.Trade, error) {
+	query := "SELECT " + tradeColumns + " FROM trades WHERE instrument = ? AND (timestamp, id) < (?, ?) ORDER BY timestamp DESC, id DESC LIMIT ?"
+	rows, err := s.db.Query(query, instrument, before.UTC(), beforeID.String(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTrades(rows)
+}
+
+// GetTradesAfter retrieves trades ordered after a (timestamp, id) cursor,
+// oldest first, for paging forward through history
+func (s *SQLiteDB) GetTradesAfter(instrument string, after time.Time, afterID uuid.UUID, limit int) ([]*domain.Trade, error) {
+	query := "SELECT " + tradeColumns + " FROM trades WHERE instrument = ? AND (timestamp, id) > (?, ?) ORDER BY timestamp ASC, id ASC LIMIT ?"
+	rows, err := s.db.Query(query, instrument, after.UTC(), afterID.String(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTrades(rows)
+}
+
+// GetTradesInRange retrieves trades within a time range, newest first
+func (s *SQLiteDB) GetTradesInRange(instrument string, start, end time.Time, limit int) ([]*domain.Trade, error) {
+	query := "SELECT " + tradeColumns + " FROM trades WHERE instrument = ? AND timestamp >= ? AND timestamp <= ? ORDER BY timestamp DESC LIMIT ?"
+	rows, err := s.db.Query(query, instrument, start.UTC(), end.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTrades(rows)
+}
+
+// TradeStats aggregates an instrument's trades over a period
+type TradeStats struct {
+	High, Low             decimal.Decimal
+	Volume                decimal.Decimal // Quote volume
+	BuyVolume, SellVolume decimal.Decimal // Quote volume by aggressor side
+	Count                 int
+}
+
+// GetTradeStatsSince returns the high and low price, quote volume (in total
+// and by aggressor side) and count of an instrument's trades after a point in
+// time, aggregated in SQL over the instrument/timestamp index. Prices and
+// sizes are stored as text, so they are compared and summed as reals.
+func (s *SQLiteDB) GetTradeStatsSince(instrument string, since time.Time) (*TradeStats, error) {
+	query := `SELECT COUNT(*), MAX(CAST(price AS REAL)), MIN(CAST(price AS REAL)),
+		SUM(CAST(size AS REAL) * CAST(price AS REAL)),
+		SUM(CASE WHEN aggressor_side = ? THEN CAST(size AS REAL) * CAST(price AS REAL) ELSE 0 END),
+		SUM(CASE WHEN aggressor_side = ? THEN CAST(size AS REAL) * CAST(price AS REAL) ELSE 0 END)
+	FROM trades WHERE instrument = ? AND timestamp > ?`
+	var stats TradeStats
+	var maxPrice, minPrice, sum, buySum, sellSum sql.NullFloat64
+	row := s.db.QueryRow(query, string(domain.SideBuy), string(domain.SideSell), instrument, since.UTC())
+	if err := row.Scan(&stats.Count, &maxPrice, &minPrice, &sum, &buySum, &sellSum); err != nil {
+		return nil, err
+	}
+	if stats.Count == 0 {
+		return &stats, nil
+	}
+	stats.High = decimal.NewFromFloat(maxPrice.Float64)
+	stats.Low = decimal.NewFromFloat(minPrice.Float64)
+	stats.Volume = decimal.NewFromFloat(sum.Float64)
+	stats.BuyVolume = decimal.NewFromFloat(buySum.Float64)
+	stats.SellVolume = decimal.NewFromFloat(sellSum.Float64)
+	return &stats, nil
+}
+
+// GetTradesByPriceRange retrieves trades priced within [minPrice, maxPrice]
+// inside a time range, newest first. Prices are stored as text, so the band is
+// compared numerically; the timestamp index narrows the scan.
+func (s *SQLiteDB) GetTradesByPriceRange(instrument string, minPrice, maxPrice decimal.Decimal, start, end time.Time, limit int) ([]*domain.Trade, error) {
+	query := "SELECT " + tradeColumns + " FROM trades WHERE instrument = ? AND timestamp >= ? AND timestamp <= ? AND CAST(price AS REAL) >= ? AND CAST(price AS REAL) <= ? ORDER BY timestamp DESC LIMIT ?"
+	rows, err := s.db.Query(query, instrument, start.UTC(), end.UTC(), minPrice.InexactFloat64(), maxPrice.InexactFloat64(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTrades(rows)
+}
+
+// GetRealizedPnLByTrader sums each trader's realized P&L within a time range
+// from closing trades and liquidation losses, along with how many closing
+// fills and liquidations contributed
+func (s *SQLiteDB) GetRealizedPnLByTrader(start, end time.Time) (map[uuid.UUID]*domain.PnLLeaderboardEntry, error) {
+	entries := make(map[uuid.UUID]*domain.PnLLeaderboardEntry)
+	entry := func(idStr string) *domain.PnLLeaderboardEntry {
+		id, _ := uuid.Parse(idStr)
+		e, ok := entries[id]
+		if !ok {
+			e = &domain.PnLLeaderboardEntry{TraderID: id}
+			entries[id] = e
+		}
+		return e
+	}
+
+	query := `SELECT buyer_id, seller_id, buyer_realized_pnl, seller_realized_pnl FROM trades WHERE timestamp >= ? AND timestamp <= ? AND (buyer_realized_pnl != '0' OR seller_realized_pnl != '0')`
+	rows, err := s.db.Query(query, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var buyerIDStr, sellerIDStr, buyerPnLStr, sellerPnLStr string
+		if err := rows.Scan(&buyerIDStr, &sellerIDStr, &buyerPnLStr, &sellerPnLStr); err != nil {
+			return nil, err
+		}
+		for _, side := range [][2]string{{buyerIDStr, buyerPnLStr}, {sellerIDStr, sellerPnLStr}} {
+			pnl, _ := decimal.NewFromString(side[1])
+			if pnl.IsZero() {
+				continue
+			}
+			e := entry(side[0])
+			e.RealizedPnL = e.RealizedPnL.Add(pnl)
+			e.ClosingTrades++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	liqRows, err := s.db.Query(`SELECT trader_id, loss, effect FROM liquidations WHERE timestamp >= ? AND timestamp <= ?`, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer liqRows.Close()
+	for liqRows.Next() {
+		var traderIDStr, lossStr, effectStr string
+		if err := liqRows.Scan(&traderIDStr, &lossStr, &effectStr); err != nil {
+			return nil, err
+		}
+		loss, _ := decimal.NewFromString(lossStr)
+		e := entry(traderIDStr)
+		e.RealizedPnL = e.RealizedPnL.Sub(loss)
+		if domain.PositionEffect(effectStr) != domain.EffectADL {
+			e.Liquidations++
+		}
+	}
+
+	return entries, liqRows.Err()
+}
+
+// tradeColumns is the column list scanTrades expects
+const tradeColumns = "id, instrument, price, size, buyer_id, seller_id, buyer_leverage, seller_leverage, buyer_effect, seller_effect, aggressor_side, buyer_fee, seller_fee, buyer_fee_rate, seller_fee_rate, buyer_realized_pnl, seller_realized_pnl, buyer_order_id, seller_order_id, buyer_new_position, seller_new_position, timestamp"
+
+// scanTrades reads trade rows selected with tradeColumns
+func scanTrades(rows *sql.Rows) ([]*domain.Trade, error) {
 	var trades []*domain.Trade
 	for rows.Next() {
-		var trade domain.Trade
-		var idStr, buyerIDStr, sellerIDStr, priceStr, sizeStr, buyerEffectStr, sellerEffectStr, aggressorStr string
-		if err := rows.Scan(&idStr, &trade.Instrument, &priceStr, &sizeStr, &buyerIDStr, &sellerIDStr, &trade.BuyerLeverage, &trade.SellerLeverage, &buyerEffectStr, &sellerEffectStr, &aggressorStr, &trade.Timestamp); err != nil {
+		trade, err := scanTrade(rows)
+		if err != nil {
 			return nil, err
 		}
-		trade.ID, _ = uuid.Parse(idStr)
-		trade.BuyerID, _ = uuid.Parse(buyerIDStr)
-		trade.SellerID, _ = uuid.Parse(sellerIDStr)
-		trade.Price, _ = decimal.NewFromString(priceStr)
-		trade.Size, _ = decimal.NewFromString(sizeStr)
-		trade.BuyerEffect = domain.PositionEffect(buyerEffectStr)
-		trade.SellerEffect = domain.PositionEffect(sellerEffectStr)
-		trade.AggressorSide = domain.Side(aggressorStr)
-		trades = append(trades, &trade)
+		trades = append(trades, trade)
 	}
 
-	return trades, nil
+	return trades, rows.Err()
+}
+
+// scanTrade reads the current row of a trades query selected with tradeColumns
+func scanTrade(rows *sql.Rows) (*domain.Trade, error) {
+	var trade domain.Trade
+	var idStr, buyerIDStr, sellerIDStr, priceStr, sizeStr, buyerEffectStr, sellerEffectStr, aggressorStr string
+	var buyerFeeStr, sellerFeeStr, buyerRateStr, sellerRateStr, buyerPnLStr, sellerPnLStr string
+	var buyerOrderStr, sellerOrderStr, buyerPosStr, sellerPosStr string
+	if err := rows.Scan(&idStr, &trade.Instrument, &priceStr, &sizeStr, &buyerIDStr, &sellerIDStr, &trade.BuyerLeverage, &trade.SellerLeverage, &buyerEffectStr, &sellerEffectStr, &aggressorStr, &buyerFeeStr, &sellerFeeStr, &buyerRateStr, &sellerRateStr, &buyerPnLStr, &sellerPnLStr, &buyerOrderStr, &sellerOrderStr, &buyerPosStr, &sellerPosStr, &trade.Timestamp); err != nil {
+		return nil, err
+	}
+	trade.ID, _ = uuid.Parse(idStr)
+	trade.BuyerID, _ = uuid.Parse(buyerIDStr)
+	trade.SellerID, _ = uuid.Parse(sellerIDStr)
+	trade.Price, _ = decimal.NewFromString(priceStr)
+	trade.Size, _ = decimal.NewFromString(sizeStr)
+	trade.BuyerEffect = domain.PositionEffect(buyerEffectStr)
+	trade.SellerEffect = domain.PositionEffect(sellerEffectStr)
+	trade.AggressorSide = domain.Side(aggressorStr)
+	trade.BuyerFee, _ = decimal.NewFromString(buyerFeeStr)
+	trade.SellerFee, _ = decimal.NewFromString(sellerFeeStr)
+	trade.BuyerFeeRate, _ = decimal.NewFromString(buyerRateStr)
+	trade.SellerFeeRate, _ = decimal.NewFromString(sellerRateStr)
+	trade.BuyerRealizedPnL, _ = decimal.NewFromString(buyerPnLStr)
+	trade.SellerRealizedPnL, _ = decimal.NewFromString(sellerPnLStr)
+	trade.BuyerOrderID, _ = uuid.Parse(buyerOrderStr)
+	trade.SellerOrderID, _ = uuid.Parse(sellerOrderStr)
+	trade.BuyerNewPosition, _ = decimal.NewFromString(buyerPosStr)
+	trade.SellerNewPosition, _ = decimal.NewFromString(sellerPosStr)
+	return &trade, nil
 }
 
 // GetTraderTrades retrieves trades for a specific trader
 func (s *SQLiteDB) GetTraderTrades(traderID uuid.UUID, instrument string, limit int) ([]*domain.Trade, error) {
-	query := `SELECT id, instrument, price, size, buyer_id, seller_id, buyer_leverage, seller_leverage, buyer_effect, seller_effect, aggressor_side, timestamp FROM trades WHERE instrument = ? AND (buyer_id = ? OR seller_id = ?) ORDER BY timestamp DESC LIMIT ?`
+	query := "SELECT " + tradeColumns + " FROM trades WHERE instrument = ? AND (buyer_id = ? OR seller_id = ?) ORDER BY timestamp DESC LIMIT ?"
 	rows, err := s.db.Query(query, instrument, traderID.String(), traderID.String(), limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var trades []*domain.Trade
-	for rows.Next() {
-		var trade domain.Trade
-		var idStr, buyerIDStr, sellerIDStr, priceStr, sizeStr, buyerEffectStr, sellerEffectStr, aggressorStr string
-		if err := rows.Scan(&idStr, &trade.Instrument, &priceStr, &sizeStr, &buyerIDStr, &sellerIDStr, &trade.BuyerLeverage, &trade.SellerLeverage, &buyerEffectStr, &sellerEffectStr, &aggressorStr, &trade.Timestamp); err != nil {
-			return nil, err
-		}
-		trade.ID, _ = uuid.Parse(idStr)
-		trade.BuyerID, _ = uuid.Parse(buyerIDStr)
-		trade.SellerID, _ = uuid.Parse(sellerIDStr)
-		trade.Price, _ = decimal.NewFromString(priceStr)
-		trade.Size, _ = decimal.NewFromString(sizeStr)
-		trade.BuyerEffect = domain.PositionEffect(buyerEffectStr)
-		trade.SellerEffect = domain.PositionEffect(sellerEffectStr)
-		trade.AggressorSide = domain.Side(aggressorStr)
-		trades = append(trades, &trade)
+	return scanTrades(rows)
+}
+
+// GetTraderTradesSince retrieves a trader's trades from a point in time, oldest first
+func (s *SQLiteDB) GetTraderTradesSince(traderID uuid.UUID, instrument string, start time.Time, limit int) ([]*domain.Trade, error) {
+	query := "SELECT " + tradeColumns + " FROM trades WHERE instrument = ? AND (buyer_id = ? OR seller_id = ?) AND timestamp >= ? ORDER BY timestamp ASC LIMIT ?"
+	rows, err := s.db.Query(query, instrument, traderID.String(), traderID.String(), start.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTrades(rows)
+}
+
+// GetTradesUntil returns every trade on an instrument at or before end, oldest
+// first. Used to replay position history.
+func (s *SQLiteDB) GetTradesUntil(instrument string, end time.Time) ([]*domain.Trade, error) {
+	query := "SELECT " + tradeColumns + " FROM trades WHERE instrument = ? AND timestamp <= ? ORDER BY timestamp ASC"
+	rows, err := s.db.Query(query, instrument, end.UTC())
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return trades, nil
+	return scanTrades(rows)
 }
 
 // === Liquidation Operations ===
@@ -501,14 +973,18 @@ func (s *SQLiteDB) GetTraderTrades(traderID uuid.UUID, instrument string, limit
 // SaveLiquidation inserts a liquidation
 func (s *SQLiteDB) SaveLiquidation(liq *domain.Liquidation) error {
 	query := `
-	INSERT INTO liquidations (id, trader_id, instrument, side, size, entry_price, liquidation_price, mark_price, leverage, loss, insurance_fund_hit, timestamp)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	INSERT INTO liquidations (id, trader_id, instrument, side, size, entry_price, liquidation_price, mark_price, leverage, loss, insurance_fund_hit, effect, timestamp)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	insuranceFundHit := 0
 	if liq.InsuranceFundHit {
 		insuranceFundHit = 1
 	}
-	_, err := s.db.Exec(query,
+	effect := liq.Effect
+	if effect == "" {
+		effect = domain.EffectLiquidation
+	}
+	err := s.exec(query,
 		liq.ID.String(),
 		liq.TraderID.String(),
 		liq.Instrument,
@@ -520,41 +996,131 @@ func (s *SQLiteDB) SaveLiquidation(liq *domain.Liquidation) error {
 		liq.Leverage,
 		liq.Loss.String(),
 		insuranceFundHit,
-		liq.Timestamp,
+		string(effect),
+		liq.Timestamp.UTC(),
 	)
 	return err
 }
 
 // GetRecentLiquidations retrieves recent liquidations
 func (s *SQLiteDB) GetRecentLiquidations(instrument string, limit int) ([]*domain.Liquidation, error) {
-	query := `SELECT id, trader_id, instrument, side, size, entry_price, liquidation_price, mark_price, leverage, loss, insurance_fund_hit, timestamp FROM liquidations WHERE instrument = ? ORDER BY timestamp DESC LIMIT ?`
+	query := "SELECT " + liquidationColumns + " FROM liquidations WHERE instrument = ? ORDER BY timestamp DESC LIMIT ?"
 	rows, err := s.db.Query(query, instrument, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	return scanLiquidations(rows)
+}
+
+// GetLiquidationsBefore retrieves liquidations ordered before a (timestamp,
+// id) cursor, newest first
+func (s *SQLiteDB) GetLiquidationsBefore(instrument string, before time.Time, beforeID uuid.UUID, limit int) ([]*domain.Liquidation, error) {
+	query := "SELECT " + liquidationColumns + " FROM liquidations WHERE instrument = ? AND (timestamp, id) < (?, ?) ORDER BY timestamp DESC, id DESC LIMIT ?"
+	rows, err := s.db.Query(query, instrument, before.UTC(), beforeID.String(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanLiquidations(rows)
+}
+
+// GetLiquidationsAfter retrieves liquidations ordered after a (timestamp, id)
+// cursor, oldest first
+func (s *SQLiteDB) GetLiquidationsAfter(instrument string, after time.Time, afterID uuid.UUID, limit int) ([]*domain.Liquidation, error) {
+	query := "SELECT " + liquidationColumns + " FROM liquidations WHERE instrument = ? AND (timestamp, id) > (?, ?) ORDER BY timestamp ASC, id ASC LIMIT ?"
+	rows, err := s.db.Query(query, instrument, after.UTC(), afterID.String(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanLiquidations(rows)
+}
+
+// GetTraderLiquidationsSince retrieves a trader's liquidations from a point in time, oldest first
+func (s *SQLiteDB) GetTraderLiquidationsSince(traderID uuid.UUID, instrument string, start time.Time) ([]*domain.Liquidation, error) {
+	query := "SELECT " + liquidationColumns + " FROM liquidations WHERE instrument = ? AND trader_id = ? AND timestamp >= ? ORDER BY timestamp ASC"
+	rows, err := s.db.Query(query, instrument, traderID.String(), start.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanLiquidations(rows)
+}
+
+// GetLiquidationsUntil returns every liquidation on an instrument at or before
+// end, oldest first
+func (s *SQLiteDB) GetLiquidationsUntil(instrument string, end time.Time) ([]*domain.Liquidation, error) {
+	query := "SELECT " + liquidationColumns + " FROM liquidations WHERE instrument = ? AND timestamp <= ? ORDER BY timestamp ASC"
+	rows, err := s.db.Query(query, instrument, end.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanLiquidations(rows)
+}
+
+// GetLargestLiquidations returns an instrument's liquidations with the largest
+// loss or notional (size times mark price), largest first. The sort
+// expressions match the idx_liquidations_loss and idx_liquidations_notional
+// indexes; amounts are stored as text, so they are compared as reals.
+func (s *SQLiteDB) GetLargestLiquidations(instrument string, by domain.LiquidationRanking, limit int) ([]*domain.Liquidation, error) {
+	order := "CAST(loss AS REAL) DESC"
+	if by == domain.LiquidationRankingNotional {
+		order = "(CAST(size AS REAL) * CAST(mark_price AS REAL)) DESC"
+	}
+	query := "SELECT " + liquidationColumns + " FROM liquidations WHERE instrument = ? ORDER BY " + order + " LIMIT ?"
+	rows, err := s.db.Query(query, instrument, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanLiquidations(rows)
+}
+
+// liquidationColumns is the column list scanLiquidations expects
+const liquidationColumns = "id, trader_id, instrument, side, size, entry_price, liquidation_price, mark_price, leverage, loss, insurance_fund_hit, effect, timestamp"
+
+// scanLiquidations reads liquidation rows selected with liquidationColumns
+func scanLiquidations(rows *sql.Rows) ([]*domain.Liquidation, error) {
 	var liquidations []*domain.Liquidation
 	for rows.Next() {
-		var liq domain.Liquidation
-		var idStr, traderIDStr, sideStr, sizeStr, entryStr, liqPriceStr, markStr, lossStr string
-		var insuranceFundHit int
-		if err := rows.Scan(&idStr, &traderIDStr, &liq.Instrument, &sideStr, &sizeStr, &entryStr, &liqPriceStr, &markStr, &liq.Leverage, &lossStr, &insuranceFundHit, &liq.Timestamp); err != nil {
+		liq, err := scanLiquidation(rows)
+		if err != nil {
 			return nil, err
 		}
-		liq.ID, _ = uuid.Parse(idStr)
-		liq.TraderID, _ = uuid.Parse(traderIDStr)
-		liq.Side = domain.Side(sideStr)
-		liq.Size, _ = decimal.NewFromString(sizeStr)
-		liq.EntryPrice, _ = decimal.NewFromString(entryStr)
-		liq.LiquidationPrice, _ = decimal.NewFromString(liqPriceStr)
-		liq.MarkPrice, _ = decimal.NewFromString(markStr)
-		liq.Loss, _ = decimal.NewFromString(lossStr)
-		liq.InsuranceFundHit = insuranceFundHit == 1
-		liquidations = append(liquidations, &liq)
+		liquidations = append(liquidations, liq)
 	}
 
-	return liquidations, nil
+	return liquidations, rows.Err()
+}
+
+// scanLiquidation reads the current row of a liquidations query selected with
+// liquidationColumns
+func scanLiquidation(rows *sql.Rows) (*domain.Liquidation, error) {
+	var liq domain.Liquidation
+	var idStr, traderIDStr, sideStr, sizeStr, entryStr, liqPriceStr, markStr, lossStr, effectStr string
+	var insuranceFundHit int
+	if err := rows.Scan(&idStr, &traderIDStr, &liq.Instrument, &sideStr, &sizeStr, &entryStr, &liqPriceStr, &markStr, &liq.Leverage, &lossStr, &insuranceFundHit, &effectStr, &liq.Timestamp); err != nil {
+		return nil, err
+	}
+	liq.ID, _ = uuid.Parse(idStr)
+	liq.TraderID, _ = uuid.Parse(traderIDStr)
+	liq.Side = domain.Side(sideStr)
+	liq.Size, _ = decimal.NewFromString(sizeStr)
+	liq.EntryPrice, _ = decimal.NewFromString(entryStr)
+	liq.LiquidationPrice, _ = decimal.NewFromString(liqPriceStr)
+	liq.MarkPrice, _ = decimal.NewFromString(markStr)
+	liq.Loss, _ = decimal.NewFromString(lossStr)
+	liq.InsuranceFundHit = insuranceFundHit == 1
+	liq.Effect = domain.PositionEffect(effectStr)
+	return &liq, nil
 }
 
 // === Market Stats Operations ===
@@ -574,7 +1140,7 @@ func (s *SQLiteDB) SaveMarketStats(stats *domain.MarketStats) error {
 		insurance_fund = excluded.insurance_fund,
 		updated_at = excluded.updated_at
 	`
-	_, err := s.db.Exec(query,
+	err := s.exec(query,
 		stats.Instrument,
 		stats.LastPrice.String(),
 		stats.MarkPrice.String(),
@@ -623,3 +1189,313 @@ func (s *SQLiteDB) GetMarketStats(instrument string) (*domain.MarketStats, error
 
 	return &stats, nil
 }
+
+// === Open Interest History Operations ===
+
+// SaveOISnapshot records an open interest snapshot
+func (s *SQLiteDB) SaveOISnapshot(snap *domain.OISnapshot) error {
+	query := `
+	INSERT INTO oi_history (instrument, timestamp, open_interest, long_oi, short_oi, long_positions, short_positions, price)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(instrument, timestamp) DO NOTHING
+	`
+	err := s.exec(query,
+		snap.Instrument,
+		snap.Timestamp.UTC(),
+		snap.OpenInterest.String(),
+		snap.LongOI.String(),
+		snap.ShortOI.String(),
+		snap.LongPositions,
+		snap.ShortPositions,
+		snap.Price.String(),
+	)
+	return err
+}
+
+// GetOIHistory retrieves open interest snapshots within a time range (oldest first)
+func (s *SQLiteDB) GetOIHistory(instrument string, start, end time.Time) ([]*domain.OISnapshot, error) {
+	query := `SELECT instrument, timestamp, open_interest, long_oi, short_oi, long_positions, short_positions, price FROM oi_history WHERE instrument = ? AND timestamp >= ? AND timestamp <= ? ORDER BY timestamp ASC`
+	rows, err := s.db.Query(query, instrument, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []*domain.OISnapshot
+	for rows.Next() {
+		var snap domain.OISnapshot
+		var oiStr, longStr, shortStr, priceStr string
+		if err := rows.Scan(&snap.Instrument, &snap.Timestamp, &oiStr, &longStr, &shortStr, &snap.LongPositions, &snap.ShortPositions, &priceStr); err != nil {
+			return nil, err
+		}
+		snap.OpenInterest, _ = decimal.NewFromString(oiStr)
+		snap.LongOI, _ = decimal.NewFromString(longStr)
+		snap.ShortOI, _ = decimal.NewFromString(shortStr)
+		snap.Price, _ = decimal.NewFromString(priceStr)
+		snapshots = append(snapshots, &snap)
+	}
+
+	return snapshots, nil
+}
+
+// === Candle Operations ===
+
+// SaveCandle inserts a candle, replacing any saved earlier for the same period
+func (s *SQLiteDB) SaveCandle(c *domain.Candle) error {
+	query := `
+	INSERT INTO candles (instrument, interval, open_time, close_time, open, high, low, close, volume, vwap, trade_count)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(instrument, interval, open_time) DO UPDATE SET
+		close_time = excluded.close_time,
+		open = excluded.open,
+		high = excluded.high,
+		low = excluded.low,
+		close = excluded.close,
+		volume = excluded.volume,
+		vwap = excluded.vwap,
+		trade_count = excluded.trade_count
+	`
+	err := s.exec(query,
+		c.Instrument,
+		string(c.Interval),
+		c.OpenTime.UTC(),
+		c.CloseTime.UTC(),
+		c.Open.String(),
+		c.High.String(),
+		c.Low.String(),
+		c.Close.String(),
+		c.Volume.String(),
+		c.VWAP.String(),
+		c.TradeCount,
+	)
+	return err
+}
+
+// GetCandles retrieves candles opening within a time range (oldest first)
+func (s *SQLiteDB) GetCandles(instrument string, interval domain.CandleInterval, start, end time.Time, limit int) ([]*domain.Candle, error) {
+	var candles []*domain.Candle
+	err := s.EachCandle(instrument, interval, start, end, limit, func(c *domain.Candle) error {
+		candles = append(candles, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return candles, nil
+}
+
+// EachCandle calls fn for up to limit saved candles whose open time is within
+// a range, oldest first, reading rows one at a time
+func (s *SQLiteDB) EachCandle(instrument string, interval domain.CandleInterval, start, end time.Time, limit int, fn func(*domain.Candle) error) error {
+	query := `SELECT instrument, interval, open_time, close_time, open, high, low, close, volume, vwap, trade_count FROM candles WHERE instrument = ? AND interval = ? AND open_time >= ? AND open_time <= ? ORDER BY open_time ASC LIMIT ?`
+	rows, err := s.db.Query(query, instrument, string(interval), start.UTC(), end.UTC(), limit)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c domain.Candle
+		var intervalStr, openStr, highStr, lowStr, closeStr, volumeStr, vwapStr string
+		if err := rows.Scan(&c.Instrument, &intervalStr, &c.OpenTime, &c.CloseTime, &openStr, &highStr, &lowStr, &closeStr, &volumeStr, &vwapStr, &c.TradeCount); err != nil {
+			return err
+		}
+		c.Interval = domain.CandleInterval(intervalStr)
+		c.OpenTime, c.CloseTime = c.OpenTime.UTC(), c.CloseTime.UTC()
+		c.Open, _ = decimal.NewFromString(openStr)
+		c.High, _ = decimal.NewFromString(highStr)
+		c.Low, _ = decimal.NewFromString(lowStr)
+		c.Close, _ = decimal.NewFromString(closeStr)
+		c.Volume, _ = decimal.NewFromString(volumeStr)
+		c.VWAP, _ = decimal.NewFromString(vwapStr)
+		if err := fn(&c); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// === Insurance Fund History Operations ===
+
+// SaveInsuranceFundEvent records a change to the insurance fund balance
+func (s *SQLiteDB) SaveInsuranceFundEvent(event *domain.InsuranceFundEvent) error {
+	query := `
+	INSERT INTO insurance_fund_history (id, timestamp, cause, delta, balance, liquidation_id, trade_id, note)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	var liquidationID, tradeID string
+	if event.LiquidationID != nil {
+		liquidationID = event.LiquidationID.String()
+	}
+	if event.TradeID != nil {
+		tradeID = event.TradeID.String()
+	}
+	err := s.exec(query,
+		event.ID.String(),
+		event.Timestamp.UTC(),
+		string(event.Cause),
+		event.Delta.String(),
+		event.Balance.String(),
+		liquidationID,
+		tradeID,
+		event.Note,
+	)
+	return err
+}
+
+// GetInsuranceFundHistory retrieves insurance fund changes within a time range (oldest first)
+func (s *SQLiteDB) GetInsuranceFundHistory(start, end time.Time) ([]*domain.InsuranceFundEvent, error) {
+	query := `SELECT id, timestamp, cause, delta, balance, liquidation_id, trade_id, note FROM insurance_fund_history WHERE timestamp >= ? AND timestamp <= ? ORDER BY timestamp ASC`
+	rows, err := s.db.Query(query, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanInsuranceFundEvents(rows)
+}
+
+// GetRecentInsuranceFundEvents retrieves the latest insurance fund changes
+// (newest first)
+func (s *SQLiteDB) GetRecentInsuranceFundEvents(limit int) ([]*domain.InsuranceFundEvent, error) {
+	query := `SELECT id, timestamp, cause, delta, balance, liquidation_id, trade_id, note FROM insurance_fund_history ORDER BY timestamp DESC LIMIT ?`
+	rows, err := s.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanInsuranceFundEvents(rows)
+}
+
+func scanInsuranceFundEvents(rows *sql.Rows) ([]*domain.InsuranceFundEvent, error) {
+	events := make([]*domain.InsuranceFundEvent, 0)
+	for rows.Next() {
+		var event domain.InsuranceFundEvent
+		var idStr, cause, deltaStr, balanceStr, liquidationID, tradeID string
+		if err := rows.Scan(&idStr, &event.Timestamp, &cause, &deltaStr, &balanceStr, &liquidationID, &tradeID, &event.Note); err != nil {
+			return nil, err
+		}
+		event.ID, _ = uuid.Parse(idStr)
+		event.Cause = domain.InsuranceFundCause(cause)
+		event.Delta, _ = decimal.NewFromString(deltaStr)
+		event.Balance, _ = decimal.NewFromString(balanceStr)
+		if id, err := uuid.Parse(liquidationID); err == nil {
+			event.LiquidationID = &id
+		}
+		if id, err := uuid.Parse(tradeID); err == nil {
+			event.TradeID = &id
+		}
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+// SaveAuditEvent appends an entry to the audit log
+func (s *SQLiteDB) SaveAuditEvent(event *domain.AuditEvent) error {
+	query := `INSERT INTO audit_log (id, timestamp, trader_id, action, details) VALUES (?, ?, ?, ?, ?)`
+	err := s.exec(query,
+		event.ID.String(),
+		event.Timestamp.UTC(),
+		event.TraderID.String(),
+		event.Action,
+		event.Details,
+	)
+	return err
+}
+
+// === Export Operations ===
+
+// EachTrader calls fn for every trader, oldest account first, stopping at the
+// first error
+func (s *SQLiteDB) EachTrader(fn func(*domain.Trader) error) error {
+	query := `SELECT id, username, type, balance, total_pnl, trade_count, max_leverage_used, margin_mode, created_at FROM traders ORDER BY created_at ASC`
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var trader domain.Trader
+		var idStr, typeStr, balanceStr, pnlStr, modeStr string
+		if err := rows.Scan(&idStr, &trader.Username, &typeStr, &balanceStr, &pnlStr, &trader.TradeCount, &trader.MaxLeverageUsed, &modeStr, &trader.CreatedAt); err != nil {
+			return err
+		}
+		trader.ID, _ = uuid.Parse(idStr)
+		trader.Type = domain.TraderType(typeStr)
+		trader.Balance, _ = decimal.NewFromString(balanceStr)
+		trader.TotalPnL, _ = decimal.NewFromString(pnlStr)
+		trader.MarginMode = domain.MarginMode(modeStr)
+		if err := fn(&trader); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// EachTrade calls fn for every trade within a time range, oldest first,
+// reading rows one at a time so large ranges don't have to fit in memory
+func (s *SQLiteDB) EachTrade(start, end time.Time, fn func(*domain.Trade) error) error {
+	query := "SELECT " + tradeColumns + " FROM trades WHERE timestamp >= ? AND timestamp <= ? ORDER BY timestamp ASC"
+	rows, err := s.db.Query(query, start.UTC(), end.UTC())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		trade, err := scanTrade(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(trade); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// EachLiquidation calls fn for every liquidation within a time range, oldest first
+func (s *SQLiteDB) EachLiquidation(start, end time.Time, fn func(*domain.Liquidation) error) error {
+	query := "SELECT " + liquidationColumns + " FROM liquidations WHERE timestamp >= ? AND timestamp <= ? ORDER BY timestamp ASC"
+	rows, err := s.db.Query(query, start.UTC(), end.UTC())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		liq, err := scanLiquidation(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(liq); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// EachPosition calls fn for every position last updated within a time range,
+// oldest update first. Positions are stored as their latest state only.
+func (s *SQLiteDB) EachPosition(start, end time.Time, fn func(*domain.Position) error) error {
+	query := "SELECT " + positionColumns + " FROM positions WHERE updated_at >= ? AND updated_at <= ? ORDER BY updated_at ASC"
+	rows, err := s.db.Query(query, start.UTC(), end.UTC())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		pos, err := scanPosition(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(pos); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
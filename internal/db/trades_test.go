@@ -0,0 +1,158 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+func TestGetTradesByPriceRange(t *testing.T) {
+	s, err := NewSQLite(filepath.Join(t.TempDir(), "trades.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	traders := make([]*domain.Trader, 2)
+	for i := range traders {
+		traders[i] = &domain.Trader{ID: uuid.New(), Username: uuid.NewString(), Type: domain.TraderTypeBot, CreatedAt: time.Now()}
+		if err := s.SaveTrader(traders[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	save := func(price string, at time.Time) {
+		trade := &domain.Trade{
+			ID:         uuid.New(),
+			Instrument: domain.RIndexSymbol,
+			Price:      decimal.RequireFromString(price),
+			Size:       decimal.NewFromInt(1),
+			BuyerID:    traders[0].ID,
+			SellerID:   traders[1].ID,
+			Timestamp:  at,
+		}
+		if err := s.SaveTrade(trade); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 990 and 1005.01 fall just outside the band; 995 and 1005 are its edges.
+	// 9.5 would sort inside it if prices were compared as text.
+	now := time.Now()
+	prices := []string{"990", "995", "999.99", "1005", "1005.01", "9.5", "1000"}
+	for i, price := range prices {
+		save(price, now.Add(time.Duration(i-len(prices))*time.Minute))
+	}
+	// Inside the band but before the window
+	save("1000", now.Add(-time.Hour))
+
+	trades, err := s.GetTradesByPriceRange(domain.RIndexSymbol, decimal.NewFromInt(995), decimal.NewFromInt(1005), now.Add(-30*time.Minute), now, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, trade := range trades {
+		got = append(got, trade.Price.String())
+	}
+	// Newest first
+	want := []string{"1000", "1005", "999.99", "995"}
+	if len(got) != len(want) {
+		t.Fatalf("prices = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("prices = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestTradePagingKeepsTradesSharingATimestamp(t *testing.T) {
+	s, err := NewSQLite(filepath.Join(t.TempDir(), "trades.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	buyer := &domain.Trader{ID: uuid.New(), Username: "buyer", Type: domain.TraderTypeBot, CreatedAt: time.Now()}
+	seller := &domain.Trader{ID: uuid.New(), Username: "seller", Type: domain.TraderTypeBot, CreatedAt: time.Now()}
+	for _, trader := range []*domain.Trader{buyer, seller} {
+		if err := s.SaveTrader(trader); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Three fills from one sweep share a timestamp, so a page boundary can
+	// fall between them
+	base := time.Now().UTC().Truncate(time.Second)
+	saved := make(map[uuid.UUID]bool)
+	for _, at := range []time.Time{base.Add(-time.Second), base, base, base, base.Add(time.Second)} {
+		trade := &domain.Trade{
+			ID:         uuid.New(),
+			Instrument: domain.RIndexSymbol,
+			Price:      decimal.NewFromInt(1000),
+			Size:       decimal.NewFromInt(1),
+			BuyerID:    buyer.ID,
+			SellerID:   seller.ID,
+			Timestamp:  at,
+		}
+		if err := s.SaveTrade(trade); err != nil {
+			t.Fatal(err)
+		}
+		saved[trade.ID] = true
+	}
+
+	walk := func(page func(at time.Time, id uuid.UUID) ([]*domain.Trade, error), at time.Time, id uuid.UUID) []*domain.Trade {
+		var all []*domain.Trade
+		for {
+			trades, err := page(at, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			all = append(all, trades...)
+			if len(trades) < 2 {
+				return all
+			}
+			last := trades[len(trades)-1]
+			at, id = last.Timestamp, last.ID
+		}
+	}
+	check := func(name string, trades []*domain.Trade, newestFirst bool) {
+		t.Helper()
+		seen := make(map[uuid.UUID]bool)
+		for i, trade := range trades {
+			if !saved[trade.ID] || seen[trade.ID] {
+				t.Fatalf("%s: trade %d (%s) unknown or repeated", name, i, trade.ID)
+			}
+			seen[trade.ID] = true
+			if i > 0 && (newestFirst && trade.Timestamp.After(trades[i-1].Timestamp) || !newestFirst && trade.Timestamp.Before(trades[i-1].Timestamp)) {
+				t.Fatalf("%s: trade %d at %s is out of order", name, i, trade.Timestamp)
+			}
+		}
+		if len(seen) != len(saved) {
+			t.Fatalf("%s: paged through %d of %d trades", name, len(seen), len(saved))
+		}
+	}
+
+	back := walk(func(at time.Time, id uuid.UUID) ([]*domain.Trade, error) {
+		return s.GetTradesBefore(domain.RIndexSymbol, at, id, 2)
+	}, base.Add(time.Minute), uuid.Nil)
+	check("before", back, true)
+
+	forward := walk(func(at time.Time, id uuid.UUID) ([]*domain.Trade, error) {
+		return s.GetTradesAfter(domain.RIndexSymbol, at, id, 2)
+	}, base.Add(-time.Minute), uuid.Max)
+	check("after", forward, false)
+
+	// A bare timestamp still excludes everything at that instant
+	trades, err := s.GetTradesBefore(domain.RIndexSymbol, base, uuid.Nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 1 || !trades[0].Timestamp.Equal(base.Add(-time.Second)) {
+		t.Fatalf("before %s = %d trades, want only the older one", base, len(trades))
+	}
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,6 +11,27 @@ import (
 // Instrument name - single virtual index
 const RIndexSymbol = "R.index"
 
+// ErrDivisionByZero is returned by SafeDiv instead of panicking
+var ErrDivisionByZero = errors.New("division by zero")
+
+// SafeDiv divides a by b, returning ErrDivisionByZero when b is zero
+// (shopspring's Div panics on a zero divisor)
+func SafeDiv(a, b decimal.Decimal) (decimal.Decimal, error) {
+	if b.IsZero() {
+		return decimal.Zero, ErrDivisionByZero
+	}
+	return a.Div(b), nil
+}
+
+// SafeDivRound is SafeDiv rounded half up to places decimal places, whatever
+// decimal.DivisionPrecision is set to
+func SafeDivRound(a, b decimal.Decimal, places int32) (decimal.Decimal, error) {
+	if b.IsZero() {
+		return decimal.Zero, ErrDivisionByZero
+	}
+	return a.DivRound(b, places), nil
+}
+
 // Side represents buy or sell
 type Side string
 
@@ -25,6 +47,7 @@ const (
 	EffectOpen        PositionEffect = "open"        // New position opened
 	EffectClose       PositionEffect = "close"       // Position closed voluntarily
 	EffectLiquidation PositionEffect = "liquidation" // Forced closure
+	EffectADL         PositionEffect = "adl"         // Profitable position closed to cover a shortfall the insurance fund could not
 )
 
 // OrderType represents the type of order
@@ -33,8 +56,60 @@ type OrderType string
 const (
 	OrderTypeLimit  OrderType = "limit"
 	OrderTypeMarket OrderType = "market"
+	OrderTypeStop   OrderType = "stop" // Stop-market: trades at market once the last price crosses StopPrice
+)
+
+// IsValid returns true for a supported order type
+func (t OrderType) IsValid() bool {
+	switch t {
+	case OrderTypeLimit, OrderTypeMarket, OrderTypeStop:
+		return true
+	}
+	return false
+}
+
+// TimeInForce controls what happens to an order's unfilled remainder
+type TimeInForce string
+
+const (
+	TimeInForceGTC TimeInForce = "GTC" // Good-til-cancelled: a limit remainder rests (the default)
+	TimeInForceIOC TimeInForce = "IOC" // Immediate-or-cancel: fill what is available, cancel the rest
+	TimeInForceFOK TimeInForce = "FOK" // Fill-or-kill: fill in full immediately or not at all
+)
+
+// IsValid returns true for a supported time in force. Empty means GTC.
+func (t TimeInForce) IsValid() bool {
+	switch t {
+	case "", TimeInForceGTC, TimeInForceIOC, TimeInForceFOK:
+		return true
+	}
+	return false
+}
+
+// Immediate reports whether the order must trade on arrival and never rests
+func (t TimeInForce) Immediate() bool {
+	return t == TimeInForceIOC || t == TimeInForceFOK
+}
+
+// SelfTradePrevention decides what happens when an order meets a resting
+// order from the same trader
+type SelfTradePrevention string
+
+const (
+	STPSkip            SelfTradePrevention = "skip"             // Leave the resting order and match past it
+	STPCancelResting   SelfTradePrevention = "cancel-resting"   // Cancel the resting order and keep matching
+	STPCancelAggressor SelfTradePrevention = "cancel-aggressor" // Stop matching and cancel the incoming order's remainder
 )
 
+// IsValid returns true for a supported mode. Empty means the engine default.
+func (m SelfTradePrevention) IsValid() bool {
+	switch m {
+	case "", STPSkip, STPCancelResting, STPCancelAggressor:
+		return true
+	}
+	return false
+}
+
 // OrderStatus represents the current state of an order
 type OrderStatus string
 
@@ -45,6 +120,54 @@ const (
 	OrderStatusCancelled OrderStatus = "cancelled"
 )
 
+// IsValid returns true for a known order status
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case OrderStatusPending, OrderStatusPartial, OrderStatusFilled, OrderStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// IsOpen reports whether an order in this status can still trade
+func (s OrderStatus) IsOpen() bool {
+	return s == OrderStatusPending || s == OrderStatusPartial
+}
+
+// MarketState represents the current trading phase of the market
+type MarketState string
+
+const (
+	MarketStatePreOpen    MarketState = "pre_open"    // Not yet trading
+	MarketStateOpen       MarketState = "open"        // Normal continuous trading
+	MarketStateHalted     MarketState = "halted"      // Trading suspended
+	MarketStateCancelOnly MarketState = "cancel_only" // Only cancellations accepted
+	MarketStateClosed     MarketState = "closed"      // Market closed
+
+	// Only orders that reduce a position are accepted, just before funding settles
+	MarketStateFundingFreeze MarketState = "funding_freeze"
+)
+
+// IsValid returns true if the state is a known market phase
+func (s MarketState) IsValid() bool {
+	switch s {
+	case MarketStatePreOpen, MarketStateOpen, MarketStateHalted, MarketStateCancelOnly, MarketStateClosed, MarketStateFundingFreeze:
+		return true
+	}
+	return false
+}
+
+// AcceptsOrders returns true if new orders may be submitted in this state
+func (s MarketState) AcceptsOrders() bool {
+	return s == MarketStateOpen
+}
+
+// AcceptsReducingOrders returns true if orders that only reduce a position
+// may be submitted in this state
+func (s MarketState) AcceptsReducingOrders() bool {
+	return s == MarketStateOpen || s == MarketStateFundingFreeze
+}
+
 // TraderType identifies the kind of participant
 type TraderType string
 
@@ -54,6 +177,21 @@ const (
 	TraderTypeMarketMaker TraderType = "market_maker"
 )
 
+// IsValid returns true for the known trader types
+func (t TraderType) IsValid() bool {
+	switch t {
+	case TraderTypeHuman, TraderTypeBot, TraderTypeMarketMaker:
+		return true
+	}
+	return false
+}
+
+// Privileged returns true for types only an operator may assign, since they
+// carry a larger starting balance or leverage cap
+func (t TraderType) Privileged() bool {
+	return t == TraderTypeMarketMaker
+}
+
 // LeverageTier categorizes leverage usage
 type LeverageTier string
 
@@ -64,6 +202,14 @@ const (
 	LeverageTierDegen        LeverageTier = "degen"        // 101-150x
 )
 
+// NormalizeLeverage clamps missing or invalid leverage (e.g. 0 from old rows) to 1x
+func NormalizeLeverage(leverage int) int {
+	if leverage < 1 {
+		return 1
+	}
+	return leverage
+}
+
 // GetLeverageTier returns the tier for a given leverage
 func GetLeverageTier(leverage int) LeverageTier {
 	switch {
@@ -78,6 +224,23 @@ func GetLeverageTier(leverage int) LeverageTier {
 	}
 }
 
+// MarginMode decides what backs a trader's positions
+type MarginMode string
+
+const (
+	MarginModeIsolated MarginMode = "isolated" // Each position is backed only by its own margin
+	MarginModeCross    MarginMode = "cross"    // The whole balance backs every position
+)
+
+// IsValid returns true for a supported mode. Empty means isolated.
+func (m MarginMode) IsValid() bool {
+	switch m {
+	case "", MarginModeIsolated, MarginModeCross:
+		return true
+	}
+	return false
+}
+
 // Trader represents a market participant
 type Trader struct {
 	ID              uuid.UUID       `json:"id"`
@@ -88,6 +251,7 @@ type Trader struct {
 	TotalPnL        decimal.Decimal `json:"total_pnl"`        // Cumulative P&L
 	TradeCount      int64           `json:"trade_count"`
 	MaxLeverageUsed int             `json:"max_leverage_used"` // Highest leverage ever used (public!)
+	MarginMode      MarginMode      `json:"margin_mode"`
 
 	// Auth fields (not exposed in JSON)
 	PasswordHash    string          `json:"-"`
@@ -98,14 +262,26 @@ type Trader struct {
 type Order struct {
 	ID           uuid.UUID       `json:"id"`
 	TraderID     uuid.UUID       `json:"trader_id"`
-	Instrument   string          `json:"instrument"` // Always "R.index"
+	Instrument   string          `json:"instrument"` // Registered instrument, e.g. "R.index"
 	Side         Side            `json:"side"`
 	Type         OrderType       `json:"type"`
 	Price        decimal.Decimal `json:"price"`         // Limit price (zero for market)
 	Size         decimal.Decimal `json:"size"`          // Original size
+	DisplaySize  decimal.Decimal `json:"display_size"`  // Iceberg: size shown on the public book (zero = all of it)
+	PostOnly     bool            `json:"post_only,omitempty"` // Rejected rather than matched if it would take liquidity
+	ReduceOnly   bool            `json:"reduce_only,omitempty"` // May only shrink the trader's position, never open or flip it
+	TimeInForce  TimeInForce     `json:"time_in_force,omitempty"` // GTC (default), IOC or FOK
+	SelfTradePrevention SelfTradePrevention `json:"self_trade_prevention,omitempty"` // Overrides the engine's mode when set
 	FilledSize   decimal.Decimal `json:"filled_size"`   // How much has been filled
 	Leverage     int             `json:"leverage"`      // PUBLIC: leverage for this order
 	Status       OrderStatus     `json:"status"`
+	WorkCapped   bool            `json:"work_capped,omitempty"` // Matching stopped at the engine's work bound
+	MaxSlippageBps int           `json:"max_slippage_bps,omitempty"` // Market and stop orders: price band in bps past the best price (zero = engine default)
+	SlippageCapped bool          `json:"slippage_capped,omitempty"`  // Matching stopped at the slippage band
+	StopPrice    decimal.Decimal `json:"stop_price"`             // Trigger price (stop orders only)
+	Triggered    bool            `json:"triggered,omitempty"`    // Stop order has been triggered
+	OCOGroupID   *uuid.UUID      `json:"oco_group_id,omitempty"` // One-cancels-other pair this order belongs to
+	SentAt       *time.Time      `json:"sent_at,omitempty"`      // Client send time, opting a market order into the staleness check
 	CreatedAt    time.Time       `json:"created_at"`
 	UpdatedAt    time.Time       `json:"updated_at"`
 }
@@ -115,10 +291,35 @@ func (o *Order) RemainingSize() decimal.Decimal {
 	return o.Size.Sub(o.FilledSize)
 }
 
+// DisplayedSize returns how much of the unfilled quantity the public book
+// shows. An iceberg order shows at most DisplaySize, refreshed from the hidden
+// remainder as it fills.
+func (o *Order) DisplayedSize() decimal.Decimal {
+	remaining := o.RemainingSize()
+	if o.DisplaySize.IsPositive() && o.DisplaySize.LessThan(remaining) {
+		return o.DisplaySize
+	}
+	return remaining
+}
+
+// HasLimitPrice reports whether the order only trades at its Price or better.
+// Market and triggered stop orders take any available price.
+func (o *Order) HasLimitPrice() bool {
+	return o.Type == OrderTypeLimit
+}
+
+// StopTriggered reports whether a stop order's trigger condition is met at price
+func (o *Order) StopTriggered(price decimal.Decimal) bool {
+	if o.Side == SideBuy {
+		return price.GreaterThanOrEqual(o.StopPrice)
+	}
+	return price.LessThanOrEqual(o.StopPrice)
+}
+
 // Trade represents an executed trade - the core of transparency
 type Trade struct {
 	ID                   uuid.UUID       `json:"id"`
-	Instrument           string          `json:"instrument"` // Always "R.index"
+	Instrument           string          `json:"instrument"` // Registered instrument, e.g. "R.index"
 	Price                decimal.Decimal `json:"price"`
 	Size                 decimal.Decimal `json:"size"`
 	Timestamp            time.Time       `json:"timestamp"`
@@ -143,20 +344,65 @@ type Trade struct {
 
 	// Aggressor side (who took liquidity)
 	AggressorSide        Side            `json:"aggressor_side"`
+
+	// PUBLIC: Fees charged to each side and the rate applied
+	BuyerFee             decimal.Decimal `json:"buyer_fee"`
+	SellerFee            decimal.Decimal `json:"seller_fee"`
+	BuyerFeeRate         decimal.Decimal `json:"buyer_fee_rate"`
+	SellerFeeRate        decimal.Decimal `json:"seller_fee_rate"`
+
+	// P&L each side realized by reducing a position (zero when opening)
+	BuyerRealizedPnL     decimal.Decimal `json:"buyer_realized_pnl"`
+	SellerRealizedPnL    decimal.Decimal `json:"seller_realized_pnl"`
 }
 
 // Position represents a trader's current position - ALL FIELDS PUBLIC
 type Position struct {
 	TraderID         uuid.UUID       `json:"trader_id"`
-	Instrument       string          `json:"instrument"`        // Always "R.index"
+	Instrument       string          `json:"instrument"`        // Registered instrument, e.g. "R.index"
 	Size             decimal.Decimal `json:"size"`              // Positive = long, Negative = short
 	EntryPrice       decimal.Decimal `json:"entry_price"`       // Average entry price
 	Leverage         int             `json:"leverage"`          // PUBLIC: current leverage
 	Margin           decimal.Decimal `json:"margin"`            // Margin used
-	UnrealizedPnL    decimal.Decimal `json:"unrealized_pnl"`
+	UnrealizedPnL    decimal.Decimal `json:"unrealized_pnl"`    // At the mark price
 	RealizedPnL      decimal.Decimal `json:"realized_pnl"`
 	LiquidationPrice decimal.Decimal `json:"liquidation_price"` // PUBLIC: where they get liquidated
+	MarginMode       MarginMode      `json:"margin_mode"`       // The owner's mode; not stored
 	UpdatedAt        time.Time       `json:"updated_at"`
+
+	// Derived risk figures, set by ComputeHealth for API responses; not stored
+	MarginRatio              *decimal.Decimal `json:"margin_ratio,omitempty"`                // (margin + unrealized P&L) / notional
+	DistanceToLiquidationBps *decimal.Decimal `json:"distance_to_liquidation_bps,omitempty"` // How far the mark can move against the position first
+}
+
+// ComputeUnrealizedPnL returns the position's profit or loss if closed at
+// markPrice: (mark-entry)*size for a long, (entry-mark)*|size| for a short
+func (p *Position) ComputeUnrealizedPnL(markPrice decimal.Decimal) decimal.Decimal {
+	return markPrice.Sub(p.EntryPrice).Mul(p.Size)
+}
+
+// ComputeHealth sets the position's unrealized P&L, margin ratio and distance
+// to liquidation at markPrice. The distance is in basis points of the mark
+// and negative once the mark is past the liquidation price.
+func (p *Position) ComputeHealth(markPrice decimal.Decimal) {
+	p.UnrealizedPnL = p.ComputeUnrealizedPnL(markPrice)
+	p.MarginRatio, p.DistanceToLiquidationBps = nil, nil
+	if p.Size.IsZero() || !markPrice.IsPositive() {
+		return
+	}
+
+	notional := p.Size.Abs().Mul(markPrice)
+	ratio := p.Margin.Add(p.UnrealizedPnL).Div(notional).Round(6)
+	p.MarginRatio = &ratio
+
+	if p.LiquidationPrice.IsPositive() {
+		gap := markPrice.Sub(p.LiquidationPrice)
+		if p.IsShort() {
+			gap = gap.Neg()
+		}
+		distance := gap.Mul(decimal.NewFromInt(10000)).Div(markPrice).Round(2)
+		p.DistanceToLiquidationBps = &distance
+	}
 }
 
 // IsLong returns true if position is long
@@ -174,6 +420,14 @@ func (p *Position) LeverageTier() LeverageTier {
 	return GetLeverageTier(p.Leverage)
 }
 
+// CrossMarginAccount is the equity backing a cross margin trader's positions
+type CrossMarginAccount struct {
+	TraderID    uuid.UUID       `json:"trader_id"`
+	Balance     decimal.Decimal `json:"balance"`     // Free balance
+	Equity      decimal.Decimal `json:"equity"`      // Balance plus each position's margin and unrealized P&L
+	Maintenance decimal.Decimal `json:"maintenance"` // Maintenance margin summed over the positions
+}
+
 // Liquidation records a liquidation event - fully public
 type Liquidation struct {
 	ID               uuid.UUID       `json:"id"`
@@ -191,6 +445,119 @@ type Liquidation struct {
 	// Who took the other side
 	CounterpartyID   uuid.UUID       `json:"counterparty_id,omitempty"`
 	InsuranceFundHit bool            `json:"insurance_fund_hit"` // Did insurance fund cover?
+
+	// EffectLiquidation, or EffectADL for a position auto-deleveraged to
+	// cover another trader's shortfall
+	Effect PositionEffect `json:"effect"`
+}
+
+// LifecycleStep classifies one event in a position's lifecycle
+type LifecycleStep string
+
+const (
+	LifecycleOpen        LifecycleStep = "open"
+	LifecycleIncrease    LifecycleStep = "increase"
+	LifecycleReduce      LifecycleStep = "reduce"
+	LifecycleClose       LifecycleStep = "close"
+	LifecycleFlip        LifecycleStep = "flip" // Closed by a trade that opened a new position on the other side
+	LifecycleLiquidation LifecycleStep = "liquidation"
+)
+
+// PositionLifecycleEvent is one trade or liquidation that changed a position
+type PositionLifecycleEvent struct {
+	Step          LifecycleStep   `json:"step"`
+	Timestamp     time.Time       `json:"timestamp"`
+	TradeID       *uuid.UUID      `json:"trade_id,omitempty"`
+	OrderID       *uuid.UUID      `json:"order_id,omitempty"`
+	LiquidationID *uuid.UUID      `json:"liquidation_id,omitempty"`
+	Side          Side            `json:"side"` // Direction of the fill, not of the position
+	Price         decimal.Decimal `json:"price"`
+	Size          decimal.Decimal `json:"size"`          // Portion of the fill that applied to this position
+	PositionSize  decimal.Decimal `json:"position_size"` // Running size after the event
+	Fee           decimal.Decimal `json:"fee"`
+	RealizedPnL   decimal.Decimal `json:"realized_pnl"`
+	CumulativePnL decimal.Decimal `json:"cumulative_pnl"` // Realized P&L so far, before fees
+}
+
+// PositionLifecycle is the complete story of one position, from the trade that
+// opened it to the trade or liquidation that closed it
+type PositionLifecycle struct {
+	TraderID    uuid.UUID                 `json:"trader_id"`
+	Instrument  string                    `json:"instrument"`
+	Side        Side                      `json:"side"`   // Buy = long, sell = short
+	Status      string                    `json:"status"` // open, closed or liquidated
+	OpenedAt    time.Time                 `json:"opened_at"`
+	ClosedAt    *time.Time                `json:"closed_at,omitempty"`
+	PeakSize    decimal.Decimal           `json:"peak_size"`
+	RealizedPnL decimal.Decimal           `json:"realized_pnl"`
+	TotalFees   decimal.Decimal           `json:"total_fees"`
+	Events      []*PositionLifecycleEvent `json:"events"`
+}
+
+// PnLLeaderboardEntry ranks a trader by P&L realized within a time window
+type PnLLeaderboardEntry struct {
+	Rank          int             `json:"rank"`
+	TraderID      uuid.UUID       `json:"trader_id"`
+	Username      string          `json:"username"`
+	Type          TraderType      `json:"type"`
+	RealizedPnL   decimal.Decimal `json:"realized_pnl"`
+	ClosingTrades int             `json:"closing_trades"`
+	Liquidations  int             `json:"liquidations"`
+}
+
+// LeaderboardSort selects the metric GetLeaderboard ranks traders by
+type LeaderboardSort string
+
+const (
+	LeaderboardSortPnL         LeaderboardSort = "pnl"          // All-time P&L
+	LeaderboardSortROI         LeaderboardSort = "roi"          // P&L over starting balance
+	LeaderboardSortVolume      LeaderboardSort = "volume"       // Number of trades
+	LeaderboardSortMaxLeverage LeaderboardSort = "max_leverage" // Highest leverage ever used
+)
+
+// IsValid reports whether s is a known leaderboard sort
+func (s LeaderboardSort) IsValid() bool {
+	switch s {
+	case LeaderboardSortPnL, LeaderboardSortROI, LeaderboardSortVolume, LeaderboardSortMaxLeverage:
+		return true
+	}
+	return false
+}
+
+// LeaderboardEntry ranks a trader on the all-time leaderboard. Metric is the
+// value of the metric the board is sorted by.
+type LeaderboardEntry struct {
+	Rank            int             `json:"rank"`
+	TraderID        uuid.UUID       `json:"trader_id"`
+	Username        string          `json:"username"`
+	Type            TraderType      `json:"type"`
+	TotalPnL        decimal.Decimal `json:"total_pnl"`
+	ROI             decimal.Decimal `json:"roi"` // TotalPnL / starting balance
+	TradeCount      int64           `json:"trade_count"`
+	MaxLeverageUsed int             `json:"max_leverage_used"`
+	Metric          decimal.Decimal `json:"metric"`
+}
+
+// InsuranceFundCause explains why the insurance fund balance changed
+type InsuranceFundCause string
+
+const (
+	InsuranceFundLiquidationSurplus InsuranceFundCause = "liquidation_surplus" // Margin left after a liquidation
+	InsuranceFundShortfallCover     InsuranceFundCause = "shortfall_cover"     // Loss beyond margin paid by the fund
+	InsuranceFundFee                InsuranceFundCause = "fee"                 // Trading and liquidation fees
+	InsuranceFundAdminAdjustment    InsuranceFundCause = "admin_adjustment"    // Operator top-up or withdrawal
+)
+
+// InsuranceFundEvent is one change to the insurance fund balance (PUBLIC)
+type InsuranceFundEvent struct {
+	ID            uuid.UUID          `json:"id"`
+	Timestamp     time.Time          `json:"timestamp"`
+	Cause         InsuranceFundCause `json:"cause"`
+	Delta         decimal.Decimal    `json:"delta"`   // Signed change
+	Balance       decimal.Decimal    `json:"balance"` // Balance after the change
+	LiquidationID *uuid.UUID         `json:"liquidation_id,omitempty"`
+	TradeID       *uuid.UUID         `json:"trade_id,omitempty"`
+	Note          string             `json:"note,omitempty"`
 }
 
 // OpenInterestBreakdown provides the transparent OI data
@@ -214,6 +581,45 @@ type OpenInterestBreakdown struct {
 	ShortsLiquidated  int64           `json:"shorts_liquidated"`
 }
 
+// PositionConcentration measures how much of the open interest a few holders
+// control. Shares are of gross position size (longs plus shorts).
+type PositionConcentration struct {
+	Instrument string          `json:"instrument"`
+	Timestamp  time.Time       `json:"timestamp"`
+	Holders    int             `json:"holders"`
+	GrossSize  decimal.Decimal `json:"gross_size"`
+	TopN       int             `json:"top_n"`
+	TopShare   decimal.Decimal `json:"top_share"` // Share of gross size held by the top N holders, 0-1
+	Gini       decimal.Decimal `json:"gini"`      // 0 = evenly spread, approaching 1 = one holder dominates
+}
+
+// OISnapshot records open interest at a point in time (for OI history)
+type OISnapshot struct {
+	Instrument     string          `json:"instrument"`
+	Timestamp      time.Time       `json:"timestamp"`
+	OpenInterest   decimal.Decimal `json:"open_interest"`
+	LongOI         decimal.Decimal `json:"long_oi"`
+	ShortOI        decimal.Decimal `json:"short_oi"`
+	LongPositions  int64           `json:"long_positions"`
+	ShortPositions int64           `json:"short_positions"`
+	Price          decimal.Decimal `json:"price"`                   // Last price when snapshot was taken (close price for buckets)
+	Reconstructed  bool            `json:"reconstructed,omitempty"` // Replayed from trades rather than recorded
+}
+
+// MakerStats summarizes how a trader's resting orders performed
+type MakerStats struct {
+	TraderID        uuid.UUID       `json:"trader_id"`
+	Instrument      string          `json:"instrument"`
+	OrdersPlaced    int64           `json:"orders_placed"`
+	OrdersFilled    int64           `json:"orders_filled"`
+	OrdersCancelled int64           `json:"orders_cancelled"`
+	OrdersOpen      int64           `json:"orders_open"`
+	PlacedVolume    decimal.Decimal `json:"placed_volume"`
+	FilledVolume    decimal.Decimal `json:"filled_volume"`
+	FillRate        decimal.Decimal `json:"fill_rate"`        // Filled volume / placed volume
+	AvgRestTimeMs   int64           `json:"avg_rest_time_ms"` // Mean time from placement to fill or cancel
+}
+
 // OrderBookLevel represents a price level in the book
 type OrderBookLevel struct {
 	Price      decimal.Decimal `json:"price"`
@@ -226,9 +632,20 @@ type OrderBook struct {
 	Instrument string           `json:"instrument"`
 	Bids       []OrderBookLevel `json:"bids"` // Sorted high to low
 	Asks       []OrderBookLevel `json:"asks"` // Sorted low to high
+	Seq        uint64           `json:"seq"`  // Seq of the last level update applied
 	Timestamp  time.Time        `json:"timestamp"`
 }
 
+// BookLevelUpdate is a change to one price level's displayed size. Seq rises
+// by one per update on an instrument, so a gap means an update was missed.
+type BookLevelUpdate struct {
+	Instrument string          `json:"instrument"`
+	Side       Side            `json:"side"`
+	Price      decimal.Decimal `json:"price"`
+	Size       decimal.Decimal `json:"size"` // New displayed size; zero removes the level
+	Seq        uint64          `json:"seq"`
+}
+
 // InsuranceFund tracks the insurance fund state
 type InsuranceFund struct {
 	Balance     decimal.Decimal `json:"balance"`
@@ -237,20 +654,137 @@ type InsuranceFund struct {
 	UpdatedAt   time.Time       `json:"updated_at"`
 }
 
+// InsuranceFundStatus is the fund balance alongside its fee replenishment policy
+type InsuranceFundStatus struct {
+	Timestamp  time.Time             `json:"timestamp"`
+	Balance    decimal.Decimal       `json:"balance"`
+	Target     decimal.Decimal       `json:"target"`      // Zero = no target
+	FeeShare   decimal.Decimal       `json:"fee_share"`   // Fraction of fees diverted to the fund
+	Diverting  bool                  `json:"diverting"`   // Whether fees are currently being diverted
+	FeeRevenue decimal.Decimal       `json:"fee_revenue"` // Fees kept as exchange revenue since startup
+	Inflow24h  decimal.Decimal       `json:"inflow_24h"`  // Increases over the last 24h
+	Outflow24h decimal.Decimal       `json:"outflow_24h"` // Decreases over the last 24h, as a positive amount
+	Recent     []*InsuranceFundEvent `json:"recent"`      // Latest changes, newest first
+}
+
+// LiquidationRanking selects how GetLargestLiquidations orders liquidations
+type LiquidationRanking string
+
+const (
+	LiquidationRankingLoss     LiquidationRanking = "loss"     // Loss realized
+	LiquidationRankingNotional LiquidationRanking = "notional" // Size times mark price
+)
+
+// IsValid reports whether r is a known ranking
+func (r LiquidationRanking) IsValid() bool {
+	return r == LiquidationRankingLoss || r == LiquidationRankingNotional
+}
+
+// ADLQueueEntry is one profitable position in the auto-deleveraging queue.
+// Positions are ranked by Score, the return on margin times leverage; the
+// top of the opposite side is closed first when the insurance fund cannot
+// cover a liquidation.
+type ADLQueueEntry struct {
+	Rank          int             `json:"rank"`
+	TraderID      uuid.UUID       `json:"trader_id"`
+	Username      string          `json:"username"`
+	Side          Side            `json:"side"` // Buy for a long, sell for a short
+	Size          decimal.Decimal `json:"size"` // Absolute position size
+	EntryPrice    decimal.Decimal `json:"entry_price"`
+	Leverage      int             `json:"leverage"`
+	UnrealizedPnL decimal.Decimal `json:"unrealized_pnl"` // At the mark price
+	Score         decimal.Decimal `json:"score"`
+}
+
+// LargestLiquidation is one entry in the all-time largest liquidations
+type LargestLiquidation struct {
+	Rank     int             `json:"rank"`
+	Username string          `json:"username"`
+	Notional decimal.Decimal `json:"notional"` // Size times mark price
+	Liquidation
+}
+
+// TraderExposure aggregates a trader's positions across every instrument,
+// valued at each instrument's last trade price
+type TraderExposure struct {
+	TraderID      uuid.UUID            `json:"trader_id"`
+	Timestamp     time.Time            `json:"timestamp"`
+	LongNotional  decimal.Decimal      `json:"long_notional"`
+	ShortNotional decimal.Decimal      `json:"short_notional"` // Positive
+	NetNotional   decimal.Decimal      `json:"net_notional"`   // Long minus short
+	MarginUsed    decimal.Decimal      `json:"margin_used"`
+	UnrealizedPnL decimal.Decimal      `json:"unrealized_pnl"`
+	Positions     []InstrumentExposure `json:"positions"`
+}
+
+// InstrumentExposure is one position's contribution to a TraderExposure
+type InstrumentExposure struct {
+	Instrument    string          `json:"instrument"`
+	Size          decimal.Decimal `json:"size"`
+	MarkPrice     decimal.Decimal `json:"mark_price"`
+	Notional      decimal.Decimal `json:"notional"` // |size| * mark price
+	Margin        decimal.Decimal `json:"margin"`
+	UnrealizedPnL decimal.Decimal `json:"unrealized_pnl"`
+}
+
+// DegenIndex is a 0-100 gauge of how recklessly the market is trading, with
+// the components it is built from. Each component score is in [0, 1].
+type DegenIndex struct {
+	Instrument          string          `json:"instrument"`
+	Timestamp           time.Time       `json:"timestamp"`
+	WindowSeconds       int             `json:"window_seconds"`
+	AvgLeverage         decimal.Decimal `json:"avg_leverage"`        // Size-weighted, over opening fills
+	HighLeverageShare   decimal.Decimal `json:"high_leverage_share"` // Opened size at 51x and above
+	LiquidationNotional decimal.Decimal `json:"liquidation_notional"`
+	Liquidations        int             `json:"liquidations"`
+	OrdersPlaced        int             `json:"orders_placed"`
+	Fills               int             `json:"fills"`
+	ChurnRatio          decimal.Decimal `json:"churn_ratio"` // Orders placed per fill
+	LeverageScore       decimal.Decimal `json:"leverage_score"`
+	HighLeverageScore   decimal.Decimal `json:"high_leverage_score"`
+	LiquidationScore    decimal.Decimal `json:"liquidation_score"`
+	ChurnScore          decimal.Decimal `json:"churn_score"`
+	Index               decimal.Decimal `json:"index"`
+}
+
 // MarketStats provides current market statistics
 type MarketStats struct {
-	Instrument       string          `json:"instrument"`
-	LastPrice        decimal.Decimal `json:"last_price"`
-	MarkPrice        decimal.Decimal `json:"mark_price"`
-	IndexPrice       decimal.Decimal `json:"index_price"` // Same as mark for R.index
-	High24h          decimal.Decimal `json:"high_24h"`
-	Low24h           decimal.Decimal `json:"low_24h"`
-	Volume24h        decimal.Decimal `json:"volume_24h"`
-	OpenInterest     decimal.Decimal `json:"open_interest"`
-	FundingRate      decimal.Decimal `json:"funding_rate"`
-	NextFundingTime  time.Time       `json:"next_funding_time"`
-	InsuranceFund    decimal.Decimal `json:"insurance_fund"`
-	Timestamp        time.Time       `json:"timestamp"`
+	Instrument        string          `json:"instrument"`
+	LastPrice         decimal.Decimal `json:"last_price"`
+	MarkPrice         decimal.Decimal `json:"mark_price"`
+	IndexPrice        decimal.Decimal `json:"index_price"` // From the index source; the mark without one
+	High24h           decimal.Decimal `json:"high_24h"`
+	Low24h            decimal.Decimal `json:"low_24h"`
+	Volume24h         decimal.Decimal `json:"volume_24h"`
+	BuyVolume24h      decimal.Decimal `json:"buy_volume_24h"`  // Part of volume_24h where the buyer took liquidity
+	SellVolume24h     decimal.Decimal `json:"sell_volume_24h"` // Part of volume_24h where the seller took liquidity
+	OpenInterest      decimal.Decimal `json:"open_interest"`
+	FundingRate       decimal.Decimal `json:"funding_rate"`
+	NextFundingTime   time.Time       `json:"next_funding_time"`
+	NextFundingFreeze *FundingFreeze  `json:"next_funding_freeze,omitempty"` // Set when a pre-settlement freeze is configured
+	InsuranceFund     decimal.Decimal `json:"insurance_fund"`
+	MarketState       MarketState     `json:"market_state"`
+	Timestamp         time.Time       `json:"timestamp"`
+}
+
+// Instrument is a registered instrument's contract specification and current prices
+type Instrument struct {
+	Symbol       string          `json:"symbol"`
+	TickSize     decimal.Decimal `json:"tick_size"`
+	LotSize      decimal.Decimal `json:"lot_size"`
+	MinOrderSize decimal.Decimal `json:"min_order_size"`
+	MaxLeverage  int             `json:"max_leverage"`
+	LastPrice    decimal.Decimal `json:"last_price"` // Starting price until the first trade
+	MarkPrice    decimal.Decimal `json:"mark_price"`
+	MarketState  MarketState     `json:"market_state"`
+}
+
+// MarketStateChange records a market phase transition - broadcast to everyone
+type MarketStateChange struct {
+	State         MarketState `json:"state"`
+	PreviousState MarketState `json:"previous_state"`
+	Reason        string      `json:"reason"`
+	Timestamp     time.Time   `json:"timestamp"`
 }
 
 // CandleInterval represents the timeframe for candles
@@ -265,6 +799,15 @@ const (
 	CandleInterval1d  CandleInterval = "1d"
 )
 
+// IsValid returns true if the interval is a supported candle timeframe
+func (i CandleInterval) IsValid() bool {
+	switch i {
+	case CandleInterval1m, CandleInterval5m, CandleInterval15m, CandleInterval1h, CandleInterval4h, CandleInterval1d:
+		return true
+	}
+	return false
+}
+
 // Candle represents OHLCV data for a time period
 type Candle struct {
 	Instrument string          `json:"instrument"`
@@ -275,6 +818,188 @@ type Candle struct {
 	High       decimal.Decimal `json:"high"`
 	Low        decimal.Decimal `json:"low"`
 	Close      decimal.Decimal `json:"close"`
-	Volume     decimal.Decimal `json:"volume"`     // Total traded volume
+	Volume     decimal.Decimal `json:"volume"`      // Total traded volume
+	VWAP       decimal.Decimal `json:"vwap"`        // Volume-weighted average price; zero with no volume
 	TradeCount int64           `json:"trade_count"` // Number of trades in period
 }
+
+// AuditEvent records a sensitive account action for later review
+type AuditEvent struct {
+	ID        uuid.UUID `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	TraderID  uuid.UUID `json:"trader_id"`
+	Action    string    `json:"action"`
+	Details   string    `json:"details"`
+}
+
+// FlattenResult reports what FlattenTrader cancelled and closed
+type FlattenResult struct {
+	TraderID           uuid.UUID       `json:"trader_id"`
+	CancelledOrders    []*Order        `json:"cancelled_orders"`
+	Trades             []*Trade        `json:"trades"`
+	RealizedPnL        decimal.Decimal `json:"realized_pnl"`
+	RemainingPositions []*Position     `json:"remaining_positions"` // Non-empty if the book lacked liquidity
+}
+
+// MMPConfig sets a market maker's protection thresholds. Protection trips when
+// either limit is reached by maker fills within the window.
+type MMPConfig struct {
+	WindowMs int64           `json:"window_ms"`
+	MaxFills int             `json:"max_fills"` // 0 = no fill-count limit
+	MaxSize  decimal.Decimal `json:"max_size"`  // 0 = no size limit
+}
+
+// MMPStatus reports a trader's market-maker protection state
+type MMPStatus struct {
+	TraderID    uuid.UUID       `json:"trader_id"`
+	Config      MMPConfig       `json:"config"`
+	Frozen      bool            `json:"frozen"`
+	TriggeredAt *time.Time      `json:"triggered_at,omitempty"`
+	WindowFills int             `json:"window_fills"`
+	WindowSize  decimal.Decimal `json:"window_size"`
+}
+
+// LiquidityScore is a composite market-quality metric with its components.
+// Each component score is in [0, 1]; Score is their weighted sum scaled to 0-100.
+type LiquidityScore struct {
+	Instrument      string          `json:"instrument"`
+	Timestamp       time.Time       `json:"timestamp"`
+	BestBid         decimal.Decimal `json:"best_bid"`
+	BestAsk         decimal.Decimal `json:"best_ask"`
+	MidPrice        decimal.Decimal `json:"mid_price"`
+	SpreadBps       decimal.Decimal `json:"spread_bps"`
+	DepthBandPct    decimal.Decimal `json:"depth_band_pct"`
+	BidDepth        decimal.Decimal `json:"bid_depth"` // Resting size within the band below mid
+	AskDepth        decimal.Decimal `json:"ask_depth"` // Resting size within the band above mid
+	VolumeWindowSec int             `json:"volume_window_sec"`
+	RecentVolume    decimal.Decimal `json:"recent_volume"`
+	SpreadScore     decimal.Decimal `json:"spread_score"`
+	DepthScore      decimal.Decimal `json:"depth_score"`
+	VolumeScore     decimal.Decimal `json:"volume_score"`
+	Score           decimal.Decimal `json:"score"`
+}
+
+// SLOAlert is raised when a latency objective has been breached for longer
+// than its sustain period
+type SLOAlert struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Metric        string    `json:"metric"` // e.g. "match_latency_p99"
+	ThresholdMs   float64   `json:"threshold_ms"`
+	ValueMs       float64   `json:"value_ms"`
+	BreachedSince time.Time `json:"breached_since"`
+	Samples       int       `json:"samples"`
+}
+
+// CascadeStep is one simulated liquidation in a cascade
+type CascadeStep struct {
+	Step             int             `json:"step"`
+	TraderID         uuid.UUID       `json:"trader_id"`
+	Side             Side            `json:"side"` // Side of the position being liquidated
+	Size             decimal.Decimal `json:"size"`
+	LiquidationPrice decimal.Decimal `json:"liquidation_price"`
+	Notional         decimal.Decimal `json:"notional"`
+	Loss             decimal.Decimal `json:"loss"`
+	PriceAfter       decimal.Decimal `json:"price_after"` // Simulated price once this liquidation has hit the book
+}
+
+// CascadeSimulation is the outcome of moving the price to a target on a copy
+// of current positions. Nothing is actually liquidated.
+type CascadeSimulation struct {
+	Instrument    string          `json:"instrument"`
+	Timestamp     time.Time       `json:"timestamp"`
+	StartPrice    decimal.Decimal `json:"start_price"`
+	TargetPrice   decimal.Decimal `json:"target_price"`
+	FinalPrice    decimal.Decimal `json:"final_price"` // Past the target if book impact pushed it further
+	BookImpact    bool            `json:"book_impact"`
+	BookExhausted bool            `json:"book_exhausted,omitempty"`
+	Positions     int             `json:"positions"`
+	TotalSize     decimal.Decimal `json:"total_size"`
+	TotalNotional decimal.Decimal `json:"total_notional"`
+	TotalLoss     decimal.Decimal `json:"total_loss"`
+	Chain         []CascadeStep   `json:"chain"`
+}
+
+// SimulatedFill is one fill an order would make against a resting order
+type SimulatedFill struct {
+	Price        decimal.Decimal `json:"price"`
+	Size         decimal.Decimal `json:"size"`
+	MakerID      uuid.UUID       `json:"maker_id"`
+	MakerOrderID uuid.UUID       `json:"maker_order_id"`
+}
+
+// OrderSimulation previews what an order would do if submitted now. Nothing
+// is executed.
+type OrderSimulation struct {
+	Instrument    string          `json:"instrument"`
+	Side          Side            `json:"side"`
+	Size          decimal.Decimal `json:"size"`
+	FilledSize    decimal.Decimal `json:"filled_size"`
+	AveragePrice  decimal.Decimal `json:"average_price"` // Zero if nothing would fill
+	Notional      decimal.Decimal `json:"notional"`
+	Fee           decimal.Decimal `json:"fee"`
+	Fills         []SimulatedFill `json:"fills"`
+	Rests         decimal.Decimal `json:"rests"`                 // Left on the book as a limit order
+	Cancels       decimal.Decimal `json:"cancels"`               // Cancelled unfilled (IOC, FOK, market, or matching stopped)
+	StopReason    string          `json:"stop_reason,omitempty"` // killed, self_trade, work_capped or slippage_capped
+	Position      decimal.Decimal `json:"position"`              // Trader's current signed position
+	NewPosition   decimal.Decimal `json:"new_position"`
+	NewEntryPrice decimal.Decimal `json:"new_entry_price"`
+	Timestamp     time.Time       `json:"timestamp"`
+}
+
+// LiquidationMapBucket is the open interest that would be liquidated with
+// the mark in [Price, Price+bucket size)
+type LiquidationMapBucket struct {
+	Price         decimal.Decimal `json:"price"`
+	LongSize      decimal.Decimal `json:"long_size"`
+	ShortSize     decimal.Decimal `json:"short_size"`
+	LongNotional  decimal.Decimal `json:"long_notional"`  // At the positions' liquidation prices
+	ShortNotional decimal.Decimal `json:"short_notional"` // At the positions' liquidation prices
+	Positions     int             `json:"positions"`
+}
+
+// LiquidationMap groups an instrument's open positions by liquidation price
+// within a band around the mark, lowest price first
+type LiquidationMap struct {
+	Instrument string                 `json:"instrument"`
+	Timestamp  time.Time              `json:"timestamp"`
+	MarkPrice  decimal.Decimal        `json:"mark_price"`
+	BucketSize decimal.Decimal        `json:"bucket_size"`
+	BandPct    decimal.Decimal        `json:"band_pct"`
+	Buckets    []LiquidationMapBucket `json:"buckets"`
+}
+
+// TierLiquidationRate is how often positions opened at one leverage tier end
+// in liquidation
+type TierLiquidationRate struct {
+	Tier       LeverageTier    `json:"tier"`
+	Opened     int             `json:"opened"`     // Positions opened from flat (or by flipping side)
+	Liquidated int             `json:"liquidated"` // Liquidations at this tier
+	Rate       decimal.Decimal `json:"rate"`       // Liquidated / opened
+}
+
+// LiquidationRates reports liquidation rates for each leverage tier over a window
+type LiquidationRates struct {
+	Instrument    string                `json:"instrument"`
+	Timestamp     time.Time             `json:"timestamp"`
+	WindowSeconds int                   `json:"window_seconds"`
+	Tiers         []TierLiquidationRate `json:"tiers"`
+}
+
+// FundingFreeze is the window before a funding settlement in which only
+// orders reducing a position are accepted
+type FundingFreeze struct {
+	StartsAt       time.Time `json:"starts_at"`
+	SettlementTime time.Time `json:"settlement_time"` // The freeze lifts once this settlement is paid
+}
+
+// FundingSettlement records one funding payment applied to every open
+// position. Positive rates are paid by longs to shorts.
+type FundingSettlement struct {
+	Instrument string          `json:"instrument"`
+	Timestamp  time.Time       `json:"timestamp"`
+	Rate       decimal.Decimal `json:"rate"`
+	MarkPrice  decimal.Decimal `json:"mark_price"`
+	Positions  int             `json:"positions"`
+	TotalPaid  decimal.Decimal `json:"total_paid"` // Paid by one side, received by the other
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestSafeDivByZero(t *testing.T) {
+	one := decimal.NewFromInt(1)
+	if got, err := SafeDiv(one, decimal.Zero); !errors.Is(err, ErrDivisionByZero) || !got.IsZero() {
+		t.Fatalf("SafeDiv(1, 0) = %s, %v, want 0, ErrDivisionByZero", got, err)
+	}
+	if got, err := SafeDivRound(one, decimal.Zero, 8); !errors.Is(err, ErrDivisionByZero) || !got.IsZero() {
+		t.Fatalf("SafeDivRound(1, 0) = %s, %v, want 0, ErrDivisionByZero", got, err)
+	}
+	if got, err := SafeDivRound(one, decimal.NewFromInt(3), 4); err != nil || got.String() != "0.3333" {
+		t.Fatalf("SafeDivRound(1, 3, 4) = %s, %v, want 0.3333", got, err)
+	}
+}
+
+func TestNormalizeLeverage(t *testing.T) {
+	for leverage, want := range map[int]int{-5: 1, 0: 1, 1: 1, 150: 150} {
+		if got := NormalizeLeverage(leverage); got != want {
+			t.Errorf("NormalizeLeverage(%d) = %d, want %d", leverage, got, want)
+		}
+	}
+}
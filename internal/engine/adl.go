@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// GetADLQueue ranks an instrument's profitable positions for auto-deleveraging,
+// highest score (return on margin times leverage) first. Both sides are
+// listed; a shortfall on one side is covered from the top of the other.
+func (me *MatchingEngine) GetADLQueue(instrument string) []*domain.ADLQueueEntry {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	mark := me.markPriceLocked(instrument)
+	queue := make([]*domain.ADLQueueEntry, 0)
+	for _, pos := range me.positions {
+		if pos.Instrument != instrument || pos.Size.IsZero() {
+			continue
+		}
+		pnl := mark.Sub(pos.EntryPrice).Mul(pos.Size)
+		if !pnl.IsPositive() {
+			continue
+		}
+		ret, err := domain.SafeDiv(pnl, pos.Margin)
+		if err != nil {
+			continue
+		}
+
+		entry := &domain.ADLQueueEntry{
+			TraderID:      pos.TraderID,
+			Side:          domain.SideBuy,
+			Size:          pos.Size.Abs(),
+			EntryPrice:    pos.EntryPrice,
+			Leverage:      pos.Leverage,
+			UnrealizedPnL: pnl,
+			Score:         ret.Mul(decimal.NewFromInt(int64(domain.NormalizeLeverage(pos.Leverage)))),
+		}
+		if pos.IsShort() {
+			entry.Side = domain.SideSell
+		}
+		if trader, ok := me.traders[pos.TraderID]; ok {
+			entry.Username = trader.Username
+		}
+		queue = append(queue, entry)
+	}
+
+	sort.Slice(queue, func(i, j int) bool {
+		if !queue[i].Score.Equal(queue[j].Score) {
+			return queue[i].Score.GreaterThan(queue[j].Score)
+		}
+		return queue[i].TraderID.String() < queue[j].TraderID.String()
+	})
+	for i, entry := range queue {
+		entry.Rank = i + 1
+	}
+	return queue
+}
+
+// DeleveragePosition closes size of a profitable position at the mark price.
+// Up to cover of the profit on the closed part goes toward another trader's
+// shortfall instead of to this trader. The size is rounded up to the lot grid.
+// Returns the size closed and the amount covered (implements
+// liquidation.PositionStore).
+func (me *MatchingEngine) DeleveragePosition(traderID uuid.UUID, instrument string, size, markPrice, cover decimal.Decimal) (decimal.Decimal, decimal.Decimal, error) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	posKey := fmt.Sprintf("%s:%s", traderID, instrument)
+	pos, exists := me.positions[posKey]
+	if !exists || pos.Size.IsZero() {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("no position to deleverage")
+	}
+
+	if ic := me.instrumentConfig; ic != nil && ic.LotSize.IsPositive() {
+		size = size.Div(ic.LotSize).Ceil().Mul(ic.LotSize)
+	}
+	size = decimal.Min(size, pos.Size.Abs())
+	fraction := size.Div(pos.Size.Abs())
+
+	closed := size
+	if pos.IsShort() {
+		closed = size.Neg()
+	}
+	profit := markPrice.Sub(pos.EntryPrice).Mul(closed)
+	cover = decimal.Max(decimal.Zero, decimal.Min(cover, profit))
+	realized := profit.Sub(cover)
+	released := pos.Margin.Mul(fraction)
+
+	if trader, ok := me.traders[traderID]; ok {
+		trader.Balance = trader.Balance.Add(released).Add(realized)
+		trader.TotalPnL = trader.TotalPnL.Add(realized)
+		if me.db != nil {
+			if err := me.db.SaveTrader(trader); err != nil {
+				slog.Error("Error saving trader after ADL", "error", err)
+			}
+		}
+	}
+
+	pos.Size = pos.Size.Sub(closed)
+	pos.Margin = pos.Margin.Sub(released)
+	pos.RealizedPnL = pos.RealizedPnL.Add(realized)
+	pos.UpdatedAt = time.Now()
+
+	if pos.Size.IsZero() {
+		delete(me.positions, posKey)
+		if me.db != nil {
+			if err := me.db.DeletePosition(traderID, instrument); err != nil {
+				slog.Error("Error deleting deleveraged position", "error", err)
+			}
+		}
+	} else if me.db != nil {
+		if err := me.db.SavePosition(pos); err != nil {
+			slog.Error("Error saving deleveraged position", "error", err)
+		}
+	}
+
+	me.refreshCrossLiquidationPricesLocked(traderID)
+	for _, handler := range me.positionHandlers {
+		handler(pos)
+	}
+	me.trimReduceOnlyLocked(traderID, instrument)
+
+	return size, cover, nil
+}
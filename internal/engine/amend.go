@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// AmendOrder changes the price and/or total size of one of a trader's resting
+// orders; a zero newPrice or newSize leaves that field as it is. A size
+// decrease at the same price keeps the order's queue priority. A price change
+// or size increase re-validates the order and moves it to the back of its
+// new level, matching first if the new price crosses the book; it keeps its
+// ID and fills so far. The new size may not be below the filled size, and
+// an amend that fails validation leaves the order untouched. Returns the
+// amended order and any trades it made.
+func (me *MatchingEngine) AmendOrder(traderID, orderID uuid.UUID, instrument string, newPrice, newSize decimal.Decimal) (*domain.Order, []*domain.Trade, error) {
+	defer me.observeMatchLatency(time.Now())
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	book, order, err := me.traderRestingOrderLocked(traderID, orderID, instrument)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if newPrice.IsZero() {
+		newPrice = order.Price
+	}
+	if newSize.IsZero() {
+		newSize = order.Size
+	}
+	if !newPrice.IsPositive() || !newSize.IsPositive() {
+		return nil, nil, fmt.Errorf("price and size must be positive")
+	}
+	if newSize.LessThan(order.FilledSize) {
+		return nil, nil, fmt.Errorf("cannot amend size to %s: %s already filled", newSize, order.FilledSize)
+	}
+
+	switch {
+	case newPrice.Equal(order.Price) && newSize.Equal(order.Size):
+		return order, nil, nil
+	case newPrice.Equal(order.Price) && newSize.LessThan(order.Size):
+		if err := me.reduceOrderLocked(book, order, order.Size.Sub(newSize)); err != nil {
+			return nil, nil, err
+		}
+		return order, nil, nil
+	}
+
+	// Validate the amended order before touching the resting one
+	amended := *order
+	amended.Price = newPrice
+	amended.Size = newSize
+	if _, err := me.validateOrderLocked(&amended); err != nil {
+		return nil, nil, err
+	}
+
+	defer me.correlateLocked(order.ID)()
+	book.RemoveOrder(order.ID)
+	order.Price = amended.Price
+	order.Size = amended.Size
+	order.UpdatedAt = time.Now()
+
+	trades := me.executeOrderLocked(book, order)
+
+	// A rested order was saved as it went back on the book; one that filled
+	// or was cancelled on the way still needs its final state saved
+	if _, resting := book.GetOrder(order.ID); !resting && me.db != nil {
+		if err := me.db.SaveOrder(order); err != nil {
+			slog.Error("Error saving amended order to database", "error", err)
+		}
+	}
+	me.processStopsLocked(instrument)
+	return order, trades, nil
+}
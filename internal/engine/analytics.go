@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// GetMakerStats computes fill rate and average resting time for a trader's orders.
+// Only orders that rested on the book are counted - immediately filled taker
+// orders never reach the order history.
+func (me *MatchingEngine) GetMakerStats(traderID uuid.UUID, instrument string) (*domain.MakerStats, error) {
+	stats := &domain.MakerStats{
+		TraderID:   traderID,
+		Instrument: instrument,
+	}
+	if me.db == nil {
+		return stats, nil
+	}
+
+	orders, err := me.db.GetTraderOrderLifecycles(traderID, instrument)
+	if err != nil {
+		return nil, fmt.Errorf("loading order history: %w", err)
+	}
+
+	var totalRestMs, terminalCount int64
+	for _, o := range orders {
+		stats.OrdersPlaced++
+		stats.PlacedVolume = stats.PlacedVolume.Add(o.Size)
+		stats.FilledVolume = stats.FilledVolume.Add(o.FilledSize)
+
+		switch o.Status {
+		case domain.OrderStatusFilled:
+			stats.OrdersFilled++
+		case domain.OrderStatusCancelled:
+			stats.OrdersCancelled++
+		default:
+			stats.OrdersOpen++
+			continue
+		}
+		totalRestMs += o.UpdatedAt.Sub(o.CreatedAt).Milliseconds()
+		terminalCount++
+	}
+
+	if rate, err := domain.SafeDiv(stats.FilledVolume, stats.PlacedVolume); err == nil {
+		stats.FillRate = rate
+	}
+	if terminalCount > 0 {
+		stats.AvgRestTimeMs = totalRestMs / terminalCount
+	}
+
+	return stats, nil
+}
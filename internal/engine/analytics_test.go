@@ -0,0 +1,61 @@
+package engine_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+func TestMakerStatsFromOrderHistory(t *testing.T) {
+	const rest = 100 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "maker.db")
+	cfg := config.Default()
+	h, database, _ := restartEngine(t, cfg, path)
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+
+	h.MustLimit(maker, domain.SideSell, "1000", "1") // Filled straight away
+	h.MustMarket(taker, domain.SideBuy, "1")
+	slow := h.MustLimit(maker, domain.SideSell, "1010", "1")  // Rests, then is cancelled
+	partial := h.MustLimit(maker, domain.SideBuy, "990", "2") // Half filled, still resting
+	h.MustMarket(taker, domain.SideSell, "1")
+	time.Sleep(rest)
+	if err := h.Engine.CancelOrder(maker.ID, slow.ID, h.Instrument); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := h.Engine.GetMakerStats(maker.ID, h.Instrument)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.OrdersPlaced != 3 || stats.OrdersFilled != 1 || stats.OrdersCancelled != 1 || stats.OrdersOpen != 1 {
+		t.Fatalf("orders = %d placed, %d filled, %d cancelled, %d open, want 3, 1, 1 and 1",
+			stats.OrdersPlaced, stats.OrdersFilled, stats.OrdersCancelled, stats.OrdersOpen)
+	}
+	if !stats.PlacedVolume.Equal(dec("4")) || !stats.FilledVolume.Equal(dec("2")) || !stats.FillRate.Equal(dec("0.5")) {
+		t.Fatalf("volume %s of %s, fill rate %s, want 2 of 4 and 0.5", stats.FilledVolume, stats.PlacedVolume, stats.FillRate)
+	}
+	// The quick fill rested for next to nothing and the cancelled order for
+	// at least rest; the open order is not counted yet
+	if lo, hi := (rest / 2).Milliseconds(), rest.Milliseconds(); stats.AvgRestTimeMs < lo || stats.AvgRestTimeMs >= hi {
+		t.Fatalf("average rest = %dms, want between %d and %dms", stats.AvgRestTimeMs, lo, hi)
+	}
+
+	// Filled and cancelled orders stay in the database for these stats, but
+	// only pending and partial ones are put back on the book at startup
+	if err := database.Close(); err != nil {
+		t.Fatal(err)
+	}
+	h, database, _ = restartEngine(t, cfg, path)
+	defer database.Close()
+	bids, asks := h.Bids(), h.Asks()
+	if len(asks) != 0 || len(bids) != 1 || !bids[0].Price.Equal(dec("990")) || !bids[0].Size.Equal(dec("1")) {
+		t.Fatalf("book after restart = %+v / %+v, want only the partial bid's remaining 1", bids, asks)
+	}
+	if orders := h.Engine.GetOpenOrders(maker.ID, h.Instrument); len(orders) != 1 || orders[0].ID != partial.ID {
+		t.Fatalf("%d open orders after restart, want the partial bid", len(orders))
+	}
+}
@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// Audit actions
+const (
+	AuditActionFlatten = "flatten"
+)
+
+// recordAudit logs and persists an audit event
+func (me *MatchingEngine) recordAudit(traderID uuid.UUID, action, details string) {
+	event := &domain.AuditEvent{
+		ID:        uuid.New(),
+		Timestamp: time.Now(),
+		TraderID:  traderID,
+		Action:    action,
+		Details:   details,
+	}
+	slog.Info("Audit event", "trader_id", traderID, "action", action, "details", details)
+
+	if me.db != nil {
+		if err := me.db.SaveAuditEvent(event); err != nil {
+			slog.Error("Error saving audit event to database", "error", err)
+		}
+	}
+}
+
+// CancelAllOrders cancels every resting and untriggered stop order a trader
+// has on any instrument, returning the cancelled orders
+func (me *MatchingEngine) CancelAllOrders(traderID uuid.UUID) []*domain.Order {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	return me.cancelAllOrdersLocked(traderID)
+}
+
+// cancelAllOrdersLocked cancels all of a trader's open orders. Caller must
+// hold me.mu.
+func (me *MatchingEngine) cancelAllOrdersLocked(traderID uuid.UUID) []*domain.Order {
+	var cancelled []*domain.Order
+	for instrument, book := range me.books {
+		orders := append(book.GetTraderOrders(traderID), me.traderStopsLocked(traderID, instrument)...)
+		for _, order := range orders {
+			// The OCO sibling of an earlier cancel is already gone
+			if order.Status != domain.OrderStatusCancelled {
+				if err := me.cancelOrderLocked(order.ID, instrument); err != nil {
+					slog.Error("Error cancelling order", "order_id", order.ID, "error", err)
+					continue
+				}
+			}
+			cancelled = append(cancelled, order)
+		}
+	}
+	return cancelled
+}
+
+// FlattenTrader cancels all of a trader's resting orders and closes all their
+// positions at market across every instrument, under one lock acquisition.
+// Positions that cannot be fully closed (thin book, market not open) are
+// reported in RemainingPositions and cause an error alongside the result.
+func (me *MatchingEngine) FlattenTrader(traderID uuid.UUID) (*domain.FlattenResult, error) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	if _, exists := me.traders[traderID]; !exists {
+		return nil, fmt.Errorf("unknown trader: %s", traderID)
+	}
+
+	result := &domain.FlattenResult{
+		TraderID:           traderID,
+		CancelledOrders:    make([]*domain.Order, 0),
+		Trades:             make([]*domain.Trade, 0),
+		RealizedPnL:        decimal.Zero,
+		RemainingPositions: make([]*domain.Position, 0),
+	}
+
+	// Cancel first so closing orders can't trade against the trader's own quotes
+	result.CancelledOrders = append(result.CancelledOrders, me.cancelAllOrdersLocked(traderID)...)
+
+	// Collect up front: closing trades add counterparty positions to the map
+	open := make([]*domain.Position, 0)
+	for _, pos := range me.positions {
+		if pos.TraderID == traderID && !pos.Size.IsZero() {
+			open = append(open, pos)
+		}
+	}
+
+	var closeErr error
+	for _, pos := range open {
+		side := domain.SideSell
+		if pos.IsShort() {
+			side = domain.SideBuy
+		}
+		realizedBefore := pos.RealizedPnL
+
+		order := &domain.Order{
+			TraderID:   traderID,
+			Instrument: pos.Instrument,
+			Side:       side,
+			Type:       domain.OrderTypeMarket,
+			Size:       pos.Size.Abs(),
+			Leverage:   pos.Leverage,
+		}
+		trades, err := me.submitOrderLocked(order)
+		if err != nil && closeErr == nil {
+			closeErr = fmt.Errorf("closing %s position: %w", pos.Instrument, err)
+		}
+		result.Trades = append(result.Trades, trades...)
+		result.RealizedPnL = result.RealizedPnL.Add(pos.RealizedPnL.Sub(realizedBefore))
+
+		if !pos.Size.IsZero() {
+			posCopy := *pos
+			result.RemainingPositions = append(result.RemainingPositions, &posCopy)
+		}
+	}
+
+	if closeErr == nil && len(result.RemainingPositions) > 0 {
+		closeErr = fmt.Errorf("insufficient liquidity to close %d position(s)", len(result.RemainingPositions))
+	}
+
+	me.recordAudit(traderID, AuditActionFlatten, fmt.Sprintf(
+		"cancelled_orders=%d trades=%d realized_pnl=%s remaining_positions=%d",
+		len(result.CancelledOrders), len(result.Trades), result.RealizedPnL, len(result.RemainingPositions)))
+
+	return result, closeErr
+}
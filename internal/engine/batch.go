@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// BatchOrderResult is the outcome of one order in a batch: its trades, or the
+// error that rejected it
+type BatchOrderResult struct {
+	Order  *domain.Order
+	Trades []*domain.Trade
+	Err    error
+}
+
+// SubmitOrders submits orders one after another under a single lock
+// acquisition, so the batch sees a consistent book and nothing else trades in
+// between. A rejected order does not stop the rest. Results are in the order
+// given.
+func (me *MatchingEngine) SubmitOrders(orders []*domain.Order) []*BatchOrderResult {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	results := make([]*BatchOrderResult, len(orders))
+	for i, order := range orders {
+		start := time.Now()
+		trades, err := me.submitOrderLocked(order)
+		me.observeMatchLatency(start)
+		results[i] = &BatchOrderResult{Order: order, Trades: trades, Err: err}
+	}
+	return results
+}
@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// candleIntervals are the timeframes rolled to the database
+var candleIntervals = []domain.CandleInterval{
+	domain.CandleInterval1m,
+	domain.CandleInterval5m,
+	domain.CandleInterval15m,
+	domain.CandleInterval1h,
+	domain.CandleInterval4h,
+	domain.CandleInterval1d,
+}
+
+// candleKey identifies an in-progress candle by instrument and interval
+func candleKey(instrument string, interval domain.CandleInterval) string {
+	return instrument + ":" + string(interval)
+}
+
+// rollCandlesLocked folds a trade into the in-progress candle of every
+// interval. A trade in a later period completes the previous candle, which
+// is saved. Caller must hold me.mu.
+func (me *MatchingEngine) rollCandlesLocked(trade *domain.Trade) {
+	if me.db == nil {
+		return
+	}
+
+	for _, interval := range candleIntervals {
+		d := getIntervalDuration(interval)
+		openTime := truncateToInterval(trade.Timestamp, d)
+		key := candleKey(trade.Instrument, interval)
+
+		bucket, exists := me.openCandles[key]
+		if exists && !bucket.candle.OpenTime.Equal(openTime) {
+			me.saveCandleLocked(bucket.candle)
+			exists = false
+		}
+		if !exists {
+			bucket = me.resumeCandleLocked(trade.Instrument, interval, openTime)
+			if bucket == nil {
+				bucket = &candleBucket{
+					candle: &domain.Candle{
+						Instrument: trade.Instrument,
+						Interval:   interval,
+						OpenTime:   openTime,
+						CloseTime:  openTime.Add(d),
+						Open:       trade.Price,
+						High:       trade.Price,
+						Low:        trade.Price,
+						Close:      trade.Price,
+						TradeCount: 1,
+					},
+					openAt:  trade.Timestamp,
+					closeAt: trade.Timestamp,
+				}
+				bucket.addVolume(trade.Price, trade.Size)
+				me.openCandles[key] = bucket
+				continue
+			}
+			me.openCandles[key] = bucket
+		}
+
+		candle := bucket.candle
+		if trade.Timestamp.Before(bucket.openAt) {
+			candle.Open, bucket.openAt = trade.Price, trade.Timestamp
+		}
+		if !trade.Timestamp.Before(bucket.closeAt) {
+			candle.Close, bucket.closeAt = trade.Price, trade.Timestamp
+		}
+		if trade.Price.GreaterThan(candle.High) {
+			candle.High = trade.Price
+		}
+		if trade.Price.LessThan(candle.Low) {
+			candle.Low = trade.Price
+		}
+		bucket.addVolume(trade.Price, trade.Size)
+		candle.TradeCount++
+	}
+}
+
+// resumeCandleLocked picks up a candle saved before a restart so trades after
+// it extend the period rather than replace it. Returns nil if none was saved.
+// Caller must hold me.mu.
+func (me *MatchingEngine) resumeCandleLocked(instrument string, interval domain.CandleInterval, openTime time.Time) *candleBucket {
+	saved, err := me.db.GetCandles(instrument, interval, openTime, openTime, 1)
+	if err != nil {
+		slog.Error("Error loading candle from database", "error", err)
+		return nil
+	}
+	if len(saved) == 0 {
+		return nil
+	}
+	// The saved candle's trades all predate any trade seen since. Candles
+	// saved before VWAP was recorded resume from their close.
+	candle := saved[0]
+	if candle.VWAP.IsZero() {
+		candle.VWAP = candle.Close
+	}
+	return &candleBucket{candle: candle, openAt: openTime, closeAt: openTime, notional: candle.VWAP.Mul(candle.Volume)}
+}
+
+// saveCandleLocked persists a candle. Caller must hold me.mu.
+func (me *MatchingEngine) saveCandleLocked(candle *domain.Candle) {
+	if err := me.db.SaveCandle(candle); err != nil {
+		slog.Error("Error saving candle to database", "error", err)
+	}
+}
+
+// saveCandles saves candles whose period has ended by now. With all set, the
+// in-progress candles are saved too, for a final write at shutdown; they are
+// resumed from the database after a restart.
+func (me *MatchingEngine) saveCandles(now time.Time, all bool) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	if me.db == nil {
+		return
+	}
+	for key, bucket := range me.openCandles {
+		if all || !now.Before(bucket.candle.CloseTime) {
+			me.saveCandleLocked(bucket.candle)
+		}
+		if !now.Before(bucket.candle.CloseTime) {
+			delete(me.openCandles, key)
+		}
+	}
+}
+
+// openCandlesLocked returns a copy of an instrument's in-progress candle for
+// an interval if it opened within a range, to merge with candles read from
+// the database. Caller must hold me.mu.
+func (me *MatchingEngine) openCandlesLocked(instrument string, interval domain.CandleInterval, start, end time.Time) []*domain.Candle {
+	bucket, ok := me.openCandles[candleKey(instrument, interval)]
+	if !ok || bucket.candle.OpenTime.Before(start) || bucket.candle.OpenTime.After(end) {
+		return nil
+	}
+	candle := *bucket.candle
+	return []*domain.Candle{&candle}
+}
+
+// mergeCandles combines candles for the same periods from two sources,
+// keeping whichever saw more trades in a period, oldest first
+func mergeCandles(a, b []*domain.Candle) []*domain.Candle {
+	byOpen := make(map[int64]*domain.Candle, len(a)+len(b))
+	for _, c := range append(a, b...) {
+		key := c.OpenTime.Unix()
+		if existing, ok := byOpen[key]; !ok || c.TradeCount > existing.TradeCount {
+			byOpen[key] = c
+		}
+	}
+
+	merged := make([]*domain.Candle, 0, len(byOpen))
+	for _, c := range byOpen {
+		merged = append(merged, c)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].OpenTime.Before(merged[j].OpenTime)
+	})
+	return merged
+}
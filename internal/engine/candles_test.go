@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+func TestCandlesFromUnorderedTrades(t *testing.T) {
+	minute := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	trade := func(offset time.Duration, price, size string) *domain.Trade {
+		return &domain.Trade{
+			ID:         uuid.New(),
+			Instrument: domain.RIndexSymbol,
+			Price:      dec(price),
+			Size:       dec(size),
+			Timestamp:  minute.Add(offset),
+		}
+	}
+	trades := []*domain.Trade{
+		trade(20*time.Second, "110", "1"),
+		trade(time.Second, "100", "1"), // First of the minute
+		trade(70*time.Second, "130", "2"),
+		trade(10*time.Second, "90", "1"),
+		trade(50*time.Second, "105", "1"), // Last of the minute
+		trade(5*time.Second, "120", "1"),
+		trade(65*time.Second, "125", "1"),
+	}
+	want := []struct {
+		open, high, low, close, volume string
+		count                          int64
+	}{
+		{"100", "120", "90", "105", "5", 5},
+		{"125", "130", "125", "130", "3", 2},
+	}
+
+	// The order the trades are handed over in must not change the candles
+	for _, order := range []string{"shuffled", "newest first", "oldest first"} {
+		switch order {
+		case "newest first":
+			sort.Slice(trades, func(i, j int) bool { return trades[i].Timestamp.After(trades[j].Timestamp) })
+		case "oldest first":
+			sort.Slice(trades, func(i, j int) bool { return trades[i].Timestamp.Before(trades[j].Timestamp) })
+		}
+
+		candles := buildCandles(trades, domain.RIndexSymbol, domain.CandleInterval1m, func(*domain.Trade) bool { return true })
+		sort.Slice(candles, func(i, j int) bool { return candles[i].OpenTime.Before(candles[j].OpenTime) })
+		if len(candles) != len(want) {
+			t.Fatalf("%s: %d candles, want %d", order, len(candles), len(want))
+		}
+		for i, w := range want {
+			c := candles[i]
+			if !c.OpenTime.Equal(minute.Add(time.Duration(i) * time.Minute)) {
+				t.Errorf("%s: candle %d opens at %s", order, i, c.OpenTime)
+			}
+			if !c.Open.Equal(dec(w.open)) || !c.High.Equal(dec(w.high)) || !c.Low.Equal(dec(w.low)) ||
+				!c.Close.Equal(dec(w.close)) || !c.Volume.Equal(dec(w.volume)) || c.TradeCount != w.count {
+				t.Errorf("%s: candle %d = O %s H %s L %s C %s V %s n %d, want %+v",
+					order, i, c.Open, c.High, c.Low, c.Close, c.Volume, c.TradeCount, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// SimulateCascade works out what would be liquidated if the price moved from
+// the current mark to targetPrice, without touching any state. Positions whose
+// liquidation price is crossed are liquidated in the order the move reaches
+// them. With bookImpact, each liquidation is also sold (or bought) into a copy
+// of the book, and the price that leaves can carry the move past the target
+// and liquidate further positions.
+func (me *MatchingEngine) SimulateCascade(instrument string, targetPrice decimal.Decimal, bookImpact bool) (*domain.CascadeSimulation, error) {
+	if !targetPrice.IsPositive() {
+		return nil, fmt.Errorf("target price must be positive")
+	}
+
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	book, exists := me.books[instrument]
+	if !exists {
+		return nil, fmt.Errorf("unknown instrument: %s", instrument)
+	}
+
+	start, ok := me.lastTradePrice(instrument)
+	if !ok {
+		start = me.startingPrice()
+	}
+	sim := &domain.CascadeSimulation{
+		Instrument:  instrument,
+		Timestamp:   time.Now(),
+		StartPrice:  start,
+		TargetPrice: targetPrice,
+		FinalPrice:  targetPrice,
+		BookImpact:  bookImpact,
+		Chain:       []domain.CascadeStep{},
+	}
+
+	// A fall liquidates longs, least cushioned (highest liquidation price)
+	// first; a rise liquidates shorts, lowest liquidation price first
+	falling := targetPrice.LessThan(start)
+	var candidates []*domain.Position
+	for _, pos := range me.positions {
+		if pos.Instrument != instrument || pos.Size.IsZero() || !pos.LiquidationPrice.IsPositive() {
+			continue
+		}
+		if pos.IsLong() == falling {
+			candidates = append(candidates, pos)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].LiquidationPrice.Equal(candidates[j].LiquidationPrice) {
+			return candidates[i].TraderID.String() < candidates[j].TraderID.String()
+		}
+		if falling {
+			return candidates[i].LiquidationPrice.GreaterThan(candidates[j].LiquidationPrice)
+		}
+		return candidates[i].LiquidationPrice.LessThan(candidates[j].LiquidationPrice)
+	})
+
+	// Liquidating a long sells into the bids, a short buys from the asks
+	var levels []domain.OrderBookLevel
+	if bookImpact {
+		bidLevels, askLevels, _, _ := book.Counts()
+		snapshot := book.GetSnapshot(max(bidLevels, askLevels))
+		levels = snapshot.Asks
+		if falling {
+			levels = snapshot.Bids
+		}
+		levels = append([]domain.OrderBookLevel(nil), levels...)
+	}
+
+	// reached is the furthest price the move has got to
+	reached := targetPrice
+	crossed := func(liqPrice decimal.Decimal) bool {
+		if falling {
+			return liqPrice.GreaterThanOrEqual(reached)
+		}
+		return liqPrice.LessThanOrEqual(reached)
+	}
+
+	for _, pos := range candidates {
+		if !crossed(pos.LiquidationPrice) {
+			break
+		}
+
+		// Closed at the mark, taken to be the liquidation price
+		size := pos.Size.Abs()
+		loss := pos.EntryPrice.Sub(pos.LiquidationPrice).Mul(size)
+		side := domain.SideBuy
+		if pos.IsShort() {
+			loss = loss.Neg()
+			side = domain.SideSell
+		}
+		step := domain.CascadeStep{
+			Step:             len(sim.Chain) + 1,
+			TraderID:         pos.TraderID,
+			Side:             side,
+			Size:             size,
+			LiquidationPrice: pos.LiquidationPrice,
+			Notional:         size.Mul(pos.LiquidationPrice),
+			Loss:             loss,
+			PriceAfter:       reached,
+		}
+
+		if bookImpact {
+			var price decimal.Decimal
+			levels, price, ok = sweepLevels(levels, size)
+			if !ok {
+				sim.BookExhausted = true
+			}
+			if price.IsPositive() && ((falling && price.LessThan(reached)) || (!falling && price.GreaterThan(reached))) {
+				reached = price
+			}
+			step.PriceAfter = reached
+		}
+
+		sim.Chain = append(sim.Chain, step)
+		sim.Positions++
+		sim.TotalSize = sim.TotalSize.Add(size)
+		sim.TotalNotional = sim.TotalNotional.Add(step.Notional)
+		sim.TotalLoss = sim.TotalLoss.Add(loss)
+	}
+
+	sim.FinalPrice = reached
+	return sim, nil
+}
+
+// sweepLevels fills size against book levels, best first, and returns the
+// levels left, the price of the last level touched and whether the book had
+// enough size. The levels slice is modified in place.
+func sweepLevels(levels []domain.OrderBookLevel, size decimal.Decimal) ([]domain.OrderBookLevel, decimal.Decimal, bool) {
+	var last decimal.Decimal
+	for len(levels) > 0 && size.IsPositive() {
+		level := &levels[0]
+		last = level.Price
+		if level.Size.GreaterThan(size) {
+			level.Size = level.Size.Sub(size)
+			return levels, last, true
+		}
+		size = size.Sub(level.Size)
+		levels = levels[1:]
+	}
+	return levels, last, !size.IsPositive()
+}
@@ -0,0 +1,65 @@
+package engine_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestSimulateCascadeCountsCrossedPositions(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	seller := h.AddTrader("seller")
+	longs := map[int]*domain.Trader{}
+	for _, leverage := range []int{5, 10, 20} {
+		trader := h.AddTrader(fmt.Sprintf("long%dx", leverage))
+		h.MustLimit(seller, domain.SideSell, "1000", "1")
+		if _, err := h.Submit(&domain.Order{TraderID: trader.ID, Side: domain.SideBuy, Type: domain.OrderTypeMarket, Size: dec("1"), Leverage: leverage}); err != nil {
+			t.Fatal(err)
+		}
+		longs[leverage] = trader
+	}
+	liq5, liq10, liq20 := h.Position(longs[5]).LiquidationPrice, h.Position(longs[10]).LiquidationPrice, h.Position(longs[20]).LiquidationPrice
+	if !liq20.GreaterThan(liq10) || !liq10.GreaterThan(liq5) {
+		t.Fatalf("liquidation prices 5x %s, 10x %s, 20x %s, want higher leverage closer to 1000", liq5, liq10, liq20)
+	}
+
+	// Between the 10x and 5x liquidation prices: the 20x goes first, then the 10x
+	target := liq10.Add(liq5).Div(dec("2")).Round(2)
+	sim, err := h.Engine.SimulateCascade(h.Instrument, target, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sim.Positions != 2 || len(sim.Chain) != 2 || !sim.TotalSize.Equal(dec("2")) {
+		t.Fatalf("simulated %d liquidations of %s, want 2 of 2: %+v", sim.Positions, sim.TotalSize, sim.Chain)
+	}
+	if sim.Chain[0].TraderID != longs[20].ID || sim.Chain[1].TraderID != longs[10].ID {
+		t.Fatalf("chain = %+v, want the 20x then the 10x", sim.Chain)
+	}
+	if want := liq20.Add(liq10); !sim.TotalNotional.Equal(want) {
+		t.Fatalf("total notional = %s, want %s at their liquidation prices", sim.TotalNotional, want)
+	}
+	if !sim.StartPrice.Equal(dec("1000")) || !sim.FinalPrice.Equal(target) {
+		t.Fatalf("moved %s to %s, want 1000 to %s", sim.StartPrice, sim.FinalPrice, target)
+	}
+
+	// Past every liquidation price: all three
+	if sim, err := h.Engine.SimulateCascade(h.Instrument, liq5.Sub(dec("1")), false); err != nil || sim.Positions != 3 {
+		t.Fatalf("simulated %v liquidations below the 5x price (%v), want 3", sim, err)
+	}
+	// Above the last trade, only shorts are at risk, and the 1x short is not
+	if sim, err := h.Engine.SimulateCascade(h.Instrument, dec("1500"), false); err != nil || sim.Positions != 0 {
+		t.Fatalf("simulated %v liquidations at 1500 (%v), want none", sim, err)
+	}
+
+	// Nothing was actually liquidated
+	for leverage, trader := range longs {
+		if pos := h.Position(trader); pos == nil || !pos.Size.Equal(dec("1")) {
+			t.Fatalf("%dx position after simulating = %+v, want it untouched", leverage, pos)
+		}
+	}
+	if got := len(h.Engine.GetRecentLiquidations(h.Instrument, 10)); got != 0 {
+		t.Fatalf("%d liquidations recorded by a simulation", got)
+	}
+}
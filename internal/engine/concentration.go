@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// GetConcentration reports what share of an instrument's positions the top N
+// holders control, and a Gini coefficient over all position sizes
+func (me *MatchingEngine) GetConcentration(instrument string, topN int) *domain.PositionConcentration {
+	me.mu.RLock()
+	var sizes []decimal.Decimal
+	for _, pos := range me.positions {
+		if pos.Instrument == instrument && !pos.Size.IsZero() {
+			sizes = append(sizes, pos.Size.Abs())
+		}
+	}
+	me.mu.RUnlock()
+
+	c := &domain.PositionConcentration{
+		Instrument: instrument,
+		Timestamp:  time.Now(),
+		Holders:    len(sizes),
+		TopN:       topN,
+	}
+	if len(sizes) == 0 {
+		return c
+	}
+
+	// Ascending, as the Gini formula below expects
+	sort.Slice(sizes, func(i, j int) bool {
+		return sizes[i].LessThan(sizes[j])
+	})
+
+	// G = sum((2i - n - 1) * x_i) / (n * sum(x)), i = 1..n
+	n := int64(len(sizes))
+	weighted := decimal.Zero
+	for i, size := range sizes {
+		c.GrossSize = c.GrossSize.Add(size)
+		weighted = weighted.Add(size.Mul(decimal.NewFromInt(2*int64(i+1) - n - 1)))
+	}
+	if gini, err := domain.SafeDiv(weighted, c.GrossSize.Mul(decimal.NewFromInt(n))); err == nil {
+		c.Gini = gini.Round(4)
+	}
+
+	top := decimal.Zero
+	for i := len(sizes) - 1; i >= 0 && i >= len(sizes)-topN; i-- {
+		top = top.Add(sizes[i])
+	}
+	if share, err := domain.SafeDiv(top, c.GrossSize); err == nil {
+		c.TopShare = share.Round(4)
+	}
+
+	return c
+}
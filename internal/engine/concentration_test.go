@@ -0,0 +1,53 @@
+package engine_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestConcentrationRanksWhaleAboveEvenBook(t *testing.T) {
+	// Even: two pairs, everyone holding 1
+	even := enginetest.NewTestEngine()
+	for i := 0; i < 2; i++ {
+		seller := even.AddTrader(fmt.Sprintf("seller-%d", i))
+		buyer := even.AddTrader(fmt.Sprintf("buyer-%d", i))
+		even.MustLimit(seller, domain.SideSell, "1000", "1")
+		even.MustMarket(buyer, domain.SideBuy, "1")
+	}
+
+	// Whale: one long of 10 against ten shorts of 1
+	whaled := enginetest.NewTestEngine()
+	whale := whaled.AddTrader("whale")
+	for i := 0; i < 10; i++ {
+		whaled.MustLimit(whaled.AddTrader(fmt.Sprintf("seller-%d", i)), domain.SideSell, "1000", "1")
+	}
+	// At 2x, so 10 at 1000 fits the starting balance
+	if _, err := whaled.Submit(&domain.Order{TraderID: whale.ID, Side: domain.SideBuy, Type: domain.OrderTypeMarket, Size: dec("10"), Leverage: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	low := even.Engine.GetConcentration(even.Instrument, 1)
+	high := whaled.Engine.GetConcentration(whaled.Instrument, 1)
+
+	if low.Holders != 4 || !low.Gini.IsZero() || !low.TopShare.Equal(dec("0.25")) {
+		t.Fatalf("even book = %d holders, gini %s, top share %s, want 4, 0 and 0.25", low.Holders, low.Gini, low.TopShare)
+	}
+	// 90 / (11 * 20), and the whale holds half the gross size
+	if high.Holders != 11 || !high.Gini.Equal(dec("0.4091")) || !high.TopShare.Equal(dec("0.5")) {
+		t.Fatalf("whale book = %d holders, gini %s, top share %s, want 11, 0.4091 and 0.5", high.Holders, high.Gini, high.TopShare)
+	}
+	if !high.Gini.GreaterThan(low.Gini) || !high.TopShare.GreaterThan(low.TopShare) {
+		t.Fatal("a dominant holder does not score as more concentrated")
+	}
+}
+
+func TestConcentrationOfEmptyBook(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	c := h.Engine.GetConcentration(h.Instrument, 5)
+	if c.Holders != 0 || !c.Gini.IsZero() || !c.TopShare.IsZero() {
+		t.Fatalf("concentration = %+v, want zeros with no positions", c)
+	}
+}
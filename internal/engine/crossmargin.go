@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// SetMarginMode switches a trader between isolated and cross margin. The mode
+// cannot change while the trader holds a position.
+func (me *MatchingEngine) SetMarginMode(traderID uuid.UUID, mode domain.MarginMode) error {
+	if !mode.IsValid() || mode == "" {
+		return fmt.Errorf("invalid margin_mode: %s", mode)
+	}
+
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	trader, ok := me.traders[traderID]
+	if !ok {
+		return fmt.Errorf("trader not found")
+	}
+	for _, pos := range me.positions {
+		if pos.TraderID == traderID && !pos.Size.IsZero() {
+			return fmt.Errorf("cannot change margin mode with open positions")
+		}
+	}
+
+	trader.MarginMode = mode
+	if me.db != nil {
+		if err := me.db.SaveTrader(trader); err != nil {
+			slog.Error("Error saving trader margin mode", "error", err)
+		}
+	}
+	return nil
+}
+
+// marginModeLocked returns a trader's margin mode, isolated if unset.
+// Caller must hold me.mu.
+func (me *MatchingEngine) marginModeLocked(traderID uuid.UUID) domain.MarginMode {
+	if trader, ok := me.traders[traderID]; ok && trader.MarginMode == domain.MarginModeCross {
+		return domain.MarginModeCross
+	}
+	return domain.MarginModeIsolated
+}
+
+// GetCrossMarginAccount returns the equity backing a cross margin trader's
+// positions at current mark prices, or nil for an isolated trader (implements
+// liquidation.PositionStore)
+func (me *MatchingEngine) GetCrossMarginAccount(traderID uuid.UUID) *domain.CrossMarginAccount {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	if me.marginModeLocked(traderID) != domain.MarginModeCross {
+		return nil
+	}
+	trader := me.traders[traderID]
+	account := &domain.CrossMarginAccount{
+		TraderID: traderID,
+		Balance:  trader.Balance,
+		Equity:   trader.Balance,
+	}
+	for _, pos := range me.positions {
+		if pos.TraderID != traderID || pos.Size.IsZero() {
+			continue
+		}
+		account.Equity = account.Equity.Add(pos.Margin).Add(pos.ComputeUnrealizedPnL(me.markPriceLocked(pos.Instrument)))
+		account.Maintenance = account.Maintenance.Add(me.maintenanceRequirementLocked(pos))
+	}
+	return account
+}
+
+// maintenanceRequirementLocked is the part of a position's margin that must
+// remain: the maintenance rate of its margin, the same amount an isolated
+// position still holds at its liquidation price. Caller must hold me.mu.
+func (me *MatchingEngine) maintenanceRequirementLocked(pos *domain.Position) decimal.Decimal {
+	if me.liqConfig == nil {
+		return decimal.Zero
+	}
+	rate := me.maintenanceMargin(domain.NormalizeLeverage(pos.Leverage), pos.EntryPrice.Mul(pos.Size.Abs()))
+	return pos.Margin.Mul(rate)
+}
+
+// liquidationPriceLocked returns the price at which a position is liquidated.
+// An isolated position is backed only by its margin. A cross position is also
+// backed by the trader's free balance and the equity of their other positions
+// above maintenance, which moves its price further away, or closer if they
+// are underwater. Caller must hold me.mu.
+func (me *MatchingEngine) liquidationPriceLocked(pos *domain.Position) decimal.Decimal {
+	price := me.calculateLiquidationPrice(pos.EntryPrice, pos.Leverage, pos.Size)
+	if me.liqConfig == nil || pos.Size.IsZero() || me.marginModeLocked(pos.TraderID) != domain.MarginModeCross {
+		return price
+	}
+
+	backing := me.traders[pos.TraderID].Balance
+	for _, other := range me.positions {
+		if other == pos || other.TraderID != pos.TraderID || other.Size.IsZero() {
+			continue
+		}
+		pnl := other.ComputeUnrealizedPnL(me.markPriceLocked(other.Instrument))
+		backing = backing.Add(other.Margin).Add(pnl).Sub(me.maintenanceRequirementLocked(other))
+	}
+
+	// Each unit of price moves equity by the position size
+	price = price.Sub(backing.DivRound(pos.Size, me.divisionPlaces()))
+	return decimal.Max(price, decimal.Zero)
+}
+
+// refreshCrossLiquidationPricesLocked recomputes the liquidation price of
+// each of a cross margin trader's positions, which move with the balance and
+// each other. Caller must hold me.mu.
+func (me *MatchingEngine) refreshCrossLiquidationPricesLocked(traderID uuid.UUID) {
+	if me.marginModeLocked(traderID) != domain.MarginModeCross {
+		return
+	}
+	for _, pos := range me.positions {
+		if pos.TraderID == traderID && !pos.Size.IsZero() {
+			pos.LiquidationPrice = me.liquidationPriceLocked(pos)
+		}
+	}
+}
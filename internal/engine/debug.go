@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/db"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// BookDebugState summarizes one order book for diagnostics
+type BookDebugState struct {
+	Instrument string          `json:"instrument"`
+	BidLevels  int             `json:"bid_levels"`
+	AskLevels  int             `json:"ask_levels"`
+	BidOrders  int             `json:"bid_orders"`
+	AskOrders  int             `json:"ask_orders"`
+	BestBid    decimal.Decimal `json:"best_bid"`
+	BestAsk    decimal.Decimal `json:"best_ask"`
+	BidHidden  decimal.Decimal `json:"bid_hidden"` // Undisplayed iceberg size
+	AskHidden  decimal.Decimal `json:"ask_hidden"`
+}
+
+// DebugState is a read-only snapshot of engine internals for operators
+type DebugState struct {
+	Timestamp          time.Time           `json:"timestamp"`
+	MarketState        domain.MarketState  `json:"market_state"`
+	BookCount          int                 `json:"book_count"`
+	Books              []BookDebugState    `json:"books"`
+	TotalBidOrders     int                 `json:"total_bid_orders"`
+	TotalAskOrders     int                 `json:"total_ask_orders"`
+	TraderCount        int                 `json:"trader_count"`
+	PositionCount      int                 `json:"position_count"`
+	OpenPositionCount  int                 `json:"open_position_count"`
+	NetPosition        decimal.Decimal     `json:"net_position"` // Should always be zero
+	InsuranceFund      decimal.Decimal     `json:"insurance_fund"`
+	RecentTradesLength int                 `json:"recent_trades_length"`
+	LiquidationsLength int                 `json:"liquidations_length"`
+	Goroutines         int                 `json:"goroutines"`
+	WriteQueue         *db.WriteQueueStats `json:"write_queue,omitempty"` // Set with write batching on
+}
+
+// GetDebugState captures engine internals under a single lock acquisition
+func (me *MatchingEngine) GetDebugState() *DebugState {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	state := &DebugState{
+		Timestamp:          time.Now(),
+		MarketState:        me.marketState,
+		BookCount:          len(me.books),
+		Books:              make([]BookDebugState, 0, len(me.books)),
+		TraderCount:        len(me.traders),
+		PositionCount:      len(me.positions),
+		InsuranceFund:      me.insuranceFundBalance(),
+		RecentTradesLength: len(me.recentTrades),
+		LiquidationsLength: len(me.liquidations),
+		Goroutines:         runtime.NumGoroutine(),
+	}
+
+	for instrument, book := range me.books {
+		bidLevels, askLevels, bidOrders, askOrders := book.Counts()
+		bestBid, _, _ := book.BestBid()
+		bestAsk, _, _ := book.BestAsk()
+		bidHidden, askHidden := book.HiddenSize()
+		state.Books = append(state.Books, BookDebugState{
+			Instrument: instrument,
+			BidLevels:  bidLevels,
+			AskLevels:  askLevels,
+			BidOrders:  bidOrders,
+			AskOrders:  askOrders,
+			BestBid:    bestBid,
+			BestAsk:    bestAsk,
+			BidHidden:  bidHidden,
+			AskHidden:  askHidden,
+		})
+		state.TotalBidOrders += bidOrders
+		state.TotalAskOrders += askOrders
+	}
+
+	for _, pos := range me.positions {
+		if !pos.Size.IsZero() {
+			state.OpenPositionCount++
+		}
+		state.NetPosition = state.NetPosition.Add(pos.Size)
+	}
+
+	if me.db != nil {
+		state.WriteQueue = me.db.WriteQueueStats()
+	}
+
+	return state
+}
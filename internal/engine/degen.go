@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// Degen index reference levels; liquidations and churn map to [0, 1] with
+// saturate, scoring 0.5 at these values
+var (
+	degenRefLiquidationNotional = decimal.NewFromInt(100000)
+	degenRefChurn               = decimal.NewFromInt(10)
+	degenHighLeverage           = 51 // Aggressive and degen tiers
+	maxOrderTimes               = 10000
+)
+
+// SetDegenIndexConfig sets the degen index window and component weights
+func (me *MatchingEngine) SetDegenIndexConfig(cfg *config.DegenIndexConfig) {
+	me.degenConfig = cfg
+}
+
+// recordOrderTime remembers when an order was placed for the churn component.
+// Caller must hold me.mu.
+func (me *MatchingEngine) recordOrderTime(t time.Time) {
+	me.orderTimes = append(me.orderTimes, t)
+	if len(me.orderTimes) > maxOrderTimes {
+		me.orderTimes = me.orderTimes[len(me.orderTimes)-maxOrderTimes:]
+	}
+}
+
+// GetDegenIndex combines leverage on newly opened size, liquidated notional
+// and order churn over the configured window into a 0-100 gauge. Leverage is
+// read from opening fills, where traders' chosen leverage is recorded.
+func (me *MatchingEngine) GetDegenIndex(instrument string) *domain.DegenIndex {
+	cfg := config.Default().DegenIndex
+	if me.degenConfig != nil {
+		cfg = *me.degenConfig
+	}
+	window := time.Duration(cfg.WindowMinutes) * time.Minute
+
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	now := time.Now()
+	cutoff := now.Add(-window)
+	idx := &domain.DegenIndex{
+		Instrument:    instrument,
+		Timestamp:     now,
+		WindowSeconds: int(window.Seconds()),
+	}
+
+	// recentTrades is newest first
+	openedSize, leveragedSize, highSize := decimal.Zero, decimal.Zero, decimal.Zero
+	for _, trade := range me.recentTrades {
+		if trade.Timestamp.Before(cutoff) {
+			break
+		}
+		if trade.Instrument != instrument {
+			continue
+		}
+		idx.Fills++
+		for _, side := range []struct {
+			effect   domain.PositionEffect
+			leverage int
+		}{
+			{trade.BuyerEffect, trade.BuyerLeverage},
+			{trade.SellerEffect, trade.SellerLeverage},
+		} {
+			if side.effect != domain.EffectOpen {
+				continue
+			}
+			openedSize = openedSize.Add(trade.Size)
+			leveragedSize = leveragedSize.Add(trade.Size.Mul(decimal.NewFromInt(int64(side.leverage))))
+			if side.leverage >= degenHighLeverage {
+				highSize = highSize.Add(trade.Size)
+			}
+		}
+	}
+	if avg, err := domain.SafeDiv(leveragedSize, openedSize); err == nil {
+		idx.AvgLeverage = avg.Round(2)
+	}
+	if share, err := domain.SafeDiv(highSize, openedSize); err == nil {
+		idx.HighLeverageShare = share.Round(4)
+	}
+
+	for _, liq := range me.liquidations {
+		if liq.Timestamp.Before(cutoff) {
+			break
+		}
+		if liq.Instrument == instrument {
+			idx.Liquidations++
+			idx.LiquidationNotional = idx.LiquidationNotional.Add(liq.Size.Abs().Mul(liq.MarkPrice))
+		}
+	}
+
+	// orderTimes is oldest first
+	for i := len(me.orderTimes) - 1; i >= 0 && !me.orderTimes[i].Before(cutoff); i-- {
+		idx.OrdersPlaced++
+	}
+	idx.ChurnRatio = decimal.NewFromInt(int64(idx.OrdersPlaced)).
+		Div(decimal.NewFromInt(int64(max(idx.Fills, 1)))).Round(2)
+
+	maxLeverage := 150
+	if me.instrumentConfig != nil && me.instrumentConfig.MaxLeverage > 1 {
+		maxLeverage = me.instrumentConfig.MaxLeverage
+	}
+	if idx.AvgLeverage.GreaterThan(decimal.NewFromInt(1)) {
+		score := idx.AvgLeverage.Sub(decimal.NewFromInt(1)).Div(decimal.NewFromInt(int64(maxLeverage - 1)))
+		idx.LeverageScore = decimal.Min(score, decimal.NewFromInt(1)).Round(4)
+	}
+	idx.HighLeverageScore = idx.HighLeverageShare
+	idx.LiquidationScore = saturate(idx.LiquidationNotional, degenRefLiquidationNotional).Round(4)
+	idx.ChurnScore = saturate(idx.ChurnRatio, degenRefChurn).Round(4)
+
+	totalWeight := cfg.LeverageWeight.Add(cfg.HighLeverageWeight).Add(cfg.LiquidationWeight).Add(cfg.ChurnWeight)
+	weighted := idx.LeverageScore.Mul(cfg.LeverageWeight).
+		Add(idx.HighLeverageScore.Mul(cfg.HighLeverageWeight)).
+		Add(idx.LiquidationScore.Mul(cfg.LiquidationWeight)).
+		Add(idx.ChurnScore.Mul(cfg.ChurnWeight))
+	if index, err := domain.SafeDiv(weighted.Mul(decimal.NewFromInt(100)), totalWeight); err == nil {
+		idx.Index = index.Round(2)
+	}
+
+	return idx
+}
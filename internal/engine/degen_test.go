@@ -0,0 +1,71 @@
+package engine_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+// degenScenario trades 5 at 1000 with both sides opening at leverage, after
+// quoting and pulling quotes orders, then records a liquidation of
+// liquidated at 1000 if not zero
+func degenScenario(t *testing.T, leverage, quotes int, liquidated string) *domain.DegenIndex {
+	t.Helper()
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+
+	for i := 0; i < quotes; i++ {
+		quote := h.MustLimit(maker, domain.SideBuy, "990", "0.1")
+		if err := h.Engine.CancelOrder(maker.ID, quote.ID, h.Instrument); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, o := range []*domain.Order{
+		{TraderID: maker.ID, Side: domain.SideSell, Type: domain.OrderTypeLimit, Price: dec("1000"), Size: dec("5"), Leverage: leverage},
+		{TraderID: taker.ID, Side: domain.SideBuy, Type: domain.OrderTypeMarket, Size: dec("5"), Leverage: leverage},
+	} {
+		if _, err := h.Submit(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if liquidated != "" {
+		h.Engine.AddLiquidation(&domain.Liquidation{
+			ID:         uuid.New(),
+			TraderID:   taker.ID,
+			Instrument: h.Instrument,
+			Side:       domain.SideBuy,
+			Size:       dec(liquidated),
+			MarkPrice:  dec("1000"),
+			Leverage:   leverage,
+			Timestamp:  time.Now(),
+		})
+	}
+	return h.Engine.GetDegenIndex(h.Instrument)
+}
+
+func TestDegenIndexHighVersusCalm(t *testing.T) {
+	degen := degenScenario(t, 100, 40, "300")
+	calm := degenScenario(t, 1, 0, "")
+
+	if !degen.AvgLeverage.Equal(dec("100")) || !degen.HighLeverageShare.Equal(dec("1")) ||
+		degen.Liquidations != 1 || !degen.LiquidationNotional.Equal(dec("300000")) {
+		t.Fatalf("degen components = %+v, want 100x, all high leverage, one liquidation of 300000", degen)
+	}
+	if degen.OrdersPlaced != 42 || degen.Fills != 1 || !degen.ChurnRatio.Equal(dec("42")) {
+		t.Fatalf("degen churn = %d orders over %d fills, want 42 over 1", degen.OrdersPlaced, degen.Fills)
+	}
+	if degen.Index.LessThan(dec("70")) {
+		t.Fatalf("degen index = %s, want at least 70: %+v", degen.Index, degen)
+	}
+
+	if !calm.LeverageScore.IsZero() || !calm.HighLeverageScore.IsZero() || !calm.LiquidationScore.IsZero() {
+		t.Fatalf("calm components = %+v, want no leverage or liquidation score", calm)
+	}
+	if calm.Index.GreaterThan(dec("10")) {
+		t.Fatalf("calm index = %s, want at most 10: %+v", calm.Index, calm)
+	}
+}
@@ -0,0 +1,68 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestZeroLeverageOrderTradesAtOneX(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+
+	h.MustLimit(maker, domain.SideSell, "1000", "1")
+	order := &domain.Order{TraderID: taker.ID, Side: domain.SideBuy, Type: domain.OrderTypeMarket, Size: dec("1")}
+	if _, err := h.Submit(order); err != nil {
+		t.Fatal(err)
+	}
+	if order.Leverage != 1 {
+		t.Fatalf("order leverage = %d, want 0 normalized to 1", order.Leverage)
+	}
+	pos := h.Position(taker)
+	if pos == nil || pos.Leverage != 1 || !pos.Margin.Equal(dec("1000")) {
+		t.Fatalf("position = %+v, want 1x with the full 1000 notional as margin", pos)
+	}
+	if pos.LiquidationPrice.IsNegative() || pos.LiquidationPrice.GreaterThanOrEqual(dec("1000")) {
+		t.Fatalf("liquidation price = %s, want below entry", pos.LiquidationPrice)
+	}
+}
+
+func TestEntryPriceDivisionPrecision(t *testing.T) {
+	saved := decimal.DivisionPrecision
+	t.Cleanup(func() { decimal.DivisionPrecision = saved })
+
+	// 1 at 1000 and 2 at 1000.01 average to 3000.02 / 3 = 1000.00666...
+	for _, tc := range []struct {
+		precision int
+		entry     string
+	}{
+		{0, "1000.0066666666666667"}, // Unset uses the default of 16
+		{6, "1000.006667"},
+		{5, "1000.00667"}, // The fewest a 0.01 tick and 0.001 lot allow
+	} {
+		cfg := config.Default()
+		cfg.Engine.DivisionPrecision = tc.precision
+		h := enginetest.NewTestEngineWithConfig(cfg)
+		maker := h.AddTrader("maker")
+		taker := h.AddTrader("taker")
+
+		want := tc.precision
+		if want == 0 {
+			want = config.DefaultDivisionPrecision
+		}
+		if decimal.DivisionPrecision != want {
+			t.Errorf("precision %d: decimal.DivisionPrecision = %d, want %d", tc.precision, decimal.DivisionPrecision, want)
+		}
+
+		h.MustLimit(maker, domain.SideSell, "1000", "1")
+		h.MustLimit(maker, domain.SideSell, "1000.01", "2")
+		h.MustMarket(taker, domain.SideBuy, "3")
+		if pos := h.Position(taker); pos == nil || pos.EntryPrice.String() != tc.entry {
+			t.Errorf("precision %d: entry = %v, want %s", tc.precision, pos, tc.entry)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// EffectiveInstrumentConfig is the live contract specification
+type EffectiveInstrumentConfig struct {
+	Symbol        string          `json:"symbol"`
+	StartingPrice decimal.Decimal `json:"starting_price"`
+	TickSize      decimal.Decimal `json:"tick_size"`
+	MinOrderSize  decimal.Decimal `json:"min_order_size"`
+	LotSize       decimal.Decimal `json:"lot_size"`
+	MaxLeverage   int             `json:"max_leverage"`
+}
+
+// EffectiveTraderLimits are the resolved limits for one trader type
+type EffectiveTraderLimits struct {
+	StartingBalance decimal.Decimal `json:"starting_balance"`
+	MaxLeverage     int             `json:"max_leverage"` // 0 = uncapped
+}
+
+// EffectiveRiskTier is one notional bracket of the tiered margin table
+type EffectiveRiskTier struct {
+	MaxNotional       decimal.Decimal `json:"max_notional"` // Zero = unbounded
+	MaxLeverage       int             `json:"max_leverage"`
+	MaintenanceMargin decimal.Decimal `json:"maintenance_margin"`
+}
+
+// EffectiveConfig is the configuration the engine is actually running with,
+// including runtime state an operator may have changed. Sections the engine
+// was never given are omitted.
+type EffectiveConfig struct {
+	Timestamp         time.Time          `json:"timestamp"`
+	MarketState       domain.MarketState `json:"market_state"`
+	MarketStateReason string             `json:"market_state_reason"`
+	AcceptingOrders   bool               `json:"accepting_orders"`
+	ShuttingDown      bool               `json:"shutting_down"`
+
+	Instrument *EffectiveInstrumentConfig `json:"instrument,omitempty"`
+
+	LiquidationCheckIntervalMs int                                         `json:"liquidation_check_interval_ms,omitempty"`
+	MaintenanceMargins         map[string]decimal.Decimal                  `json:"maintenance_margins,omitempty"` // Keyed by leverage band
+	RiskTiers                  []EffectiveRiskTier                         `json:"risk_tiers,omitempty"`
+	CancelOrdersOnLiquidation  bool                                        `json:"cancel_orders_on_liquidation"`
+	Fees                       map[string]decimal.Decimal                  `json:"fees,omitempty"`
+	MaxMatchLevels             int                                         `json:"max_match_levels"`
+	MaxMatchOrders             int                                         `json:"max_match_orders"`
+	MaxMarketSlippageBps       int                                         `json:"max_market_slippage_bps"`
+	MarkPriceMethod            string                                      `json:"mark_price_method"`
+	SelfTradePrevention        string                                      `json:"self_trade_prevention"`
+	SnapshotIntervalSeconds    int                                         `json:"snapshot_interval_seconds"`
+	PnLRefreshIntervalMs       int                                         `json:"pnl_refresh_interval_ms"`
+	MaxRecentTrades            int                                         `json:"max_recent_trades"`
+	MaxRecentLiquidations      int                                         `json:"max_recent_liquidations"`
+	DivisionPrecision          int                                         `json:"division_precision"`
+	TraderTypes                map[domain.TraderType]EffectiveTraderLimits `json:"trader_types"`
+	InsuranceFund              decimal.Decimal                             `json:"insurance_fund"`
+}
+
+// GetEffectiveConfig resolves the live configuration under a single lock acquisition
+func (me *MatchingEngine) GetEffectiveConfig() *EffectiveConfig {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	cfg := &EffectiveConfig{
+		Timestamp:         time.Now(),
+		MarketState:       me.marketState,
+		MarketStateReason: me.marketStateReason,
+		AcceptingOrders:   me.marketState.AcceptsOrders() && !me.shuttingDown,
+		ShuttingDown:      me.shuttingDown,
+		TraderTypes:       make(map[domain.TraderType]EffectiveTraderLimits),
+		InsuranceFund:     me.insuranceFundBalance(),
+	}
+
+	if ic := me.instrumentConfig; ic != nil {
+		cfg.Instrument = &EffectiveInstrumentConfig{
+			Symbol:        domain.RIndexSymbol,
+			StartingPrice: ic.StartingPrice,
+			TickSize:      ic.TickSize,
+			MinOrderSize:  ic.MinOrderSize,
+			LotSize:       ic.LotSize,
+			MaxLeverage:   ic.MaxLeverage,
+		}
+	}
+
+	if lc := me.liqConfig; lc != nil {
+		cfg.LiquidationCheckIntervalMs = lc.CheckIntervalMs
+		cfg.CancelOrdersOnLiquidation = lc.CancelOrdersOnLiquidation
+		cfg.MaintenanceMargins = map[string]decimal.Decimal{
+			"1-10x":    lc.MaintenanceMargins.Conservative,
+			"11-50x":   lc.MaintenanceMargins.Moderate,
+			"51-100x":  lc.MaintenanceMargins.Aggressive,
+			"101-150x": lc.MaintenanceMargins.Degen,
+		}
+		for _, tier := range lc.RiskTiers {
+			cfg.RiskTiers = append(cfg.RiskTiers, EffectiveRiskTier(tier))
+		}
+	}
+
+	if fc := me.feeConfig; fc != nil {
+		cfg.Fees = map[string]decimal.Decimal{
+			"maker_rate":       fc.MakerRate,
+			"taker_open_rate":  fc.TakerOpenRate,
+			"taker_close_rate": fc.TakerCloseRate,
+			"liquidation_rate": fc.LiquidationRate,
+
+			"insurance_fund_share":  fc.InsuranceFundShare,
+			"insurance_fund_target": fc.InsuranceFundTarget,
+		}
+	}
+
+	if ec := me.engineConfig; ec != nil {
+		cfg.MaxMatchLevels = ec.MaxMatchLevels
+		cfg.MaxMatchOrders = ec.MaxMatchOrders
+		cfg.MaxMarketSlippageBps = ec.MaxMarketSlippageBps
+		cfg.MarkPriceMethod = ec.MarkPriceMethod
+		cfg.SelfTradePrevention = string(me.selfTradeMode(&domain.Order{}))
+		cfg.SnapshotIntervalSeconds = ec.SnapshotIntervalSeconds
+		cfg.PnLRefreshIntervalMs = ec.PnLRefreshIntervalMs
+		cfg.MaxRecentTrades = me.maxRecentTrades()
+		cfg.MaxRecentLiquidations = me.maxRecentLiquidations()
+		cfg.DivisionPrecision = decimal.DivisionPrecision
+	}
+
+	for _, t := range []domain.TraderType{domain.TraderTypeHuman, domain.TraderTypeBot, domain.TraderTypeMarketMaker} {
+		cfg.TraderTypes[t] = EffectiveTraderLimits{
+			StartingBalance: me.StartingBalance(t),
+			MaxLeverage:     me.MaxLeverage(t),
+		}
+	}
+
+	return cfg
+}
@@ -0,0 +1,167 @@
+// Package enginetest provides an in-memory matching engine harness for tests.
+//
+// The harness has no database, registers R.index with the default config, and
+// offers terse helpers for registering traders, submitting orders and
+// inspecting book and position state:
+//
+//	h := enginetest.NewTestEngine()
+//	maker := h.AddTrader("maker")
+//	taker := h.AddTrader("taker")
+//	h.MustLimit(maker, domain.SideSell, "1000", "5")
+//	trades := h.MustMarket(taker, domain.SideBuy, "2")
+package enginetest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine"
+)
+
+// Harness wraps a MatchingEngine with helpers for tests
+type Harness struct {
+	Engine     *engine.MatchingEngine
+	Config     *config.Config
+	Instrument string
+}
+
+// NewTestEngine creates an engine with no persistence and R.index registered
+func NewTestEngine() *Harness {
+	cfg := config.Default()
+	return NewTestEngineWithConfig(cfg)
+}
+
+// NewTestEngineWithConfig creates an engine using the given configuration
+func NewTestEngineWithConfig(cfg *config.Config) *Harness {
+	eng := engine.NewMatchingEngine()
+	eng.SetLiquidationConfig(&cfg.Liquidation)
+	eng.SetInstrumentConfig(&cfg.RIndex)
+	eng.SetEngineConfig(&cfg.Engine)
+	eng.SetSLOConfig(&cfg.SLO)
+	eng.SetFeeConfig(&cfg.Fees)
+	eng.SetGameConfig(&cfg.Game)
+	eng.SetDegenIndexConfig(&cfg.DegenIndex)
+	eng.RegisterInstrument(domain.RIndexSymbol)
+
+	return &Harness{
+		Engine:     eng,
+		Config:     cfg,
+		Instrument: domain.RIndexSymbol,
+	}
+}
+
+// AddTrader registers a human trader with the configured starting balance
+func (h *Harness) AddTrader(username string) *domain.Trader {
+	return h.AddTraderOfType(username, domain.TraderTypeHuman)
+}
+
+// AddTraderOfType registers a trader of the given type with that type's starting balance
+func (h *Harness) AddTraderOfType(username string, traderType domain.TraderType) *domain.Trader {
+	trader := &domain.Trader{
+		ID:        uuid.New(),
+		Username:  username,
+		Type:      traderType,
+		Balance:   h.Engine.StartingBalance(traderType),
+		CreatedAt: time.Now(),
+	}
+	if err := h.Engine.RegisterTrader(trader); err != nil {
+		panic(fmt.Sprintf("enginetest: registering %s failed: %v", username, err))
+	}
+	return trader
+}
+
+// Limit submits a 1x limit order; price and size are decimal strings
+func (h *Harness) Limit(trader *domain.Trader, side domain.Side, price, size string) (*domain.Order, []*domain.Trade, error) {
+	order := &domain.Order{
+		TraderID:   trader.ID,
+		Instrument: h.Instrument,
+		Side:       side,
+		Type:       domain.OrderTypeLimit,
+		Price:      decimal.RequireFromString(price),
+		Size:       decimal.RequireFromString(size),
+		Leverage:   1,
+	}
+	trades, err := h.Engine.SubmitOrder(order)
+	return order, trades, err
+}
+
+// Market submits a 1x market order; size is a decimal string
+func (h *Harness) Market(trader *domain.Trader, side domain.Side, size string) (*domain.Order, []*domain.Trade, error) {
+	order := &domain.Order{
+		TraderID:   trader.ID,
+		Instrument: h.Instrument,
+		Side:       side,
+		Type:       domain.OrderTypeMarket,
+		Size:       decimal.RequireFromString(size),
+		Leverage:   1,
+	}
+	trades, err := h.Engine.SubmitOrder(order)
+	return order, trades, err
+}
+
+// Submit sends an arbitrary order, filling in the harness instrument if unset
+func (h *Harness) Submit(order *domain.Order) ([]*domain.Trade, error) {
+	if order.Instrument == "" {
+		order.Instrument = h.Instrument
+	}
+	return h.Engine.SubmitOrder(order)
+}
+
+// MustLimit is Limit that panics on error
+func (h *Harness) MustLimit(trader *domain.Trader, side domain.Side, price, size string) *domain.Order {
+	order, _, err := h.Limit(trader, side, price, size)
+	if err != nil {
+		panic(fmt.Sprintf("enginetest: limit order failed: %v", err))
+	}
+	return order
+}
+
+// MustMarket is Market that panics on error and returns the resulting trades
+func (h *Harness) MustMarket(trader *domain.Trader, side domain.Side, size string) []*domain.Trade {
+	_, trades, err := h.Market(trader, side, size)
+	if err != nil {
+		panic(fmt.Sprintf("enginetest: market order failed: %v", err))
+	}
+	return trades
+}
+
+// Book returns the full order book snapshot
+func (h *Harness) Book() *domain.OrderBook {
+	book, err := h.Engine.GetOrderBook(h.Instrument, 10000)
+	if err != nil {
+		panic(fmt.Sprintf("enginetest: %v", err))
+	}
+	return book
+}
+
+// Bids returns all bid levels, best first
+func (h *Harness) Bids() []domain.OrderBookLevel {
+	return h.Book().Bids
+}
+
+// Asks returns all ask levels, best first
+func (h *Harness) Asks() []domain.OrderBookLevel {
+	return h.Book().Asks
+}
+
+// Position returns the trader's position, or nil if flat
+func (h *Harness) Position(trader *domain.Trader) *domain.Position {
+	return h.Engine.GetPosition(trader.ID, h.Instrument)
+}
+
+// PositionSize returns the trader's signed position size (zero if flat)
+func (h *Harness) PositionSize(trader *domain.Trader) decimal.Decimal {
+	if pos := h.Position(trader); pos != nil {
+		return pos.Size
+	}
+	return decimal.Zero
+}
+
+// Trader returns the engine's current view of a trader
+func (h *Harness) Trader(trader *domain.Trader) *domain.Trader {
+	return h.Engine.GetTrader(trader.ID)
+}
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// Event topics published to an EventSink
+const (
+	TopicTrades       = "trades"
+	TopicOrders       = "orders"
+	TopicPositions    = "positions"
+	TopicLiquidations = "liquidations"
+	TopicAlerts       = "alerts"
+)
+
+// EventSink receives engine events for an external message queue or data
+// pipeline. Payloads are JSON, encoded when the event happens.
+type EventSink interface {
+	Publish(topic string, payload []byte) error
+}
+
+// SetEventSink publishes every trade, order update, position change,
+// liquidation and SLO alert to sink. Events are published from inside the
+// match path, so the sink must not block (see events.Async). Call before the
+// engine starts.
+func (me *MatchingEngine) SetEventSink(sink EventSink) {
+	if sink == nil {
+		return
+	}
+	me.OnTrade(func(trade *domain.Trade) {
+		publishEvent(sink, TopicTrades, trade)
+	})
+	me.OnOrderUpdate(func(order *domain.Order) {
+		publishEvent(sink, TopicOrders, order)
+	})
+	me.OnPositionUpdate(func(pos *domain.Position) {
+		publishEvent(sink, TopicPositions, pos)
+	})
+	me.OnLiquidation(func(liq *domain.Liquidation) {
+		publishEvent(sink, TopicLiquidations, liq)
+	})
+	me.OnSLOBreach(func(alert *domain.SLOAlert) {
+		publishEvent(sink, TopicAlerts, alert)
+	})
+}
+
+// publishEvent encodes v now, while the engine lock still guards it, so a
+// sink that publishes later sees the state at the time of the event
+func publishEvent(sink EventSink, topic string, v interface{}) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("Error encoding event", "topic", topic, "error", err)
+		return
+	}
+	if err := sink.Publish(topic, payload); err != nil {
+		slog.Error("Error publishing event", "topic", topic, "error", err)
+	}
+}
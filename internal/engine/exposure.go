@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// GetTraderExposure sums a trader's notional, margin and unrealized P&L over
+// all of their open positions, marking each at its instrument's last trade
+// price (the entry price if it has never traded). Returns nil for an unknown
+// trader.
+func (me *MatchingEngine) GetTraderExposure(traderID uuid.UUID) *domain.TraderExposure {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	if _, exists := me.traders[traderID]; !exists {
+		return nil
+	}
+
+	exp := &domain.TraderExposure{
+		TraderID:  traderID,
+		Timestamp: time.Now(),
+		Positions: []domain.InstrumentExposure{},
+	}
+	for _, pos := range me.positions {
+		if pos.TraderID != traderID || pos.Size.IsZero() {
+			continue
+		}
+
+		mark, ok := me.lastTradePrice(pos.Instrument)
+		if !ok {
+			mark = pos.EntryPrice
+		}
+		ie := domain.InstrumentExposure{
+			Instrument:    pos.Instrument,
+			Size:          pos.Size,
+			MarkPrice:     mark,
+			Notional:      pos.Size.Abs().Mul(mark),
+			Margin:        pos.Margin,
+			UnrealizedPnL: mark.Sub(pos.EntryPrice).Mul(pos.Size),
+		}
+
+		if pos.IsLong() {
+			exp.LongNotional = exp.LongNotional.Add(ie.Notional)
+		} else {
+			exp.ShortNotional = exp.ShortNotional.Add(ie.Notional)
+		}
+		exp.MarginUsed = exp.MarginUsed.Add(ie.Margin)
+		exp.UnrealizedPnL = exp.UnrealizedPnL.Add(ie.UnrealizedPnL)
+		exp.Positions = append(exp.Positions, ie)
+	}
+	exp.NetNotional = exp.LongNotional.Sub(exp.ShortNotional)
+
+	sort.Slice(exp.Positions, func(i, j int) bool {
+		return exp.Positions[i].Instrument < exp.Positions[j].Instrument
+	})
+	return exp
+}
@@ -0,0 +1,71 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestTraderExposureAcrossInstruments(t *testing.T) {
+	const alt = "R.alt"
+	h := enginetest.NewTestEngine()
+	h.Engine.RegisterInstrument(alt)
+	trader := h.AddTrader("trader")
+	maker := h.AddTrader("maker")
+	other := h.AddTrader("other")
+
+	submit := func(who *domain.Trader, instrument string, side domain.Side, price, size string, leverage int) {
+		t.Helper()
+		o := &domain.Order{TraderID: who.ID, Instrument: instrument, Side: side, Type: domain.OrderTypeMarket, Size: dec(size), Leverage: leverage}
+		if price != "" {
+			o.Type, o.Price = domain.OrderTypeLimit, dec(price)
+		}
+		if _, err := h.Submit(o); err != nil {
+			t.Fatalf("%s %s %s %s@%s: %v", who.Username, instrument, side, size, price, err)
+		}
+	}
+
+	// Long 2 of R.index at 1000 on 2x, marked up to 1010
+	submit(maker, h.Instrument, domain.SideSell, "1000", "2", 1)
+	submit(trader, h.Instrument, domain.SideBuy, "", "2", 2)
+	submit(maker, h.Instrument, domain.SideSell, "1010", "0.1", 1)
+	submit(other, h.Instrument, domain.SideBuy, "", "0.1", 1)
+	// Short 1 of R.alt at 1000 on 1x, marked down to 990
+	submit(maker, alt, domain.SideBuy, "1000", "1", 1)
+	submit(trader, alt, domain.SideSell, "", "1", 1)
+	submit(maker, alt, domain.SideSell, "990", "0.1", 1)
+	submit(other, alt, domain.SideBuy, "", "0.1", 1)
+
+	exp := h.Engine.GetTraderExposure(trader.ID)
+	if exp == nil || len(exp.Positions) != 2 {
+		t.Fatalf("exposure = %+v, want two positions", exp)
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want string
+	}{
+		{"long notional", exp.LongNotional.String(), "2020"},
+		{"short notional", exp.ShortNotional.String(), "990"},
+		{"net notional", exp.NetNotional.String(), "1030"},
+		{"margin used", exp.MarginUsed.String(), "2000"},
+		{"unrealized pnl", exp.UnrealizedPnL.String(), "30"}, // 20 on the long, 10 on the short
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %s, want %s", tc.name, tc.got, tc.want)
+		}
+	}
+
+	altPos, index := exp.Positions[0], exp.Positions[1]
+	if altPos.Instrument != alt || !altPos.Size.Equal(dec("-1")) || !altPos.MarkPrice.Equal(dec("990")) || !altPos.UnrealizedPnL.Equal(dec("10")) {
+		t.Errorf("%s exposure = %+v, want short 1 marked at 990", alt, altPos)
+	}
+	if index.Instrument != h.Instrument || !index.Size.Equal(dec("2")) || !index.Notional.Equal(dec("2020")) || !index.Margin.Equal(dec("1000")) {
+		t.Errorf("%s exposure = %+v, want long 2 worth 2020 on 1000 margin", h.Instrument, index)
+	}
+
+	if exp := h.Engine.GetTraderExposure(uuid.New()); exp != nil {
+		t.Fatalf("exposure of an unknown trader = %+v, want nil", exp)
+	}
+}
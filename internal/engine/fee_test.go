@@ -0,0 +1,49 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestTakerFeeDependsOnPositionEffect(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+	opener := h.AddTrader("opener")
+	closer := h.AddTrader("closer")
+
+	// The closer starts short 1, bought back below
+	h.MustLimit(maker, domain.SideBuy, "1000", "1")
+	h.MustMarket(closer, domain.SideSell, "1")
+	h.MustLimit(maker, domain.SideSell, "1000", "2")
+
+	// Both buy 1 at 1000 as takers; only the effect differs
+	opening := h.MustMarket(opener, domain.SideBuy, "1")
+	closing := h.MustMarket(closer, domain.SideBuy, "1")
+	if len(opening) != 1 || len(closing) != 1 {
+		t.Fatalf("trades = %d and %d, want one each", len(opening), len(closing))
+	}
+
+	fees := h.Config.Fees
+	for _, tc := range []struct {
+		name   string
+		trade  *domain.Trade
+		effect domain.PositionEffect
+		rate   string
+		fee    string
+	}{
+		{"opening", opening[0], domain.EffectOpen, fees.TakerOpenRate.String(), "0.6"},
+		{"closing", closing[0], domain.EffectClose, fees.TakerCloseRate.String(), "0.4"},
+	} {
+		if tc.trade.BuyerEffect != tc.effect {
+			t.Fatalf("%s buy effect = %s, want %s", tc.name, tc.trade.BuyerEffect, tc.effect)
+		}
+		if !tc.trade.BuyerFeeRate.Equal(dec(tc.rate)) || !tc.trade.BuyerFee.Equal(dec(tc.fee)) {
+			t.Errorf("%s buy charged %s at %s, want %s at %s", tc.name, tc.trade.BuyerFee, tc.trade.BuyerFeeRate, tc.fee, tc.rate)
+		}
+		if !tc.trade.SellerFeeRate.Equal(fees.MakerRate) {
+			t.Errorf("%s trade maker rate = %s, want %s", tc.name, tc.trade.SellerFeeRate, fees.MakerRate)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package engine_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/thatreguy/trade.re/internal/db"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestFlattenClearsOrdersAndPositions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flatten.db")
+	database, err := db.NewSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	h := enginetest.NewTestEngine()
+	h.Engine.SetDatabase(database)
+
+	trader := h.AddTrader("trader")
+	other := h.AddTrader("other")
+
+	// Long 1 from 1000, plus a resting bid and ask of the trader's own
+	h.MustLimit(other, domain.SideSell, "1000", "1")
+	h.MustMarket(trader, domain.SideBuy, "1")
+	h.MustLimit(trader, domain.SideBuy, "990", "1")
+	h.MustLimit(trader, domain.SideSell, "1020", "1")
+	h.MustLimit(other, domain.SideBuy, "1010", "1")
+
+	result, err := h.Engine.FlattenTrader(trader.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.CancelledOrders) != 2 {
+		t.Fatalf("cancelled %d orders, want both resting orders", len(result.CancelledOrders))
+	}
+	if len(result.Trades) != 1 || !result.Trades[0].Price.Equal(dec("1010")) {
+		t.Fatalf("trades = %+v, want the close at 1010", result.Trades)
+	}
+	if !result.RealizedPnL.Equal(dec("10")) || len(result.RemainingPositions) != 0 {
+		t.Fatalf("realized %s with %d positions left, want 10 and none", result.RealizedPnL, len(result.RemainingPositions))
+	}
+
+	if open := h.Engine.GetOpenOrders(trader.ID, h.Instrument); len(open) != 0 {
+		t.Fatalf("%d orders still open", len(open))
+	}
+	if size := h.PositionSize(trader); !size.IsZero() {
+		t.Fatalf("position = %s, want flat", size)
+	}
+	if bids, asks := h.Bids(), h.Asks(); len(bids) != 0 || len(asks) != 0 {
+		t.Fatalf("book = %+v / %+v, want empty", bids, asks)
+	}
+
+	conn, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var details string
+	row := conn.QueryRow(`SELECT details FROM audit_log WHERE trader_id = ? AND action = ?`, trader.ID.String(), engine.AuditActionFlatten)
+	if err := row.Scan(&details); err != nil {
+		t.Fatalf("reading the audit log: %v", err)
+	}
+	if want := "cancelled_orders=2 trades=1 realized_pnl=10 remaining_positions=0"; details != want {
+		t.Fatalf("audit details = %q, want %q", details, want)
+	}
+}
+
+func TestFlattenReportsWhatItCouldNotClose(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	trader := h.AddTrader("trader")
+	other := h.AddTrader("other")
+
+	h.MustLimit(other, domain.SideSell, "1000", "2")
+	h.MustMarket(trader, domain.SideBuy, "2")
+	h.MustLimit(other, domain.SideBuy, "1000", "0.5")
+
+	result, err := h.Engine.FlattenTrader(trader.ID)
+	if err == nil {
+		t.Fatal("flatten against a thin book reported success")
+	}
+	if len(result.RemainingPositions) != 1 || !result.RemainingPositions[0].Size.Equal(dec("1.5")) {
+		t.Fatalf("remaining = %+v, want 1.5 left open", result.RemainingPositions)
+	}
+}
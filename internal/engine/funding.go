@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// FundingSource reports the predicted funding rate, next settlement time and
+// the freeze before it, if one is configured
+type FundingSource interface {
+	GetCurrentRate(instrument string) decimal.Decimal
+	NextFundingTime() time.Time
+	NextFundingFreeze() *domain.FundingFreeze
+}
+
+// SetFundingSource sets where market stats read the funding rate from.
+// Without one, funding_rate stays zero.
+func (me *MatchingEngine) SetFundingSource(src FundingSource) {
+	me.fundingSource = src
+}
+
+// BeginFundingFreeze moves an open market into the pre-settlement freeze, in
+// which only orders reducing a position are accepted. A market an operator
+// has halted or otherwise moved out of open is left alone.
+func (me *MatchingEngine) BeginFundingFreeze(reason string) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	if me.marketState == domain.MarketStateOpen {
+		me.setMarketStateLocked(domain.MarketStateFundingFreeze, reason)
+	}
+}
+
+// EndFundingFreeze reopens a market frozen for funding. Any other state is
+// left alone, so an operator's halt during the freeze still holds.
+func (me *MatchingEngine) EndFundingFreeze(reason string) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	if me.marketState == domain.MarketStateFundingFreeze {
+		me.setMarketStateLocked(domain.MarketStateOpen, reason)
+	}
+}
+
+// checkFundingFreezeLocked rejects an order that would open or add to a
+// position while the market is frozen for funding. Orders that only reduce a
+// position, reduce-only or not, are still accepted; resting orders are left
+// on the book. Caller must hold me.mu.
+func (me *MatchingEngine) checkFundingFreezeLocked(order *domain.Order) error {
+	if me.marketState != domain.MarketStateFundingFreeze {
+		return nil
+	}
+	if current, projected := me.projectedPositionLocked(order); openingSize(current, projected).IsPositive() {
+		return fmt.Errorf("market is %s: only orders reducing a position are accepted until funding settles", me.marketState)
+	}
+	return nil
+}
+
+// GetTWAP returns the time-weighted average trade price over the window
+// ending now. The price in effect when the window opened counts from its
+// start. Returns false if the instrument has never traded.
+func (me *MatchingEngine) GetTWAP(instrument string, window time.Duration) (decimal.Decimal, bool) {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	now := time.Now()
+	cutoff := now.Add(-window)
+
+	// recentTrades is newest first, so walk back holding each price until the
+	// next (newer) trade
+	weighted, covered := decimal.Zero, time.Duration(0)
+	until := now
+	traded := false
+	for _, t := range me.recentTrades {
+		if t.Instrument != instrument {
+			continue
+		}
+		traded = true
+		from := t.Timestamp
+		if from.Before(cutoff) {
+			from = cutoff
+		}
+		if held := until.Sub(from); held > 0 {
+			weighted = weighted.Add(t.Price.Mul(decimal.NewFromInt(int64(held))))
+			covered += held
+		}
+		if !t.Timestamp.After(cutoff) {
+			break
+		}
+		until = t.Timestamp
+	}
+	if !traded {
+		return decimal.Zero, false
+	}
+	if covered == 0 {
+		price, _ := me.lastTradePrice(instrument)
+		return price, true
+	}
+	return weighted.Div(decimal.NewFromInt(int64(covered))), true
+}
+
+// ApplyFunding charges every open position on an instrument size * mark *
+// rate: longs pay and shorts receive when the rate is positive, and the other
+// way round when it is negative. Payments go through the trader's balance and
+// the position's realized P&L (implements funding.PositionStore).
+func (me *MatchingEngine) ApplyFunding(instrument string, rate, markPrice decimal.Decimal) *domain.FundingSettlement {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	settlement := &domain.FundingSettlement{
+		Instrument: instrument,
+		Timestamp:  time.Now(),
+		Rate:       rate,
+		MarkPrice:  markPrice,
+	}
+
+	for _, pos := range me.positions {
+		if pos.Instrument != instrument || pos.Size.IsZero() {
+			continue
+		}
+
+		// Signed so a long pays a positive rate and a short receives it
+		payment := pos.Size.Mul(markPrice).Mul(rate)
+		pos.RealizedPnL = pos.RealizedPnL.Sub(payment)
+		pos.UpdatedAt = settlement.Timestamp
+		if payment.IsPositive() {
+			settlement.TotalPaid = settlement.TotalPaid.Add(payment)
+		}
+		settlement.Positions++
+
+		if trader, ok := me.traders[pos.TraderID]; ok {
+			trader.Balance = trader.Balance.Sub(payment)
+			trader.TotalPnL = trader.TotalPnL.Sub(payment)
+			if me.db != nil {
+				if err := me.db.SaveTrader(trader); err != nil {
+					slog.Error("Error saving trader after funding", "error", err)
+				}
+			}
+		}
+		me.refreshCrossLiquidationPricesLocked(pos.TraderID)
+		if me.db != nil {
+			if err := me.db.SavePosition(pos); err != nil {
+				slog.Error("Error saving position after funding", "error", err)
+			}
+		}
+		for _, handler := range me.positionHandlers {
+			handler(pos)
+		}
+	}
+
+	return settlement
+}
@@ -0,0 +1,35 @@
+package engine
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// IndexSource supplies the reference index an instrument's trading is
+// measured against
+type IndexSource interface {
+	CurrentIndex(instrument string) decimal.Decimal
+}
+
+// SetIndexSource sets where index prices come from. Without one, the index
+// price is the mark price and funding is anchored to the trade TWAP.
+func (me *MatchingEngine) SetIndexSource(src IndexSource) {
+	me.indexSource = src
+}
+
+// GetIndexPrice returns an instrument's index price from the index source,
+// or false if there is none (implements funding.PriceProvider)
+func (me *MatchingEngine) GetIndexPrice(instrument string) (decimal.Decimal, bool) {
+	if me.indexSource == nil {
+		return decimal.Zero, false
+	}
+	return me.indexSource.CurrentIndex(instrument), true
+}
+
+// indexPriceLocked returns an instrument's index price, the mark price
+// without an index source. Caller must hold me.mu.
+func (me *MatchingEngine) indexPriceLocked(instrument string) decimal.Decimal {
+	if price, ok := me.GetIndexPrice(instrument); ok && price.IsPositive() {
+		return price
+	}
+	return me.markPriceLocked(instrument)
+}
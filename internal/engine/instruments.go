@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// Instruments returns the registered instrument symbols in registration order
+// (implements liquidation.PositionStore)
+func (me *MatchingEngine) Instruments() []string {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+	return append([]string(nil), me.instruments...)
+}
+
+// HasInstrument reports whether an instrument has been registered
+func (me *MatchingEngine) HasInstrument(instrument string) bool {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+	_, exists := me.books[instrument]
+	return exists
+}
+
+// GetInstruments lists the registered instruments with their contract
+// specification and current prices. Every instrument shares the one
+// instrument config the engine was given.
+func (me *MatchingEngine) GetInstruments() []*domain.Instrument {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	instruments := make([]*domain.Instrument, 0, len(me.instruments))
+	for _, symbol := range me.instruments {
+		info := &domain.Instrument{
+			Symbol:      symbol,
+			MarkPrice:   me.markPriceLocked(symbol),
+			MarketState: me.marketState,
+		}
+		if last, ok := me.lastTradePrice(symbol); ok {
+			info.LastPrice = last
+		} else {
+			info.LastPrice = me.startingPrice()
+		}
+		if ic := me.instrumentConfig; ic != nil {
+			info.TickSize = ic.TickSize
+			info.LotSize = ic.LotSize
+			info.MinOrderSize = ic.MinOrderSize
+			info.MaxLeverage = ic.MaxLeverage
+		}
+		instruments = append(instruments, info)
+	}
+	return instruments
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// RecordInsuranceFundEvent persists an insurance fund balance change for the
+// fund history
+func (me *MatchingEngine) RecordInsuranceFundEvent(event *domain.InsuranceFundEvent) {
+	if me.db == nil {
+		return
+	}
+	if err := me.db.SaveInsuranceFundEvent(event); err != nil {
+		slog.Error("Error saving insurance fund event to database", "error", err)
+	}
+}
+
+// GetInsuranceFundHistory returns insurance fund balance changes within a time
+// range, oldest first
+func (me *MatchingEngine) GetInsuranceFundHistory(start, end time.Time) ([]*domain.InsuranceFundEvent, error) {
+	if me.db == nil {
+		return []*domain.InsuranceFundEvent{}, nil
+	}
+
+	events, err := me.db.GetInsuranceFundHistory(start, end)
+	if err != nil {
+		return nil, fmt.Errorf("loading insurance fund history: %w", err)
+	}
+	return events, nil
+}
+
+// recentInsuranceFundEvents is how many of the latest fund changes the status lists
+const recentInsuranceFundEvents = 20
+
+// LastInsuranceFundBalance returns the fund balance after the most recent
+// recorded change, or false if none has been recorded
+func (me *MatchingEngine) LastInsuranceFundBalance() (decimal.Decimal, bool, error) {
+	if me.db == nil {
+		return decimal.Zero, false, nil
+	}
+	events, err := me.db.GetRecentInsuranceFundEvents(1)
+	if err != nil {
+		return decimal.Zero, false, fmt.Errorf("loading insurance fund balance: %w", err)
+	}
+	if len(events) == 0 {
+		return decimal.Zero, false, nil
+	}
+	return events[0].Balance, true, nil
+}
+
+// GetInsuranceFundStatus returns the fund balance, how fees are replenishing
+// it and, with a database, its recent inflows and outflows
+func (me *MatchingEngine) GetInsuranceFundStatus() *domain.InsuranceFundStatus {
+	me.mu.RLock()
+	status := &domain.InsuranceFundStatus{
+		Timestamp:  time.Now(),
+		Balance:    me.insuranceFundBalance(),
+		FeeShare:   decimal.NewFromInt(1),
+		FeeRevenue: me.feeRevenue,
+		Recent:     []*domain.InsuranceFundEvent{},
+	}
+	if fc := me.feeConfig; fc != nil {
+		status.Target = fc.InsuranceFundTarget
+		status.FeeShare = fc.InsuranceFundShare
+	}
+	status.Diverting = me.insuranceFund != nil && status.FeeShare.IsPositive() &&
+		(!status.Target.IsPositive() || status.Balance.LessThan(status.Target))
+	database := me.db
+	me.mu.RUnlock()
+
+	if database == nil {
+		return status
+	}
+	day, err := database.GetInsuranceFundHistory(status.Timestamp.Add(-24*time.Hour), status.Timestamp)
+	if err != nil {
+		slog.Error("Error loading insurance fund history", "error", err)
+	}
+	for _, event := range day {
+		if event.Delta.IsPositive() {
+			status.Inflow24h = status.Inflow24h.Add(event.Delta)
+		} else {
+			status.Outflow24h = status.Outflow24h.Sub(event.Delta)
+		}
+	}
+	recent, err := database.GetRecentInsuranceFundEvents(recentInsuranceFundEvents)
+	if err != nil {
+		slog.Error("Error loading recent insurance fund events", "error", err)
+	} else {
+		status.Recent = recent
+	}
+	return status
+}
+
+// AdjustInsuranceFund applies an operator top-up (positive delta) or
+// withdrawal (negative delta) to the insurance fund
+func (me *MatchingEngine) AdjustInsuranceFund(delta decimal.Decimal, note string) (*domain.InsuranceFundEvent, error) {
+	if me.insuranceFund == nil {
+		return nil, fmt.Errorf("no insurance fund configured")
+	}
+	if delta.IsZero() {
+		return nil, fmt.Errorf("delta must be non-zero")
+	}
+
+	event := &domain.InsuranceFundEvent{
+		Cause: domain.InsuranceFundAdminAdjustment,
+		Delta: delta,
+		Note:  note,
+	}
+	if err := me.insuranceFund.ApplyInsuranceFundChange(event); err != nil {
+		return nil, err
+	}
+	slog.Info("Insurance fund adjusted", "delta", delta, "note", note, "balance", event.Balance)
+	return event, nil
+}
@@ -0,0 +1,215 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// GetLeaderboard ranks every trader on an all-time metric, best first: P&L,
+// ROI (P&L over the trader type's starting balance), volume (trade count) or
+// the highest leverage used. Ties go to the older account.
+func (me *MatchingEngine) GetLeaderboard(by domain.LeaderboardSort, limit int) ([]*domain.LeaderboardEntry, error) {
+	if !by.IsValid() {
+		return nil, fmt.Errorf("unknown leaderboard sort: %s", by)
+	}
+
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	board := make([]*domain.LeaderboardEntry, 0, len(me.traders))
+	created := make(map[uuid.UUID]time.Time, len(me.traders))
+	for _, trader := range me.traders {
+		entry := &domain.LeaderboardEntry{
+			TraderID:        trader.ID,
+			Username:        trader.Username,
+			Type:            trader.Type,
+			TotalPnL:        trader.TotalPnL,
+			TradeCount:      trader.TradeCount,
+			MaxLeverageUsed: trader.MaxLeverageUsed,
+		}
+		if roi, err := domain.SafeDiv(trader.TotalPnL, me.StartingBalance(trader.Type)); err == nil {
+			entry.ROI = roi
+		}
+		switch by {
+		case domain.LeaderboardSortPnL:
+			entry.Metric = entry.TotalPnL
+		case domain.LeaderboardSortROI:
+			entry.Metric = entry.ROI
+		case domain.LeaderboardSortVolume:
+			entry.Metric = decimal.NewFromInt(entry.TradeCount)
+		case domain.LeaderboardSortMaxLeverage:
+			entry.Metric = decimal.NewFromInt(int64(entry.MaxLeverageUsed))
+		}
+		created[trader.ID] = trader.CreatedAt
+		board = append(board, entry)
+	}
+	sort.Slice(board, func(i, j int) bool {
+		if !board[i].Metric.Equal(board[j].Metric) {
+			return board[i].Metric.GreaterThan(board[j].Metric)
+		}
+		ci, cj := created[board[i].TraderID], created[board[j].TraderID]
+		if !ci.Equal(cj) {
+			return ci.Before(cj)
+		}
+		return board[i].TraderID.String() < board[j].TraderID.String()
+	})
+
+	if limit > 0 && len(board) > limit {
+		board = board[:limit]
+	}
+	for i, entry := range board {
+		entry.Rank = i + 1
+	}
+	return board, nil
+}
+
+// GetPnLLeaderboard ranks traders by the P&L they realized within a time
+// window, from closing trades and liquidations, best first. Unlike the
+// all-time TotalPnL it ignores anything realized outside the window.
+func (me *MatchingEngine) GetPnLLeaderboard(start, end time.Time, limit int) ([]*domain.PnLLeaderboardEntry, error) {
+	var entries map[uuid.UUID]*domain.PnLLeaderboardEntry
+	if me.db != nil {
+		var err error
+		entries, err = me.db.GetRealizedPnLByTrader(start, end)
+		if err != nil {
+			return nil, fmt.Errorf("loading realized P&L: %w", err)
+		}
+	}
+
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	if entries == nil {
+		entries = me.realizedPnLFromMemoryLocked(start, end)
+	}
+
+	board := make([]*domain.PnLLeaderboardEntry, 0, len(entries))
+	for id, entry := range entries {
+		if trader, ok := me.traders[id]; ok {
+			entry.Username = trader.Username
+			entry.Type = trader.Type
+		}
+		board = append(board, entry)
+	}
+	sort.Slice(board, func(i, j int) bool {
+		if !board[i].RealizedPnL.Equal(board[j].RealizedPnL) {
+			return board[i].RealizedPnL.GreaterThan(board[j].RealizedPnL)
+		}
+		return board[i].TraderID.String() < board[j].TraderID.String()
+	})
+
+	if limit > 0 && len(board) > limit {
+		board = board[:limit]
+	}
+	for i, entry := range board {
+		entry.Rank = i + 1
+	}
+	return board, nil
+}
+
+// realizedPnLFromMemoryLocked sums realized P&L from the in-memory trade and
+// liquidation history. Caller must hold me.mu.
+func (me *MatchingEngine) realizedPnLFromMemoryLocked(start, end time.Time) map[uuid.UUID]*domain.PnLLeaderboardEntry {
+	entries := make(map[uuid.UUID]*domain.PnLLeaderboardEntry)
+	entry := func(id uuid.UUID) *domain.PnLLeaderboardEntry {
+		e, ok := entries[id]
+		if !ok {
+			e = &domain.PnLLeaderboardEntry{TraderID: id}
+			entries[id] = e
+		}
+		return e
+	}
+	inWindow := func(t time.Time) bool {
+		return !t.Before(start) && !t.After(end)
+	}
+
+	for _, t := range me.recentTrades {
+		if !inWindow(t.Timestamp) {
+			continue
+		}
+		if !t.BuyerRealizedPnL.IsZero() {
+			e := entry(t.BuyerID)
+			e.RealizedPnL = e.RealizedPnL.Add(t.BuyerRealizedPnL)
+			e.ClosingTrades++
+		}
+		if !t.SellerRealizedPnL.IsZero() {
+			e := entry(t.SellerID)
+			e.RealizedPnL = e.RealizedPnL.Add(t.SellerRealizedPnL)
+			e.ClosingTrades++
+		}
+	}
+
+	for _, liq := range me.liquidations {
+		if !inWindow(liq.Timestamp) {
+			continue
+		}
+		e := entry(liq.TraderID)
+		e.RealizedPnL = e.RealizedPnL.Sub(liq.Loss)
+		if liq.Effect != domain.EffectADL {
+			e.Liquidations++
+		}
+	}
+
+	return entries
+}
+
+// GetLargestLiquidations ranks an instrument's liquidations by loss or by
+// notional, largest first - the hall of shame
+func (me *MatchingEngine) GetLargestLiquidations(instrument string, by domain.LiquidationRanking, limit int) ([]*domain.LargestLiquidation, error) {
+	if !by.IsValid() {
+		return nil, fmt.Errorf("unknown ranking: %s", by)
+	}
+
+	var liqs []*domain.Liquidation
+	if me.db != nil {
+		var err error
+		liqs, err = me.db.GetLargestLiquidations(instrument, by, limit)
+		if err != nil {
+			return nil, fmt.Errorf("loading liquidations: %w", err)
+		}
+	}
+
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	if me.db == nil {
+		for _, l := range me.liquidations {
+			if l.Instrument == instrument {
+				liqs = append(liqs, l)
+			}
+		}
+		sort.SliceStable(liqs, func(i, j int) bool {
+			return liquidationMeasure(liqs[i], by).GreaterThan(liquidationMeasure(liqs[j], by))
+		})
+		if limit > 0 && len(liqs) > limit {
+			liqs = liqs[:limit]
+		}
+	}
+
+	ranked := make([]*domain.LargestLiquidation, len(liqs))
+	for i, l := range liqs {
+		entry := &domain.LargestLiquidation{
+			Rank:        i + 1,
+			Notional:    l.Size.Mul(l.MarkPrice),
+			Liquidation: *l,
+		}
+		if trader, ok := me.traders[l.TraderID]; ok {
+			entry.Username = trader.Username
+		}
+		ranked[i] = entry
+	}
+	return ranked, nil
+}
+
+// liquidationMeasure is the amount a liquidation is ranked on
+func liquidationMeasure(l *domain.Liquidation, by domain.LiquidationRanking) decimal.Decimal {
+	if by == domain.LiquidationRankingNotional {
+		return l.Size.Mul(l.MarkPrice)
+	}
+	return l.Loss
+}
@@ -0,0 +1,149 @@
+package engine_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thatreguy/trade.re/internal/db"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestPnLLeaderboardRanksOnlyTheWindow(t *testing.T) {
+	for _, persisted := range []bool{false, true} {
+		name := "memory"
+		if persisted {
+			name = "database"
+		}
+		t.Run(name, func(t *testing.T) {
+			h := enginetest.NewTestEngine()
+			if persisted {
+				database, err := db.NewSQLite(filepath.Join(t.TempDir(), "leaderboard.db"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer database.Close()
+				h.Engine.SetDatabase(database)
+			}
+			a := h.AddTrader("a")
+			b := h.AddTrader("b")
+
+			// roundTrip has long open 1 from short at 1000 and close at exit
+			roundTrip := func(long, short *domain.Trader, exit string) {
+				h.MustLimit(short, domain.SideSell, "1000", "1")
+				h.MustMarket(long, domain.SideBuy, "1")
+				h.MustLimit(short, domain.SideBuy, exit, "1")
+				h.MustMarket(long, domain.SideSell, "1")
+			}
+			tick := func() time.Time {
+				time.Sleep(5 * time.Millisecond)
+				now := time.Now()
+				time.Sleep(5 * time.Millisecond)
+				return now
+			}
+
+			start := tick()
+			roundTrip(a, b, "1010") // a +10, b -10
+			boundary := tick()
+			roundTrip(b, a, "1030") // b +30, a -30
+			end := tick()
+
+			for _, tc := range []struct {
+				name       string
+				start, end time.Time
+				first      *domain.Trader
+				pnl        []string
+			}{
+				{"first window", start, boundary, a, []string{"10", "-10"}},
+				{"second window", boundary, end, b, []string{"30", "-30"}},
+				{"both", start, end, b, []string{"20", "-20"}},
+			} {
+				board, err := h.Engine.GetPnLLeaderboard(tc.start, tc.end, 10)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(board) != 2 || board[0].TraderID != tc.first.ID || board[0].Rank != 1 {
+					t.Fatalf("%s: board = %+v, want %s first", tc.name, board, tc.first.Username)
+				}
+				for i, want := range tc.pnl {
+					if !board[i].RealizedPnL.Equal(dec(want)) {
+						t.Errorf("%s: rank %d P&L = %s, want %s", tc.name, i+1, board[i].RealizedPnL, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestLargestLiquidationsRankedByEachCriterion(t *testing.T) {
+	for _, persisted := range []bool{false, true} {
+		name := "memory"
+		if persisted {
+			name = "database"
+		}
+		t.Run(name, func(t *testing.T) {
+			h := enginetest.NewTestEngine()
+			if persisted {
+				database, err := db.NewSQLite(filepath.Join(t.TempDir(), "largest.db"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer database.Close()
+				h.Engine.SetDatabase(database)
+			}
+
+			// Loss and notional order them differently, and 9 sorts above
+			// 100 and 250 if compared as text
+			liquidate := func(username, instrument, size, mark, loss string) {
+				trader := h.AddTrader(username)
+				h.Engine.AddLiquidation(&domain.Liquidation{
+					ID:         uuid.New(),
+					TraderID:   trader.ID,
+					Instrument: instrument,
+					Side:       domain.SideBuy,
+					Size:       dec(size),
+					MarkPrice:  dec(mark),
+					Leverage:   50,
+					Loss:       dec(loss),
+					Timestamp:  time.Now(),
+				})
+			}
+			liquidate("small", h.Instrument, "1", "1000", "9")    // Notional 1000
+			liquidate("big", h.Instrument, "5", "900", "100")     // Notional 4500
+			liquidate("painful", h.Instrument, "2", "950", "250") // Notional 1900
+			liquidate("elsewhere", "R.alt", "100", "1000", "5000")
+
+			for _, tc := range []struct {
+				by    domain.LiquidationRanking
+				limit int
+				want  []string
+			}{
+				{domain.LiquidationRankingLoss, 10, []string{"painful", "big", "small"}},
+				{domain.LiquidationRankingNotional, 10, []string{"big", "painful", "small"}},
+				{domain.LiquidationRankingNotional, 2, []string{"big", "painful"}},
+			} {
+				ranked, err := h.Engine.GetLargestLiquidations(h.Instrument, tc.by, tc.limit)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []string
+				for i, entry := range ranked {
+					got = append(got, entry.Username)
+					if entry.Rank != i+1 || !entry.Notional.Equal(entry.Size.Mul(entry.MarkPrice)) || entry.Leverage != 50 {
+						t.Errorf("by %s: entry %d = rank %d notional %s leverage %d", tc.by, i, entry.Rank, entry.Notional, entry.Leverage)
+					}
+				}
+				if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+					t.Errorf("by %s limit %d = %v, want %v", tc.by, tc.limit, got, tc.want)
+				}
+			}
+
+			if _, err := h.Engine.GetLargestLiquidations(h.Instrument, "size", 10); err == nil {
+				t.Fatal("unknown ranking accepted")
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// lifecycleTradeLimit caps how many trades one lifecycle reconstruction reads
+const lifecycleTradeLimit = 10000
+
+// GetPositionLifecycle reconstructs the first position a trader opened at or
+// after from: every trade that opened, added to or reduced it, through the
+// trade or liquidation that closed it. Returns nil if no position was opened.
+func (me *MatchingEngine) GetPositionLifecycle(traderID uuid.UUID, instrument string, from time.Time) (*domain.PositionLifecycle, error) {
+	var trades []*domain.Trade
+	var liqs []*domain.Liquidation
+
+	if me.db != nil {
+		var err error
+		trades, err = me.db.GetTraderTradesSince(traderID, instrument, from, lifecycleTradeLimit)
+		if err != nil {
+			return nil, fmt.Errorf("loading trades: %w", err)
+		}
+		liqs, err = me.db.GetTraderLiquidationsSince(traderID, instrument, from)
+		if err != nil {
+			return nil, fmt.Errorf("loading liquidations: %w", err)
+		}
+	} else {
+		me.mu.RLock()
+		// History is kept newest first
+		for i := len(me.recentTrades) - 1; i >= 0; i-- {
+			t := me.recentTrades[i]
+			if t.Instrument == instrument && (t.BuyerID == traderID || t.SellerID == traderID) && !t.Timestamp.Before(from) {
+				trades = append(trades, t)
+			}
+		}
+		for i := len(me.liquidations) - 1; i >= 0; i-- {
+			l := me.liquidations[i]
+			if l.Instrument == instrument && l.TraderID == traderID && !l.Timestamp.Before(from) {
+				liqs = append(liqs, l)
+			}
+		}
+		me.mu.RUnlock()
+	}
+
+	return buildPositionLifecycle(traderID, instrument, trades, liqs), nil
+}
+
+// lifecycleFill is one trader's side of a trade
+type lifecycleFill struct {
+	trade   *domain.Trade
+	side    domain.Side
+	orderID uuid.UUID
+	after   decimal.Decimal // Position size after the fill
+	delta   decimal.Decimal // Signed size change
+	fee     decimal.Decimal
+	pnl     decimal.Decimal
+}
+
+// traderFills returns the trader's fills in a trade (two for a self-trade)
+func traderFills(traderID uuid.UUID, t *domain.Trade) []lifecycleFill {
+	var fills []lifecycleFill
+	if t.BuyerID == traderID {
+		fills = append(fills, lifecycleFill{t, domain.SideBuy, t.BuyerOrderID, t.BuyerNewPosition, t.Size, t.BuyerFee, t.BuyerRealizedPnL})
+	}
+	if t.SellerID == traderID {
+		fills = append(fills, lifecycleFill{t, domain.SideSell, t.SellerOrderID, t.SellerNewPosition, t.Size.Neg(), t.SellerFee, t.SellerRealizedPnL})
+	}
+	return fills
+}
+
+// buildPositionLifecycle walks a trader's trades and liquidations, both
+// oldest first, from the first fill that opens a position until it is flat
+func buildPositionLifecycle(traderID uuid.UUID, instrument string, trades []*domain.Trade, liqs []*domain.Liquidation) *domain.PositionLifecycle {
+	var lc *domain.PositionLifecycle
+	size := decimal.Zero
+
+	record := func(e *domain.PositionLifecycleEvent) {
+		lc.RealizedPnL = lc.RealizedPnL.Add(e.RealizedPnL)
+		lc.TotalFees = lc.TotalFees.Add(e.Fee)
+		e.CumulativePnL = lc.RealizedPnL
+		lc.Events = append(lc.Events, e)
+		if e.PositionSize.Abs().GreaterThan(lc.PeakSize) {
+			lc.PeakSize = e.PositionSize.Abs()
+		}
+	}
+	finish := func(status string, at time.Time) *domain.PositionLifecycle {
+		lc.Status = status
+		lc.ClosedAt = &at
+		return lc
+	}
+	// liquidationBefore applies the first liquidation after the position
+	// opened and no later than t, which ends the position
+	liquidationBefore := func(t time.Time) bool {
+		for _, liq := range liqs {
+			if liq.Timestamp.Before(lc.OpenedAt) || liq.Timestamp.After(t) {
+				continue
+			}
+			id := liq.ID
+			side := domain.SideSell
+			if liq.Side == domain.SideSell {
+				side = domain.SideBuy
+			}
+			record(&domain.PositionLifecycleEvent{
+				Step:          domain.LifecycleLiquidation,
+				Timestamp:     liq.Timestamp,
+				LiquidationID: &id,
+				Side:          side,
+				Price:         liq.MarkPrice,
+				Size:          size.Abs(),
+				RealizedPnL:   liq.Loss.Neg(),
+			})
+			return true
+		}
+		return false
+	}
+
+	for _, t := range trades {
+		for _, f := range traderFills(traderID, t) {
+			before := f.after.Sub(f.delta)
+			flipped := !before.IsZero() && !f.after.IsZero() && before.Sign() != f.after.Sign()
+
+			if lc == nil {
+				// Skip fills on a position opened before the window
+				if !before.IsZero() && !flipped {
+					continue
+				}
+				positionSide := domain.SideBuy
+				if f.after.IsNegative() {
+					positionSide = domain.SideSell
+				}
+				lc = &domain.PositionLifecycle{
+					TraderID:   traderID,
+					Instrument: instrument,
+					Side:       positionSide,
+					Status:     "open",
+					OpenedAt:   t.Timestamp,
+				}
+				size = f.after
+				id, orderID := t.ID, f.orderID
+				// A flip's closing portion belongs to the previous position
+				fee := f.fee
+				if flipped {
+					fee = f.fee.Mul(f.after.Abs()).Div(f.delta.Abs())
+				}
+				record(&domain.PositionLifecycleEvent{
+					Step:         domain.LifecycleOpen,
+					Timestamp:    t.Timestamp,
+					TradeID:      &id,
+					OrderID:      &orderID,
+					Side:         f.side,
+					Price:        t.Price,
+					Size:         f.after.Abs(),
+					PositionSize: f.after,
+					Fee:          fee,
+				})
+				continue
+			}
+
+			if liquidationBefore(t.Timestamp) {
+				return finish("liquidated", lc.Events[len(lc.Events)-1].Timestamp)
+			}
+
+			id, orderID := t.ID, f.orderID
+			e := &domain.PositionLifecycleEvent{
+				Timestamp:    t.Timestamp,
+				TradeID:      &id,
+				OrderID:      &orderID,
+				Side:         f.side,
+				Price:        t.Price,
+				Size:         f.delta.Abs(),
+				PositionSize: f.after,
+				Fee:          f.fee,
+				RealizedPnL:  f.pnl,
+			}
+			switch {
+			case flipped:
+				e.Step = domain.LifecycleFlip
+				e.Size = before.Abs()
+				e.PositionSize = decimal.Zero
+				e.Fee = f.fee.Mul(before.Abs()).Div(f.delta.Abs())
+			case f.after.IsZero():
+				e.Step = domain.LifecycleClose
+			case f.after.Abs().GreaterThan(before.Abs()):
+				e.Step = domain.LifecycleIncrease
+			default:
+				e.Step = domain.LifecycleReduce
+			}
+			size = e.PositionSize
+			record(e)
+
+			if size.IsZero() {
+				return finish("closed", t.Timestamp)
+			}
+		}
+	}
+
+	if lc == nil {
+		return nil
+	}
+	if liquidationBefore(time.Now()) {
+		return finish("liquidated", lc.Events[len(lc.Events)-1].Timestamp)
+	}
+	return lc
+}
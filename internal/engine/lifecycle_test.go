@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+// tradeSequence builds the trader's fills in order, a second apart, each
+// against a fresh counterparty
+type tradeSequence struct {
+	trader uuid.UUID
+	at     time.Time
+	trades []*domain.Trade
+}
+
+// fill records the trader buying (positive size) or selling (negative size)
+// at price, leaving the position at after
+func (s *tradeSequence) fill(size, price, after, fee, pnl string) *domain.Trade {
+	s.at = s.at.Add(time.Second)
+	signed := dec(size)
+	t := &domain.Trade{
+		ID:         uuid.New(),
+		Instrument: domain.RIndexSymbol,
+		Price:      dec(price),
+		Size:       signed.Abs(),
+		Timestamp:  s.at,
+		BuyerID:    uuid.New(),
+		SellerID:   uuid.New(),
+	}
+	if signed.IsPositive() {
+		t.BuyerID, t.BuyerOrderID = s.trader, uuid.New()
+		t.BuyerNewPosition = dec(after)
+		t.BuyerFee = dec(fee)
+		t.BuyerRealizedPnL = dec(pnl)
+	} else {
+		t.SellerID, t.SellerOrderID = s.trader, uuid.New()
+		t.SellerNewPosition = dec(after)
+		t.SellerFee = dec(fee)
+		t.SellerRealizedPnL = dec(pnl)
+	}
+	s.trades = append(s.trades, t)
+	return t
+}
+
+func TestPositionLifecycleFromTrades(t *testing.T) {
+	s := &tradeSequence{trader: uuid.New(), at: time.Now().Add(-time.Hour)}
+	s.fill("1", "990", "3", "0.6", "0")  // Adds to a position opened before the window
+	s.fill("-3", "995", "0", "1.8", "5") // and closes it
+	open := s.fill("2", "1000", "2", "1.2", "0")
+	s.fill("1", "1010", "3", "0.6", "0")
+	s.fill("-1", "1020", "2", "0.4", "13.33")
+	flip := s.fill("-4", "1030", "-2", "1.6", "53.33") // Closes 2 and opens a short of 2
+
+	lc := buildPositionLifecycle(s.trader, domain.RIndexSymbol, s.trades, nil)
+	if lc == nil {
+		t.Fatal("no lifecycle reconstructed")
+	}
+
+	want := []struct {
+		step             domain.LifecycleStep
+		size, position   string
+		fee, pnl, cumPnL string
+	}{
+		{domain.LifecycleOpen, "2", "2", "1.2", "0", "0"},
+		{domain.LifecycleIncrease, "1", "3", "0.6", "0", "0"},
+		{domain.LifecycleReduce, "1", "2", "0.4", "13.33", "13.33"},
+		{domain.LifecycleFlip, "2", "0", "0.8", "53.33", "66.66"}, // Half the fill, so half its fee
+	}
+	if len(lc.Events) != len(want) {
+		t.Fatalf("%d events, want %d: %+v", len(lc.Events), len(want), lc.Events)
+	}
+	for i, w := range want {
+		e := lc.Events[i]
+		if e.Step != w.step || !e.Size.Equal(dec(w.size)) || !e.PositionSize.Equal(dec(w.position)) ||
+			!e.Fee.Equal(dec(w.fee)) || !e.RealizedPnL.Equal(dec(w.pnl)) || !e.CumulativePnL.Equal(dec(w.cumPnL)) {
+			t.Errorf("event %d = %s size %s to %s fee %s pnl %s (cum %s), want %+v",
+				i, e.Step, e.Size, e.PositionSize, e.Fee, e.RealizedPnL, e.CumulativePnL, w)
+		}
+	}
+	if *lc.Events[0].TradeID != open.ID || *lc.Events[0].OrderID != open.BuyerOrderID {
+		t.Error("open event does not link its trade and order")
+	}
+
+	if lc.Side != domain.SideBuy || lc.Status != "closed" || !lc.OpenedAt.Equal(open.Timestamp) ||
+		lc.ClosedAt == nil || !lc.ClosedAt.Equal(flip.Timestamp) {
+		t.Fatalf("lifecycle = %s %s from %s to %v, want a long closed by the flip", lc.Side, lc.Status, lc.OpenedAt, lc.ClosedAt)
+	}
+	if !lc.PeakSize.Equal(dec("3")) || !lc.RealizedPnL.Equal(dec("66.66")) || !lc.TotalFees.Equal(dec("3")) {
+		t.Fatalf("peak %s, pnl %s, fees %s, want 3, 66.66 and 3", lc.PeakSize, lc.RealizedPnL, lc.TotalFees)
+	}
+}
+
+func TestPositionLifecycleEndsAtLiquidation(t *testing.T) {
+	s := &tradeSequence{trader: uuid.New(), at: time.Now().Add(-time.Hour)}
+	s.fill("-1", "1000", "-1", "0.6", "0")
+	s.fill("-1", "1000", "-2", "0.6", "0")
+	liq := &domain.Liquidation{
+		ID:         uuid.New(),
+		TraderID:   s.trader,
+		Instrument: domain.RIndexSymbol,
+		Side:       domain.SideSell,
+		Size:       dec("2"),
+		MarkPrice:  dec("1100"),
+		Loss:       dec("200"),
+		Timestamp:  s.at.Add(time.Second),
+	}
+	s.at = liq.Timestamp
+	s.fill("1", "1100", "1", "0.6", "0") // A new position after the liquidation
+
+	lc := buildPositionLifecycle(s.trader, domain.RIndexSymbol, s.trades, []*domain.Liquidation{liq})
+	if lc == nil || lc.Status != "liquidated" || lc.Side != domain.SideSell || lc.ClosedAt == nil || !lc.ClosedAt.Equal(liq.Timestamp) {
+		t.Fatalf("lifecycle = %+v, want a short liquidated at %s", lc, liq.Timestamp)
+	}
+	if len(lc.Events) != 3 {
+		t.Fatalf("%d events, want open, increase and liquidation", len(lc.Events))
+	}
+	last := lc.Events[2]
+	if last.Step != domain.LifecycleLiquidation || *last.LiquidationID != liq.ID || last.Side != domain.SideBuy ||
+		!last.Size.Equal(dec("2")) || !last.Price.Equal(dec("1100")) || !last.RealizedPnL.Equal(dec("-200")) {
+		t.Fatalf("last event = %+v, want the liquidation buying back 2 at 1100 for -200", last)
+	}
+	if !lc.RealizedPnL.Equal(dec("-200")) || !lc.PeakSize.Equal(dec("2")) {
+		t.Fatalf("pnl %s, peak %s, want -200 and 2", lc.RealizedPnL, lc.PeakSize)
+	}
+}
@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// leverageTiers lists the tiers from safest to most reckless
+var leverageTiers = []domain.LeverageTier{
+	domain.LeverageTierConservative,
+	domain.LeverageTierModerate,
+	domain.LeverageTierAggressive,
+	domain.LeverageTierDegen,
+}
+
+// GetLiquidationRatesByTier reports, for each leverage tier, how many
+// positions were opened within the window and how many were liquidated. A
+// position counts as opened at the leverage of the fill that took it off flat
+// or flipped its side. Positions opened before the window can be liquidated
+// inside it, so a short window can show a rate above 1.
+func (me *MatchingEngine) GetLiquidationRatesByTier(instrument string, window time.Duration) (*domain.LiquidationRates, error) {
+	now := time.Now()
+	start := now.Add(-window)
+
+	opened := make(map[domain.LeverageTier]int)
+	liquidated := make(map[domain.LeverageTier]int)
+	countTrade := func(t *domain.Trade) error {
+		if t.Instrument != instrument {
+			return nil
+		}
+		if opensPosition(t.BuyerNewPosition, t.Size) {
+			opened[domain.GetLeverageTier(domain.NormalizeLeverage(t.BuyerLeverage))]++
+		}
+		if opensPosition(t.SellerNewPosition, t.Size.Neg()) {
+			opened[domain.GetLeverageTier(domain.NormalizeLeverage(t.SellerLeverage))]++
+		}
+		return nil
+	}
+	countLiquidation := func(l *domain.Liquidation) error {
+		if l.Instrument == instrument {
+			liquidated[domain.GetLeverageTier(domain.NormalizeLeverage(l.Leverage))]++
+		}
+		return nil
+	}
+
+	if me.db != nil {
+		if err := me.db.EachTrade(start, now, countTrade); err != nil {
+			return nil, fmt.Errorf("loading trades: %w", err)
+		}
+		if err := me.db.EachLiquidation(start, now, countLiquidation); err != nil {
+			return nil, fmt.Errorf("loading liquidations: %w", err)
+		}
+	} else {
+		me.mu.RLock()
+		for _, t := range me.recentTrades {
+			if !t.Timestamp.Before(start) {
+				countTrade(t)
+			}
+		}
+		for _, l := range me.liquidations {
+			if !l.Timestamp.Before(start) {
+				countLiquidation(l)
+			}
+		}
+		me.mu.RUnlock()
+	}
+
+	rates := &domain.LiquidationRates{
+		Instrument:    instrument,
+		Timestamp:     now,
+		WindowSeconds: int(window.Seconds()),
+		Tiers:         make([]domain.TierLiquidationRate, 0, len(leverageTiers)),
+	}
+	for _, tier := range leverageTiers {
+		entry := domain.TierLiquidationRate{
+			Tier:       tier,
+			Opened:     opened[tier],
+			Liquidated: liquidated[tier],
+		}
+		if entry.Opened > 0 {
+			entry.Rate = decimal.NewFromInt(int64(entry.Liquidated)).Div(decimal.NewFromInt(int64(entry.Opened)))
+		}
+		rates.Tiers = append(rates.Tiers, entry)
+	}
+	return rates, nil
+}
+
+// opensPosition reports whether a fill of the given signed size that left the
+// trader at newPosition opened a position, from flat or by flipping side
+func opensPosition(newPosition, change decimal.Decimal) bool {
+	if newPosition.IsZero() {
+		return false
+	}
+	previous := newPosition.Sub(change)
+	return previous.IsZero() || previous.Sign() != newPosition.Sign()
+}
+
+// closesPosition reports whether a fill of the given signed size that left
+// the trader at newPosition closed a position, to flat or by flipping side
+func closesPosition(newPosition, change decimal.Decimal) bool {
+	previous := newPosition.Sub(change)
+	if previous.IsZero() {
+		return false
+	}
+	return newPosition.IsZero() || previous.Sign() != newPosition.Sign()
+}
@@ -0,0 +1,72 @@
+package engine_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestLiquidationRatesByTier(t *testing.T) {
+	h := enginetest.NewTestEngine()
+
+	// Each pair opens a short for the maker and a long for the taker
+	var makers, takers []*domain.Trader
+	for i, pair := range [][2]int{{1, 5}, {20, 100}, {150, 150}, {10, 75}} {
+		maker := h.AddTrader(fmt.Sprintf("maker%d", i))
+		taker := h.AddTrader(fmt.Sprintf("taker%d", i))
+		for _, o := range []*domain.Order{
+			{TraderID: maker.ID, Side: domain.SideSell, Type: domain.OrderTypeLimit, Price: dec("1000"), Size: dec("1"), Leverage: pair[0]},
+			{TraderID: taker.ID, Side: domain.SideBuy, Type: domain.OrderTypeMarket, Size: dec("1"), Leverage: pair[1]},
+		} {
+			if _, err := h.Submit(o); err != nil {
+				t.Fatal(err)
+			}
+		}
+		makers, takers = append(makers, maker), append(takers, taker)
+	}
+	// Adding to open positions is not another opening
+	h.MustLimit(makers[0], domain.SideSell, "1000", "1")
+	h.MustMarket(takers[0], domain.SideBuy, "1")
+
+	liquidate := func(trader *domain.Trader, leverage int, at time.Time) {
+		h.Engine.AddLiquidation(&domain.Liquidation{
+			ID: uuid.New(), TraderID: trader.ID, Instrument: h.Instrument, Side: domain.SideBuy,
+			Size: dec("1"), MarkPrice: dec("900"), Leverage: leverage, Timestamp: at,
+		})
+	}
+	now := time.Now()
+	liquidate(takers[0], 5, now)
+	liquidate(takers[1], 100, now)
+	liquidate(takers[2], 150, now)
+	liquidate(takers[2], 150, now)
+	liquidate(takers[3], 75, now.Add(-2*time.Hour)) // Before the window
+
+	rates, err := h.Engine.GetLiquidationRatesByTier(h.Instrument, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		tier               domain.LeverageTier
+		opened, liquidated int
+		rate               string
+	}{
+		{domain.LeverageTierConservative, 3, 1, "0.3333333333333333"}, // 1x, 5x and 10x
+		{domain.LeverageTierModerate, 1, 0, "0"},                      // 20x
+		{domain.LeverageTierAggressive, 2, 1, "0.5"},                  // 100x and 75x
+		{domain.LeverageTierDegen, 2, 2, "1"},                         // Both 150x
+	}
+	if len(rates.Tiers) != len(want) {
+		t.Fatalf("%d tiers, want %d", len(rates.Tiers), len(want))
+	}
+	for i, w := range want {
+		got := rates.Tiers[i]
+		if got.Tier != w.tier || got.Opened != w.opened || got.Liquidated != w.liquidated || !got.Rate.Equal(dec(w.rate)) {
+			t.Errorf("tier %d = %s %d opened %d liquidated rate %s, want %s %d %d %s",
+				i, got.Tier, got.Opened, got.Liquidated, got.Rate, w.tier, w.opened, w.liquidated, w.rate)
+		}
+	}
+}
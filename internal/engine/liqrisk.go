@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// A liquidation map without a bucket size splits its band into
+// defaultLiquidationMapBuckets, and may never span more than
+// maxLiquidationMapBuckets
+const (
+	defaultLiquidationMapBuckets = 50
+	maxLiquidationMapBuckets     = 1000
+)
+
+// GetLiquidationRisk returns an instrument's open positions whose liquidation
+// price is within threshold (a fraction of the mark, e.g. 0.02 for 2%) of the
+// mark price, closest first, with their health figures filled in. Positions
+// already past their liquidation price lead with a negative distance.
+func (me *MatchingEngine) GetLiquidationRisk(instrument string, threshold decimal.Decimal) ([]*domain.Position, error) {
+	if !threshold.IsPositive() {
+		return nil, fmt.Errorf("threshold must be positive")
+	}
+
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	if _, exists := me.books[instrument]; !exists {
+		return nil, fmt.Errorf("unknown instrument: %s", instrument)
+	}
+
+	mark := me.markPriceLocked(instrument)
+	limit := threshold.Mul(decimal.NewFromInt(10000))
+	atRisk := make([]*domain.Position, 0)
+	for _, pos := range me.positions {
+		if pos.Instrument != instrument || pos.Size.IsZero() {
+			continue
+		}
+		marked := me.markedPositionLocked(pos)
+		marked.ComputeHealth(mark)
+		if marked.DistanceToLiquidationBps == nil || marked.DistanceToLiquidationBps.GreaterThan(limit) {
+			continue
+		}
+		atRisk = append(atRisk, marked)
+	}
+
+	sort.Slice(atRisk, func(i, j int) bool {
+		di, dj := *atRisk[i].DistanceToLiquidationBps, *atRisk[j].DistanceToLiquidationBps
+		if !di.Equal(dj) {
+			return di.LessThan(dj)
+		}
+		return atRisk[i].TraderID.String() < atRisk[j].TraderID.String()
+	})
+	return atRisk, nil
+}
+
+// GetLiquidationMap sums the open positions that would be liquidated at each
+// price level within bandPct percent of the mark, longs and shorts apart, in
+// buckets of bucketSize starting at a multiple of it. A zero bucketSize
+// splits the band into defaultLiquidationMapBuckets, rounded up to the tick.
+// Every bucket in the band is listed, empty or not.
+func (me *MatchingEngine) GetLiquidationMap(instrument string, bucketSize, bandPct decimal.Decimal) (*domain.LiquidationMap, error) {
+	hundred := decimal.NewFromInt(100)
+	if !bandPct.IsPositive() || bandPct.GreaterThan(hundred) {
+		return nil, fmt.Errorf("band must be between 0 and 100 percent")
+	}
+	if bucketSize.IsNegative() {
+		return nil, fmt.Errorf("bucket size must be positive")
+	}
+
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	if _, exists := me.books[instrument]; !exists {
+		return nil, fmt.Errorf("unknown instrument: %s", instrument)
+	}
+
+	mark := me.markPriceLocked(instrument)
+	offset := mark.Mul(bandPct).Div(hundred)
+	low, high := decimal.Max(mark.Sub(offset), decimal.Zero), mark.Add(offset)
+	if bucketSize.IsZero() {
+		bucketSize = high.Sub(low).Div(decimal.NewFromInt(defaultLiquidationMapBuckets))
+		if ic := me.instrumentConfig; ic != nil && ic.TickSize.IsPositive() {
+			bucketSize = decimal.Max(bucketSize.Div(ic.TickSize).Ceil().Mul(ic.TickSize), ic.TickSize)
+		}
+	}
+	if !bucketSize.IsPositive() {
+		return nil, fmt.Errorf("bucket size must be positive")
+	}
+
+	first := low.Div(bucketSize).Floor().Mul(bucketSize)
+	count := high.Sub(first).Div(bucketSize).Floor().IntPart() + 1
+	if count > maxLiquidationMapBuckets {
+		return nil, fmt.Errorf("band spans %d buckets, more than %d: use a larger bucket size", count, maxLiquidationMapBuckets)
+	}
+
+	liqMap := &domain.LiquidationMap{
+		Instrument: instrument,
+		Timestamp:  time.Now(),
+		MarkPrice:  mark,
+		BucketSize: bucketSize,
+		BandPct:    bandPct,
+		Buckets:    make([]domain.LiquidationMapBucket, count),
+	}
+	for i := range liqMap.Buckets {
+		liqMap.Buckets[i].Price = first.Add(bucketSize.Mul(decimal.NewFromInt(int64(i))))
+	}
+
+	for _, pos := range me.positions {
+		if pos.Instrument != instrument || pos.Size.IsZero() {
+			continue
+		}
+		liq := me.markedPositionLocked(pos).LiquidationPrice
+		if !liq.IsPositive() || liq.LessThan(low) || liq.GreaterThan(high) {
+			continue
+		}
+
+		bucket := &liqMap.Buckets[liq.Sub(first).Div(bucketSize).Floor().IntPart()]
+		size := pos.Size.Abs()
+		if pos.IsLong() {
+			bucket.LongSize = bucket.LongSize.Add(size)
+			bucket.LongNotional = bucket.LongNotional.Add(size.Mul(liq))
+		} else {
+			bucket.ShortSize = bucket.ShortSize.Add(size)
+			bucket.ShortNotional = bucket.ShortNotional.Add(size.Mul(liq))
+		}
+		bucket.Positions++
+	}
+	return liqMap, nil
+}
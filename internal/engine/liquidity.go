@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// Liquidity score parameters. Each component maps to [0, 1] as x/(x+ref),
+// so a market exactly at the reference level scores 0.5 on that component.
+var (
+	liquidityDepthBandPct = decimal.NewFromInt(1)    // Depth counted within ±1% of mid
+	liquidityRefSpreadBps = decimal.NewFromInt(10)   // Spread scoring 0.5
+	liquidityRefDepth     = decimal.NewFromInt(100)  // Two-sided depth scoring 0.5
+	liquidityRefVolume    = decimal.NewFromInt(1000) // Window volume scoring 0.5
+	liquiditySpreadWeight = decimal.NewFromFloat(0.4)
+	liquidityDepthWeight  = decimal.NewFromFloat(0.4)
+	liquidityVolumeWeight = decimal.NewFromFloat(0.2)
+	liquidityVolumeWindow = 5 * time.Minute
+)
+
+// saturate maps a non-negative value to [0, 1) as x/(x+ref)
+func saturate(x, ref decimal.Decimal) decimal.Decimal {
+	score, err := domain.SafeDiv(x, x.Add(ref))
+	if err != nil {
+		return decimal.Zero
+	}
+	return score
+}
+
+// GetLiquidityScore combines spread tightness, depth near mid and recent volume
+// into a single 0-100 market-quality score
+func (me *MatchingEngine) GetLiquidityScore(instrument string) (*domain.LiquidityScore, error) {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	book, exists := me.books[instrument]
+	if !exists {
+		return nil, fmt.Errorf("unknown instrument: %s", instrument)
+	}
+
+	now := time.Now()
+	score := &domain.LiquidityScore{
+		Instrument:      instrument,
+		Timestamp:       now,
+		DepthBandPct:    liquidityDepthBandPct,
+		VolumeWindowSec: int(liquidityVolumeWindow.Seconds()),
+	}
+
+	bestBid, _, hasBid := book.BestBid()
+	bestAsk, _, hasAsk := book.BestAsk()
+	score.BestBid = bestBid
+	score.BestAsk = bestAsk
+
+	// Spread and depth need a two-sided book
+	if hasBid && hasAsk {
+		score.MidPrice = bestBid.Add(bestAsk).Div(decimal.NewFromInt(2))
+		if spreadBps, err := domain.SafeDiv(bestAsk.Sub(bestBid).Mul(decimal.NewFromInt(10000)), score.MidPrice); err == nil {
+			score.SpreadBps = spreadBps
+			// Tighter is better: ref/(spread+ref)
+			score.SpreadScore = decimal.NewFromInt(1).Sub(saturate(spreadBps, liquidityRefSpreadBps))
+		}
+
+		band := score.MidPrice.Mul(liquidityDepthBandPct).Div(decimal.NewFromInt(100))
+		score.BidDepth, score.AskDepth = book.DepthWithin(score.MidPrice.Sub(band), score.MidPrice.Add(band))
+		// The thinner side limits how much can trade
+		score.DepthScore = saturate(decimal.Min(score.BidDepth, score.AskDepth).Mul(decimal.NewFromInt(2)), liquidityRefDepth)
+	}
+
+	// recentTrades is newest first
+	cutoff := now.Add(-liquidityVolumeWindow)
+	for _, trade := range me.recentTrades {
+		if trade.Timestamp.Before(cutoff) {
+			break
+		}
+		if trade.Instrument == instrument {
+			score.RecentVolume = score.RecentVolume.Add(trade.Size)
+		}
+	}
+	score.VolumeScore = saturate(score.RecentVolume, liquidityRefVolume)
+
+	score.SpreadBps = score.SpreadBps.Round(2)
+	score.SpreadScore = score.SpreadScore.Round(4)
+	score.DepthScore = score.DepthScore.Round(4)
+	score.VolumeScore = score.VolumeScore.Round(4)
+	score.Score = score.SpreadScore.Mul(liquiditySpreadWeight).
+		Add(score.DepthScore.Mul(liquidityDepthWeight)).
+		Add(score.VolumeScore.Mul(liquidityVolumeWeight)).
+		Mul(decimal.NewFromInt(100)).
+		Round(2)
+
+	return score, nil
+}
@@ -0,0 +1,56 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+// quotedBook scores a book quoting the same size at bid and ask
+func quotedBook(t *testing.T, bid, ask string) *domain.LiquidityScore {
+	t.Helper()
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+	h.MustLimit(maker, domain.SideBuy, bid, "2")
+	h.MustLimit(maker, domain.SideSell, ask, "2")
+
+	score, err := h.Engine.GetLiquidityScore(h.Instrument)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return score
+}
+
+func TestTightBookScoresAboveWideBook(t *testing.T) {
+	tight := quotedBook(t, "999.5", "1000.5")
+	wide := quotedBook(t, "995", "1005")
+
+	if !tight.SpreadBps.Equal(dec("10")) || !wide.SpreadBps.Equal(dec("100")) {
+		t.Fatalf("spreads = %s and %s bps, want 10 and 100", tight.SpreadBps, wide.SpreadBps)
+	}
+	if !tight.SpreadScore.GreaterThan(wide.SpreadScore) {
+		t.Fatalf("spread scores = %s tight, %s wide, want tight higher", tight.SpreadScore, wide.SpreadScore)
+	}
+	// Both books hold the same size inside the 1% band
+	if !tight.DepthScore.Equal(wide.DepthScore) || !tight.BidDepth.Equal(dec("2")) {
+		t.Fatalf("depth = %s (%s) tight, %s wide, want equal with 2 bid", tight.DepthScore, tight.BidDepth, wide.DepthScore)
+	}
+	if !tight.Score.GreaterThan(wide.Score) {
+		t.Fatalf("scores = %s tight, %s wide, want tight higher", tight.Score, wide.Score)
+	}
+}
+
+func TestOneSidedBookHasNoSpreadOrDepthScore(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+	h.MustLimit(maker, domain.SideBuy, "999", "2")
+
+	score, err := h.Engine.GetLiquidityScore(h.Instrument)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !score.SpreadScore.IsZero() || !score.DepthScore.IsZero() || !score.Score.IsZero() {
+		t.Fatalf("score = %+v, want zero without asks", score)
+	}
+}
@@ -0,0 +1,26 @@
+package engine
+
+import (
+	"log/slog"
+
+	"github.com/google/uuid"
+)
+
+// correlateLocked tags the engine's log lines with id, tying everything an
+// incoming order (liquidation orders included) causes (trades, triggered stops, position
+// updates) to it, until the returned func restores the previous tag. Caller
+// must hold me.mu.
+func (me *MatchingEngine) correlateLocked(id uuid.UUID) func() {
+	previous := me.correlationID
+	me.correlationID = id
+	return func() { me.correlationID = previous }
+}
+
+// loggerLocked returns the default logger with the current correlation ID,
+// if any. Caller must hold me.mu.
+func (me *MatchingEngine) loggerLocked() *slog.Logger {
+	if me.correlationID == uuid.Nil {
+		return slog.Default()
+	}
+	return slog.Default().With("correlation_id", me.correlationID)
+}
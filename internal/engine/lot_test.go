@@ -0,0 +1,51 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+// newLotEngine uses a 0.01 lot above the 0.001 minimum, so rounding to the lot
+// is what decides whether a small order survives
+func newLotEngine() *enginetest.Harness {
+	cfg := config.Default()
+	cfg.RIndex.LotSize = dec("0.01")
+	return enginetest.NewTestEngineWithConfig(cfg)
+}
+
+func TestOrderSizesRoundDownToLot(t *testing.T) {
+	h := newLotEngine()
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+
+	order := h.MustLimit(maker, domain.SideSell, "1000", "1.0375")
+	if !order.Size.Equal(dec("1.03")) {
+		t.Fatalf("resting size = %s, want 1.03", order.Size)
+	}
+
+	trades := h.MustMarket(taker, domain.SideBuy, "0.519")
+	if len(trades) != 1 || !trades[0].Size.Equal(dec("0.51")) {
+		t.Fatalf("trades = %+v, want one 0.51 fill", trades)
+	}
+	if got := h.PositionSize(taker); !got.Equal(dec("0.51")) {
+		t.Fatalf("taker position = %s, want 0.51", got)
+	}
+	if asks := h.Asks(); len(asks) != 1 || !asks[0].Size.Equal(dec("0.52")) {
+		t.Fatalf("asks = %+v, want 0.52 left", asks)
+	}
+}
+
+func TestOrderSizeRoundingToZeroIsRejected(t *testing.T) {
+	h := newLotEngine()
+	trader := h.AddTrader("trader")
+
+	if _, _, err := h.Limit(trader, domain.SideBuy, "999", "0.0099"); err == nil {
+		t.Fatal("order rounding to zero lots accepted")
+	}
+	if bids := h.Bids(); len(bids) != 0 {
+		t.Fatalf("bids = %+v, want nothing rested", bids)
+	}
+}
@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/liquidation"
+)
+
+// orderPriceLocked is the price an order is expected to trade at: its limit
+// or stop price, or for a market order the last trade (or starting) price.
+// Caller must hold me.mu.
+func (me *MatchingEngine) orderPriceLocked(order *domain.Order) decimal.Decimal {
+	switch order.Type {
+	case domain.OrderTypeLimit:
+		return order.Price
+	case domain.OrderTypeStop:
+		return order.StopPrice
+	}
+	if last, ok := me.lastTradePrice(order.Instrument); ok {
+		return last
+	}
+	return me.startingPrice()
+}
+
+// projectedPositionLocked returns the trader's current signed position and
+// what it would be if the order filled in full. Caller must hold me.mu.
+func (me *MatchingEngine) projectedPositionLocked(order *domain.Order) (current, projected decimal.Decimal) {
+	if pos, exists := me.positions[fmt.Sprintf("%s:%s", order.TraderID, order.Instrument)]; exists {
+		current = pos.Size
+	}
+	if order.Side == domain.SideSell {
+		return current, current.Sub(order.Size)
+	}
+	return current, current.Add(order.Size)
+}
+
+// openingSize is how much of a move from current to projected adds exposure:
+// the increase when staying on one side, or the whole new side when flipping
+func openingSize(current, projected decimal.Decimal) decimal.Decimal {
+	if current.IsZero() || current.Sign() == projected.Sign() {
+		return decimal.Max(projected.Abs().Sub(current.Abs()), decimal.Zero)
+	}
+	return projected.Abs()
+}
+
+// checkMarginLocked rejects an order whose opening part needs more margin and
+// taker fee, at the order's leverage, than the trader's balance holds once
+// their open orders on the same side are covered too. Resting orders and
+// untriggered stops take their margin only as they fill, so each new order
+// is checked as if every open order on its side filled first, in turn against
+// the current position; the other side can only close what this side opens,
+// or open once this side is flat. Caller must hold me.mu.
+func (me *MatchingEngine) checkMarginLocked(trader *domain.Trader, order *domain.Order) error {
+	position, _ := me.projectedPositionLocked(order)
+	reserved := decimal.Zero
+	for _, open := range me.openOrdersLocked(trader.ID, order.Instrument) {
+		if open.Side != order.Side || open.ID == order.ID || open.ID == me.replacingOrderID {
+			continue
+		}
+		var required decimal.Decimal
+		position, required = me.orderMarginLocked(position, open)
+		reserved = reserved.Add(required)
+	}
+
+	_, required := me.orderMarginLocked(position, order)
+	if !required.IsPositive() {
+		return nil
+	}
+	if reserved.Add(required).GreaterThan(trader.Balance) {
+		available := decimal.Max(trader.Balance.Sub(reserved), decimal.Zero)
+		return fmt.Errorf("insufficient balance: %s margin and fees required at %dx, %s available after open orders",
+			required.StringFixed(2), order.Leverage, available.StringFixed(2))
+	}
+	return nil
+}
+
+// orderMarginLocked returns the position after the rest of an order fills
+// from position, and the margin and worst-case taker fee its opening part
+// needs. Caller must hold me.mu.
+func (me *MatchingEngine) orderMarginLocked(position decimal.Decimal, order *domain.Order) (decimal.Decimal, decimal.Decimal) {
+	projected := position.Add(order.RemainingSize())
+	if order.Side == domain.SideSell {
+		projected = position.Sub(order.RemainingSize())
+	}
+	opening := openingSize(position, projected)
+	if !opening.IsPositive() {
+		return projected, decimal.Zero
+	}
+
+	price := me.orderPriceLocked(order)
+	margin := liquidation.CalculateRequiredMargin(opening, price, domain.NormalizeLeverage(order.Leverage), me.divisionPlaces())
+	fee := opening.Mul(price).Mul(decimal.Max(me.feeRate(true, domain.EffectOpen), decimal.Zero))
+	return projected, margin.Add(fee)
+}
+
+// openOrdersLocked returns a trader's resting orders and untriggered stops on
+// an instrument, oldest first. Caller must hold me.mu.
+func (me *MatchingEngine) openOrdersLocked(traderID uuid.UUID, instrument string) []*domain.Order {
+	var open []*domain.Order
+	if book, exists := me.books[instrument]; exists {
+		open = book.GetTraderOrders(traderID)
+	}
+	open = append(open, me.traderStopsLocked(traderID, instrument)...)
+	sort.Slice(open, func(i, j int) bool {
+		return open[i].CreatedAt.Before(open[j].CreatedAt)
+	})
+	return open
+}
+
+// settleMarginLocked moves margin between a trader's balance and their
+// position for a fill taking it from oldSize to newSize. The closed part
+// releases its share of the position's margin along with its realized P&L;
+// the opened part locks margin at the fill's leverage. Caller must hold me.mu.
+func (me *MatchingEngine) settleMarginLocked(pos *domain.Position, oldSize, newSize, price decimal.Decimal, leverage int, realized decimal.Decimal) {
+	trader := me.traders[pos.TraderID]
+	credit := func(amount decimal.Decimal) {
+		if trader != nil {
+			trader.Balance = trader.Balance.Add(amount)
+		}
+	}
+
+	opening := openingSize(oldSize, newSize)
+	// Released margin and P&L for whatever part of the old position closed
+	if closedSize := oldSize.Abs().Sub(newSize.Abs().Sub(opening)); !oldSize.IsZero() && closedSize.IsPositive() {
+		released := pos.Margin
+		if closedSize.LessThan(oldSize.Abs()) {
+			released = pos.Margin.Mul(closedSize).DivRound(oldSize.Abs(), me.divisionPlaces())
+		}
+		pos.Margin = pos.Margin.Sub(released)
+		credit(released.Add(realized))
+		if trader != nil {
+			trader.TotalPnL = trader.TotalPnL.Add(realized)
+		}
+	}
+
+	if !opening.IsPositive() {
+		return
+	}
+	leverage = domain.NormalizeLeverage(leverage)
+	margin := liquidation.CalculateRequiredMargin(opening, price, leverage, me.divisionPlaces())
+	pos.Margin = pos.Margin.Add(margin)
+	credit(margin.Neg())
+
+	// Quote the position at the leverage its margin actually backs
+	if opening.Equal(newSize.Abs()) || !pos.Margin.IsPositive() {
+		pos.Leverage = leverage
+	} else {
+		effective, _ := pos.EntryPrice.Mul(newSize.Abs()).Div(pos.Margin).Float64()
+		pos.Leverage = domain.NormalizeLeverage(int(math.Round(effective)))
+	}
+	if trader != nil && leverage > trader.MaxLeverageUsed {
+		trader.MaxLeverageUsed = leverage
+	}
+}
@@ -0,0 +1,87 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestMarginCoversRestingOrdersOnSameSide(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	buyer := h.AddTrader("buyer")
+	seller := h.AddTrader("seller")
+
+	// 4000 margin + 2.40 fee each at 1x; a third would need 12007.20 of 10000
+	h.MustLimit(buyer, domain.SideBuy, "1000", "4")
+	h.MustLimit(buyer, domain.SideBuy, "999", "4")
+	if _, _, err := h.Limit(buyer, domain.SideBuy, "998", "4"); err == nil {
+		t.Fatal("third resting buy accepted beyond the balance")
+	}
+
+	// Orders on the other side close what this side opens, so they still pass
+	h.MustLimit(buyer, domain.SideSell, "1100", "4")
+
+	// Filling every resting buy must not overdraw the balance
+	h.MustMarket(seller, domain.SideSell, "8")
+	if got := h.PositionSize(buyer); !got.Equal(decimal.NewFromInt(8)) {
+		t.Fatalf("buyer position = %s, want 8", got)
+	}
+	if balance := h.Trader(buyer).Balance; balance.IsNegative() {
+		t.Fatalf("buyer balance = %s, want >= 0", balance)
+	}
+}
+
+func TestMarginIncludesTakerFee(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+	if _, err := h.Submit(&domain.Order{
+		TraderID: maker.ID,
+		Side:     domain.SideSell,
+		Type:     domain.OrderTypeLimit,
+		Price:    decimal.NewFromInt(1000),
+		Size:     decimal.NewFromInt(20),
+		Leverage: 5,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 10 at 1000 is the whole 10000 balance in margin, leaving nothing for the fee
+	if _, _, err := h.Market(taker, domain.SideBuy, "10"); err == nil {
+		t.Fatal("order needing the whole balance plus a fee accepted")
+	}
+
+	trades := h.MustMarket(taker, domain.SideBuy, "9.99")
+	if len(trades) != 1 {
+		t.Fatalf("trades = %d, want 1", len(trades))
+	}
+	if balance := h.Trader(taker).Balance; balance.IsNegative() {
+		t.Fatalf("taker balance = %s after margin and fee, want >= 0", balance)
+	}
+}
+
+func TestAmendKeepsItsOwnMarginReservation(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	buyer := h.AddTrader("buyer")
+
+	order := h.MustLimit(buyer, domain.SideBuy, "1000", "9")
+	// The amended order replaces its own reservation rather than adding to it
+	if _, _, err := h.Engine.AmendOrder(buyer.ID, order.ID, h.Instrument, decimal.NewFromInt(990), decimal.NewFromInt(9)); err != nil {
+		t.Fatalf("amend rejected: %v", err)
+	}
+
+	replacement := &domain.Order{
+		TraderID:   buyer.ID,
+		Instrument: h.Instrument,
+		Side:       domain.SideBuy,
+		Type:       domain.OrderTypeLimit,
+		Price:      decimal.NewFromInt(995),
+		Size:       decimal.NewFromInt(9),
+		Leverage:   1,
+	}
+	if _, err := h.Engine.CancelReplace(order.ID, replacement); err != nil {
+		t.Fatalf("cancel-replace rejected: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// markPriceLocked derives the mark price. The default is the last trade, so
+// a single small print moves it; the average and mid methods smooth that out.
+// With no trades yet it is the starting price. Caller must hold me.mu.
+func (me *MatchingEngine) markPriceLocked(instrument string) decimal.Decimal {
+	last, ok := me.lastTradePrice(instrument)
+	if !ok {
+		return me.startingPrice()
+	}
+	if me.engineConfig == nil {
+		return last
+	}
+
+	switch me.engineConfig.MarkPriceMethod {
+	case "average":
+		return me.averageTradePriceLocked(instrument, me.engineConfig.MarkPriceTrades)
+	case "mid":
+		return me.clampedMidLocked(instrument, last, me.engineConfig.MarkPriceBand)
+	}
+	return last
+}
+
+// averageTradePriceLocked is the plain average of an instrument's last n
+// trade prices. Caller must hold me.mu and ensure there is at least one trade.
+func (me *MatchingEngine) averageTradePriceLocked(instrument string, n int) decimal.Decimal {
+	sum, count := decimal.Zero, 0
+	for _, t := range me.recentTrades {
+		if count == n {
+			break
+		}
+		if t.Instrument == instrument {
+			sum = sum.Add(t.Price)
+			count++
+		}
+	}
+	return sum.Div(decimal.NewFromInt(int64(count)))
+}
+
+// clampedMidLocked is the mid of the best bid and ask, held within band of
+// the last trade so a thin quote cannot drag it far. With either side empty
+// it falls back to the last trade. Caller must hold me.mu.
+func (me *MatchingEngine) clampedMidLocked(instrument string, last, band decimal.Decimal) decimal.Decimal {
+	book, exists := me.books[instrument]
+	if !exists {
+		return last
+	}
+	bid, _, hasBid := book.BestBid()
+	ask, _, hasAsk := book.BestAsk()
+	if !hasBid || !hasAsk {
+		return last
+	}
+
+	mid := bid.Add(ask).Div(decimal.NewFromInt(2))
+	one := decimal.NewFromInt(1)
+	lo, hi := last.Mul(one.Sub(band)), last.Mul(one.Add(band))
+	return decimal.Max(lo, decimal.Min(hi, mid))
+}
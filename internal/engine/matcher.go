@@ -1,8 +1,10 @@
 package engine
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sort"
 	"sync"
 	"time"
 
@@ -19,22 +21,77 @@ type TradeHandler func(trade *domain.Trade)
 // OrderHandler is called when an order is updated
 type OrderHandler func(order *domain.Order)
 
+// PositionHandler is called when a position changes size
+type PositionHandler func(pos *domain.Position)
+
 // LiquidationHandler is called when a liquidation occurs
 type LiquidationHandler func(liq *domain.Liquidation)
 
+// MarketStateHandler is called when the market changes phase
+type MarketStateHandler func(change *domain.MarketStateChange)
+
+// BookLevelHandler is called when a price level's displayed size changes
+type BookLevelHandler func(update *domain.BookLevelUpdate)
+
+// InsuranceFund reports the insurance fund balance and applies changes to it
+type InsuranceFund interface {
+	GetInsuranceFund() decimal.Decimal
+	ApplyInsuranceFundChange(event *domain.InsuranceFundEvent) error
+}
+
+// ErrUsernameTaken is returned when registering a trader under a username
+// another trader already has
+var ErrUsernameTaken = errors.New("username already taken")
+
 // MatchingEngine handles order matching for all instruments
 type MatchingEngine struct {
 	books               map[string]*OrderBook
+	instruments         []string                    // Registered instruments, in registration order
 	positions           map[string]*domain.Position // key: traderID:instrument
 	traders             map[uuid.UUID]*domain.Trader
+	usernames           map[string]uuid.UUID  // Trader ID by username
 	recentTrades        []*domain.Trade       // Recent trades for history
+	tradesTrimmed       bool                  // Older trades have been dropped from recentTrades
 	liquidations        []*domain.Liquidation // Liquidation history
 	mu                  sync.RWMutex
 	tradeHandlers       []TradeHandler
 	orderHandlers       []OrderHandler
+	positionHandlers    []PositionHandler
 	liquidationHandlers []LiquidationHandler
+	marketStateHandlers []MarketStateHandler
+	mmpHandlers         []MMPHandler
+	bookHandlers        []BookLevelHandler
+	marketState         domain.MarketState
+	marketStateReason   string
 	db                  *db.SQLiteDB // Optional database for persistence
 	liqConfig           *config.LiquidationConfig
+	instrumentConfig    *config.RIndexConfig
+	engineConfig        *config.EngineConfig
+	now                 func() time.Time // Server clock for order staleness checks
+	degenConfig         *config.DegenIndexConfig
+	orderTimes          []time.Time // When recent orders were placed, oldest first (for churn)
+	feeConfig           *config.FeeConfig
+	feeRevenue          decimal.Decimal // Fees kept by the exchange rather than diverted to the insurance fund, since startup
+	gameConfig          *config.GameConfig
+	insuranceFund       InsuranceFund
+	fundingSource       FundingSource
+	indexSource         IndexSource
+	correlationID       uuid.UUID                   // Incoming order the engine is working on, tagged on log lines
+	replacingOrderID    uuid.UUID                   // Resting order whose margin a cancel-replace's replacement takes over
+	mmp                 map[uuid.UUID]*mmpState     // Market-maker protection by trader
+	stopOrders          map[uuid.UUID]*domain.Order // Untriggered stop orders (not on the book)
+	ocoSiblings         map[uuid.UUID]*domain.Order // OCO leg ID -> its open sibling
+	oiCacheMu           sync.Mutex
+	oiCache             map[string]*domain.OISnapshot // Reconstructed historical OI by instrument and time
+	openCandles         map[string]*candleBucket      // In-progress candles by instrument and interval, not yet saved
+	slo                 latencyWatcher                // Match latency samples and SLO alerting
+	statsCacheMu        sync.Mutex
+	statsCache          map[string]*dayTradeStats // 24h trade stats read from the database, by instrument
+
+	// Background writers and shutdown coordination
+	stopCh       chan struct{}
+	wg           sync.WaitGroup
+	shuttingDown bool
 }
 
 // NewMatchingEngine creates a new matching engine
@@ -43,8 +100,18 @@ func NewMatchingEngine() *MatchingEngine {
 		books:        make(map[string]*OrderBook),
 		positions:    make(map[string]*domain.Position),
 		traders:      make(map[uuid.UUID]*domain.Trader),
+		usernames:    make(map[string]uuid.UUID),
 		recentTrades: make([]*domain.Trade, 0),
 		liquidations: make([]*domain.Liquidation, 0),
+		marketState:  domain.MarketStateOpen, // R.index trades 24/7
+		mmp:          make(map[uuid.UUID]*mmpState),
+		stopOrders:   make(map[uuid.UUID]*domain.Order),
+		ocoSiblings:  make(map[uuid.UUID]*domain.Order),
+		oiCache:      make(map[string]*domain.OISnapshot),
+		openCandles:  make(map[string]*candleBucket),
+		statsCache:   make(map[string]*dayTradeStats),
+		stopCh:       make(chan struct{}),
+		now:          time.Now,
 	}
 }
 
@@ -69,46 +136,92 @@ func (me *MatchingEngine) LoadFromDatabase() error {
 	}
 	for _, t := range traders {
 		me.traders[t.ID] = t
+		me.usernames[t.Username] = t.ID
 	}
-	log.Printf("Loaded %d traders from database", len(traders))
+	slog.Info("Loaded traders from database", "count", len(traders))
 
-	// Load positions for R.index
-	positions, err := me.db.GetAllPositions("R.index")
-	if err != nil {
-		return fmt.Errorf("loading positions: %w", err)
-	}
-	for _, p := range positions {
-		posKey := fmt.Sprintf("%s:%s", p.TraderID, p.Instrument)
-		me.positions[posKey] = p
+	// Load positions for every registered instrument
+	for _, instrument := range me.instruments {
+		positions, err := me.db.GetAllPositions(instrument)
+		if err != nil {
+			return fmt.Errorf("loading %s positions: %w", instrument, err)
+		}
+		for _, p := range positions {
+			posKey := fmt.Sprintf("%s:%s", p.TraderID, p.Instrument)
+			me.positions[posKey] = p
+		}
+		slog.Info("Loaded positions from database", "instrument", instrument, "count", len(positions))
 	}
-	log.Printf("Loaded %d positions from database", len(positions))
 
-	// Load recent trades
-	trades, err := me.db.GetRecentTrades("R.index", 1000)
-	if err != nil {
-		return fmt.Errorf("loading trades: %w", err)
+	// Load recent trades, from the saved ring when it is current. The
+	// in-memory history is shared by all instruments, newest first.
+	// The ring may hold less than the database, so it counts as trimmed.
+	maxTrades := me.maxRecentTrades()
+	if trades, ok := me.loadTradeRingLocked(); ok {
+		if len(trades) > maxTrades {
+			trades = trades[:maxTrades]
+		}
+		me.recentTrades = trades
+		me.tradesTrimmed = true
+		slog.Info("Loaded trades from trade ring", "count", len(trades))
+	} else {
+		var trades []*domain.Trade
+		for _, instrument := range me.instruments {
+			loaded, err := me.db.GetRecentTrades(instrument, maxTrades)
+			if err != nil {
+				return fmt.Errorf("loading %s trades: %w", instrument, err)
+			}
+			if len(loaded) >= maxTrades {
+				me.tradesTrimmed = true
+			}
+			trades = append(trades, loaded...)
+		}
+		sort.SliceStable(trades, func(i, j int) bool {
+			return trades[i].Timestamp.After(trades[j].Timestamp)
+		})
+		if len(trades) > maxTrades {
+			trades = trades[:maxTrades]
+			me.tradesTrimmed = true
+		}
+		me.recentTrades = trades
+		slog.Info("Loaded trades from database", "count", len(trades))
 	}
-	me.recentTrades = trades
-	log.Printf("Loaded %d trades from database", len(trades))
 
 	// Load recent liquidations
-	liquidations, err := me.db.GetRecentLiquidations("R.index", 100)
-	if err != nil {
-		return fmt.Errorf("loading liquidations: %w", err)
+	maxLiquidations := me.maxRecentLiquidations()
+	var liquidations []*domain.Liquidation
+	for _, instrument := range me.instruments {
+		loaded, err := me.db.GetRecentLiquidations(instrument, maxLiquidations)
+		if err != nil {
+			return fmt.Errorf("loading %s liquidations: %w", instrument, err)
+		}
+		liquidations = append(liquidations, loaded...)
+	}
+	sort.SliceStable(liquidations, func(i, j int) bool {
+		return liquidations[i].Timestamp.After(liquidations[j].Timestamp)
+	})
+	if len(liquidations) > maxLiquidations {
+		liquidations = liquidations[:maxLiquidations]
 	}
 	me.liquidations = liquidations
-	log.Printf("Loaded %d liquidations from database", len(liquidations))
+	slog.Info("Loaded liquidations from database", "count", len(liquidations))
 
-	// Load open orders and rebuild order book
-	orders, err := me.db.GetOpenOrders("R.index")
-	if err != nil {
-		return fmt.Errorf("loading orders: %w", err)
-	}
-	if book, exists := me.books["R.index"]; exists {
+	// Load open orders and rebuild each order book
+	for _, instrument := range me.instruments {
+		orders, err := me.db.GetOpenOrders(instrument)
+		if err != nil {
+			return fmt.Errorf("loading %s orders: %w", instrument, err)
+		}
+		book := me.books[instrument]
 		for _, order := range orders {
-			book.AddOrder(order)
+			if order.Type == domain.OrderTypeStop {
+				me.stopOrders[order.ID] = order
+			} else {
+				book.AddOrder(order)
+			}
 		}
-		log.Printf("Loaded %d open orders from database", len(orders))
+		me.relinkOCO(orders)
+		slog.Info("Loaded open orders from database", "instrument", instrument, "count", len(orders))
 	}
 
 	return nil
@@ -119,22 +232,31 @@ func (me *MatchingEngine) RegisterInstrument(instrument string) {
 	me.mu.Lock()
 	defer me.mu.Unlock()
 	if _, exists := me.books[instrument]; !exists {
-		me.books[instrument] = NewOrderBook(instrument)
+		book := NewOrderBook(instrument)
+		book.onChange = me.notifyBookLevel
+		me.books[instrument] = book
+		me.instruments = append(me.instruments, instrument)
 	}
 }
 
-// RegisterTrader adds a trader to the system
-func (me *MatchingEngine) RegisterTrader(trader *domain.Trader) {
+// RegisterTrader adds a trader to the system, or returns ErrUsernameTaken if
+// another trader already has its username
+func (me *MatchingEngine) RegisterTrader(trader *domain.Trader) error {
 	me.mu.Lock()
 	defer me.mu.Unlock()
+	if id, exists := me.usernames[trader.Username]; exists && id != trader.ID {
+		return ErrUsernameTaken
+	}
 	me.traders[trader.ID] = trader
+	me.usernames[trader.Username] = trader.ID
 
 	// Persist to database
 	if me.db != nil {
 		if err := me.db.SaveTrader(trader); err != nil {
-			log.Printf("Error saving trader to database: %v", err)
+			slog.Error("Error saving trader to database", "error", err)
 		}
 	}
+	return nil
 }
 
 // OnTrade registers a trade handler
@@ -142,35 +264,338 @@ func (me *MatchingEngine) OnTrade(handler TradeHandler) {
 	me.tradeHandlers = append(me.tradeHandlers, handler)
 }
 
+// OnPositionUpdate registers a position update handler
+func (me *MatchingEngine) OnPositionUpdate(handler PositionHandler) {
+	me.positionHandlers = append(me.positionHandlers, handler)
+}
+
 // OnOrderUpdate registers an order update handler
 func (me *MatchingEngine) OnOrderUpdate(handler OrderHandler) {
 	me.orderHandlers = append(me.orderHandlers, handler)
 }
 
+// OnBookLevelChange registers a handler for changes to a price level's
+// displayed size. Handlers run under the engine lock, in seq order.
+func (me *MatchingEngine) OnBookLevelChange(handler BookLevelHandler) {
+	me.bookHandlers = append(me.bookHandlers, handler)
+}
+
+// notifyBookLevel passes a level update to the book level handlers
+func (me *MatchingEngine) notifyBookLevel(update *domain.BookLevelUpdate) {
+	for _, handler := range me.bookHandlers {
+		handler(update)
+	}
+}
+
+// OnMarketStateChange registers a market state change handler
+func (me *MatchingEngine) OnMarketStateChange(handler MarketStateHandler) {
+	me.marketStateHandlers = append(me.marketStateHandlers, handler)
+}
+
+// SetMarketState transitions the market to a new phase and notifies handlers
+func (me *MatchingEngine) SetMarketState(state domain.MarketState, reason string) error {
+	if !state.IsValid() {
+		return fmt.Errorf("unknown market state: %s", state)
+	}
+
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	me.setMarketStateLocked(state, reason)
+	return nil
+}
+
+// setMarketStateLocked moves the market to a phase, notifying handlers if
+// it changed. Caller must hold me.mu.
+func (me *MatchingEngine) setMarketStateLocked(state domain.MarketState, reason string) {
+	if me.marketState == state {
+		return
+	}
+
+	change := &domain.MarketStateChange{
+		State:         state,
+		PreviousState: me.marketState,
+		Reason:        reason,
+		Timestamp:     time.Now(),
+	}
+	me.marketState = state
+	me.marketStateReason = reason
+	slog.Info("Market state changed", "from", change.PreviousState, "to", state, "reason", reason)
+
+	for _, handler := range me.marketStateHandlers {
+		handler(change)
+	}
+}
+
+// GetMarketState returns the current market phase and the reason it was entered
+func (me *MatchingEngine) GetMarketState() (domain.MarketState, string) {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+	return me.marketState, me.marketStateReason
+}
+
 // SubmitOrder processes a new order through the matching engine
 func (me *MatchingEngine) SubmitOrder(order *domain.Order) ([]*domain.Trade, error) {
+	defer me.observeMatchLatency(time.Now())
 	me.mu.Lock()
 	defer me.mu.Unlock()
 
+	return me.submitOrderLocked(order)
+}
+
+// validateOrderLocked checks an incoming order and normalizes its size and
+// leverage, returning the book it would trade on. Caller must hold me.mu.
+func (me *MatchingEngine) validateOrderLocked(order *domain.Order) (*OrderBook, error) {
+	if me.shuttingDown {
+		return nil, fmt.Errorf("engine is shutting down: new orders are not accepted")
+	}
+	if !me.marketState.AcceptsReducingOrders() {
+		return nil, fmt.Errorf("market is %s: new orders are not accepted", me.marketState)
+	}
+
 	book, exists := me.books[order.Instrument]
 	if !exists {
 		return nil, fmt.Errorf("unknown instrument: %s", order.Instrument)
 	}
 
-	if _, exists := me.traders[order.TraderID]; !exists {
+	trader, exists := me.traders[order.TraderID]
+	if !exists {
 		return nil, fmt.Errorf("unknown trader: %s", order.TraderID)
 	}
 
-	order.ID = uuid.New()
+	if !order.Type.IsValid() {
+		return nil, fmt.Errorf("invalid order type: %s", order.Type)
+	}
+	if order.Type == domain.OrderTypeStop && !order.StopPrice.IsPositive() {
+		return nil, fmt.Errorf("stop orders require a positive stop_price")
+	}
+
+	if err := me.validatePrice(order); err != nil {
+		return nil, err
+	}
+	if err := me.checkStaleness(order); err != nil {
+		return nil, err
+	}
+
+	// A tripped market maker must reset protection before quoting again
+	if order.Type == domain.OrderTypeLimit && me.mmpFrozen(order.TraderID) {
+		return nil, fmt.Errorf("market-maker protection triggered: reset required before quoting")
+	}
+
+	// Keep sizes on lot boundaries so fills and positions stay clean
+	if me.instrumentConfig != nil {
+		order.Size = me.instrumentConfig.RoundToLot(order.Size)
+		if !order.Size.IsPositive() {
+			return nil, fmt.Errorf("order size rounds to zero at lot size %s", me.instrumentConfig.LotSize)
+		}
+	}
+	if err := me.capReduceOnlyLocked(order); err != nil {
+		return nil, err
+	}
+	if err := me.checkFundingFreezeLocked(order); err != nil {
+		return nil, err
+	}
+	if err := me.validateMinSize(order); err != nil {
+		return nil, err
+	}
+	if err := me.validateDisplaySize(order); err != nil {
+		return nil, err
+	}
+	if err := checkPostOnly(book, order); err != nil {
+		return nil, err
+	}
+	if err := validateTimeInForce(order); err != nil {
+		return nil, err
+	}
+	if err := validateSlippage(order); err != nil {
+		return nil, err
+	}
+	if !order.SelfTradePrevention.IsValid() {
+		return nil, fmt.Errorf("invalid self_trade_prevention: %s", order.SelfTradePrevention)
+	}
+	order.Leverage = domain.NormalizeLeverage(order.Leverage)
+	if maxLeverage := me.MaxLeverage(trader.Type); maxLeverage > 0 && order.Leverage > maxLeverage {
+		return nil, fmt.Errorf("leverage %dx exceeds the %dx maximum for %s traders", order.Leverage, maxLeverage, trader.Type)
+	}
+	if err := me.checkRiskTierLocked(order); err != nil {
+		return nil, err
+	}
+	if err := me.checkMarginLocked(trader, order); err != nil {
+		return nil, err
+	}
+
+	return book, nil
+}
+
+// validateDisplaySize checks an iceberg order's displayed slice: limit orders
+// only, positive, no larger than the order and on the lot grid. A display size
+// equal to the order size is an ordinary order.
+func (me *MatchingEngine) validateDisplaySize(order *domain.Order) error {
+	if order.DisplaySize.IsZero() {
+		return nil
+	}
+	if order.Type != domain.OrderTypeLimit {
+		return fmt.Errorf("display_size is only supported on limit orders")
+	}
+	if !order.DisplaySize.IsPositive() || order.DisplaySize.GreaterThan(order.Size) {
+		return fmt.Errorf("display_size must be positive and no larger than size")
+	}
+	if me.instrumentConfig != nil && !me.instrumentConfig.RoundToLot(order.DisplaySize).Equal(order.DisplaySize) {
+		return fmt.Errorf("display_size must be a multiple of lot size %s", me.instrumentConfig.LotSize)
+	}
+	return nil
+}
+
+// checkPostOnly refuses a post-only order that would trade on arrival: a buy
+// priced at or above the best ask, or a sell at or below the best bid. It
+// runs before anything is matched, so a refused order never partially fills.
+func checkPostOnly(book *OrderBook, order *domain.Order) error {
+	if !order.PostOnly {
+		return nil
+	}
+	if order.Type != domain.OrderTypeLimit {
+		return fmt.Errorf("post_only is only supported on limit orders")
+	}
+	if order.Side == domain.SideBuy {
+		if bestAsk, _, ok := book.BestAsk(); ok && order.Price.GreaterThanOrEqual(bestAsk) {
+			return fmt.Errorf("post-only buy at %s would take liquidity: best ask is %s", order.Price, bestAsk)
+		}
+	} else if bestBid, _, ok := book.BestBid(); ok && order.Price.LessThanOrEqual(bestBid) {
+		return fmt.Errorf("post-only sell at %s would take liquidity: best bid is %s", order.Price, bestBid)
+	}
+	return nil
+}
+
+// validateMinSize rejects orders smaller than the instrument's minimum size,
+// which would only clutter the book. An order that just reduces the trader's
+// position is exempt, so a dust position left by lot rounding can still be
+// closed.
+func (me *MatchingEngine) validateMinSize(order *domain.Order) error {
+	if me.instrumentConfig == nil || !me.instrumentConfig.MinOrderSize.IsPositive() {
+		return nil
+	}
+	minSize := me.instrumentConfig.MinOrderSize
+	if !order.Size.LessThan(minSize) {
+		return nil
+	}
+	if pos, exists := me.positions[fmt.Sprintf("%s:%s", order.TraderID, order.Instrument)]; exists {
+		reduces := (pos.IsLong() && order.Side == domain.SideSell) || (pos.IsShort() && order.Side == domain.SideBuy)
+		if reduces && order.Size.LessThanOrEqual(pos.Size.Abs()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("order size %s is below the minimum order size %s", order.Size, minSize)
+}
+
+// validatePrice rejects limit and stop prices that are not on the tick grid.
+// Every path that places an order (submit, replace, OCO legs) goes through
+// validateOrderLocked, so none can put a sub-tick price on the book.
+func (me *MatchingEngine) validatePrice(order *domain.Order) error {
+	if me.instrumentConfig == nil {
+		return nil
+	}
+	tick := me.instrumentConfig.TickSize
+	if order.Type == domain.OrderTypeLimit && !me.instrumentConfig.OnTick(order.Price) {
+		return fmt.Errorf("price %s is not a multiple of tick size %s", order.Price, tick)
+	}
+	if order.Type == domain.OrderTypeStop && !me.instrumentConfig.OnTick(order.StopPrice) {
+		return fmt.Errorf("stop_price %s is not a multiple of tick size %s", order.StopPrice, tick)
+	}
+	return nil
+}
+
+// submitOrderLocked validates, matches and rests an order. Caller must hold me.mu.
+func (me *MatchingEngine) submitOrderLocked(order *domain.Order) ([]*domain.Trade, error) {
+	book, err := me.validateOrderLocked(order)
+	if err != nil {
+		return nil, err
+	}
+	return me.placeOrderLocked(book, order), nil
+}
+
+// placeOrderLocked initializes a validated order and executes it: stop orders
+// wait for their trigger, everything else is matched and (if a limit) rested.
+// Any stops triggered by the resulting trades are then executed too; only the
+// order's own trades are returned. Caller must hold me.mu.
+func (me *MatchingEngine) placeOrderLocked(book *OrderBook, order *domain.Order) []*domain.Trade {
+	if order.ID == uuid.Nil {
+		order.ID = uuid.New()
+	}
 	order.Status = domain.OrderStatusPending
 	order.FilledSize = decimal.Zero
 	order.CreatedAt = time.Now()
 	order.UpdatedAt = time.Now()
+	me.recordOrderTime(order.CreatedAt)
 
-	trades := me.matchOrder(book, order)
+	defer me.correlateLocked(order.ID)()
+	me.loggerLocked().Debug("Order placed", "order_id", order.ID, "trader_id", order.TraderID,
+		"side", order.Side, "type", order.Type, "price", order.Price, "size", order.Size)
 
-	// If order has remaining size and is a limit order, rest it
-	if order.RemainingSize().IsPositive() && order.Type == domain.OrderTypeLimit {
+	var trades []*domain.Trade
+	if order.Type == domain.OrderTypeStop {
+		me.addStopLocked(order)
+	} else {
+		trades = me.executeOrderLocked(book, order)
+	}
+
+	me.processStopsLocked(order.Instrument)
+	return trades
+}
+
+// executeOrderLocked matches an order, rests a limit remainder and runs the
+// deferred follow-up actions. Caller must hold me.mu.
+func (me *MatchingEngine) executeOrderLocked(book *OrderBook, order *domain.Order) []*domain.Trade {
+	// The position a reduce-only stop was placed against may have shrunk or
+	// gone while it waited for its trigger
+	if err := me.capReduceOnlyLocked(order); err != nil {
+		order.Status = domain.OrderStatusCancelled
+		me.loggerLocked().Debug("Reduce-only order cancelled", "order_id", order.ID, "trader_id", order.TraderID, "reason", err)
+		for _, handler := range me.orderHandlers {
+			handler(order)
+		}
+		return nil
+	}
+
+	// A fill-or-kill order that cannot fill in full is cancelled untouched
+	if order.TimeInForce == domain.TimeInForceFOK && !me.canFillLocked(book, order) {
+		order.Status = domain.OrderStatusCancelled
+		me.loggerLocked().Debug("FOK order killed", "order_id", order.ID, "trader_id", order.TraderID, "size", order.RemainingSize())
+		for _, handler := range me.orderHandlers {
+			handler(order)
+		}
+		return nil
+	}
+
+	result := me.matchOrder(book, order)
+	trades := result.trades
+
+	// Pull the trader's own resting orders before anything of this order can
+	// rest against them
+	me.cancelSelfTradesLocked(order, result.selfTrades)
+
+	// If matching hit the work bound, cancel the remainder rather than resting
+	// it - it may still cross the book.
+	if result.capped {
+		order.WorkCapped = true
+		order.Status = domain.OrderStatusCancelled
+		me.loggerLocked().Debug("Order work-capped, remainder cancelled",
+			"order_id", order.ID, "trades", len(trades), "remaining", order.RemainingSize())
+	} else if result.slipped {
+		// A market order that ran out of book within its band is not filled
+		// at whatever price is left
+		order.SlippageCapped = true
+		order.Status = domain.OrderStatusCancelled
+		me.loggerLocked().Debug("Order reached its slippage band, remainder cancelled",
+			"order_id", order.ID, "trades", len(trades), "remaining", order.RemainingSize())
+	} else if result.selfTraded {
+		order.Status = domain.OrderStatusCancelled
+		me.loggerLocked().Debug("Order met its trader's own resting order, remainder cancelled",
+			"order_id", order.ID, "trades", len(trades), "remaining", order.RemainingSize())
+	} else if order.RemainingSize().IsPositive() && order.TimeInForce.Immediate() {
+		// IOC (or a FOK cut short) never rests: the remainder is cancelled
+		order.Status = domain.OrderStatusCancelled
+	} else if order.RemainingSize().IsPositive() && order.Type == domain.OrderTypeLimit {
+		// If order has remaining size and is a limit order, rest it
 		book.AddOrder(order)
 		order.Status = domain.OrderStatusPartial
 		if order.FilledSize.IsZero() {
@@ -179,11 +604,32 @@ func (me *MatchingEngine) SubmitOrder(order *domain.Order) ([]*domain.Trade, err
 		// Persist resting order
 		if me.db != nil {
 			if err := me.db.SaveOrder(order); err != nil {
-				log.Printf("Error saving order to database: %v", err)
+				slog.Error("Error saving order to database", "order_id", order.ID, "error", err)
 			}
 		}
 	} else if order.RemainingSize().IsZero() {
 		order.Status = domain.OrderStatusFilled
+	} else if order.Type == domain.OrderTypeStop {
+		// A triggered stop executes as a market order and never rests
+		order.Status = domain.OrderStatusCancelled
+	}
+
+	// An OCO leg that trades cancels its sibling
+	if len(trades) > 0 {
+		if sibling := me.unlinkOCO(order); sibling != nil {
+			result.ocoCancels = append(result.ocoCancels, sibling)
+		}
+	}
+
+	// Positions changed by this match may leave resting reduce-only orders
+	// larger than what they can still close
+	trimmed := map[uuid.UUID]bool{order.TraderID: true}
+	me.trimReduceOnlyLocked(order.TraderID, order.Instrument)
+	for _, traderID := range result.makers {
+		if !trimmed[traderID] {
+			trimmed[traderID] = true
+			me.trimReduceOnlyLocked(traderID, order.Instrument)
+		}
 	}
 
 	// Notify handlers
@@ -191,58 +637,316 @@ func (me *MatchingEngine) SubmitOrder(order *domain.Order) ([]*domain.Trade, err
 		handler(order)
 	}
 
-	return trades, nil
+	for _, sibling := range result.ocoCancels {
+		me.cancelIfOpenLocked(sibling)
+	}
+
+	// Pull quotes of makers whose protection tripped during this match
+	for _, traderID := range result.mmpTripped {
+		me.tripMMP(traderID)
+	}
+
+	return trades
 }
 
-// matchOrder attempts to match an incoming order against the book
-func (me *MatchingEngine) matchOrder(book *OrderBook, order *domain.Order) []*domain.Trade {
-	var trades []*domain.Trade
-	var matchLevels []*priceLevel
+// SetInstrumentConfig sets the instrument configuration (tick, lot, size limits)
+func (me *MatchingEngine) SetInstrumentConfig(cfg *config.RIndexConfig) {
+	me.instrumentConfig = cfg
+}
+
+// SetInsuranceFund sets the live insurance fund, which also receives collected fees
+func (me *MatchingEngine) SetInsuranceFund(fund InsuranceFund) {
+	me.insuranceFund = fund
+}
 
+// insuranceFundBalance returns the live fund balance, or the default seed if no source is set
+func (me *MatchingEngine) insuranceFundBalance() decimal.Decimal {
+	if me.insuranceFund == nil {
+		return decimal.NewFromInt(1000000)
+	}
+	return me.insuranceFund.GetInsuranceFund()
+}
+
+// SetFeeConfig sets the trading fee schedule (no fees are charged if unset)
+func (me *MatchingEngine) SetFeeConfig(cfg *config.FeeConfig) {
+	me.feeConfig = cfg
+}
+
+// feeRate picks the fee rate for one side of a trade from its role and position effect
+func (me *MatchingEngine) feeRate(isTaker bool, effect domain.PositionEffect) decimal.Decimal {
+	if me.feeConfig == nil {
+		return decimal.Zero
+	}
+	switch {
+	case effect == domain.EffectLiquidation:
+		return me.feeConfig.LiquidationRate
+	case !isTaker:
+		return me.feeConfig.MakerRate
+	case effect == domain.EffectClose:
+		return me.feeConfig.TakerCloseRate
+	default:
+		return me.feeConfig.TakerOpenRate
+	}
+}
+
+// capRebate limits a maker rebate (a negative maker rate) to the taker's rate
+// on the same trade, so a rebate is always paid out of the fee it came with
+func capRebate(makerRate, takerRate decimal.Decimal) decimal.Decimal {
+	return decimal.Max(makerRate, takerRate.Neg())
+}
+
+// chargeFee deducts a fee from a trader's balance (a negative fee credits it)
+func (me *MatchingEngine) chargeFee(traderID uuid.UUID, fee decimal.Decimal) {
+	if fee.IsZero() {
+		return
+	}
+	if trader, ok := me.traders[traderID]; ok {
+		trader.Balance = trader.Balance.Sub(fee)
+	}
+}
+
+// creditFees diverts the configured share of collected fees into the
+// insurance fund until it reaches its target. The remainder is exchange
+// revenue. Caller must hold me.mu.
+func (me *MatchingEngine) creditFees(amount decimal.Decimal, event *domain.InsuranceFundEvent) {
+	if me.insuranceFund == nil || !amount.IsPositive() {
+		return
+	}
+
+	diverted := amount
+	if fc := me.feeConfig; fc != nil {
+		diverted = amount.Mul(fc.InsuranceFundShare)
+		if fc.InsuranceFundTarget.IsPositive() {
+			room := fc.InsuranceFundTarget.Sub(me.insuranceFund.GetInsuranceFund())
+			diverted = decimal.Max(decimal.Zero, decimal.Min(diverted, room))
+		}
+	}
+	me.feeRevenue = me.feeRevenue.Add(amount.Sub(diverted))
+	if !diverted.IsPositive() {
+		return
+	}
+
+	event.Cause = domain.InsuranceFundFee
+	event.Delta = diverted
+	if err := me.insuranceFund.ApplyInsuranceFundChange(event); err != nil {
+		slog.Error("Error crediting fees to insurance fund", "error", err)
+	}
+}
+
+// SetGameConfig sets the starting balances and per trader type limits
+func (me *MatchingEngine) SetGameConfig(cfg *config.GameConfig) {
+	me.gameConfig = cfg
+}
+
+// StartingBalance returns the balance a new trader of the given type receives
+func (me *MatchingEngine) StartingBalance(traderType domain.TraderType) decimal.Decimal {
+	if me.gameConfig == nil {
+		return decimal.NewFromInt(10000)
+	}
+	if tt, ok := me.gameConfig.TraderTypes[string(traderType)]; ok && tt.StartingBalance.IsPositive() {
+		return tt.StartingBalance
+	}
+	return me.gameConfig.StartingBalance
+}
+
+// MaxLeverage returns the highest leverage a trader of the given type may use
+// (0 = no cap configured)
+func (me *MatchingEngine) MaxLeverage(traderType domain.TraderType) int {
+	if me.gameConfig != nil {
+		if tt, ok := me.gameConfig.TraderTypes[string(traderType)]; ok && tt.MaxLeverage > 0 {
+			return tt.MaxLeverage
+		}
+	}
+	if me.instrumentConfig != nil {
+		return me.instrumentConfig.MaxLeverage
+	}
+	return 0
+}
+
+// SetEngineConfig sets the matching engine configuration. Entry prices,
+// liquidation prices and margins round to its division precision explicitly;
+// it is also applied to the process-wide decimal.DivisionPrecision for the
+// rates and averages divided anywhere else.
+func (me *MatchingEngine) SetEngineConfig(cfg *config.EngineConfig) {
+	me.engineConfig = cfg
+	precision := cfg.DivisionPrecision
+	if precision == 0 {
+		precision = config.DefaultDivisionPrecision
+	}
+	decimal.DivisionPrecision = precision
+}
+
+// divisionPlaces returns the decimal places entry prices, liquidation prices
+// and margins are rounded to: engine.division_precision, and never fewer
+// than the instrument's tick plus lot precision
+func (me *MatchingEngine) divisionPlaces() int32 {
+	places := config.DefaultDivisionPrecision
+	if me.engineConfig != nil && me.engineConfig.DivisionPrecision != 0 {
+		places = me.engineConfig.DivisionPrecision
+	}
+	if me.instrumentConfig != nil {
+		places = max(places, me.instrumentConfig.MinDivisionPrecision())
+	}
+	return int32(places)
+}
+
+// SetClock replaces the clock used to judge order staleness
+func (me *MatchingEngine) SetClock(now func() time.Time) {
+	me.now = now
+}
+
+// checkStaleness rejects a market order whose client send time is older than
+// the configured maximum age, so a lagged client doesn't fill against a book
+// that has moved on. Orders without a send time are not checked.
+func (me *MatchingEngine) checkStaleness(order *domain.Order) error {
+	if order.Type != domain.OrderTypeMarket || order.SentAt == nil || me.engineConfig == nil {
+		return nil
+	}
+	maxAge := time.Duration(me.engineConfig.MaxMarketOrderAgeMs) * time.Millisecond
+	if maxAge <= 0 {
+		return nil
+	}
+	if age := me.now().Sub(*order.SentAt); age > maxAge {
+		return fmt.Errorf("stale market order: sent %s ago, maximum age is %s", age.Round(time.Millisecond), maxAge)
+	}
+	return nil
+}
+
+// matchResult collects the outcome of matching one incoming order. Follow-up
+// actions (MMP trips, OCO sibling cancels) are deferred until matching is done
+// so the book is never modified while it is being walked.
+type matchResult struct {
+	trades     []*domain.Trade
+	capped     bool               // Stopped early at the configured work bound
+	slipped    bool               // Stopped at a level past the market order's slippage band
+	mmpTripped []uuid.UUID        // Makers whose protection tripped
+	ocoCancels []*domain.Order    // Siblings of filled OCO legs, to cancel
+	selfTrades []*domain.Order    // Own resting orders met under cancel-resting, to cancel
+	selfTraded bool               // Matching stopped at an own resting order under cancel-aggressor
+	skip       map[uuid.UUID]bool // Resting orders that must not fill further
+	makers     []uuid.UUID        // Traders whose resting orders were met, to trim their reduce-only orders
+}
+
+// matchBounds returns the configured work bound on a single match (zero = unbounded)
+func (me *MatchingEngine) matchBounds() (maxLevels, maxOrders int) {
+	if me.engineConfig == nil {
+		return 0, 0
+	}
+	return me.engineConfig.MaxMatchLevels, me.engineConfig.MaxMatchOrders
+}
+
+// matchLevelsFor returns a cursor over the opposite-side levels an order can
+// trade against, best price first
+func matchLevelsFor(book *OrderBook, order *domain.Order) *levelCursor {
 	if order.Side == domain.SideBuy {
-		if order.Type == domain.OrderTypeMarket {
+		if !order.HasLimitPrice() {
 			// Market buy matches any ask
-			matchLevels = book.matchableAsks(decimal.New(1, 18)) // Very high price
-		} else {
-			// Limit buy matches asks at or below limit price
-			matchLevels = book.matchableAsks(order.Price)
-		}
-	} else {
-		if order.Type == domain.OrderTypeMarket {
-			// Market sell matches any bid
-			matchLevels = book.matchableBids(decimal.Zero)
-		} else {
-			// Limit sell matches bids at or above limit price
-			matchLevels = book.matchableBids(order.Price)
+			return book.matchableAsks(decimal.New(1, 18)) // Very high price
 		}
+		// Limit buy matches asks at or below limit price
+		return book.matchableAsks(order.Price)
 	}
+	if !order.HasLimitPrice() {
+		// Market sell matches any bid
+		return book.matchableBids(decimal.Zero)
+	}
+	// Limit sell matches bids at or above limit price
+	return book.matchableBids(order.Price)
+}
 
-	for _, level := range matchLevels {
+// matchOrder attempts to match an incoming order against the book, stopping
+// early if the configured work bound (levels or resting orders visited) is reached
+func (me *MatchingEngine) matchOrder(book *OrderBook, order *domain.Order) *matchResult {
+	result := &matchResult{skip: make(map[uuid.UUID]bool)}
+	maxLevels, maxOrders := me.matchBounds()
+	levelsVisited, ordersVisited := 0, 0
+	stp := me.selfTradeMode(order)
+	levels := matchLevelsFor(book, order)
+	band, banded := me.slippageBand(levels.best(), order)
+
+	for level := levels.next(); level != nil; level = levels.next() {
 		if order.RemainingSize().IsZero() {
 			break
 		}
+		if banded && beyondBand(order, level.price, band) {
+			result.slipped = true
+			return result
+		}
+		if maxLevels > 0 && levelsVisited >= maxLevels {
+			result.capped = true
+			return result
+		}
+		levelsVisited++
 
 		curr := level.head
 		for curr != nil && order.RemainingSize().IsPositive() {
+			if maxOrders > 0 && ordersVisited >= maxOrders {
+				result.capped = true
+				return result
+			}
+			ordersVisited++
+
 			restingOrder := curr.order
 
-			// Don't self-trade
-			if restingOrder.TraderID == order.TraderID {
+			// Never self-trade: depending on the mode, match past the trader's
+			// own order, cancel it once matching is done, or stop here
+			if restingOrder.TraderID == order.TraderID && !result.skip[restingOrder.ID] {
+				switch stp {
+				case domain.STPCancelAggressor:
+					result.selfTraded = true
+					return result
+				case domain.STPCancelResting:
+					result.selfTrades = append(result.selfTrades, restingOrder)
+					result.skip[restingOrder.ID] = true
+				}
+			}
+
+			// Don't fill makers whose protection just tripped or OCO legs whose
+			// sibling already filled
+			if restingOrder.TraderID == order.TraderID || me.mmpFrozen(restingOrder.TraderID) || result.skip[restingOrder.ID] {
 				curr = curr.next
 				continue
 			}
 
+			// A resting reduce-only order never fills past its trader's
+			// position; what it can no longer close is trimmed afterwards
+			if restingOrder.ReduceOnly {
+				reducible := me.reducibleLocked(restingOrder)
+				if !reducible.IsPositive() {
+					result.skip[restingOrder.ID] = true
+					result.makers = append(result.makers, restingOrder.TraderID)
+					curr = curr.next
+					continue
+				}
+				if restingOrder.RemainingSize().GreaterThan(reducible) {
+					book.ReduceOrder(restingOrder.ID, restingOrder.RemainingSize().Sub(reducible))
+				}
+			}
+
 			// Calculate fill size
 			fillSize := decimal.Min(order.RemainingSize(), restingOrder.RemainingSize())
 			fillPrice := restingOrder.Price // Price-time priority: resting order's price
 
 			// Create the trade
 			trade := me.createTrade(order, restingOrder, fillPrice, fillSize)
-			trades = append(trades, trade)
+			result.trades = append(result.trades, trade)
+			result.makers = append(result.makers, restingOrder.TraderID)
+			if me.recordMakerFill(restingOrder.TraderID, fillSize) {
+				result.mmpTripped = append(result.mmpTripped, restingOrder.TraderID)
+			}
+			if sibling := me.unlinkOCO(restingOrder); sibling != nil {
+				result.ocoCancels = append(result.ocoCancels, sibling)
+				result.skip[sibling.ID] = true
+			}
 
-			// Update order fill sizes
+			// Update order fill sizes. Re-counting the resting order in its level
+			// replenishes an iceberg's displayed slice from its hidden remainder
+			// (RemoveOrder only subtracts what is still unfilled).
+			level.subSize(restingOrder)
 			order.FilledSize = order.FilledSize.Add(fillSize)
 			restingOrder.FilledSize = restingOrder.FilledSize.Add(fillSize)
+			level.addSize(restingOrder)
+			book.levelChanged(restingOrder.Side, level)
 			order.UpdatedAt = time.Now()
 			restingOrder.UpdatedAt = time.Now()
 
@@ -250,20 +954,18 @@ func (me *MatchingEngine) matchOrder(book *OrderBook, order *domain.Order) []*do
 			if restingOrder.RemainingSize().IsZero() {
 				restingOrder.Status = domain.OrderStatusFilled
 				book.RemoveOrder(restingOrder.ID)
-				// Remove filled order from database
+				// Keep filled order in database for lifecycle stats
 				if me.db != nil {
-					if err := me.db.DeleteOrder(restingOrder.ID); err != nil {
-						log.Printf("Error deleting filled order from database: %v", err)
+					if err := me.db.SaveOrder(restingOrder); err != nil {
+						slog.Error("Error saving filled order to database", "error", err)
 					}
 				}
 			} else {
 				restingOrder.Status = domain.OrderStatusPartial
-				// Update level size
-				level.totalSize = level.totalSize.Sub(fillSize)
 				// Update partial fill in database
 				if me.db != nil {
 					if err := me.db.SaveOrder(restingOrder); err != nil {
-						log.Printf("Error updating order in database: %v", err)
+						slog.Error("Error updating order in database", "error", err)
 					}
 				}
 			}
@@ -282,7 +984,7 @@ func (me *MatchingEngine) matchOrder(book *OrderBook, order *domain.Order) []*do
 		}
 	}
 
-	return trades
+	return result
 }
 
 // createTrade creates a trade record with full transparency
@@ -305,8 +1007,8 @@ func (me *MatchingEngine) createTrade(aggressor, resting *domain.Order, price, s
 	sellerEffect := me.determinePositionEffect(sellerOrder.TraderID, sellerOrder.Instrument, size.Neg())
 
 	// Update positions
-	buyerNewPos := me.updatePosition(buyerOrder.TraderID, buyerOrder.Instrument, size, price)
-	sellerNewPos := me.updatePosition(sellerOrder.TraderID, sellerOrder.Instrument, size.Neg(), price)
+	buyerNewPos, buyerPnL := me.updatePosition(buyerOrder.TraderID, buyerOrder.Instrument, size, price, buyerOrder.Leverage)
+	sellerNewPos, sellerPnL := me.updatePosition(sellerOrder.TraderID, sellerOrder.Instrument, size.Neg(), price, sellerOrder.Leverage)
 
 	trade := &domain.Trade{
 		ID:                uuid.New(),
@@ -325,8 +1027,25 @@ func (me *MatchingEngine) createTrade(aggressor, resting *domain.Order, price, s
 		BuyerNewPosition:  buyerNewPos,
 		SellerNewPosition: sellerNewPos,
 		AggressorSide:     aggressorSide,
+		BuyerRealizedPnL:  buyerPnL,
+		SellerRealizedPnL: sellerPnL,
 	}
 
+	// Charge fees by role and position effect (closing is cheaper than opening)
+	notional := price.Mul(size)
+	trade.BuyerFeeRate = me.feeRate(aggressorSide == domain.SideBuy, buyerEffect)
+	trade.SellerFeeRate = me.feeRate(aggressorSide == domain.SideSell, sellerEffect)
+	if aggressorSide == domain.SideBuy {
+		trade.SellerFeeRate = capRebate(trade.SellerFeeRate, trade.BuyerFeeRate)
+	} else {
+		trade.BuyerFeeRate = capRebate(trade.BuyerFeeRate, trade.SellerFeeRate)
+	}
+	trade.BuyerFee = notional.Mul(trade.BuyerFeeRate)
+	trade.SellerFee = notional.Mul(trade.SellerFeeRate)
+	me.chargeFee(buyerOrder.TraderID, trade.BuyerFee)
+	me.chargeFee(sellerOrder.TraderID, trade.SellerFee)
+	me.creditFees(trade.BuyerFee.Add(trade.SellerFee), &domain.InsuranceFundEvent{TradeID: &trade.ID})
+
 	// Update trader stats
 	if buyer, ok := me.traders[buyerOrder.TraderID]; ok {
 		buyer.TradeCount++
@@ -335,26 +1054,31 @@ func (me *MatchingEngine) createTrade(aggressor, resting *domain.Order, price, s
 		seller.TradeCount++
 	}
 
-	// Store trade in history (keep last 1000)
+	// Store trade in history (keep the last engine.max_recent_trades)
 	me.recentTrades = append([]*domain.Trade{trade}, me.recentTrades...)
-	if len(me.recentTrades) > 1000 {
-		me.recentTrades = me.recentTrades[:1000]
+	if limit := me.maxRecentTrades(); len(me.recentTrades) > limit {
+		me.recentTrades = me.recentTrades[:limit]
+		me.tradesTrimmed = true
 	}
+	me.rollCandlesLocked(trade)
+	me.loggerLocked().Debug("Trade", "trade_id", trade.ID, "price", trade.Price, "size", trade.Size,
+		"buyer_id", trade.BuyerID, "seller_id", trade.SellerID,
+		"buyer_order_id", trade.BuyerOrderID, "seller_order_id", trade.SellerOrderID)
 
 	// Persist to database
 	if me.db != nil {
 		if err := me.db.SaveTrade(trade); err != nil {
-			log.Printf("Error saving trade to database: %v", err)
+			slog.Error("Error saving trade to database", "trade_id", trade.ID, "error", err)
 		}
 		// Save updated trader stats
 		if buyer, ok := me.traders[buyerOrder.TraderID]; ok {
 			if err := me.db.SaveTrader(buyer); err != nil {
-				log.Printf("Error saving buyer to database: %v", err)
+				slog.Error("Error saving buyer to database", "error", err)
 			}
 		}
 		if seller, ok := me.traders[sellerOrder.TraderID]; ok {
 			if err := me.db.SaveTrader(seller); err != nil {
-				log.Printf("Error saving seller to database: %v", err)
+				slog.Error("Error saving seller to database", "error", err)
 			}
 		}
 	}
@@ -381,8 +1105,11 @@ func (me *MatchingEngine) determinePositionEffect(traderID uuid.UUID, instrument
 	return domain.EffectClose
 }
 
-// updatePosition updates a trader's position and returns new size
-func (me *MatchingEngine) updatePosition(traderID uuid.UUID, instrument string, sizeChange, price decimal.Decimal) decimal.Decimal {
+// updatePosition updates a trader's position and returns the new size and the
+// P&L realized by any part of sizeChange that reduced the position. Margin for
+// the part that opened exposure is taken from the trader's balance at the
+// fill's leverage, and margin for the part that closed is released with its P&L.
+func (me *MatchingEngine) updatePosition(traderID uuid.UUID, instrument string, sizeChange, price decimal.Decimal, leverage int) (decimal.Decimal, decimal.Decimal) {
 	posKey := fmt.Sprintf("%s:%s", traderID, instrument)
 	pos, exists := me.positions[posKey]
 
@@ -401,27 +1128,34 @@ func (me *MatchingEngine) updatePosition(traderID uuid.UUID, instrument string,
 
 	oldSize := pos.Size
 	newSize := oldSize.Add(sizeChange)
+	realized := decimal.Zero
 
 	// Calculate new entry price (weighted average for opening, unchanged for closing)
 	if oldSize.IsZero() {
 		pos.EntryPrice = price
 	} else if (oldSize.IsPositive() && sizeChange.IsPositive()) ||
 		(oldSize.IsNegative() && sizeChange.IsNegative()) {
-		// Adding to position - weighted average
+		// Adding to position - weighted average. The quotient is rounded half
+		// up at engine.division_precision, which validation keeps finer than a
+		// tick, so the error from repeated averaging stays far below one tick.
 		totalCost := oldSize.Mul(pos.EntryPrice).Add(sizeChange.Mul(price))
-		pos.EntryPrice = totalCost.Div(newSize)
+		entry, err := domain.SafeDivRound(totalCost, newSize, me.divisionPlaces())
+		if err != nil {
+			slog.Error("Error averaging entry price", "trader_id", traderID, "error", err)
+		} else {
+			pos.EntryPrice = entry
+		}
 	} else {
 		// Reducing position - realize P&L
 		closedSize := decimal.Min(oldSize.Abs(), sizeChange.Abs())
 		if oldSize.IsPositive() {
 			// Was long, selling - profit if price > entry
-			pnl := price.Sub(pos.EntryPrice).Mul(closedSize)
-			pos.RealizedPnL = pos.RealizedPnL.Add(pnl)
+			realized = price.Sub(pos.EntryPrice).Mul(closedSize)
 		} else {
 			// Was short, buying - profit if price < entry
-			pnl := pos.EntryPrice.Sub(price).Mul(closedSize)
-			pos.RealizedPnL = pos.RealizedPnL.Add(pnl)
+			realized = pos.EntryPrice.Sub(price).Mul(closedSize)
 		}
+		pos.RealizedPnL = pos.RealizedPnL.Add(realized)
 
 		// If flipping sides, set new entry for the overflow
 		if !newSize.IsZero() && ((oldSize.IsPositive() && newSize.IsNegative()) ||
@@ -430,32 +1164,47 @@ func (me *MatchingEngine) updatePosition(traderID uuid.UUID, instrument string,
 		}
 	}
 
+	me.settleMarginLocked(pos, oldSize, newSize, price, leverage, realized)
+
 	pos.Size = newSize
 	pos.UpdatedAt = time.Now()
 
-	// Calculate liquidation price if position exists
+	// Calculate liquidation price and mark P&L if position exists
+	pos.MarginMode = me.marginModeLocked(traderID)
 	if !newSize.IsZero() {
-		pos.LiquidationPrice = me.calculateLiquidationPrice(pos.EntryPrice, pos.Leverage, newSize.IsPositive())
+		pos.LiquidationPrice = me.liquidationPriceLocked(pos)
+		pos.UnrealizedPnL = pos.ComputeUnrealizedPnL(me.markPriceLocked(instrument))
+	} else {
+		pos.UnrealizedPnL = decimal.Zero
 	}
+	// A cross trader's other positions move with the balance this fill changed
+	me.refreshCrossLiquidationPricesLocked(traderID)
+	me.loggerLocked().Debug("Position updated", "trader_id", traderID, "instrument", instrument,
+		"size", pos.Size, "entry_price", pos.EntryPrice, "realized_pnl", realized)
 
 	// Persist position to database
 	if me.db != nil {
 		if newSize.IsZero() {
 			// Position closed, delete from database
 			if err := me.db.DeletePosition(traderID, instrument); err != nil {
-				log.Printf("Error deleting position from database: %v", err)
+				slog.Error("Error deleting position from database", "trader_id", traderID, "error", err)
 			}
 		} else {
 			if err := me.db.SavePosition(pos); err != nil {
-				log.Printf("Error saving position to database: %v", err)
+				slog.Error("Error saving position to database", "trader_id", traderID, "error", err)
 			}
 		}
 	}
 
-	return newSize
+	for _, handler := range me.positionHandlers {
+		handler(pos)
+	}
+
+	return newSize, realized
 }
 
-// GetPosition returns a trader's position (public - transparency!)
+// GetPosition returns a copy of a trader's position with unrealized P&L at
+// the current mark price (public - transparency!)
 func (me *MatchingEngine) GetPosition(traderID uuid.UUID, instrument string) *domain.Position {
 	me.mu.RLock()
 	defer me.mu.RUnlock()
@@ -465,19 +1214,19 @@ func (me *MatchingEngine) GetPosition(traderID uuid.UUID, instrument string) *do
 	if !exists {
 		return nil
 	}
-	return pos
+	return me.markedPositionLocked(pos)
 }
 
-// GetAllPositions returns all positions for an instrument (transparency!)
+// GetAllPositions returns copies of all positions for an instrument, with
+// unrealized P&L at the current mark price (transparency!)
 func (me *MatchingEngine) GetAllPositions(instrument string) []*domain.Position {
 	me.mu.RLock()
 	defer me.mu.RUnlock()
 
 	var positions []*domain.Position
-	for key, pos := range me.positions {
+	for _, pos := range me.positions {
 		if pos.Instrument == instrument && !pos.Size.IsZero() {
-			_ = key
-			positions = append(positions, pos)
+			positions = append(positions, me.markedPositionLocked(pos))
 		}
 	}
 	return positions
@@ -497,29 +1246,131 @@ func (me *MatchingEngine) GetOrderBook(instrument string, depth int) (*domain.Or
 	return &snapshot, nil
 }
 
-// CancelOrder cancels an existing order
-func (me *MatchingEngine) CancelOrder(orderID uuid.UUID, instrument string) error {
+// GetOrder returns a copy of an order: live from the book or the stop list
+// while it is open, otherwise from the database once it has filled or been
+// cancelled. Returns nil if the order is unknown - taker orders that complete
+// on arrival are never persisted.
+func (me *MatchingEngine) GetOrder(orderID uuid.UUID, instrument string) (*domain.Order, error) {
+	me.mu.RLock()
+	if order, open := me.openOrderLocked(orderID, instrument); open {
+		copied := *order
+		me.mu.RUnlock()
+		return &copied, nil
+	}
+	me.mu.RUnlock()
+
+	if me.db == nil {
+		return nil, nil
+	}
+	order, err := me.db.GetOrder(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("loading order: %w", err)
+	}
+	if order == nil || order.Instrument != instrument {
+		return nil, nil
+	}
+	return order, nil
+}
+
+// GetOpenOrders returns copies of a trader's resting and untriggered stop
+// orders on an instrument, oldest first
+func (me *MatchingEngine) GetOpenOrders(traderID uuid.UUID, instrument string) []*domain.Order {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	open := me.openOrdersLocked(traderID, instrument)
+	orders := make([]*domain.Order, len(open))
+	for i, order := range open {
+		copied := *order
+		orders[i] = &copied
+	}
+	return orders
+}
+
+// GetTraderOrders returns a trader's orders in one status. Open statuses are
+// read live, oldest first; filled and cancelled orders come from the
+// database, newest first, up to limit.
+func (me *MatchingEngine) GetTraderOrders(traderID uuid.UUID, instrument string, status domain.OrderStatus, limit int) ([]*domain.Order, error) {
+	if !status.IsValid() {
+		return nil, fmt.Errorf("unknown order status: %s", status)
+	}
+
+	if status.IsOpen() {
+		orders := make([]*domain.Order, 0)
+		for _, order := range me.GetOpenOrders(traderID, instrument) {
+			if order.Status == status {
+				orders = append(orders, order)
+			}
+		}
+		return orders, nil
+	}
+
+	if me.db == nil {
+		return []*domain.Order{}, nil
+	}
+	orders, err := me.db.GetOrdersByTrader(traderID, instrument, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("loading orders: %w", err)
+	}
+	if orders == nil {
+		orders = []*domain.Order{}
+	}
+	return orders, nil
+}
+
+// CancelOrder cancels one of a trader's resting or stop orders
+func (me *MatchingEngine) CancelOrder(traderID, orderID uuid.UUID, instrument string) error {
 	me.mu.Lock()
 	defer me.mu.Unlock()
 
+	order, exists := me.openOrderLocked(orderID, instrument)
+	if !exists {
+		return fmt.Errorf("order not found: %s", orderID)
+	}
+	if order.TraderID != traderID {
+		return fmt.Errorf("order %s does not belong to trader %s", orderID, traderID)
+	}
+	return me.cancelOrderLocked(orderID, instrument)
+}
+
+// openOrderLocked finds an order resting on an instrument's book or waiting
+// for its stop trigger. Caller must hold me.mu.
+func (me *MatchingEngine) openOrderLocked(orderID uuid.UUID, instrument string) (*domain.Order, bool) {
+	if book, exists := me.books[instrument]; exists {
+		if order, resting := book.GetOrder(orderID); resting {
+			return order, true
+		}
+	}
+	if stop, isStop := me.stopOrders[orderID]; isStop && stop.Instrument == instrument {
+		return stop, true
+	}
+	return nil, false
+}
+
+// cancelOrderLocked removes a resting order from its book. Caller must hold me.mu.
+func (me *MatchingEngine) cancelOrderLocked(orderID uuid.UUID, instrument string) error {
 	book, exists := me.books[instrument]
 	if !exists {
 		return fmt.Errorf("unknown instrument: %s", instrument)
 	}
 
 	order, exists := book.GetOrder(orderID)
-	if !exists {
+	if exists {
+		book.RemoveOrder(orderID)
+	} else if stop, isStop := me.stopOrders[orderID]; isStop && stop.Instrument == instrument {
+		order = stop
+		delete(me.stopOrders, orderID)
+	} else {
 		return fmt.Errorf("order not found: %s", orderID)
 	}
 
-	book.RemoveOrder(orderID)
 	order.Status = domain.OrderStatusCancelled
 	order.UpdatedAt = time.Now()
 
-	// Remove from database
+	// Record cancellation in database (kept for lifecycle stats)
 	if me.db != nil {
-		if err := me.db.DeleteOrder(orderID); err != nil {
-			log.Printf("Error deleting order from database: %v", err)
+		if err := me.db.SaveOrder(order); err != nil {
+			slog.Error("Error saving cancelled order to database", "error", err)
 		}
 	}
 
@@ -527,29 +1378,203 @@ func (me *MatchingEngine) CancelOrder(orderID uuid.UUID, instrument string) erro
 		handler(order)
 	}
 
+	// Cancelling one OCO leg cancels the pair
+	if sibling := me.unlinkOCO(order); sibling != nil {
+		me.cancelIfOpenLocked(sibling)
+	}
+
 	return nil
 }
 
-// GetOpenInterestBreakdown calculates OI stats (the core transparency feature!)
+// ReduceOrder decreases one of a trader's resting orders without losing its
+// queue priority. Reducing by the full remaining size cancels the order.
+func (me *MatchingEngine) ReduceOrder(traderID, orderID uuid.UUID, instrument string, reduceBy decimal.Decimal) (*domain.Order, error) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	book, order, err := me.traderRestingOrderLocked(traderID, orderID, instrument)
+	if err != nil {
+		return nil, err
+	}
+	if err := me.reduceOrderLocked(book, order, reduceBy); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// traderRestingOrderLocked finds one of a trader's orders resting on an
+// instrument's book. Caller must hold me.mu.
+func (me *MatchingEngine) traderRestingOrderLocked(traderID, orderID uuid.UUID, instrument string) (*OrderBook, *domain.Order, error) {
+	book, exists := me.books[instrument]
+	if !exists {
+		return nil, nil, fmt.Errorf("unknown instrument: %s", instrument)
+	}
+
+	order, exists := book.GetOrder(orderID)
+	if !exists {
+		return nil, nil, fmt.Errorf("order not found: %s", orderID)
+	}
+	if order.TraderID != traderID {
+		return nil, nil, fmt.Errorf("order %s does not belong to trader %s", orderID, traderID)
+	}
+	return book, order, nil
+}
+
+// reduceOrderLocked decreases a resting order in place, keeping its queue
+// priority, or cancels it if reduceBy is all that remains. Caller must hold
+// me.mu.
+func (me *MatchingEngine) reduceOrderLocked(book *OrderBook, order *domain.Order, reduceBy decimal.Decimal) error {
+	if !reduceBy.IsPositive() {
+		return fmt.Errorf("reduce amount must be positive")
+	}
+	if me.instrumentConfig != nil && !me.instrumentConfig.RoundToLot(reduceBy).Equal(reduceBy) {
+		return fmt.Errorf("reduce amount must be a multiple of lot size %s", me.instrumentConfig.LotSize)
+	}
+	remaining := order.RemainingSize()
+	if reduceBy.GreaterThan(remaining) {
+		return fmt.Errorf("cannot reduce by %s: only %s remaining", reduceBy, remaining)
+	}
+
+	if reduceBy.Equal(remaining) {
+		return me.cancelOrderLocked(order.ID, order.Instrument)
+	}
+
+	book.ReduceOrder(order.ID, reduceBy)
+	order.UpdatedAt = time.Now()
+
+	if me.db != nil {
+		if err := me.db.SaveOrder(order); err != nil {
+			slog.Error("Error saving reduced order to database", "error", err)
+		}
+	}
+
+	for _, handler := range me.orderHandlers {
+		handler(order)
+	}
+
+	return nil
+}
+
+// CancelReplace atomically cancels a resting order and submits its replacement.
+// Both happen under one lock acquisition, so no observer ever sees the trader
+// with neither or both orders. If the replacement fails validation the
+// original order is left untouched. Replacing one leg of an OCO pair keeps the
+// pair: the replacement, which must be a limit order that may rest, takes the
+// leg's place.
+func (me *MatchingEngine) CancelReplace(orderID uuid.UUID, replacement *domain.Order) ([]*domain.Trade, error) {
+	defer me.observeMatchLatency(time.Now())
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	book, exists := me.books[replacement.Instrument]
+	if !exists {
+		return nil, fmt.Errorf("unknown instrument: %s", replacement.Instrument)
+	}
+
+	existing, exists := book.GetOrder(orderID)
+	if !exists {
+		return nil, fmt.Errorf("order not found: %s", orderID)
+	}
+	if existing.TraderID != replacement.TraderID {
+		return nil, fmt.Errorf("order %s does not belong to trader %s", orderID, replacement.TraderID)
+	}
+
+	_, isOCOLeg := me.ocoSiblings[orderID]
+	if isOCOLeg && (replacement.Type != domain.OrderTypeLimit || replacement.TimeInForce.Immediate()) {
+		return nil, fmt.Errorf("an OCO leg can only be replaced by a limit order that may rest")
+	}
+
+	me.replacingOrderID = orderID
+	_, err := me.validateOrderLocked(replacement)
+	me.replacingOrderID = uuid.Nil
+	if err != nil {
+		return nil, err
+	}
+
+	// A replaced OCO leg hands its link to the replacement instead of
+	// cancelling its sibling
+	sibling := me.unlinkOCO(existing)
+	if err := me.cancelOrderLocked(orderID, replacement.Instrument); err != nil {
+		return nil, err
+	}
+	if sibling != nil {
+		replacement.ID = uuid.New()
+		replacement.OCOGroupID = existing.OCOGroupID
+		me.linkOCO(replacement, sibling)
+	}
+	return me.submitOrderLocked(replacement)
+}
+
+// GetOpenInterestBreakdown calculates OI stats (the core transparency feature!).
+// Average leverage is weighted by position size. The period counters cover
+// the last 24 hours of in-memory trade and liquidation history.
 func (me *MatchingEngine) GetOpenInterestBreakdown(instrument string) *domain.OpenInterestBreakdown {
 	me.mu.RLock()
 	defer me.mu.RUnlock()
 
+	now := time.Now()
 	breakdown := &domain.OpenInterestBreakdown{
 		Instrument: instrument,
-		Timestamp:  time.Now(),
+		Timestamp:  now,
 	}
 
+	var longOI, shortOI, longLeverage, shortLeverage decimal.Decimal
 	for _, pos := range me.positions {
 		if pos.Instrument != instrument || pos.Size.IsZero() {
 			continue
 		}
 
-		if pos.Size.IsPositive() {
+		size := pos.Size.Abs()
+		leverage := decimal.NewFromInt(int64(domain.NormalizeLeverage(pos.Leverage)))
+		if pos.IsLong() {
 			breakdown.LongPositions++
-			breakdown.TotalOI = breakdown.TotalOI.Add(pos.Size)
+			longOI = longOI.Add(size)
+			longLeverage = longLeverage.Add(leverage.Mul(size))
 		} else {
 			breakdown.ShortPositions++
+			shortOI = shortOI.Add(size)
+			shortLeverage = shortLeverage.Add(leverage.Mul(size))
+		}
+	}
+	// Every long is matched by a short, so OI is one side's total
+	breakdown.TotalOI = decimal.Max(longOI, shortOI)
+	if avg, err := domain.SafeDiv(longLeverage, longOI); err == nil {
+		breakdown.AvgLongLeverage = avg
+	}
+	if avg, err := domain.SafeDiv(shortLeverage, shortOI); err == nil {
+		breakdown.AvgShortLeverage = avg
+	}
+
+	since := now.Add(-24 * time.Hour)
+	for _, t := range me.recentTrades {
+		if t.Timestamp.Before(since) {
+			break // Newest first
+		}
+		if t.Instrument != instrument {
+			continue
+		}
+		// The buyer can only open a long or close a short, and the seller the reverse
+		if opensPosition(t.BuyerNewPosition, t.Size) {
+			breakdown.NewLongsOpened++
+		}
+		if closesPosition(t.BuyerNewPosition, t.Size) {
+			breakdown.ShortsClosed++
+		}
+		if opensPosition(t.SellerNewPosition, t.Size.Neg()) {
+			breakdown.NewShortsOpened++
+		}
+		if closesPosition(t.SellerNewPosition, t.Size.Neg()) {
+			breakdown.LongsClosed++
+		}
+	}
+	for _, l := range me.liquidations {
+		if l.Instrument != instrument || l.Effect == domain.EffectADL || l.Timestamp.Before(since) {
+			continue
+		}
+		if l.Side == domain.SideBuy {
+			breakdown.LongsLiquidated++
+		} else {
+			breakdown.ShortsLiquidated++
 		}
 	}
 
@@ -563,6 +1588,62 @@ func (me *MatchingEngine) GetTrader(traderID uuid.UUID) *domain.Trader {
 	return me.traders[traderID]
 }
 
+// GetTraderByUsername returns the trader with a username, or nil
+func (me *MatchingEngine) GetTraderByUsername(username string) *domain.Trader {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+	if id, exists := me.usernames[username]; exists {
+		return me.traders[id]
+	}
+	return nil
+}
+
+// GetTraderByAPIKey returns the trader an API key hash was issued to, or nil
+func (me *MatchingEngine) GetTraderByAPIKey(apiKeyHash string) (*domain.Trader, error) {
+	if apiKeyHash == "" {
+		return nil, nil
+	}
+
+	if me.db != nil {
+		stored, err := me.db.GetTraderByAPIKey(apiKeyHash)
+		if err != nil {
+			return nil, fmt.Errorf("looking up API key: %w", err)
+		}
+		if stored == nil {
+			return nil, nil
+		}
+		return me.GetTrader(stored.ID), nil
+	}
+
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+	for _, t := range me.traders {
+		if t.APIKeyHash == apiKeyHash {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+
+// SetTraderAPIKey stores the hash of a trader's new API key, replacing (and
+// so revoking) any previous key
+func (me *MatchingEngine) SetTraderAPIKey(traderID uuid.UUID, apiKeyHash string) error {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	trader, exists := me.traders[traderID]
+	if !exists {
+		return fmt.Errorf("trader not found: %s", traderID)
+	}
+	if me.db != nil {
+		if err := me.db.UpdateTraderAPIKey(traderID, apiKeyHash); err != nil {
+			return fmt.Errorf("saving API key: %w", err)
+		}
+	}
+	trader.APIKeyHash = apiKeyHash
+	return nil
+}
+
 // GetAllTraders returns all traders (public)
 func (me *MatchingEngine) GetAllTraders() []*domain.Trader {
 	me.mu.RLock()
@@ -626,80 +1707,135 @@ func (me *MatchingEngine) GetRecentLiquidations(instrument string, limit int) []
 	return liqs
 }
 
-// GetCandles returns OHLCV candles for an instrument
+// GetCandles returns the latest OHLCV candles for an instrument, newest
+// first. When the in-memory trades don't reach back limit candles, saved
+// candles fill in from the database.
 func (me *MatchingEngine) GetCandles(instrument string, interval domain.CandleInterval, limit int) []*domain.Candle {
 	me.mu.RLock()
-	defer me.mu.RUnlock()
-
-	// Get interval duration
+	candles := buildCandles(me.recentTrades, instrument, interval, func(*domain.Trade) bool { return true })
 	intervalDuration := getIntervalDuration(interval)
+	end := time.Now()
+	start := truncateToInterval(end.Add(-time.Duration(limit-1)*intervalDuration), intervalDuration)
+	buffered := me.tradesBufferedSinceLocked(start)
+	inProgress := me.openCandlesLocked(instrument, interval, start, end)
+	database := me.db
+	me.mu.RUnlock()
+
+	if database != nil && !buffered {
+		saved, err := database.GetCandles(instrument, interval, start, end, limit)
+		if err != nil {
+			slog.Error("Error loading candles from database", "error", err)
+		} else {
+			candles = mergeCandles(mergeCandles(saved, inProgress), candles)
+		}
+	}
+
+	// Sort by open time descending (newest first)
+	sort.Slice(candles, func(i, j int) bool {
+		return candles[i].OpenTime.After(candles[j].OpenTime)
+	})
 
-	// Group trades by candle period
-	candleMap := make(map[int64]*domain.Candle)
+	// Limit results
+	if len(candles) > limit {
+		candles = candles[:limit]
+	}
 
-	for _, t := range me.recentTrades {
-		if t.Instrument != instrument {
+	return candles
+}
+
+// candleBucket is a candle being built, with the times of the trades its
+// open and close were taken from and the notional its VWAP is taken from
+type candleBucket struct {
+	candle   *domain.Candle
+	openAt   time.Time
+	closeAt  time.Time
+	notional decimal.Decimal
+}
+
+// addVolume adds a trade's size and notional to the candle and updates its VWAP
+func (b *candleBucket) addVolume(price, size decimal.Decimal) {
+	b.candle.Volume = b.candle.Volume.Add(size)
+	b.notional = b.notional.Add(price.Mul(size))
+	if vwap, err := domain.SafeDiv(b.notional, b.candle.Volume); err == nil {
+		b.candle.VWAP = vwap
+	}
+}
+
+// buildCandles groups an instrument's trades that pass include into candles,
+// in no particular order. Open and close come from the earliest and latest
+// trade in each bucket, whatever order the trades are given in.
+func buildCandles(trades []*domain.Trade, instrument string, interval domain.CandleInterval, include func(*domain.Trade) bool) []*domain.Candle {
+	intervalDuration := getIntervalDuration(interval)
+	buckets := make(map[int64]*candleBucket)
+
+	for _, t := range trades {
+		if t.Instrument != instrument || !include(t) {
 			continue
 		}
 
-		// Calculate candle start time (truncate to interval)
 		candleStart := truncateToInterval(t.Timestamp, intervalDuration)
-		candleKey := candleStart.Unix()
-
-		candle, exists := candleMap[candleKey]
+		bucket, exists := buckets[candleStart.Unix()]
 		if !exists {
-			candle = &domain.Candle{
-				Instrument: instrument,
-				Interval:   interval,
-				OpenTime:   candleStart,
-				CloseTime:  candleStart.Add(intervalDuration),
-				Open:       t.Price,
-				High:       t.Price,
-				Low:        t.Price,
-				Close:      t.Price,
-				Volume:     t.Size,
-				TradeCount: 1,
-			}
-			candleMap[candleKey] = candle
-		} else {
-			// Update OHLCV - trades are newest first, so this trade is older
-			candle.Open = t.Price // Keep updating open since we iterate newest->oldest
-			if t.Price.GreaterThan(candle.High) {
-				candle.High = t.Price
+			buckets[candleStart.Unix()] = &candleBucket{
+				candle: &domain.Candle{
+					Instrument: instrument,
+					Interval:   interval,
+					OpenTime:   candleStart,
+					CloseTime:  candleStart.Add(intervalDuration),
+					Open:       t.Price,
+					High:       t.Price,
+					Low:        t.Price,
+					Close:      t.Price,
+					TradeCount: 1,
+				},
+				openAt:  t.Timestamp,
+				closeAt: t.Timestamp,
 			}
-			if t.Price.LessThan(candle.Low) {
-				candle.Low = t.Price
-			}
-			candle.Volume = candle.Volume.Add(t.Size)
-			candle.TradeCount++
+			buckets[candleStart.Unix()].addVolume(t.Price, t.Size)
+			continue
 		}
-	}
 
-	// Convert map to sorted slice (newest first)
-	var candles []*domain.Candle
-	for _, c := range candleMap {
-		candles = append(candles, c)
-	}
-
-	// Sort by open time descending (newest first)
-	for i := 0; i < len(candles)-1; i++ {
-		for j := i + 1; j < len(candles); j++ {
-			if candles[j].OpenTime.After(candles[i].OpenTime) {
-				candles[i], candles[j] = candles[j], candles[i]
-			}
+		// Equal timestamps fall back to the usual newest-first order
+		candle := bucket.candle
+		if !t.Timestamp.After(bucket.openAt) {
+			candle.Open, bucket.openAt = t.Price, t.Timestamp
+		}
+		if t.Timestamp.After(bucket.closeAt) {
+			candle.Close, bucket.closeAt = t.Price, t.Timestamp
 		}
+		if t.Price.GreaterThan(candle.High) {
+			candle.High = t.Price
+		}
+		if t.Price.LessThan(candle.Low) {
+			candle.Low = t.Price
+		}
+		bucket.addVolume(t.Price, t.Size)
+		candle.TradeCount++
 	}
 
-	// Limit results
-	if len(candles) > limit {
-		candles = candles[:limit]
+	candles := make([]*domain.Candle, 0, len(buckets))
+	for _, bucket := range buckets {
+		candles = append(candles, bucket.candle)
 	}
-
 	return candles
 }
 
-// GetHistoricalTrades returns trades within a time range
+// GetHistoricalTrades returns trades within a time range, newest first. Ranges
+// reaching back past the in-memory trades are read from the database.
 func (me *MatchingEngine) GetHistoricalTrades(instrument string, start, end time.Time, limit int) []*domain.Trade {
+	me.mu.RLock()
+	buffered := me.tradesBufferedSinceLocked(start)
+	database := me.db
+	me.mu.RUnlock()
+
+	if database != nil && !buffered {
+		trades, err := database.GetTradesInRange(instrument, start, end, limit)
+		if err == nil {
+			return trades
+		}
+		slog.Error("Error loading trades from database", "error", err)
+	}
+
 	me.mu.RLock()
 	defer me.mu.RUnlock()
 
@@ -719,72 +1855,69 @@ func (me *MatchingEngine) GetHistoricalTrades(instrument string, start, end time
 	return trades
 }
 
-// GetHistoricalCandles returns candles within a time range
-func (me *MatchingEngine) GetHistoricalCandles(instrument string, interval domain.CandleInterval, start, end time.Time, limit int) []*domain.Candle {
+// GetTradesByPriceRange returns trades priced within [minPrice, maxPrice] inside
+// a time range, newest first. It reads the full trade history from the
+// database when one is configured, otherwise the in-memory recent trades.
+func (me *MatchingEngine) GetTradesByPriceRange(instrument string, minPrice, maxPrice decimal.Decimal, start, end time.Time, limit int) ([]*domain.Trade, error) {
+	if me.db != nil {
+		trades, err := me.db.GetTradesByPriceRange(instrument, minPrice, maxPrice, start, end, limit)
+		if err != nil {
+			return nil, fmt.Errorf("loading trades by price range: %w", err)
+		}
+		return trades, nil
+	}
+
 	me.mu.RLock()
 	defer me.mu.RUnlock()
 
-	intervalDuration := getIntervalDuration(interval)
-	candleMap := make(map[int64]*domain.Candle)
-
+	trades := make([]*domain.Trade, 0)
 	for _, t := range me.recentTrades {
-		if t.Instrument != instrument {
+		if t.Instrument != instrument || t.Timestamp.Before(start) || t.Timestamp.After(end) {
 			continue
 		}
-		if t.Timestamp.Before(start) || t.Timestamp.After(end) {
+		if t.Price.LessThan(minPrice) || t.Price.GreaterThan(maxPrice) {
 			continue
 		}
-
-		candleStart := truncateToInterval(t.Timestamp, intervalDuration)
-		candleKey := candleStart.Unix()
-
-		candle, exists := candleMap[candleKey]
-		if !exists {
-			candle = &domain.Candle{
-				Instrument: instrument,
-				Interval:   interval,
-				OpenTime:   candleStart,
-				CloseTime:  candleStart.Add(intervalDuration),
-				Open:       t.Price,
-				High:       t.Price,
-				Low:        t.Price,
-				Close:      t.Price,
-				Volume:     t.Size,
-				TradeCount: 1,
-			}
-			candleMap[candleKey] = candle
-		} else {
-			candle.Open = t.Price
-			if t.Price.GreaterThan(candle.High) {
-				candle.High = t.Price
-			}
-			if t.Price.LessThan(candle.Low) {
-				candle.Low = t.Price
-			}
-			candle.Volume = candle.Volume.Add(t.Size)
-			candle.TradeCount++
+		trades = append(trades, t)
+		if len(trades) >= limit {
+			break
 		}
 	}
+	return trades, nil
+}
 
-	var candles []*domain.Candle
-	for _, c := range candleMap {
-		candles = append(candles, c)
-	}
-
-	// Sort by open time ascending (oldest first for historical)
-	for i := 0; i < len(candles)-1; i++ {
-		for j := i + 1; j < len(candles); j++ {
-			if candles[j].OpenTime.Before(candles[i].OpenTime) {
-				candles[i], candles[j] = candles[j], candles[i]
-			}
+// GetHistoricalCandles returns candles within a time range, oldest first.
+// Ranges reaching back past the in-memory trade buffer are filled from
+// candles saved to the database.
+func (me *MatchingEngine) GetHistoricalCandles(instrument string, interval domain.CandleInterval, start, end time.Time, limit int) ([]*domain.Candle, error) {
+	me.mu.RLock()
+	candles := buildCandles(me.recentTrades, instrument, interval, func(t *domain.Trade) bool {
+		return !t.Timestamp.Before(start) && !t.Timestamp.After(end)
+	})
+	// Trades older than the buffer's oldest are only in the database
+	buffered := me.tradesBufferedSinceLocked(start)
+	inProgress := me.openCandlesLocked(instrument, interval, truncateToInterval(start, getIntervalDuration(interval)), end)
+	database := me.db
+	me.mu.RUnlock()
+
+	if database != nil && !buffered {
+		saved, err := database.GetCandles(instrument, interval, truncateToInterval(start, getIntervalDuration(interval)), end, limit)
+		if err != nil {
+			return nil, fmt.Errorf("loading candles: %w", err)
 		}
+		candles = mergeCandles(mergeCandles(saved, inProgress), candles)
+	} else {
+		// Sort by open time ascending (oldest first for historical)
+		sort.Slice(candles, func(i, j int) bool {
+			return candles[i].OpenTime.Before(candles[j].OpenTime)
+		})
 	}
 
 	if len(candles) > limit {
 		candles = candles[:limit]
 	}
 
-	return candles
+	return candles, nil
 }
 
 // getIntervalDuration converts interval string to duration
@@ -812,34 +1945,64 @@ func truncateToInterval(t time.Time, d time.Duration) time.Time {
 	return t.UTC().Truncate(d)
 }
 
-// GetMarketStats returns market statistics for an instrument
+// GetMarketStats returns market statistics for an instrument. With a
+// database, the 24h figures cover the full day from the trades table (cached
+// briefly); without one they cover only the in-memory trades.
 func (me *MatchingEngine) GetMarketStats(instrument string) *domain.MarketStats {
 	me.mu.RLock()
-	defer me.mu.RUnlock()
+	stats := me.marketStatsLocked(instrument)
+	database := me.db
+	me.mu.RUnlock()
+
+	if database != nil {
+		day, err := me.dayTradeStats(database, instrument, stats.Timestamp)
+		if err != nil {
+			slog.Error("Error loading 24h trade stats from database", "error", err)
+			return stats
+		}
+		stats.High24h, stats.Low24h, stats.Volume24h = stats.LastPrice, stats.LastPrice, day.Volume
+		stats.BuyVolume24h, stats.SellVolume24h = day.BuyVolume, day.SellVolume
+		if day.Count > 0 {
+			stats.High24h = decimal.Max(stats.High24h, day.High)
+			stats.Low24h = decimal.Min(stats.Low24h, day.Low)
+		}
+	}
+	return stats
+}
 
+// marketStatsLocked builds market statistics from in-memory state, with 24h
+// figures from the buffered trades. Caller must hold me.mu.
+func (me *MatchingEngine) marketStatsLocked(instrument string) *domain.MarketStats {
 	stats := &domain.MarketStats{
 		Instrument:    instrument,
 		Timestamp:     time.Now(),
-		InsuranceFund: decimal.NewFromInt(1000000), // Default
+		InsuranceFund: me.insuranceFundBalance(),
+		MarketState:   me.marketState,
 	}
 
 	// Get last price from recent trades
 	for _, t := range me.recentTrades {
 		if t.Instrument == instrument {
 			stats.LastPrice = t.Price
-			stats.MarkPrice = t.Price
 			break
 		}
 	}
 
-	// If no trades yet, use 1000 as starting price
+	// If no trades yet, use the configured starting price
 	if stats.LastPrice.IsZero() {
-		stats.LastPrice = decimal.NewFromInt(1000)
-		stats.MarkPrice = decimal.NewFromInt(1000)
+		stats.LastPrice = me.startingPrice()
+	}
+	stats.MarkPrice = me.markPriceLocked(instrument)
+	stats.IndexPrice = me.indexPriceLocked(instrument)
+
+	if me.fundingSource != nil {
+		stats.FundingRate = me.fundingSource.GetCurrentRate(instrument)
+		stats.NextFundingTime = me.fundingSource.NextFundingTime()
+		stats.NextFundingFreeze = me.fundingSource.NextFundingFreeze()
 	}
 
 	// Calculate 24h stats from trades
-	oneDayAgo := time.Now().Add(-24 * time.Hour)
+	oneDayAgo := stats.Timestamp.Add(-24 * time.Hour)
 	stats.High24h = stats.LastPrice
 	stats.Low24h = stats.LastPrice
 
@@ -851,7 +2014,14 @@ func (me *MatchingEngine) GetMarketStats(instrument string) *domain.MarketStats
 			if t.Price.LessThan(stats.Low24h) {
 				stats.Low24h = t.Price
 			}
-			stats.Volume24h = stats.Volume24h.Add(t.Size.Mul(t.Price))
+			notional := t.Size.Mul(t.Price)
+			stats.Volume24h = stats.Volume24h.Add(notional)
+			switch t.AggressorSide {
+			case domain.SideBuy:
+				stats.BuyVolume24h = stats.BuyVolume24h.Add(notional)
+			case domain.SideSell:
+				stats.SellVolume24h = stats.SellVolume24h.Add(notional)
+			}
 		}
 	}
 
@@ -870,31 +2040,51 @@ func (me *MatchingEngine) SetLiquidationConfig(cfg *config.LiquidationConfig) {
 	me.liqConfig = cfg
 }
 
-// GetMarkPrice returns the current mark price for an instrument (implements PriceProvider)
+// GetMarkPrice returns the current mark price for an instrument, derived as
+// configured by engine.mark_price_method (implements PriceProvider)
 func (me *MatchingEngine) GetMarkPrice(instrument string) decimal.Decimal {
 	me.mu.RLock()
 	defer me.mu.RUnlock()
+	return me.markPriceLocked(instrument)
+}
+
+// defaultStartingPrice is the no-trade price when the instrument config is
+// missing or has no positive starting price
+var defaultStartingPrice = decimal.NewFromInt(1000)
+
+// startingPrice is the price an instrument is quoted at before its first trade
+func (me *MatchingEngine) startingPrice() decimal.Decimal {
+	if ic := me.instrumentConfig; ic != nil && ic.StartingPrice.IsPositive() {
+		return ic.StartingPrice
+	}
+	return defaultStartingPrice
+}
 
-	// Get last trade price as mark price
+// lastTradePrice returns the most recent trade price (caller must hold the lock)
+func (me *MatchingEngine) lastTradePrice(instrument string) (decimal.Decimal, bool) {
 	for _, t := range me.recentTrades {
 		if t.Instrument == instrument {
-			return t.Price
+			return t.Price, true
 		}
 	}
-
-	// Default to 1000 if no trades
-	return decimal.NewFromInt(1000)
+	return decimal.Zero, false
 }
 
-// ClosePosition closes a position at the given mark price (implements PositionStore)
-func (me *MatchingEngine) ClosePosition(traderID uuid.UUID, instrument string, markPrice decimal.Decimal) error {
+// ClosePosition closes a liquidated position at the given mark price and
+// returns the liquidation fee charged (implements PositionStore). An isolated
+// position's margin is forfeit: the trader gets nothing back, and what the
+// loss and fee leave of it is the surplus the liquidation engine pays into
+// the insurance fund. A cross position settles against the free balance too,
+// which keeps whatever is left. The fee is only taken from what remains, so a
+// loss the fund or ADL covers is never charged to the trader as well.
+func (me *MatchingEngine) ClosePosition(traderID uuid.UUID, instrument string, markPrice decimal.Decimal) (decimal.Decimal, error) {
 	me.mu.Lock()
 	defer me.mu.Unlock()
 
 	posKey := fmt.Sprintf("%s:%s", traderID, instrument)
 	pos, exists := me.positions[posKey]
 	if !exists || pos.Size.IsZero() {
-		return fmt.Errorf("no position to close")
+		return decimal.Zero, fmt.Errorf("no position to close")
 	}
 
 	// Calculate realized P&L
@@ -905,26 +2095,40 @@ func (me *MatchingEngine) ClosePosition(traderID uuid.UUID, instrument string, m
 		pnl = pos.EntryPrice.Sub(markPrice).Mul(pos.Size.Abs())
 	}
 
-	// Update trader balance
-	if trader, ok := me.traders[traderID]; ok {
-		trader.Balance = trader.Balance.Add(pos.Margin).Add(pnl)
+	trader, ok := me.traders[traderID]
+	cross := ok && trader.MarginMode == domain.MarginModeCross
+	remaining := pos.Margin.Add(pnl)
+	if cross {
+		remaining = remaining.Add(trader.Balance)
+	}
+	fee := markPrice.Mul(pos.Size.Abs()).Mul(me.feeRate(true, domain.EffectLiquidation))
+	fee = decimal.Min(fee, decimal.Max(remaining, decimal.Zero))
+
+	if ok {
+		// Below zero the insurance fund, then ADL, has covered the difference
+		if cross {
+			trader.Balance = decimal.Max(remaining.Sub(fee), decimal.Zero)
+		}
 		trader.TotalPnL = trader.TotalPnL.Add(pnl)
 		if me.db != nil {
 			if err := me.db.SaveTrader(trader); err != nil {
-				log.Printf("Error saving trader after liquidation: %v", err)
+				slog.Error("Error saving trader after liquidation", "trader_id", traderID, "error", err)
 			}
 		}
 	}
+	me.creditFees(fee, &domain.InsuranceFundEvent{Note: "liquidation fee"})
 
 	// Delete position
 	delete(me.positions, posKey)
 	if me.db != nil {
 		if err := me.db.DeletePosition(traderID, instrument); err != nil {
-			log.Printf("Error deleting liquidated position: %v", err)
+			slog.Error("Error deleting liquidated position", "error", err)
 		}
 	}
+	me.trimReduceOnlyLocked(traderID, instrument)
+	me.refreshCrossLiquidationPricesLocked(traderID)
 
-	return nil
+	return fee, nil
 }
 
 // OnLiquidation registers a liquidation handler
@@ -939,14 +2143,14 @@ func (me *MatchingEngine) AddLiquidation(liq *domain.Liquidation) {
 
 	// Add to history
 	me.liquidations = append([]*domain.Liquidation{liq}, me.liquidations...)
-	if len(me.liquidations) > 100 {
-		me.liquidations = me.liquidations[:100]
+	if limit := me.maxRecentLiquidations(); len(me.liquidations) > limit {
+		me.liquidations = me.liquidations[:limit]
 	}
 
 	// Persist to database
 	if me.db != nil {
 		if err := me.db.SaveLiquidation(liq); err != nil {
-			log.Printf("Error saving liquidation: %v", err)
+			slog.Error("Error saving liquidation", "error", err)
 		}
 	}
 
@@ -956,21 +2160,59 @@ func (me *MatchingEngine) AddLiquidation(liq *domain.Liquidation) {
 	}
 }
 
-// calculateLiquidationPrice computes liquidation price for a position
-func (me *MatchingEngine) calculateLiquidationPrice(entryPrice decimal.Decimal, leverage int, isLong bool) decimal.Decimal {
+// calculateLiquidationPrice computes liquidation price for a position of the given signed size
+func (me *MatchingEngine) calculateLiquidationPrice(entryPrice decimal.Decimal, leverage int, size decimal.Decimal) decimal.Decimal {
 	if me.liqConfig == nil {
 		// Default maintenance margins if not configured
 		return decimal.Zero
 	}
 
-	maintMargin := me.liqConfig.MaintenanceMargins.GetMarginForLeverage(leverage)
+	leverage = domain.NormalizeLeverage(leverage)
+	maintMargin := me.maintenanceMargin(leverage, entryPrice.Mul(size.Abs()))
 	leverageDecimal := decimal.NewFromInt(int64(leverage))
 
 	// Liquidation distance = entry / leverage * (1 - maintenance margin)
-	distance := entryPrice.Div(leverageDecimal).Mul(decimal.NewFromInt(1).Sub(maintMargin))
+	distance := entryPrice.DivRound(leverageDecimal, me.divisionPlaces()).Mul(decimal.NewFromInt(1).Sub(maintMargin))
 
-	if isLong {
+	if size.IsPositive() {
 		return entryPrice.Sub(distance)
 	}
 	return entryPrice.Add(distance)
 }
+
+// maintenanceMargin returns the margin rate for a position: the leverage
+// band's rate, raised to the risk tier's rate for the position's notional
+func (me *MatchingEngine) maintenanceMargin(leverage int, notional decimal.Decimal) decimal.Decimal {
+	margin := me.liqConfig.MaintenanceMargins.GetMarginForLeverage(leverage)
+	if tier := me.liqConfig.RiskTierFor(notional); tier != nil {
+		margin = decimal.Max(margin, tier.MaintenanceMargin)
+	}
+	return margin
+}
+
+// checkRiskTierLocked rejects an order whose leverage exceeds the risk tier
+// for the position notional it would leave the trader with. Orders that only
+// shrink a position are always allowed. Caller must hold me.mu.
+func (me *MatchingEngine) checkRiskTierLocked(order *domain.Order) error {
+	if me.liqConfig == nil || len(me.liqConfig.RiskTiers) == 0 {
+		return nil
+	}
+
+	price := me.orderPriceLocked(order)
+	if !price.IsPositive() {
+		return nil
+	}
+
+	current, projected := me.projectedPositionLocked(order)
+	if projected.Abs().LessThanOrEqual(current.Abs()) {
+		return nil
+	}
+
+	notional := projected.Abs().Mul(price)
+	tier := me.liqConfig.RiskTierFor(notional)
+	if order.Leverage > tier.MaxLeverage {
+		return fmt.Errorf("leverage %dx exceeds the %dx maximum for a %s notional position",
+			order.Leverage, tier.MaxLeverage, notional.StringFixed(2))
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func TestLimitOrdersRestOnTheirSide(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	trader := h.AddTrader("trader")
+
+	h.MustLimit(trader, domain.SideBuy, "999", "1")
+	h.MustLimit(trader, domain.SideBuy, "998", "2")
+	h.MustLimit(trader, domain.SideSell, "1001", "3")
+
+	bids, asks := h.Bids(), h.Asks()
+	if len(bids) != 2 || !bids[0].Price.Equal(dec("999")) || !bids[1].Size.Equal(dec("2")) {
+		t.Fatalf("bids = %+v, want 999x1 then 998x2", bids)
+	}
+	if len(asks) != 1 || !asks[0].Price.Equal(dec("1001")) || !asks[0].Size.Equal(dec("3")) {
+		t.Fatalf("asks = %+v, want 1001x3", asks)
+	}
+}
+
+func TestMarketOrderWalksLevelsAtMakerPrices(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+
+	h.MustLimit(maker, domain.SideSell, "1001", "1")
+	h.MustLimit(maker, domain.SideSell, "1000", "1")
+	h.MustLimit(maker, domain.SideSell, "1002", "1")
+
+	trades := h.MustMarket(taker, domain.SideBuy, "2.5")
+	if len(trades) != 3 {
+		t.Fatalf("trades = %d, want 3", len(trades))
+	}
+	for i, want := range []string{"1000", "1001", "1002"} {
+		if !trades[i].Price.Equal(dec(want)) {
+			t.Fatalf("trade %d price = %s, want %s", i, trades[i].Price, want)
+		}
+		if trades[i].BuyerID != taker.ID || trades[i].SellerID != maker.ID {
+			t.Fatalf("trade %d sides = %s/%s, want taker buying from maker", i, trades[i].BuyerID, trades[i].SellerID)
+		}
+	}
+	if !trades[2].Size.Equal(dec("0.5")) {
+		t.Fatalf("last fill = %s, want 0.5", trades[2].Size)
+	}
+
+	if got := h.PositionSize(taker); !got.Equal(dec("2.5")) {
+		t.Fatalf("taker position = %s, want 2.5", got)
+	}
+	if got := h.PositionSize(maker); !got.Equal(dec("-2.5")) {
+		t.Fatalf("maker position = %s, want -2.5", got)
+	}
+	if asks := h.Asks(); len(asks) != 1 || !asks[0].Size.Equal(dec("0.5")) {
+		t.Fatalf("asks = %+v, want 0.5 left at 1002", asks)
+	}
+}
+
+func TestSamePriceFillsInTimePriority(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	first := h.AddTrader("first")
+	second := h.AddTrader("second")
+	taker := h.AddTrader("taker")
+
+	h.MustLimit(first, domain.SideBuy, "1000", "1")
+	h.MustLimit(second, domain.SideBuy, "1000", "1")
+
+	trades := h.MustMarket(taker, domain.SideSell, "1.5")
+	if len(trades) != 2 || trades[0].BuyerID != first.ID || trades[1].BuyerID != second.ID {
+		t.Fatalf("fills = %+v, want first then second", trades)
+	}
+	if !trades[0].Size.Equal(dec("1")) || !trades[1].Size.Equal(dec("0.5")) {
+		t.Fatalf("fill sizes = %s, %s, want 1 and 0.5", trades[0].Size, trades[1].Size)
+	}
+	if bids := h.Bids(); len(bids) != 1 || bids[0].OrderCount != 1 || !bids[0].Size.Equal(dec("0.5")) {
+		t.Fatalf("bids = %+v, want second's 0.5 left", bids)
+	}
+}
+
+func TestCrossingLimitFillsThenRestsRemainder(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+
+	h.MustLimit(maker, domain.SideSell, "1000", "1")
+	h.MustLimit(maker, domain.SideSell, "1005", "1")
+
+	order, trades, err := h.Limit(taker, domain.SideBuy, "1002", "3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 1 || !trades[0].Price.Equal(dec("1000")) {
+		t.Fatalf("trades = %+v, want one fill at 1000", trades)
+	}
+	if order.Status != domain.OrderStatusPartial || !order.FilledSize.Equal(dec("1")) {
+		t.Fatalf("order = %s filled %s, want partial with 1 filled", order.Status, order.FilledSize)
+	}
+	if bids := h.Bids(); len(bids) != 1 || !bids[0].Price.Equal(dec("1002")) || !bids[0].Size.Equal(dec("2")) {
+		t.Fatalf("bids = %+v, want the remaining 2 at 1002", bids)
+	}
+	if asks := h.Asks(); len(asks) != 1 || !asks[0].Price.Equal(dec("1005")) {
+		t.Fatalf("asks = %+v, want 1005 untouched", asks)
+	}
+}
+
+func TestCancelRemovesRestingOrder(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	trader := h.AddTrader("trader")
+
+	order := h.MustLimit(trader, domain.SideBuy, "999", "1")
+	if err := h.Engine.CancelOrder(trader.ID, order.ID, h.Instrument); err != nil {
+		t.Fatal(err)
+	}
+	if bids := h.Bids(); len(bids) != 0 {
+		t.Fatalf("bids = %+v after cancel, want none", bids)
+	}
+	if err := h.Engine.CancelOrder(trader.ID, order.ID, h.Instrument); err == nil {
+		t.Fatal("cancelling twice succeeded")
+	}
+}
+
+func TestClosingTradeRealizesPnL(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	long := h.AddTrader("long")
+	short := h.AddTrader("short")
+
+	h.MustLimit(short, domain.SideSell, "1000", "2")
+	h.MustMarket(long, domain.SideBuy, "2")
+	h.MustLimit(short, domain.SideBuy, "1010", "2")
+	h.MustMarket(long, domain.SideSell, "2")
+
+	if h.Position(long) != nil && !h.PositionSize(long).IsZero() {
+		t.Fatalf("long position = %s, want flat", h.PositionSize(long))
+	}
+	if got := h.Trader(long).TotalPnL; !got.Equal(dec("20")) {
+		t.Fatalf("long P&L = %s, want 20", got)
+	}
+	if got := h.Trader(short).TotalPnL; !got.Equal(dec("-20")) {
+		t.Fatalf("short P&L = %s, want -20", got)
+	}
+}
@@ -0,0 +1,192 @@
+package engine
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// MMPHandler is called when a trader's market-maker protection trips
+type MMPHandler func(status *domain.MMPStatus)
+
+// mmpFill is one maker fill inside the protection window
+type mmpFill struct {
+	at   time.Time
+	size decimal.Decimal
+}
+
+// mmpState tracks a trader's recent maker fills against their MMP config
+type mmpState struct {
+	cfg         domain.MMPConfig
+	fills       []mmpFill
+	frozen      bool
+	triggeredAt time.Time
+}
+
+// window drops fills older than the configured window and returns the remaining totals
+func (s *mmpState) window(now time.Time) (int, decimal.Decimal) {
+	cutoff := now.Add(-time.Duration(s.cfg.WindowMs) * time.Millisecond)
+	keep := 0
+	for keep < len(s.fills) && s.fills[keep].at.Before(cutoff) {
+		keep++
+	}
+	s.fills = s.fills[keep:]
+	return s.windowAt(now)
+}
+
+// windowAt returns the totals of the fills inside the window at now without
+// dropping older ones, so it is safe under a read lock
+func (s *mmpState) windowAt(now time.Time) (int, decimal.Decimal) {
+	cutoff := now.Add(-time.Duration(s.cfg.WindowMs) * time.Millisecond)
+	fills, total := 0, decimal.Zero
+	for _, f := range s.fills {
+		if f.at.Before(cutoff) {
+			continue
+		}
+		fills++
+		total = total.Add(f.size)
+	}
+	return fills, total
+}
+
+// trips reports whether a window holding this many fills and this much size
+// reaches a threshold
+func (s *mmpState) trips(fills int, total decimal.Decimal) bool {
+	return (s.cfg.MaxFills > 0 && fills >= s.cfg.MaxFills) ||
+		(s.cfg.MaxSize.IsPositive() && total.GreaterThanOrEqual(s.cfg.MaxSize))
+}
+
+// OnMMPTrigger registers a handler for market-maker protection trips
+func (me *MatchingEngine) OnMMPTrigger(handler MMPHandler) {
+	me.mmpHandlers = append(me.mmpHandlers, handler)
+}
+
+// SetMMPConfig enables market-maker protection for a trader, replacing any previous config
+func (me *MatchingEngine) SetMMPConfig(traderID uuid.UUID, cfg domain.MMPConfig) (*domain.MMPStatus, error) {
+	if cfg.WindowMs <= 0 {
+		return nil, fmt.Errorf("window_ms must be positive")
+	}
+	if cfg.MaxFills < 0 || cfg.MaxSize.IsNegative() {
+		return nil, fmt.Errorf("max_fills and max_size must not be negative")
+	}
+	if cfg.MaxFills == 0 && cfg.MaxSize.IsZero() {
+		return nil, fmt.Errorf("at least one of max_fills or max_size must be set")
+	}
+
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	if _, exists := me.traders[traderID]; !exists {
+		return nil, fmt.Errorf("unknown trader: %s", traderID)
+	}
+
+	state, exists := me.mmp[traderID]
+	if !exists {
+		state = &mmpState{}
+		me.mmp[traderID] = state
+	}
+	state.cfg = cfg
+	return me.mmpStatusLocked(traderID, state), nil
+}
+
+// GetMMPStatus returns a trader's protection state, or nil if MMP is not configured
+func (me *MatchingEngine) GetMMPStatus(traderID uuid.UUID) *domain.MMPStatus {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	state, exists := me.mmp[traderID]
+	if !exists {
+		return nil
+	}
+	return me.mmpStatusLocked(traderID, state)
+}
+
+// ResetMMP unfreezes a tripped trader and clears their fill window
+func (me *MatchingEngine) ResetMMP(traderID uuid.UUID) (*domain.MMPStatus, error) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	state, exists := me.mmp[traderID]
+	if !exists {
+		return nil, fmt.Errorf("market-maker protection not configured")
+	}
+	state.frozen = false
+	state.triggeredAt = time.Time{}
+	state.fills = nil
+	slog.Info("MMP reset", "trader_id", traderID)
+	return me.mmpStatusLocked(traderID, state), nil
+}
+
+// mmpStatusLocked builds a status snapshot. Caller must hold me.mu.
+func (me *MatchingEngine) mmpStatusLocked(traderID uuid.UUID, state *mmpState) *domain.MMPStatus {
+	fills, size := state.window(time.Now())
+	status := &domain.MMPStatus{
+		TraderID:    traderID,
+		Config:      state.cfg,
+		Frozen:      state.frozen,
+		WindowFills: fills,
+		WindowSize:  size,
+	}
+	if state.frozen {
+		triggeredAt := state.triggeredAt
+		status.TriggeredAt = &triggeredAt
+	}
+	return status
+}
+
+// mmpFrozen reports whether a trader's quotes are frozen. Caller must hold me.mu.
+func (me *MatchingEngine) mmpFrozen(traderID uuid.UUID) bool {
+	state, exists := me.mmp[traderID]
+	return exists && state.frozen
+}
+
+// recordMakerFill adds a maker fill to the trader's window and freezes them if
+// a threshold is reached, returning true on the fill that trips. Caller must hold me.mu.
+func (me *MatchingEngine) recordMakerFill(traderID uuid.UUID, size decimal.Decimal) bool {
+	state, exists := me.mmp[traderID]
+	if !exists || state.frozen {
+		return false
+	}
+
+	now := time.Now()
+	state.fills = append(state.fills, mmpFill{at: now, size: size})
+	fills, total := state.window(now)
+
+	if state.trips(fills, total) {
+		state.frozen = true
+		state.triggeredAt = now
+		return true
+	}
+	return false
+}
+
+// tripMMP pulls all of a frozen trader's resting quotes and notifies handlers.
+// Called after matching completes so book iteration is never disturbed.
+// Caller must hold me.mu.
+func (me *MatchingEngine) tripMMP(traderID uuid.UUID) {
+	cancelled := 0
+	for instrument, book := range me.books {
+		for _, order := range book.GetTraderOrders(traderID) {
+			if order.Status == domain.OrderStatusCancelled {
+				continue // Already cancelled as an OCO sibling
+			}
+			if err := me.cancelOrderLocked(order.ID, instrument); err != nil {
+				slog.Error("Error cancelling order on MMP trip", "order_id", order.ID, "error", err)
+				continue
+			}
+			cancelled++
+		}
+	}
+
+	status := me.mmpStatusLocked(traderID, me.mmp[traderID])
+	me.loggerLocked().Warn("MMP triggered", "trader_id", traderID, "fills", status.WindowFills,
+		"size", status.WindowSize, "window_ms", status.Config.WindowMs, "quotes_pulled", cancelled)
+
+	for _, handler := range me.mmpHandlers {
+		handler(status)
+	}
+}
@@ -0,0 +1,78 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestRapidFillsTripMMPAndPullQuotes(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+
+	var tripped []*domain.MMPStatus
+	h.Engine.OnMMPTrigger(func(status *domain.MMPStatus) {
+		tripped = append(tripped, status)
+	})
+	if _, err := h.Engine.SetMMPConfig(maker.ID, domain.MMPConfig{WindowMs: 60000, MaxFills: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, price := range []string{"1000", "1001", "1002", "1003", "1004"} {
+		h.MustLimit(maker, domain.SideSell, price, "1")
+	}
+	h.MustLimit(maker, domain.SideBuy, "990", "1")
+
+	h.MustMarket(taker, domain.SideBuy, "1")
+	h.MustMarket(taker, domain.SideBuy, "1")
+	if len(tripped) != 0 {
+		t.Fatal("MMP tripped after 2 of 3 fills")
+	}
+	h.MustMarket(taker, domain.SideBuy, "1")
+
+	if len(tripped) != 1 || tripped[0].TraderID != maker.ID || tripped[0].WindowFills != 3 {
+		t.Fatalf("trips = %+v, want one for the maker at 3 fills", tripped)
+	}
+	if open := h.Engine.GetOpenOrders(maker.ID, h.Instrument); len(open) != 0 {
+		t.Fatalf("%d quotes still resting after the trip", len(open))
+	}
+	if status := h.Engine.GetMMPStatus(maker.ID); !status.Frozen || status.TriggeredAt == nil {
+		t.Fatalf("status = %+v, want frozen", status)
+	}
+
+	// Frozen until reset
+	if _, _, err := h.Limit(maker, domain.SideSell, "1000", "1"); err == nil {
+		t.Fatal("frozen maker quoted")
+	}
+	if _, err := h.Engine.ResetMMP(maker.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := h.Limit(maker, domain.SideSell, "1000", "1"); err != nil {
+		t.Fatalf("quote after reset rejected: %v", err)
+	}
+}
+
+func TestMMPTripsOnFilledSize(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+
+	if _, err := h.Engine.SetMMPConfig(maker.ID, domain.MMPConfig{WindowMs: 60000, MaxSize: dec("2")}); err != nil {
+		t.Fatal(err)
+	}
+	h.MustLimit(maker, domain.SideBuy, "1000", "5")
+
+	h.MustMarket(taker, domain.SideSell, "1.5")
+	if h.Engine.GetMMPStatus(maker.ID).Frozen {
+		t.Fatal("MMP tripped below the size threshold")
+	}
+	h.MustMarket(taker, domain.SideSell, "0.5")
+	if !h.Engine.GetMMPStatus(maker.ID).Frozen {
+		t.Fatal("MMP did not trip at 2 filled")
+	}
+	if bids := h.Bids(); len(bids) != 0 {
+		t.Fatalf("bids = %+v, want the quote pulled", bids)
+	}
+}
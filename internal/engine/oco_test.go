@@ -0,0 +1,119 @@
+package engine_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/thatreguy/trade.re/internal/db"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+// submitOCO places a take-profit sell limit at 1020 and a stop-loss sell
+// stop at 980, both for 1
+func submitOCO(t *testing.T, h *enginetest.Harness, trader *domain.Trader) (limit, stop *domain.Order) {
+	t.Helper()
+	limit = &domain.Order{
+		TraderID:   trader.ID,
+		Instrument: h.Instrument,
+		Side:       domain.SideSell,
+		Type:       domain.OrderTypeLimit,
+		Price:      dec("1020"),
+		Size:       dec("1"),
+		Leverage:   1,
+	}
+	stop = &domain.Order{
+		TraderID:   trader.ID,
+		Instrument: h.Instrument,
+		Side:       domain.SideSell,
+		Type:       domain.OrderTypeStop,
+		StopPrice:  dec("980"),
+		Size:       dec("1"),
+		Leverage:   1,
+	}
+	if _, err := h.Engine.SubmitOCO(limit, stop); err != nil {
+		t.Fatal(err)
+	}
+	if limit.OCOGroupID == nil || stop.OCOGroupID == nil || *limit.OCOGroupID != *stop.OCOGroupID {
+		t.Fatal("OCO legs not grouped")
+	}
+	return limit, stop
+}
+
+func TestOCOLimitFillCancelsStop(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	trader := h.AddTrader("trader")
+	buyer := h.AddTrader("buyer")
+
+	limit, stop := submitOCO(t, h, trader)
+	h.MustMarket(buyer, domain.SideBuy, "1")
+
+	if limit.Status != domain.OrderStatusFilled {
+		t.Fatalf("take-profit = %s, want filled", limit.Status)
+	}
+	if stop.Status != domain.OrderStatusCancelled || stop.Triggered {
+		t.Fatalf("stop-loss = %s (triggered %v), want cancelled untriggered", stop.Status, stop.Triggered)
+	}
+	if open := h.Engine.GetOpenOrders(trader.ID, h.Instrument); len(open) != 0 {
+		t.Fatalf("%d orders left open", len(open))
+	}
+}
+
+func TestOCOStopTriggerCancelsLimit(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	trader := h.AddTrader("trader")
+	maker := h.AddTrader("maker")
+	seller := h.AddTrader("seller")
+
+	limit, stop := submitOCO(t, h, trader)
+
+	// A trade at 980 triggers the stop, which sells into the rest of the bid
+	h.MustLimit(maker, domain.SideBuy, "980", "2")
+	h.MustMarket(seller, domain.SideSell, "1")
+
+	if !stop.Triggered || stop.Status != domain.OrderStatusFilled {
+		t.Fatalf("stop-loss = %s (triggered %v), want triggered and filled", stop.Status, stop.Triggered)
+	}
+	if limit.Status != domain.OrderStatusCancelled {
+		t.Fatalf("take-profit = %s, want cancelled", limit.Status)
+	}
+	if asks := h.Asks(); len(asks) != 0 {
+		t.Fatalf("asks = %+v, want the take-profit gone", asks)
+	}
+	if got := h.PositionSize(trader); !got.Equal(dec("-1")) {
+		t.Fatalf("trader position = %s, want -1 from the stop only", got)
+	}
+}
+
+func TestOCOLinkSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "oco.db")
+	open := func() *enginetest.Harness {
+		database, err := db.NewSQLite(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { database.Close() })
+		h := enginetest.NewTestEngine()
+		h.Engine.SetDatabase(database)
+		return h
+	}
+
+	h := open()
+	trader := h.AddTrader("trader")
+	buyer := h.AddTrader("buyer")
+	_, stop := submitOCO(t, h, trader)
+
+	restarted := open()
+	if err := restarted.Engine.LoadFromDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	restarted.MustMarket(buyer, domain.SideBuy, "1")
+
+	orders, err := restarted.Engine.GetTraderOrders(trader.ID, h.Instrument, domain.OrderStatusCancelled, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 || orders[0].ID != stop.ID {
+		t.Fatalf("cancelled orders after restart = %d, want the reloaded stop-loss", len(orders))
+	}
+}
@@ -0,0 +1,210 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// RecordOISnapshot captures current open interest and persists it for the OI history
+func (me *MatchingEngine) RecordOISnapshot(instrument string) error {
+	me.mu.RLock()
+	snap := &domain.OISnapshot{
+		Instrument: instrument,
+		Timestamp:  time.Now().UTC(),
+	}
+	for _, pos := range me.positions {
+		if pos.Instrument != instrument || pos.Size.IsZero() {
+			continue
+		}
+		if pos.IsLong() {
+			snap.LongPositions++
+			snap.LongOI = snap.LongOI.Add(pos.Size)
+		} else {
+			snap.ShortPositions++
+			snap.ShortOI = snap.ShortOI.Add(pos.Size.Abs())
+		}
+	}
+	// Every long is matched by a short, so OI is one side's total
+	snap.OpenInterest = decimal.Max(snap.LongOI, snap.ShortOI)
+	snap.Price = me.startingPrice()
+	if price, ok := me.lastTradePrice(instrument); ok {
+		snap.Price = price
+	}
+	me.mu.RUnlock()
+
+	if me.db == nil {
+		return nil
+	}
+	return me.db.SaveOISnapshot(snap)
+}
+
+// GetOIHistory returns open interest bucketed by interval within a time range.
+// Each bucket reports the last snapshot taken within it, so Price is the
+// bucket's close price.
+func (me *MatchingEngine) GetOIHistory(instrument string, interval domain.CandleInterval, start, end time.Time) ([]*domain.OISnapshot, error) {
+	if me.db == nil {
+		return []*domain.OISnapshot{}, nil
+	}
+
+	snapshots, err := me.db.GetOIHistory(instrument, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("loading OI history: %w", err)
+	}
+
+	intervalDuration := getIntervalDuration(interval)
+	buckets := make([]*domain.OISnapshot, 0)
+	for _, snap := range snapshots {
+		bucketStart := truncateToInterval(snap.Timestamp, intervalDuration)
+		bucket := *snap
+		bucket.Timestamp = bucketStart
+
+		// Snapshots are oldest first, so a later one in the same bucket replaces it
+		if n := len(buckets); n > 0 && buckets[n-1].Timestamp.Equal(bucketStart) {
+			buckets[n-1] = &bucket
+		} else {
+			buckets = append(buckets, &bucket)
+		}
+	}
+
+	return buckets, nil
+}
+
+// oiSnapshotTolerance is how close a recorded snapshot must be to a requested
+// time for GetHistoricalOI to use it instead of replaying trades
+const oiSnapshotTolerance = time.Second
+
+// maxOICacheEntries bounds the reconstructed OI cache; it is cleared when full
+const maxOICacheEntries = 1024
+
+// GetHistoricalOI returns open interest as it stood at a past time. A recorded
+// snapshot within oiSnapshotTolerance is used when there is one; otherwise
+// net positions are reconstructed by replaying every trade and liquidation up
+// to that time, which covers points between snapshots and before the first.
+// Reconstructed results for past times are cached.
+func (me *MatchingEngine) GetHistoricalOI(instrument string, at time.Time) (*domain.OISnapshot, error) {
+	at = at.UTC()
+	cacheable := at.Before(time.Now())
+	key := instrument + ":" + at.Format(time.RFC3339Nano)
+
+	if cacheable {
+		me.oiCacheMu.Lock()
+		cached, ok := me.oiCache[key]
+		me.oiCacheMu.Unlock()
+		if ok {
+			return cached, nil
+		}
+	}
+
+	var trades []*domain.Trade
+	var liquidations []*domain.Liquidation
+	if me.db != nil {
+		snapshots, err := me.db.GetOIHistory(instrument, at.Add(-oiSnapshotTolerance), at.Add(oiSnapshotTolerance))
+		if err != nil {
+			return nil, fmt.Errorf("loading OI snapshots: %w", err)
+		}
+		if snap := nearestOISnapshot(snapshots, at); snap != nil {
+			return snap, nil
+		}
+
+		if trades, err = me.db.GetTradesUntil(instrument, at); err != nil {
+			return nil, fmt.Errorf("loading trades: %w", err)
+		}
+		if liquidations, err = me.db.GetLiquidationsUntil(instrument, at); err != nil {
+			return nil, fmt.Errorf("loading liquidations: %w", err)
+		}
+	} else {
+		trades, liquidations = me.historyUntil(instrument, at)
+	}
+
+	snap := replayOI(instrument, at, me.startingPrice(), trades, liquidations)
+
+	if cacheable {
+		me.oiCacheMu.Lock()
+		if len(me.oiCache) >= maxOICacheEntries {
+			me.oiCache = make(map[string]*domain.OISnapshot)
+		}
+		me.oiCache[key] = snap
+		me.oiCacheMu.Unlock()
+	}
+	return snap, nil
+}
+
+// historyUntil returns the in-memory trades and liquidations on an instrument
+// at or before end, oldest first. Only as complete as the in-memory history.
+func (me *MatchingEngine) historyUntil(instrument string, end time.Time) ([]*domain.Trade, []*domain.Liquidation) {
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	var trades []*domain.Trade
+	for i := len(me.recentTrades) - 1; i >= 0; i-- {
+		if t := me.recentTrades[i]; t.Instrument == instrument && !t.Timestamp.After(end) {
+			trades = append(trades, t)
+		}
+	}
+	var liquidations []*domain.Liquidation
+	for i := len(me.liquidations) - 1; i >= 0; i-- {
+		if liq := me.liquidations[i]; liq.Instrument == instrument && !liq.Timestamp.After(end) {
+			liquidations = append(liquidations, liq)
+		}
+	}
+	return trades, liquidations
+}
+
+// nearestOISnapshot returns the snapshot closest to at, or nil if there are none
+func nearestOISnapshot(snapshots []*domain.OISnapshot, at time.Time) *domain.OISnapshot {
+	var nearest *domain.OISnapshot
+	var best time.Duration
+	for _, snap := range snapshots {
+		d := snap.Timestamp.Sub(at)
+		if d < 0 {
+			d = -d
+		}
+		if nearest == nil || d < best {
+			nearest, best = snap, d
+		}
+	}
+	return nearest
+}
+
+// replayOI rebuilds net positions from trades and liquidations, both oldest
+// first, and totals them into an OI snapshot. A liquidation closes the
+// trader's whole position. The price is startPrice until the first trade.
+func replayOI(instrument string, at time.Time, startPrice decimal.Decimal, trades []*domain.Trade, liquidations []*domain.Liquidation) *domain.OISnapshot {
+	positions := make(map[uuid.UUID]decimal.Decimal)
+	snap := &domain.OISnapshot{
+		Instrument:    instrument,
+		Timestamp:     at,
+		Price:         startPrice,
+		Reconstructed: true,
+	}
+
+	li := 0
+	for _, t := range trades {
+		for ; li < len(liquidations) && liquidations[li].Timestamp.Before(t.Timestamp); li++ {
+			delete(positions, liquidations[li].TraderID)
+		}
+		positions[t.BuyerID] = positions[t.BuyerID].Add(t.Size)
+		positions[t.SellerID] = positions[t.SellerID].Sub(t.Size)
+		snap.Price = t.Price
+	}
+	for ; li < len(liquidations); li++ {
+		delete(positions, liquidations[li].TraderID)
+	}
+
+	for _, size := range positions {
+		switch {
+		case size.IsPositive():
+			snap.LongPositions++
+			snap.LongOI = snap.LongOI.Add(size)
+		case size.IsNegative():
+			snap.ShortPositions++
+			snap.ShortOI = snap.ShortOI.Add(size.Abs())
+		}
+	}
+	snap.OpenInterest = decimal.Max(snap.LongOI, snap.ShortOI)
+	return snap
+}
@@ -0,0 +1,90 @@
+package engine_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/thatreguy/trade.re/internal/db"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestHistoricalOIReplaysTrades(t *testing.T) {
+	for _, persisted := range []bool{false, true} {
+		name := "memory"
+		if persisted {
+			name = "database"
+		}
+		t.Run(name, func(t *testing.T) {
+			h := enginetest.NewTestEngine()
+			if persisted {
+				database, err := db.NewSQLite(filepath.Join(t.TempDir(), "oi.db"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer database.Close()
+				h.Engine.SetDatabase(database)
+			}
+			a := h.AddTrader("a")
+			b := h.AddTrader("b")
+			c := h.AddTrader("c")
+			d := h.AddTrader("d")
+			tick := func() time.Time {
+				time.Sleep(5 * time.Millisecond)
+				now := time.Now()
+				time.Sleep(5 * time.Millisecond)
+				return now
+			}
+
+			before := tick()
+			h.MustLimit(b, domain.SideSell, "1000", "2")
+			h.MustMarket(a, domain.SideBuy, "2") // a long 2, b short 2
+			afterFirst := tick()
+			h.MustLimit(d, domain.SideSell, "1010", "3")
+			h.MustMarket(c, domain.SideBuy, "3") // c long 3, d short 3
+			afterSecond := tick()
+			h.MustLimit(a, domain.SideSell, "1005", "2")
+			h.MustMarket(b, domain.SideBuy, "2") // a and b flat
+			afterThird := tick()
+
+			for _, tc := range []struct {
+				name          string
+				at            time.Time
+				oi, price     string
+				longs, shorts int64
+			}{
+				{"before any trade", before, "0", "1000", 0, 0},
+				{"after the first", afterFirst, "2", "1000", 1, 1},
+				{"after the second", afterSecond, "5", "1010", 2, 2},
+				{"after the close", afterThird, "3", "1005", 1, 1},
+			} {
+				snap, err := h.Engine.GetHistoricalOI(h.Instrument, tc.at)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !snap.Reconstructed || !snap.OpenInterest.Equal(dec(tc.oi)) || !snap.Price.Equal(dec(tc.price)) ||
+					snap.LongPositions != tc.longs || snap.ShortPositions != tc.shorts {
+					t.Errorf("%s: OI %s at %s with %d longs and %d shorts (reconstructed %v), want %s at %s with %d and %d",
+						tc.name, snap.OpenInterest, snap.Price, snap.LongPositions, snap.ShortPositions, snap.Reconstructed,
+						tc.oi, tc.price, tc.longs, tc.shorts)
+				}
+			}
+
+			if !persisted {
+				return
+			}
+			// A recorded snapshot close to the requested time is preferred
+			if err := h.Engine.RecordOISnapshot(h.Instrument); err != nil {
+				t.Fatal(err)
+			}
+			snap, err := h.Engine.GetHistoricalOI(h.Instrument, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if snap.Reconstructed || !snap.OpenInterest.Equal(dec("3")) {
+				t.Fatalf("OI now = %s (reconstructed %v), want the recorded snapshot of 3", snap.OpenInterest, snap.Reconstructed)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"sort"
 	"sync"
 	"time"
 
@@ -18,7 +19,9 @@ type orderNode struct {
 // priceLevel represents all orders at a specific price
 type priceLevel struct {
 	price      decimal.Decimal
-	totalSize  decimal.Decimal
+	totalSize  decimal.Decimal // Displayed size, as shown on the public book
+	hiddenSize decimal.Decimal // Undisplayed iceberg remainder, still matchable
+	reported   decimal.Decimal // Displayed size last sent to level change listeners
 	head       *orderNode
 	tail       *orderNode
 	orderCount int
@@ -29,7 +32,11 @@ type OrderBook struct {
 	instrument string
 	bids       map[string]*priceLevel // price string -> level (buys)
 	asks       map[string]*priceLevel // price string -> level (sells)
+	bidLevels  []*priceLevel          // bids sorted best (highest) first
+	askLevels  []*priceLevel          // asks sorted best (lowest) first
 	orders     map[uuid.UUID]*domain.Order // quick order lookup
+	seq        uint64                      // Level updates emitted so far
+	onChange   func(update *domain.BookLevelUpdate)
 	mu         sync.RWMutex
 }
 
@@ -64,6 +71,7 @@ func (ob *OrderBook) AddOrder(order *domain.Order) {
 			totalSize: decimal.Zero,
 		}
 		levels[priceKey] = level
+		ob.insertLevel(order.Side, level)
 	}
 
 	// Add to FIFO queue
@@ -76,9 +84,26 @@ func (ob *OrderBook) AddOrder(order *domain.Order) {
 		level.tail = node
 	}
 
-	level.totalSize = level.totalSize.Add(order.RemainingSize())
+	level.addSize(order)
 	level.orderCount++
 	ob.orders[order.ID] = order
+	ob.levelChanged(order.Side, level)
+}
+
+// addSize counts an order's unfilled quantity into the level, split into
+// displayed and hidden parts
+func (l *priceLevel) addSize(order *domain.Order) {
+	displayed := order.DisplayedSize()
+	l.totalSize = l.totalSize.Add(displayed)
+	l.hiddenSize = l.hiddenSize.Add(order.RemainingSize().Sub(displayed))
+}
+
+// subSize removes what addSize counted for an order. Call it before changing
+// the order's size or fills and addSize again after.
+func (l *priceLevel) subSize(order *domain.Order) {
+	displayed := order.DisplayedSize()
+	l.totalSize = l.totalSize.Sub(displayed)
+	l.hiddenSize = l.hiddenSize.Sub(order.RemainingSize().Sub(displayed))
 }
 
 // RemoveOrder removes an order from the book
@@ -118,7 +143,7 @@ func (ob *OrderBook) RemoveOrder(orderID uuid.UUID) bool {
 			if curr == level.tail {
 				level.tail = prev
 			}
-			level.totalSize = level.totalSize.Sub(order.RemainingSize())
+			level.subSize(order)
 			level.orderCount--
 			break
 		}
@@ -129,9 +154,63 @@ func (ob *OrderBook) RemoveOrder(orderID uuid.UUID) bool {
 	// Remove empty price level
 	if level.orderCount == 0 {
 		delete(levels, priceKey)
+		ob.removeLevel(order.Side, level)
 	}
 
 	delete(ob.orders, orderID)
+	ob.levelChanged(order.Side, level)
+	return true
+}
+
+// levelChanged reports a level's new displayed size to the change listener,
+// numbering the update with the book's next seq. It is a no-op when the
+// displayed size has not moved, as when only an iceberg's hidden part did.
+// Caller must hold ob.mu or the engine lock.
+func (ob *OrderBook) levelChanged(side domain.Side, level *priceLevel) {
+	size := level.totalSize
+	if level.orderCount == 0 {
+		size = decimal.Zero
+	}
+	if size.Equal(level.reported) {
+		return
+	}
+	level.reported = size
+	ob.seq++
+
+	if ob.onChange != nil {
+		ob.onChange(&domain.BookLevelUpdate{
+			Instrument: ob.instrument,
+			Side:       side,
+			Price:      level.price,
+			Size:       size,
+			Seq:        ob.seq,
+		})
+	}
+}
+
+// ReduceOrder shrinks a resting order's size in place, keeping its queue position
+func (ob *OrderBook) ReduceOrder(orderID uuid.UUID, reduceBy decimal.Decimal) bool {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	order, exists := ob.orders[orderID]
+	if !exists {
+		return false
+	}
+
+	levels := ob.asks
+	if order.Side == domain.SideBuy {
+		levels = ob.bids
+	}
+	level, exists := levels[order.Price.String()]
+	if !exists {
+		return false
+	}
+
+	level.subSize(order)
+	order.Size = order.Size.Sub(reduceBy)
+	level.addSize(order)
+	ob.levelChanged(order.Side, level)
 	return true
 }
 
@@ -148,22 +227,11 @@ func (ob *OrderBook) BestBid() (decimal.Decimal, decimal.Decimal, bool) {
 	ob.mu.RLock()
 	defer ob.mu.RUnlock()
 
-	var bestPrice decimal.Decimal
-	var bestLevel *priceLevel
-	first := true
-
-	for _, level := range ob.bids {
-		if first || level.price.GreaterThan(bestPrice) {
-			bestPrice = level.price
-			bestLevel = level
-			first = false
-		}
-	}
-
-	if bestLevel == nil {
+	if len(ob.bidLevels) == 0 {
 		return decimal.Zero, decimal.Zero, false
 	}
-	return bestPrice, bestLevel.totalSize, true
+	best := ob.bidLevels[0]
+	return best.price, best.totalSize, true
 }
 
 // BestAsk returns the lowest ask price and size
@@ -171,25 +239,15 @@ func (ob *OrderBook) BestAsk() (decimal.Decimal, decimal.Decimal, bool) {
 	ob.mu.RLock()
 	defer ob.mu.RUnlock()
 
-	var bestPrice decimal.Decimal
-	var bestLevel *priceLevel
-	first := true
-
-	for _, level := range ob.asks {
-		if first || level.price.LessThan(bestPrice) {
-			bestPrice = level.price
-			bestLevel = level
-			first = false
-		}
-	}
-
-	if bestLevel == nil {
+	if len(ob.askLevels) == 0 {
 		return decimal.Zero, decimal.Zero, false
 	}
-	return bestPrice, bestLevel.totalSize, true
+	best := ob.askLevels[0]
+	return best.price, best.totalSize, true
 }
 
-// GetSnapshot returns the current order book state
+// GetSnapshot returns the current order book state. Iceberg orders count
+// only their displayed size.
 func (ob *OrderBook) GetSnapshot(depth int) domain.OrderBook {
 	ob.mu.RLock()
 	defer ob.mu.RUnlock()
@@ -199,16 +257,11 @@ func (ob *OrderBook) GetSnapshot(depth int) domain.OrderBook {
 		Timestamp:  time.Now(),
 		Bids:       make([]domain.OrderBookLevel, 0, depth),
 		Asks:       make([]domain.OrderBookLevel, 0, depth),
+		Seq:        ob.seq,
 	}
 
-	// Collect and sort bids (highest first)
-	bidLevels := make([]*priceLevel, 0, len(ob.bids))
-	for _, level := range ob.bids {
-		bidLevels = append(bidLevels, level)
-	}
-	sortLevelsDesc(bidLevels)
-
-	for i, level := range bidLevels {
+	// Bids highest first
+	for i, level := range ob.bidLevels {
 		if i >= depth {
 			break
 		}
@@ -219,14 +272,8 @@ func (ob *OrderBook) GetSnapshot(depth int) domain.OrderBook {
 		})
 	}
 
-	// Collect and sort asks (lowest first)
-	askLevels := make([]*priceLevel, 0, len(ob.asks))
-	for _, level := range ob.asks {
-		askLevels = append(askLevels, level)
-	}
-	sortLevelsAsc(askLevels)
-
-	for i, level := range askLevels {
+	// Asks lowest first
+	for i, level := range ob.askLevels {
 		if i >= depth {
 			break
 		}
@@ -240,7 +287,8 @@ func (ob *OrderBook) GetSnapshot(depth int) domain.OrderBook {
 	return snapshot
 }
 
-// GetOrdersAtPrice returns all orders at a price level (for transparency)
+// GetOrdersAtPrice returns all orders at a price level (for transparency),
+// with their full size including any hidden iceberg remainder
 func (ob *OrderBook) GetOrdersAtPrice(side domain.Side, price decimal.Decimal) []*domain.Order {
 	ob.mu.RLock()
 	defer ob.mu.RUnlock()
@@ -268,47 +316,153 @@ func (ob *OrderBook) GetOrdersAtPrice(side domain.Side, price decimal.Decimal) [
 	return orders
 }
 
-// matchableBids returns bid levels that can match at or above the given price
-func (ob *OrderBook) matchableBids(price decimal.Decimal) []*priceLevel {
-	levels := make([]*priceLevel, 0)
+// DepthWithin returns displayed resting bid size priced at or above low and
+// ask size priced at or below high
+func (ob *OrderBook) DepthWithin(low, high decimal.Decimal) (bidDepth, askDepth decimal.Decimal) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
 	for _, level := range ob.bids {
-		if level.price.GreaterThanOrEqual(price) {
-			levels = append(levels, level)
+		if level.price.GreaterThanOrEqual(low) {
+			bidDepth = bidDepth.Add(level.totalSize)
+		}
+	}
+	for _, level := range ob.asks {
+		if level.price.LessThanOrEqual(high) {
+			askDepth = askDepth.Add(level.totalSize)
 		}
 	}
-	sortLevelsDesc(levels) // Best price first
-	return levels
+	return bidDepth, askDepth
 }
 
-// matchableAsks returns ask levels that can match at or below the given price
-func (ob *OrderBook) matchableAsks(price decimal.Decimal) []*priceLevel {
-	levels := make([]*priceLevel, 0)
+// HiddenSize returns the undisplayed iceberg size resting on each side
+func (ob *OrderBook) HiddenSize() (bidHidden, askHidden decimal.Decimal) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	for _, level := range ob.bids {
+		bidHidden = bidHidden.Add(level.hiddenSize)
+	}
 	for _, level := range ob.asks {
-		if level.price.LessThanOrEqual(price) {
-			levels = append(levels, level)
+		askHidden = askHidden.Add(level.hiddenSize)
+	}
+	return bidHidden, askHidden
+}
+
+// GetTraderOrders returns all resting orders belonging to a trader
+func (ob *OrderBook) GetTraderOrders(traderID uuid.UUID) []*domain.Order {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	orders := make([]*domain.Order, 0)
+	for _, order := range ob.orders {
+		if order.TraderID == traderID {
+			orders = append(orders, order)
 		}
 	}
-	sortLevelsAsc(levels) // Best price first
-	return levels
+	return orders
 }
 
-// Helper sort functions
-func sortLevelsDesc(levels []*priceLevel) {
-	for i := 0; i < len(levels)-1; i++ {
-		for j := i + 1; j < len(levels); j++ {
-			if levels[j].price.GreaterThan(levels[i].price) {
-				levels[i], levels[j] = levels[j], levels[i]
-			}
+// Counts returns the number of price levels and resting orders on each side
+func (ob *OrderBook) Counts() (bidLevels, askLevels, bidOrders, askOrders int) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	for _, level := range ob.bids {
+		bidOrders += level.orderCount
+	}
+	for _, level := range ob.asks {
+		askOrders += level.orderCount
+	}
+	return len(ob.bids), len(ob.asks), bidOrders, askOrders
+}
+
+// levelCursor walks one side's sorted levels best price first, stopping at
+// the first level past its limit. It reads the book's own slice rather than a
+// copy, so starting a match costs nothing however deep the book is. Matching
+// may remove the level it last returned once that level empties, and the
+// cursor allows for it; no other level may be added or removed mid-walk.
+type levelCursor struct {
+	levels  *[]*priceLevel
+	matches func(price decimal.Decimal) bool
+	last    *priceLevel // Level last returned by next
+	i       int         // Index of last, while it is still on the book
+}
+
+// best returns the best matchable level without advancing, or nil
+func (c *levelCursor) best() *priceLevel {
+	if levels := *c.levels; len(levels) > 0 && c.matches(levels[0].price) {
+		return levels[0]
+	}
+	return nil
+}
+
+// next returns the next matchable level, or nil once there are no more
+func (c *levelCursor) next() *priceLevel {
+	levels := *c.levels
+	if c.last != nil && c.i < len(levels) && levels[c.i] == c.last {
+		c.i++ // Still on the book; otherwise its successor has moved up to i
+	}
+	if c.i >= len(levels) || !c.matches(levels[c.i].price) {
+		return nil
+	}
+	c.last = levels[c.i]
+	return c.last
+}
+
+// matchableBids returns a cursor over bid levels that can match at or above
+// the given price, best price first
+func (ob *OrderBook) matchableBids(price decimal.Decimal) *levelCursor {
+	return &levelCursor{
+		levels:  &ob.bidLevels,
+		matches: func(p decimal.Decimal) bool { return p.GreaterThanOrEqual(price) },
+	}
+}
+
+// matchableAsks returns a cursor over ask levels that can match at or below
+// the given price, best price first
+func (ob *OrderBook) matchableAsks(price decimal.Decimal) *levelCursor {
+	return &levelCursor{
+		levels:  &ob.askLevels,
+		matches: func(p decimal.Decimal) bool { return p.LessThanOrEqual(price) },
+	}
+}
+
+// levelIndex returns where a price belongs in one side's sorted levels: the
+// first level whose price is not better than it
+func levelIndex(levels []*priceLevel, side domain.Side, price decimal.Decimal) int {
+	return sort.Search(len(levels), func(i int) bool {
+		if side == domain.SideBuy {
+			return levels[i].price.LessThanOrEqual(price)
 		}
+		return levels[i].price.GreaterThanOrEqual(price)
+	})
+}
+
+// insertLevel adds a new price level to its side's sorted levels
+func (ob *OrderBook) insertLevel(side domain.Side, level *priceLevel) {
+	levels := &ob.askLevels
+	if side == domain.SideBuy {
+		levels = &ob.bidLevels
 	}
+	i := levelIndex(*levels, side, level.price)
+	*levels = append(*levels, nil)
+	copy((*levels)[i+1:], (*levels)[i:])
+	(*levels)[i] = level
 }
 
-func sortLevelsAsc(levels []*priceLevel) {
-	for i := 0; i < len(levels)-1; i++ {
-		for j := i + 1; j < len(levels); j++ {
-			if levels[j].price.LessThan(levels[i].price) {
-				levels[i], levels[j] = levels[j], levels[i]
-			}
+// removeLevel drops an emptied price level from its side's sorted levels
+func (ob *OrderBook) removeLevel(side domain.Side, level *priceLevel) {
+	levels := &ob.askLevels
+	if side == domain.SideBuy {
+		levels = &ob.bidLevels
+	}
+	// Levels are keyed by price string, so "100" and "100.0" can be separate
+	// levels at an equal price
+	for i := levelIndex(*levels, side, level.price); i < len(*levels) && (*levels)[i].price.Equal(level.price); i++ {
+		if (*levels)[i] == level {
+			*levels = append((*levels)[:i], (*levels)[i+1:]...)
+			return
 		}
 	}
 }
@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// deepBook returns a book with one 1-lot order at each of n ask levels from
+// 1000 up, and the orders by level, best first
+func deepBook(n int) (*OrderBook, []*domain.Order) {
+	book := NewOrderBook(domain.RIndexSymbol)
+	orders := make([]*domain.Order, n)
+	for i := range orders {
+		orders[i] = &domain.Order{
+			ID:     uuid.New(),
+			Side:   domain.SideSell,
+			Type:   domain.OrderTypeLimit,
+			Price:  decimal.NewFromInt(100000 + int64(i)).Shift(-2),
+			Size:   decimal.NewFromInt(1),
+			Status: domain.OrderStatusPending,
+		}
+		book.AddOrder(orders[i])
+	}
+	return book, orders
+}
+
+func TestLevelCursorStopsAtLimit(t *testing.T) {
+	book, _ := deepBook(5)
+
+	var prices []string
+	levels := book.matchableAsks(decimal.RequireFromString("1000.02"))
+	for level := levels.next(); level != nil; level = levels.next() {
+		prices = append(prices, level.price.String())
+	}
+	if len(prices) != 3 || prices[0] != "1000" || prices[2] != "1000.02" {
+		t.Fatalf("walked %v, want 1000 to 1000.02", prices)
+	}
+	if best := book.matchableAsks(decimal.RequireFromString("999.99")).best(); best != nil {
+		t.Fatalf("best = %s below every ask, want none", best.price)
+	}
+}
+
+func TestLevelCursorAllowsRemovingWalkedLevel(t *testing.T) {
+	book, orders := deepBook(5)
+
+	// Empty every other level as it is walked, as matching does when it fills one
+	var walked []string
+	levels := book.matchableAsks(decimal.New(1, 18))
+	for i, level := 0, levels.next(); level != nil; i, level = i+1, levels.next() {
+		walked = append(walked, level.price.String())
+		if i%2 == 0 {
+			book.RemoveOrder(level.head.order.ID)
+		}
+	}
+	if len(walked) != len(orders) {
+		t.Fatalf("walked %v, want all %d levels once", walked, len(orders))
+	}
+	if _, asks, _, _ := book.Counts(); asks != 2 {
+		t.Fatalf("%d ask levels left, want 2", asks)
+	}
+}
+
+// copyMatchableAsks is how matchableAsks worked before the cursor: a copy of
+// every crossing level, taken on every match
+func copyMatchableAsks(ob *OrderBook, price decimal.Decimal) []*priceLevel {
+	n := sort.Search(len(ob.askLevels), func(i int) bool {
+		return ob.askLevels[i].price.GreaterThan(price)
+	})
+	return append([]*priceLevel(nil), ob.askLevels[:n]...)
+}
+
+// BenchmarkMatchableAsks compares starting a market buy against 10k ask
+// levels and walking the three it fills
+func BenchmarkMatchableAsks(b *testing.B) {
+	book, _ := deepBook(10000)
+	market := decimal.New(1, 18)
+
+	b.Run("copy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, level := range copyMatchableAsks(book, market)[:3] {
+				_ = level.totalSize
+			}
+		}
+	})
+	b.Run("cursor", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			levels := book.matchableAsks(market)
+			for j, level := 0, levels.next(); j < 3 && level != nil; j, level = j+1, levels.next() {
+				_ = level.totalSize
+			}
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// GetTradesBefore returns trades ordered before the (before, beforeID)
+// cursor, newest first. The id breaks ties between trades sharing a
+// timestamp; uuid.Nil excludes every trade at before. It pages through the
+// full history when a database is configured, otherwise through the
+// in-memory recent trades.
+func (me *MatchingEngine) GetTradesBefore(instrument string, before time.Time, beforeID uuid.UUID, limit int) ([]*domain.Trade, error) {
+	if me.db != nil {
+		trades, err := me.db.GetTradesBefore(instrument, before, beforeID, limit)
+		if err != nil {
+			return nil, fmt.Errorf("loading trades: %w", err)
+		}
+		return trades, nil
+	}
+
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	trades := make([]*domain.Trade, 0)
+	for _, t := range me.recentTrades {
+		if t.Instrument == instrument && historyLess(t.Timestamp, t.ID, before, beforeID) {
+			trades = append(trades, t)
+		}
+	}
+	sort.SliceStable(trades, func(i, j int) bool {
+		return historyLess(trades[j].Timestamp, trades[j].ID, trades[i].Timestamp, trades[i].ID)
+	})
+	if len(trades) > limit {
+		trades = trades[:limit]
+	}
+	return trades, nil
+}
+
+// GetTradesAfter returns trades ordered after the (after, afterID) cursor,
+// oldest first, from the same source as GetTradesBefore
+func (me *MatchingEngine) GetTradesAfter(instrument string, after time.Time, afterID uuid.UUID, limit int) ([]*domain.Trade, error) {
+	if me.db != nil {
+		trades, err := me.db.GetTradesAfter(instrument, after, afterID, limit)
+		if err != nil {
+			return nil, fmt.Errorf("loading trades: %w", err)
+		}
+		return trades, nil
+	}
+
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	trades := make([]*domain.Trade, 0)
+	for _, t := range me.recentTrades {
+		if t.Instrument == instrument && historyLess(after, afterID, t.Timestamp, t.ID) {
+			trades = append(trades, t)
+		}
+	}
+	sort.SliceStable(trades, func(i, j int) bool {
+		return historyLess(trades[i].Timestamp, trades[i].ID, trades[j].Timestamp, trades[j].ID)
+	})
+	if len(trades) > limit {
+		trades = trades[:limit]
+	}
+	return trades, nil
+}
+
+// GetLiquidationsBefore returns liquidations ordered before the (before,
+// beforeID) cursor, newest first, from the database when configured,
+// otherwise the in-memory history
+func (me *MatchingEngine) GetLiquidationsBefore(instrument string, before time.Time, beforeID uuid.UUID, limit int) ([]*domain.Liquidation, error) {
+	if me.db != nil {
+		liqs, err := me.db.GetLiquidationsBefore(instrument, before, beforeID, limit)
+		if err != nil {
+			return nil, fmt.Errorf("loading liquidations: %w", err)
+		}
+		return liqs, nil
+	}
+
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	liqs := make([]*domain.Liquidation, 0)
+	for _, l := range me.liquidations {
+		if l.Instrument == instrument && historyLess(l.Timestamp, l.ID, before, beforeID) {
+			liqs = append(liqs, l)
+		}
+	}
+	sort.SliceStable(liqs, func(i, j int) bool {
+		return historyLess(liqs[j].Timestamp, liqs[j].ID, liqs[i].Timestamp, liqs[i].ID)
+	})
+	if len(liqs) > limit {
+		liqs = liqs[:limit]
+	}
+	return liqs, nil
+}
+
+// GetLiquidationsAfter returns liquidations ordered after the (after,
+// afterID) cursor, oldest first, from the same source as
+// GetLiquidationsBefore
+func (me *MatchingEngine) GetLiquidationsAfter(instrument string, after time.Time, afterID uuid.UUID, limit int) ([]*domain.Liquidation, error) {
+	if me.db != nil {
+		liqs, err := me.db.GetLiquidationsAfter(instrument, after, afterID, limit)
+		if err != nil {
+			return nil, fmt.Errorf("loading liquidations: %w", err)
+		}
+		return liqs, nil
+	}
+
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	liqs := make([]*domain.Liquidation, 0)
+	for _, l := range me.liquidations {
+		if l.Instrument == instrument && historyLess(after, afterID, l.Timestamp, l.ID) {
+			liqs = append(liqs, l)
+		}
+	}
+	sort.SliceStable(liqs, func(i, j int) bool {
+		return historyLess(liqs[i].Timestamp, liqs[i].ID, liqs[j].Timestamp, liqs[j].ID)
+	})
+	if len(liqs) > limit {
+		liqs = liqs[:limit]
+	}
+	return liqs, nil
+}
+
+// historyLess orders history entries by timestamp, then id, matching the
+// database's (timestamp, id) ordering
+func historyLess(aTime time.Time, aID uuid.UUID, bTime time.Time, bID uuid.UUID) bool {
+	if !aTime.Equal(bTime) {
+		return aTime.Before(bTime)
+	}
+	return bytes.Compare(aID[:], bID[:]) < 0
+}
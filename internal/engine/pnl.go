@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// A re-marked position is saved and broadcast only once its unrealized P&L
+// has moved by at least this fraction of its margin, and at least minPnLMove
+var (
+	pnlMoveFraction = decimal.NewFromFloat(0.001)
+	minPnLMove      = decimal.NewFromFloat(0.01)
+)
+
+// StartPnLRefresh re-marks open positions every engine.pnl_refresh_interval_ms
+// until Shutdown. Does nothing if the interval is zero.
+func (me *MatchingEngine) StartPnLRefresh() {
+	if me.engineConfig == nil || me.engineConfig.PnLRefreshIntervalMs <= 0 {
+		return
+	}
+	interval := time.Duration(me.engineConfig.PnLRefreshIntervalMs) * time.Millisecond
+
+	me.wg.Add(1)
+	go func() {
+		defer me.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-me.stopCh:
+				return
+			case <-ticker.C:
+				me.refreshUnrealizedPnL()
+			}
+		}
+	}()
+	slog.Info("Unrealized P&L refresh started", "interval", interval)
+}
+
+// refreshUnrealizedPnL re-marks every open position at its instrument's mark
+// price. Positions whose P&L moved materially are saved and passed to the
+// position handlers.
+func (me *MatchingEngine) refreshUnrealizedPnL() {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	marks := make(map[string]decimal.Decimal)
+	for _, pos := range me.positions {
+		if pos.Size.IsZero() {
+			continue
+		}
+		mark, ok := marks[pos.Instrument]
+		if !ok {
+			mark = me.markPriceLocked(pos.Instrument)
+			marks[pos.Instrument] = mark
+		}
+
+		pnl := pos.ComputeUnrealizedPnL(mark)
+		threshold := decimal.Max(pos.Margin.Mul(pnlMoveFraction), minPnLMove)
+		if pnl.Sub(pos.UnrealizedPnL).Abs().LessThan(threshold) {
+			continue
+		}
+		pos.UnrealizedPnL = pnl
+		pos.UpdatedAt = time.Now()
+		if me.marginModeLocked(pos.TraderID) == domain.MarginModeCross {
+			pos.LiquidationPrice = me.liquidationPriceLocked(pos)
+		}
+
+		if me.db != nil {
+			if err := me.db.SavePosition(pos); err != nil {
+				slog.Error("Error saving re-marked position", "error", err)
+			}
+		}
+		for _, handler := range me.positionHandlers {
+			handler(pos)
+		}
+	}
+}
+
+// markedPositionLocked returns a copy of a position with its unrealized P&L
+// at the current mark price and its owner's margin mode. A cross position's
+// liquidation price is recomputed too. Caller must hold me.mu.
+func (me *MatchingEngine) markedPositionLocked(pos *domain.Position) *domain.Position {
+	marked := *pos
+	marked.UnrealizedPnL = marked.ComputeUnrealizedPnL(me.markPriceLocked(pos.Instrument))
+	marked.MarginMode = me.marginModeLocked(pos.TraderID)
+	if marked.MarginMode == domain.MarginModeCross {
+		marked.LiquidationPrice = me.liquidationPriceLocked(pos)
+	}
+	return &marked
+}
@@ -0,0 +1,76 @@
+package engine_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+// ratOf converts a decimal to an exact rational
+func ratOf(d decimal.Decimal) *big.Rat {
+	r, _ := new(big.Rat).SetString(d.String())
+	return r
+}
+
+func TestEntryPriceAfterManySmallAddsWithinOneTick(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+
+	// Rounding must not depend on the process-wide default, which anything
+	// may change; at 0 places an unrounded quotient would land on whole units
+	saved := decimal.DivisionPrecision
+	decimal.DivisionPrecision = 0
+	t.Cleanup(func() { decimal.DivisionPrecision = saved })
+
+	cost, size, margin := new(big.Rat), new(big.Rat), new(big.Rat)
+	for i := 0; i < 1000; i++ {
+		price := decimal.NewFromInt(100000 + int64(i*37%97)).Shift(-2)
+		lot := decimal.NewFromInt(int64(1 + i%7)).Shift(-3)
+		h.MustLimit(maker, domain.SideSell, price.String(), lot.String())
+		if _, err := h.Submit(&domain.Order{
+			TraderID: taker.ID,
+			Side:     domain.SideBuy,
+			Type:     domain.OrderTypeMarket,
+			Size:     lot,
+			Leverage: 3,
+		}); err != nil {
+			t.Fatalf("add %d: %v", i, err)
+		}
+
+		notional := new(big.Rat).Mul(ratOf(price), ratOf(lot))
+		cost.Add(cost, notional)
+		size.Add(size, ratOf(lot))
+		margin.Add(margin, new(big.Rat).Quo(notional, big.NewRat(3, 1)))
+	}
+
+	tick := ratOf(h.Config.RIndex.TickSize)
+	withinTick := func(name string, got decimal.Decimal, want *big.Rat) {
+		t.Helper()
+		diff := new(big.Rat).Sub(ratOf(got), want)
+		if diff.Abs(diff).Cmp(tick) >= 0 {
+			t.Errorf("%s = %s, want %s within one tick", name, got, want.FloatString(12))
+		}
+	}
+
+	entry := new(big.Rat).Quo(cost, size)
+	for _, trader := range []*domain.Trader{taker, maker} {
+		pos := h.Position(trader)
+		if ratOf(pos.Size.Abs()).Cmp(size) != 0 {
+			t.Fatalf("%s position = %s, want %s", trader.Username, pos.Size, size.FloatString(3))
+		}
+		withinTick(trader.Username+" entry price", pos.EntryPrice, entry)
+	}
+
+	pos := h.Position(taker)
+	withinTick("taker margin", pos.Margin, margin)
+
+	// Liquidation distance = entry / leverage * (1 - maintenance margin)
+	maint := ratOf(h.Config.Liquidation.MaintenanceMargins.GetMarginForLeverage(pos.Leverage))
+	distance := new(big.Rat).Quo(entry, big.NewRat(int64(pos.Leverage), 1))
+	distance.Mul(distance, new(big.Rat).Sub(big.NewRat(1, 1), maint))
+	withinTick("taker liquidation price", pos.LiquidationPrice, new(big.Rat).Sub(entry, distance))
+}
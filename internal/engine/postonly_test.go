@@ -0,0 +1,73 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestPostOnlyRejectedAtTouch(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+	trader := h.AddTrader("trader")
+	h.MustLimit(maker, domain.SideBuy, "999", "1")
+	h.MustLimit(maker, domain.SideSell, "1001", "1")
+
+	postOnly := func(side domain.Side, price string) (*domain.Order, []*domain.Trade, error) {
+		order := &domain.Order{
+			TraderID: trader.ID,
+			Side:     side,
+			Type:     domain.OrderTypeLimit,
+			Price:    dec(price),
+			Size:     dec("2"),
+			Leverage: 1,
+			PostOnly: true,
+		}
+		trades, err := h.Submit(order)
+		return order, trades, err
+	}
+
+	// Exactly at the touch, and through it, would both take liquidity
+	for _, c := range []struct {
+		side  domain.Side
+		price string
+	}{
+		{domain.SideBuy, "1001"},
+		{domain.SideBuy, "1005"},
+		{domain.SideSell, "999"},
+		{domain.SideSell, "995"},
+	} {
+		if _, trades, err := postOnly(c.side, c.price); err == nil || len(trades) != 0 {
+			t.Fatalf("post-only %s at %s = %d trades, %v, want refused", c.side, c.price, len(trades), err)
+		}
+	}
+	if bids, asks := h.Bids(), h.Asks(); len(bids) != 1 || !bids[0].Size.Equal(dec("1")) || len(asks) != 1 || !asks[0].Size.Equal(dec("1")) {
+		t.Fatalf("book = %+v / %+v, want it untouched", bids, asks)
+	}
+	if orders := h.Engine.GetOpenOrders(trader.ID, h.Instrument); len(orders) != 0 {
+		t.Fatalf("%d open orders after refusals, want none", len(orders))
+	}
+	if got := h.PositionSize(trader); !got.IsZero() {
+		t.Fatalf("position = %s, want no fill at all", got)
+	}
+
+	// Inside the spread, or joining its own side of the touch, it rests as a maker
+	for _, c := range []struct {
+		side  domain.Side
+		price string
+	}{
+		{domain.SideBuy, "1000"},
+		{domain.SideSell, "1001"}, // Joining the best ask
+	} {
+		order, trades, err := postOnly(c.side, c.price)
+		if err != nil || len(trades) != 0 || order.Status != domain.OrderStatusPending {
+			t.Fatalf("passive post-only %s at %s = %s, %d trades, %v, want resting", c.side, c.price, order.Status, len(trades), err)
+		}
+	}
+
+	order := &domain.Order{TraderID: trader.ID, Side: domain.SideBuy, Type: domain.OrderTypeMarket, Size: dec("1"), Leverage: 1, PostOnly: true}
+	if _, err := h.Submit(order); err == nil {
+		t.Fatal("post-only market order accepted")
+	}
+}
@@ -0,0 +1,61 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestReduceOrderKeepsQueuePriority(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	first := h.AddTrader("first")
+	second := h.AddTrader("second")
+	taker := h.AddTrader("taker")
+
+	order := h.MustLimit(first, domain.SideBuy, "1000", "3")
+	h.MustLimit(second, domain.SideBuy, "1000", "1")
+
+	reduced, err := h.Engine.ReduceOrder(first.ID, order.ID, h.Instrument, dec("2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reduced.Status == domain.OrderStatusCancelled || !reduced.RemainingSize().Equal(dec("1")) {
+		t.Fatalf("reduced order = %s with %s remaining, want 1 left resting", reduced.Status, reduced.RemainingSize())
+	}
+	if bids := h.Bids(); len(bids) != 1 || !bids[0].Size.Equal(dec("2")) || bids[0].OrderCount != 2 {
+		t.Fatalf("bids = %+v, want 2 across both orders", bids)
+	}
+
+	// The reduced order is still first in line
+	trades := h.MustMarket(taker, domain.SideSell, "1")
+	if len(trades) != 1 || trades[0].BuyerID != first.ID {
+		t.Fatalf("fills = %+v, want the reduced order filled first", trades)
+	}
+}
+
+func TestReduceOrderToZeroCancels(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	trader := h.AddTrader("trader")
+	taker := h.AddTrader("taker")
+
+	order := h.MustLimit(trader, domain.SideSell, "1000", "2")
+	h.MustMarket(taker, domain.SideBuy, "0.5")
+
+	if _, err := h.Engine.ReduceOrder(trader.ID, order.ID, h.Instrument, dec("2")); err == nil {
+		t.Fatal("reducing past the unfilled 1.5 accepted")
+	}
+	reduced, err := h.Engine.ReduceOrder(trader.ID, order.ID, h.Instrument, dec("1.5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reduced.Status != domain.OrderStatusCancelled || !reduced.FilledSize.Equal(dec("0.5")) {
+		t.Fatalf("order = %s filled %s, want cancelled keeping the 0.5 fill", reduced.Status, reduced.FilledSize)
+	}
+	if asks := h.Asks(); len(asks) != 0 {
+		t.Fatalf("asks = %+v, want none", asks)
+	}
+	if _, err := h.Engine.ReduceOrder(trader.ID, order.ID, h.Instrument, dec("0.1")); err == nil {
+		t.Fatal("reducing a cancelled order succeeded")
+	}
+}
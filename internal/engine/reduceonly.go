@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// reducibleLocked returns how much of the trader's position an order on its
+// side would close: the position's size if the order is on the opposite
+// side, otherwise zero. Caller must hold me.mu.
+func (me *MatchingEngine) reducibleLocked(order *domain.Order) decimal.Decimal {
+	pos, exists := me.positions[fmt.Sprintf("%s:%s", order.TraderID, order.Instrument)]
+	if !exists {
+		return decimal.Zero
+	}
+	if (pos.IsLong() && order.Side == domain.SideSell) || (pos.IsShort() && order.Side == domain.SideBuy) {
+		return pos.Size.Abs()
+	}
+	return decimal.Zero
+}
+
+// capReduceOnlyLocked caps a reduce-only order's unfilled size at the
+// position it can close, before it matches. It fails if the trader has no
+// position on the other side. Caller must hold me.mu.
+func (me *MatchingEngine) capReduceOnlyLocked(order *domain.Order) error {
+	if !order.ReduceOnly {
+		return nil
+	}
+	reducible := me.reducibleLocked(order)
+	if !reducible.IsPositive() {
+		return fmt.Errorf("reduce-only %s order has no %s position to reduce", order.Side, oppositePositionSide(order.Side))
+	}
+	if order.RemainingSize().GreaterThan(reducible) {
+		order.Size = order.FilledSize.Add(reducible)
+	}
+	return nil
+}
+
+// oppositePositionSide names the position a buy or sell order would reduce
+func oppositePositionSide(side domain.Side) string {
+	if side == domain.SideBuy {
+		return "short"
+	}
+	return "long"
+}
+
+// trimReduceOnlyLocked brings a trader's resting reduce-only orders back in
+// line after their position changed. Oldest first, each keeps what is left of
+// the position to close; orders on the wrong side or beyond the position are
+// cancelled, and one that straddles the end is reduced. Caller must hold me.mu.
+func (me *MatchingEngine) trimReduceOnlyLocked(traderID uuid.UUID, instrument string) {
+	book, exists := me.books[instrument]
+	if !exists {
+		return
+	}
+
+	var orders []*domain.Order
+	for _, order := range book.GetTraderOrders(traderID) {
+		if order.ReduceOnly {
+			orders = append(orders, order)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+	})
+
+	allowance := make(map[domain.Side]decimal.Decimal)
+	for _, order := range orders {
+		left, seen := allowance[order.Side]
+		if !seen {
+			left = me.reducibleLocked(order)
+		}
+		remaining := order.RemainingSize()
+
+		switch {
+		case !left.IsPositive():
+			if err := me.cancelOrderLocked(order.ID, instrument); err != nil {
+				slog.Error("Error cancelling reduce-only order", "order_id", order.ID, "error", err)
+			}
+		case remaining.GreaterThan(left):
+			book.ReduceOrder(order.ID, remaining.Sub(left))
+			order.UpdatedAt = time.Now()
+			if me.db != nil {
+				if err := me.db.SaveOrder(order); err != nil {
+					slog.Error("Error saving reduce-only order to database", "error", err)
+				}
+			}
+			for _, handler := range me.orderHandlers {
+				handler(order)
+			}
+		}
+		allowance[order.Side] = decimal.Max(decimal.Zero, left.Sub(remaining))
+	}
+}
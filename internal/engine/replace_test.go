@@ -0,0 +1,129 @@
+package engine_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestCancelReplaceNeverShowsTwoOrNoOrders(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+
+	// 10 at 900 ties up 9000 of the 10000 balance, so a second quote would
+	// not fit: each replacement has to take over its predecessor's margin
+	order := h.MustLimit(maker, domain.SideBuy, "900", "10")
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	var counts []int
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if n := len(h.Engine.GetOpenOrders(maker.ID, h.Instrument)); n != 1 {
+				counts = append(counts, n)
+			}
+		}
+	}()
+
+	for i := 1; i <= 200; i++ {
+		replacement := &domain.Order{
+			TraderID:   maker.ID,
+			Instrument: h.Instrument,
+			Side:       domain.SideBuy,
+			Type:       domain.OrderTypeLimit,
+			Price:      decimal.NewFromInt(900).Add(decimal.New(int64(i%50), -2)),
+			Size:       dec("10"),
+			Leverage:   1,
+		}
+		if _, err := h.Engine.CancelReplace(order.ID, replacement); err != nil {
+			t.Fatalf("reprice %d: %v", i, err)
+		}
+		order = replacement
+	}
+	close(done)
+	wg.Wait()
+
+	if len(counts) > 0 {
+		t.Fatalf("observed open order counts %v during repricing, want always 1", counts)
+	}
+	open := h.Engine.GetOpenOrders(maker.ID, h.Instrument)
+	if len(open) != 1 || open[0].ID != order.ID {
+		t.Fatalf("open orders = %v, want only the last replacement", ids(open))
+	}
+}
+
+func TestCancelReplaceKeepsOriginalOnRejection(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+	order := h.MustLimit(maker, domain.SideBuy, "999", "1")
+
+	replacement := &domain.Order{
+		TraderID:   maker.ID,
+		Instrument: h.Instrument,
+		Side:       domain.SideBuy,
+		Type:       domain.OrderTypeLimit,
+		Price:      dec("999.005"), // Off the tick
+		Size:       dec("1"),
+		Leverage:   1,
+	}
+	if _, err := h.Engine.CancelReplace(order.ID, replacement); err == nil {
+		t.Fatal("off-tick replacement accepted")
+	}
+	if open := h.Engine.GetOpenOrders(maker.ID, h.Instrument); len(open) != 1 || open[0].ID != order.ID {
+		t.Fatalf("open orders = %v, want the original left resting", ids(open))
+	}
+}
+
+func ids(orders []*domain.Order) []string {
+	out := make([]string, len(orders))
+	for i, o := range orders {
+		out[i] = fmt.Sprintf("%s@%s", o.ID, o.Price)
+	}
+	return out
+}
+
+func TestCancelReplaceOCOLegKeepsPair(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	trader := h.AddTrader("trader")
+	buyer := h.AddTrader("buyer")
+	limit, stop := submitOCO(t, h, trader)
+
+	// A leg can only be swapped for another order that may rest
+	market := &domain.Order{TraderID: trader.ID, Instrument: h.Instrument, Side: domain.SideSell,
+		Type: domain.OrderTypeMarket, Size: dec("1"), Leverage: 1}
+	if _, err := h.Engine.CancelReplace(limit.ID, market); err == nil {
+		t.Fatal("OCO leg replaced by a market order")
+	}
+
+	repriced := &domain.Order{TraderID: trader.ID, Instrument: h.Instrument, Side: domain.SideSell,
+		Type: domain.OrderTypeLimit, Price: dec("1030"), Size: dec("1"), Leverage: 1}
+	if _, err := h.Engine.CancelReplace(limit.ID, repriced); err != nil {
+		t.Fatal(err)
+	}
+	if limit.Status != domain.OrderStatusCancelled || stop.Status != domain.OrderStatusPending {
+		t.Fatalf("after the reprice the old leg is %s and the stop %s, want cancelled and still pending", limit.Status, stop.Status)
+	}
+	if repriced.OCOGroupID == nil || *repriced.OCOGroupID != *stop.OCOGroupID {
+		t.Fatal("replacement not in the OCO group")
+	}
+	if open := h.Engine.GetOpenOrders(trader.ID, h.Instrument); len(open) != 2 {
+		t.Fatalf("%d open orders after the reprice, want the replacement and the stop", len(open))
+	}
+
+	// The replacement now cancels the stop when it fills
+	h.MustMarket(buyer, domain.SideBuy, "1")
+	if repriced.Status != domain.OrderStatusFilled || stop.Status != domain.OrderStatusCancelled {
+		t.Fatalf("replacement %s, stop %s, want filled and cancelled", repriced.Status, stop.Status)
+	}
+}
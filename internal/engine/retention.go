@@ -0,0 +1,36 @@
+package engine
+
+import "time"
+
+// Default in-memory history sizes when engine config leaves them unset
+const (
+	defaultMaxRecentTrades       = 1000
+	defaultMaxRecentLiquidations = 100
+)
+
+// maxRecentTrades is how many trades recentTrades keeps
+func (me *MatchingEngine) maxRecentTrades() int {
+	if me.engineConfig != nil && me.engineConfig.MaxRecentTrades > 0 {
+		return me.engineConfig.MaxRecentTrades
+	}
+	return defaultMaxRecentTrades
+}
+
+// maxRecentLiquidations is how many liquidations the in-memory history keeps
+func (me *MatchingEngine) maxRecentLiquidations() int {
+	if me.engineConfig != nil && me.engineConfig.MaxRecentLiquidations > 0 {
+		return me.engineConfig.MaxRecentLiquidations
+	}
+	return defaultMaxRecentLiquidations
+}
+
+// tradesBufferedSinceLocked reports whether recentTrades holds every trade
+// from start on, so a query over that window need not read the database.
+// Caller must hold me.mu.
+func (me *MatchingEngine) tradesBufferedSinceLocked(start time.Time) bool {
+	if !me.tradesTrimmed {
+		return true
+	}
+	n := len(me.recentTrades)
+	return n > 0 && start.After(me.recentTrades[n-1].Timestamp)
+}
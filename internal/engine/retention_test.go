@@ -0,0 +1,74 @@
+package engine_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/db"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+// tradedPastBuffer prints n trades of 0.1 at 1000 into an engine that keeps
+// only the last few in memory, alternating sides so positions stay small
+func tradedPastBuffer(tb testing.TB, n int) *enginetest.Harness {
+	tb.Helper()
+	database, err := db.NewSQLite(filepath.Join(tb.TempDir(), "retention.db"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { database.Close() })
+	cfg := config.Default()
+	cfg.Engine.MaxRecentTrades = 5
+	h := enginetest.NewTestEngineWithConfig(cfg)
+	h.Engine.SetDatabase(database)
+
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+	sides := [2]domain.Side{domain.SideSell, domain.SideBuy}
+	for i := 0; i < n; i++ {
+		h.MustLimit(maker, sides[i%2], "1000", "0.1")
+		h.MustMarket(taker, sides[(i+1)%2], "0.1")
+	}
+	return h
+}
+
+func TestHistoryFallsBackToDatabasePastBuffer(t *testing.T) {
+	const n = 20
+	h := tradedPastBuffer(t, n)
+	if trades := h.Engine.GetRecentTrades(h.Instrument, n); len(trades) != 5 {
+		t.Fatalf("%d trades in memory, want the configured 5", len(trades))
+	}
+
+	now := time.Now()
+	if trades := h.Engine.GetHistoricalTrades(h.Instrument, now.Add(-time.Hour), now, 100); len(trades) != n {
+		t.Fatalf("%d historical trades, want all %d from the database", len(trades), n)
+	}
+	stats := h.Engine.GetMarketStats(h.Instrument)
+	if want := decimal.NewFromInt(100 * n); !stats.Volume24h.Equal(want) {
+		t.Fatalf("24h volume = %s, want %s across every trade", stats.Volume24h, want)
+	}
+}
+
+func BenchmarkDatabaseFallback(b *testing.B) {
+	h := tradedPastBuffer(b, 500)
+	now := time.Now()
+	b.Run("historical_trades", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			h.Engine.GetHistoricalTrades(h.Instrument, now.Add(-time.Hour), now, 1000)
+		}
+	})
+	b.Run("candles", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			h.Engine.GetCandles(h.Instrument, domain.CandleInterval1m, 100)
+		}
+	})
+	b.Run("market_stats", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			h.Engine.GetMarketStats(h.Instrument)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func newRiskTierEngine() *enginetest.Harness {
+	cfg := config.Default()
+	cfg.Game.StartingBalance = dec("1000000")
+	cfg.Liquidation.RiskTiers = []config.RiskTier{
+		{MaxNotional: dec("10000"), MaxLeverage: 100, MaintenanceMargin: dec("0.01")},
+		{MaxNotional: dec("100000"), MaxLeverage: 20, MaintenanceMargin: dec("0.03")},
+		{MaxNotional: dec("1000000"), MaxLeverage: 5, MaintenanceMargin: dec("0.1")},
+	}
+	return enginetest.NewTestEngineWithConfig(cfg)
+}
+
+func TestRiskTiersCapLeverageByNotional(t *testing.T) {
+	h := newRiskTierEngine()
+	maker := h.AddTrader("maker")
+	trader := h.AddTrader("trader")
+	h.MustLimit(maker, domain.SideSell, "1000", "500")
+
+	buy := func(size string, leverage int) error {
+		_, err := h.Submit(&domain.Order{
+			TraderID: trader.ID,
+			Side:     domain.SideBuy,
+			Type:     domain.OrderTypeMarket,
+			Size:     dec(size),
+			Leverage: leverage,
+		})
+		return err
+	}
+	for _, tc := range []struct {
+		size     string
+		leverage int
+		ok       bool
+	}{
+		{"10", 100, true},    // 10000, the top of the first tier
+		{"1", 100, false},    // 11000 is in the 20x tier
+		{"1", 20, true},      // 11000 at 20x fits
+		{"89", 20, true},     // 100000
+		{"0.001", 20, false}, // Just over into the 5x tier
+		{"0.001", 5, true},
+	} {
+		err := buy(tc.size, tc.leverage)
+		if tc.ok && err != nil {
+			t.Fatalf("buying %s at %dx rejected: %v", tc.size, tc.leverage, err)
+		}
+		if !tc.ok && err == nil {
+			t.Fatalf("buying %s at %dx accepted", tc.size, tc.leverage)
+		}
+	}
+
+	// Shrinking is always allowed, whatever the leverage
+	if _, err := h.Submit(&domain.Order{TraderID: trader.ID, Side: domain.SideSell, Type: domain.OrderTypeLimit,
+		Price: dec("1100"), Size: dec("1"), Leverage: 100, ReduceOnly: true}); err != nil {
+		t.Fatalf("reducing at 100x rejected: %v", err)
+	}
+}
+
+func TestRiskTierMaintenanceMarginSetsLiquidationPrice(t *testing.T) {
+	h := newRiskTierEngine()
+	maker := h.AddTrader("maker")
+	small := h.AddTrader("small")
+	large := h.AddTrader("large")
+	h.MustLimit(maker, domain.SideSell, "1000", "205")
+
+	for _, tc := range []struct {
+		trader   *domain.Trader
+		size     string
+		leverage int
+		liq      string
+	}{
+		// 5000 notional: the tier's 1% beats the 0.5% band for 10x
+		{small, "5", 10, "901"},  // 1000 - 1000/10 * 0.99
+		{large, "200", 5, "820"}, // 200000 notional at 10%: 1000 - 1000/5 * 0.9
+	} {
+		if _, err := h.Submit(&domain.Order{TraderID: tc.trader.ID, Side: domain.SideBuy, Type: domain.OrderTypeMarket, Size: dec(tc.size), Leverage: tc.leverage}); err != nil {
+			t.Fatal(err)
+		}
+		if got := h.Position(tc.trader).LiquidationPrice; !got.Equal(dec(tc.liq)) {
+			t.Errorf("%s liquidation price = %s, want %s", tc.trader.Username, got, tc.liq)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"log/slog"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// selfTradeMode resolves an order's self-trade prevention mode: its own
+// setting, else the engine's, else skip
+func (me *MatchingEngine) selfTradeMode(order *domain.Order) domain.SelfTradePrevention {
+	if order.SelfTradePrevention != "" {
+		return order.SelfTradePrevention
+	}
+	if me.engineConfig != nil && me.engineConfig.SelfTradePrevention != "" {
+		return domain.SelfTradePrevention(me.engineConfig.SelfTradePrevention)
+	}
+	return domain.STPSkip
+}
+
+// cancelSelfTradesLocked cancels the aggressor's own resting orders that
+// matching met under cancel-resting. Each goes out as a normal order update.
+// Caller must hold me.mu.
+func (me *MatchingEngine) cancelSelfTradesLocked(aggressor *domain.Order, resting []*domain.Order) {
+	for _, order := range resting {
+		if order.Status == domain.OrderStatusCancelled {
+			continue // Already cancelled as an OCO sibling
+		}
+		if err := me.cancelOrderLocked(order.ID, order.Instrument); err != nil {
+			slog.Error("Error cancelling self-trade order", "order_id", order.ID, "error", err)
+			continue
+		}
+		me.loggerLocked().Debug("Resting order cancelled by self-trade prevention",
+			"order_id", order.ID, "aggressor_order_id", aggressor.ID, "trader_id", order.TraderID)
+	}
+}
@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// StartSnapshots runs the periodic snapshot writer (OI history and the
+// recent-trades ring) at the configured snapshot interval. It is stopped by
+// Shutdown.
+func (me *MatchingEngine) StartSnapshots() {
+	if me.engineConfig == nil || me.engineConfig.SnapshotIntervalSeconds <= 0 {
+		return
+	}
+	interval := time.Duration(me.engineConfig.SnapshotIntervalSeconds) * time.Second
+
+	me.wg.Add(1)
+	go me.snapshotLoop(interval)
+	slog.Info("Snapshot writer started", "interval", interval)
+}
+
+// snapshotLoop records a snapshot for every instrument on each tick
+func (me *MatchingEngine) snapshotLoop(interval time.Duration) {
+	defer me.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-me.stopCh:
+			return
+		case <-ticker.C:
+			me.recordSnapshots()
+		}
+	}
+}
+
+// recordSnapshots writes a snapshot for every registered instrument, saves
+// the recent-trades ring and completed candles, returning how many OI
+// snapshots succeeded
+func (me *MatchingEngine) recordSnapshots() int {
+	if err := me.saveTradeRing(); err != nil {
+		slog.Error("Error saving trade ring", "error", err)
+	}
+	me.saveCandles(time.Now(), false)
+
+	me.mu.RLock()
+	instruments := make([]string, 0, len(me.books))
+	for instrument := range me.books {
+		instruments = append(instruments, instrument)
+	}
+	me.mu.RUnlock()
+
+	written := 0
+	for _, instrument := range instruments {
+		if err := me.RecordOISnapshot(instrument); err != nil {
+			slog.Error("Error recording OI snapshot", "instrument", instrument, "error", err)
+			continue
+		}
+		written++
+	}
+	return written
+}
+
+// Shutdown stops accepting orders, stops the background writers, takes a
+// final snapshot and closes the database. With write batching on, writes made
+// under the engine lock may still be queued rather than committed; closing
+// the database drains that queue first. If ctx expires before the writers
+// stop or the queue drains, the database is left open and ctx's error is
+// returned.
+func (me *MatchingEngine) Shutdown(ctx context.Context) error {
+	me.mu.Lock()
+	if me.shuttingDown {
+		me.mu.Unlock()
+		return fmt.Errorf("engine already shut down")
+	}
+	me.shuttingDown = true
+	me.mu.Unlock()
+	slog.Info("Engine shutting down: new orders rejected")
+
+	close(me.stopCh)
+	done := make(chan struct{})
+	go func() {
+		me.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for background writers: %w", ctx.Err())
+	}
+
+	snapshots := me.recordSnapshots()
+	me.saveCandles(time.Now(), true)
+	slog.Info("Engine shutdown: background writers stopped", "final_snapshots", snapshots)
+
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	if me.db != nil {
+		if err := me.db.CloseContext(ctx); err != nil {
+			return fmt.Errorf("closing database: %w", err)
+		}
+		me.db = nil
+		slog.Info("Engine shutdown: database closed")
+	}
+	return nil
+}
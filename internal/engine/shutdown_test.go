@@ -0,0 +1,76 @@
+package engine_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/db"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestShutdownFlushesQueuedWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shutdown.db")
+	database, err := db.NewSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nothing flushes on its own before Shutdown
+	database.SetWriteBatching(time.Hour, 1000000)
+
+	cfg := config.Default()
+	cfg.Engine.SnapshotIntervalSeconds = 3600
+	h := enginetest.NewTestEngineWithConfig(cfg)
+	h.Engine.SetDatabase(database)
+	h.Engine.StartSnapshots()
+
+	start := time.Now()
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+	h.MustLimit(maker, domain.SideSell, "1000", "3")
+	h.MustMarket(taker, domain.SideBuy, "1")
+	h.MustMarket(taker, domain.SideBuy, "1")
+	h.MustLimit(maker, domain.SideBuy, "990", "1")
+	if stats := database.WriteQueueStats(); stats.QueueDepth == 0 {
+		t.Fatal("no writes queued before shutdown")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := h.Engine.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := h.Limit(maker, domain.SideBuy, "990", "1"); err == nil {
+		t.Fatal("order accepted after shutdown")
+	}
+
+	reopened, err := db.NewSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	traders, err := reopened.GetAllTraders()
+	if err != nil || len(traders) != 2 {
+		t.Fatalf("traders = %d (%v), want 2", len(traders), err)
+	}
+	trades, err := reopened.GetRecentTrades(h.Instrument, 10)
+	if err != nil || len(trades) != 2 {
+		t.Fatalf("trades = %d (%v), want 2", len(trades), err)
+	}
+	open, err := reopened.GetOpenOrders(h.Instrument)
+	if err != nil || len(open) != 2 {
+		t.Fatalf("open orders = %d (%v), want the rest of the ask and the bid", len(open), err)
+	}
+	positions, err := reopened.GetAllPositions(h.Instrument)
+	if err != nil || len(positions) != 2 {
+		t.Fatalf("positions = %d (%v), want 2", len(positions), err)
+	}
+	snapshots, err := reopened.GetOIHistory(h.Instrument, start, time.Now())
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("OI snapshots = %d (%v), want the final one", len(snapshots), err)
+	}
+}
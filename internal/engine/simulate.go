@@ -0,0 +1,212 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// Reasons a previewed match stops before the order is filled
+const (
+	stopSelfTrade      = "self_trade"
+	stopWorkCapped     = "work_capped"
+	stopSlippageCapped = "slippage_capped"
+	stopKilled         = "killed"
+)
+
+// matchPreview is what matching an order against the book would do right now
+type matchPreview struct {
+	fills  []domain.SimulatedFill
+	filled decimal.Decimal
+	stop   string // Why matching would stop short, if it would
+}
+
+// previewMatchLocked walks the book as matchOrder would, without changing
+// anything, and returns the fills the order would make: the same work bound
+// and slippage band, the same self-trade prevention and OCO skips, resting
+// reduce-only orders capped at their position, and makers dropping out once
+// their protection would trip. Caller must hold me.mu.
+func (me *MatchingEngine) previewMatchLocked(book *OrderBook, order *domain.Order) *matchPreview {
+	preview := &matchPreview{}
+	maxLevels, maxOrders := me.matchBounds()
+	levelsVisited, ordersVisited := 0, 0
+	need := order.RemainingSize()
+	stp := me.selfTradeMode(order)
+
+	skip := make(map[uuid.UUID]bool)                   // OCO siblings of orders already filled
+	tripped := make(map[uuid.UUID]bool)                // Makers whose protection would trip
+	makerFilled := make(map[uuid.UUID]decimal.Decimal) // Size each maker has filled so far
+	type makerWindow struct {
+		fills int
+		total decimal.Decimal
+	}
+	windows := make(map[uuid.UUID]*makerWindow)
+	now := time.Now()
+	levels := matchLevelsFor(book, order)
+	band, banded := me.slippageBand(levels.best(), order)
+
+	for level := levels.next(); level != nil; level = levels.next() {
+		if banded && beyondBand(order, level.price, band) {
+			preview.stop = stopSlippageCapped
+			return preview
+		}
+		if maxLevels > 0 && levelsVisited >= maxLevels {
+			preview.stop = stopWorkCapped
+			return preview
+		}
+		levelsVisited++
+
+		for curr := level.head; curr != nil; curr = curr.next {
+			if maxOrders > 0 && ordersVisited >= maxOrders {
+				preview.stop = stopWorkCapped
+				return preview
+			}
+			ordersVisited++
+
+			resting := curr.order
+			if resting.TraderID == order.TraderID && !skip[resting.ID] && stp == domain.STPCancelAggressor {
+				preview.stop = stopSelfTrade
+				return preview
+			}
+			if resting.TraderID == order.TraderID || me.mmpFrozen(resting.TraderID) ||
+				tripped[resting.TraderID] || skip[resting.ID] {
+				continue
+			}
+
+			available := resting.RemainingSize()
+			if resting.ReduceOnly {
+				// Every fill of this maker in one match is on the same side,
+				// so each one shrinks the position the order can still close
+				reducible := me.reducibleLocked(resting).Sub(makerFilled[resting.TraderID])
+				if !reducible.IsPositive() {
+					continue
+				}
+				available = decimal.Min(available, reducible)
+			}
+
+			fillSize := decimal.Min(need, available)
+			preview.fills = append(preview.fills, domain.SimulatedFill{
+				Price:        resting.Price,
+				Size:         fillSize,
+				MakerID:      resting.TraderID,
+				MakerOrderID: resting.ID,
+			})
+			preview.filled = preview.filled.Add(fillSize)
+			makerFilled[resting.TraderID] = makerFilled[resting.TraderID].Add(fillSize)
+			need = need.Sub(fillSize)
+			if !need.IsPositive() {
+				return preview
+			}
+
+			if sibling, linked := me.ocoSiblings[resting.ID]; linked {
+				skip[sibling.ID] = true
+			}
+			if state, exists := me.mmp[resting.TraderID]; exists {
+				w, seen := windows[resting.TraderID]
+				if !seen {
+					fills, total := state.windowAt(now)
+					w = &makerWindow{fills: fills, total: total}
+					windows[resting.TraderID] = w
+				}
+				w.fills++
+				w.total = w.total.Add(fillSize)
+				if state.trips(w.fills, w.total) {
+					tripped[resting.TraderID] = true
+				}
+			}
+		}
+	}
+	return preview
+}
+
+// SimulateOrder previews an order as if it were submitted now: the fills it
+// would make against the book as it stands, their average price and taker
+// fees, what would rest or be cancelled, and the position it would leave the
+// trader with. The order is validated as a real one would be, but nothing is
+// changed, saved or broadcast, and the order itself is left untouched. Stop
+// orders cannot be simulated, since they would only rest.
+func (me *MatchingEngine) SimulateOrder(order *domain.Order) (*domain.OrderSimulation, error) {
+	if order.Type == domain.OrderTypeStop {
+		return nil, fmt.Errorf("stop orders cannot be simulated")
+	}
+	probe := *order
+	probe.FilledSize = decimal.Zero
+
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	book, err := me.validateOrderLocked(&probe)
+	if err != nil {
+		return nil, err
+	}
+
+	sim := &domain.OrderSimulation{
+		Instrument: probe.Instrument,
+		Side:       probe.Side,
+		Size:       probe.Size,
+		Fills:      []domain.SimulatedFill{},
+		Timestamp:  time.Now(),
+	}
+	pos := &domain.Position{TraderID: probe.TraderID, Instrument: probe.Instrument}
+	if current, exists := me.positions[fmt.Sprintf("%s:%s", probe.TraderID, probe.Instrument)]; exists {
+		pos.Size, pos.EntryPrice = current.Size, current.EntryPrice
+	}
+	sim.Position = pos.Size
+
+	preview := me.previewMatchLocked(book, &probe)
+	if probe.TimeInForce == domain.TimeInForceFOK && !preview.filled.Equal(probe.Size) {
+		preview = &matchPreview{stop: stopKilled}
+	}
+
+	for _, fill := range preview.fills {
+		change := fill.Size
+		if probe.Side == domain.SideSell {
+			change = change.Neg()
+		}
+		effect := domain.EffectOpen
+		if !pos.Size.IsZero() && pos.Size.Sign() != change.Sign() {
+			effect = domain.EffectClose
+		}
+		notional := fill.Price.Mul(fill.Size)
+		sim.Notional = sim.Notional.Add(notional)
+		sim.Fee = sim.Fee.Add(notional.Mul(me.feeRate(true, effect)))
+		applySimulatedFill(pos, change, fill.Price, me.divisionPlaces())
+	}
+	sim.Fills = append(sim.Fills, preview.fills...)
+	sim.FilledSize = preview.filled
+	sim.StopReason = preview.stop
+	if sim.FilledSize.IsPositive() {
+		sim.AveragePrice = sim.Notional.Div(sim.FilledSize)
+	}
+	sim.NewPosition, sim.NewEntryPrice = pos.Size, pos.EntryPrice
+
+	remaining := probe.Size.Sub(sim.FilledSize)
+	if probe.Type == domain.OrderTypeLimit && !probe.TimeInForce.Immediate() && preview.stop == "" {
+		sim.Rests = remaining
+	} else {
+		sim.Cancels = remaining
+	}
+	return sim, nil
+}
+
+// applySimulatedFill moves a copy of a position by a fill the way
+// updatePosition does: a weighted average entry when adding, the entry kept
+// when reducing, and the fill price when opening or flipping
+func applySimulatedFill(pos *domain.Position, change, price decimal.Decimal, places int32) {
+	newSize := pos.Size.Add(change)
+	switch {
+	case pos.Size.IsZero() || (!newSize.IsZero() && newSize.Sign() != pos.Size.Sign()):
+		pos.EntryPrice = price
+	case pos.Size.Sign() == change.Sign():
+		if entry, err := domain.SafeDivRound(pos.Size.Mul(pos.EntryPrice).Add(change.Mul(price)), newSize, places); err == nil {
+			pos.EntryPrice = entry
+		}
+	}
+	pos.Size = newSize
+	if pos.Size.IsZero() {
+		pos.EntryPrice = decimal.Zero
+	}
+}
@@ -0,0 +1,74 @@
+package engine_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestSimulateOrderDoesNotMutate(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+
+	if _, err := h.Engine.SetMMPConfig(maker.ID, domain.MMPConfig{WindowMs: 20, MaxFills: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	h.MustLimit(maker, domain.SideSell, "1000", "1")
+	h.MustLimit(maker, domain.SideSell, "1001", "1")
+	h.MustLimit(maker, domain.SideSell, "1002", "5")
+	h.MustMarket(taker, domain.SideBuy, "1")
+
+	// Let the fill age out of the window, so any call trimming it would write
+	time.Sleep(30 * time.Millisecond)
+
+	order := &domain.Order{
+		TraderID:   taker.ID,
+		Instrument: h.Instrument,
+		Side:       domain.SideBuy,
+		Type:       domain.OrderTypeMarket,
+		Size:       decimal.NewFromInt(2),
+		Leverage:   1,
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				sim, err := h.Engine.SimulateOrder(order)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !sim.FilledSize.Equal(decimal.NewFromInt(2)) {
+					t.Errorf("simulated fill = %s, want 2", sim.FilledSize)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				h.Engine.GetMMPStatus(maker.ID)
+			}
+		}()
+	}
+	wg.Wait()
+
+	asks := h.Asks()
+	if len(asks) != 2 || !asks[1].Size.Equal(decimal.NewFromInt(5)) {
+		t.Fatalf("book changed by simulation: %+v", asks)
+	}
+	if got := h.PositionSize(taker); !got.Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("taker position = %s, want 1", got)
+	}
+	if status := h.Engine.GetMMPStatus(maker.ID); status.WindowFills != 0 {
+		t.Fatalf("window fills = %d, want 0", status.WindowFills)
+	}
+}
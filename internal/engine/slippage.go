@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// validateSlippage checks an order's own slippage band: market and stop
+// orders only, between 1 and 10000 basis points
+func validateSlippage(order *domain.Order) error {
+	if order.MaxSlippageBps == 0 {
+		return nil
+	}
+	if order.Type == domain.OrderTypeLimit {
+		return fmt.Errorf("max_slippage_bps is only supported on market and stop orders")
+	}
+	if order.MaxSlippageBps < 0 || order.MaxSlippageBps > 10000 {
+		return fmt.Errorf("max_slippage_bps must be between 1 and 10000")
+	}
+	return nil
+}
+
+// slippageBand returns the worst price an order without a limit price may
+// fill at: its band (or the engine default) past best, the best opposite
+// level. It returns false if the order has a limit price, no band applies or
+// the book is empty.
+func (me *MatchingEngine) slippageBand(best *priceLevel, order *domain.Order) (decimal.Decimal, bool) {
+	if order.HasLimitPrice() || best == nil {
+		return decimal.Zero, false
+	}
+	bps := order.MaxSlippageBps
+	if bps == 0 && me.engineConfig != nil {
+		bps = me.engineConfig.MaxMarketSlippageBps
+	}
+	if bps <= 0 {
+		return decimal.Zero, false
+	}
+
+	offset := best.price.Mul(decimal.NewFromInt(int64(bps))).Div(decimal.NewFromInt(10000))
+	if order.Side == domain.SideBuy {
+		return best.price.Add(offset), true
+	}
+	return best.price.Sub(offset), true
+}
+
+// beyondBand reports whether a level's price is past an order's slippage band
+func beyondBand(order *domain.Order, price, band decimal.Decimal) bool {
+	if order.Side == domain.SideBuy {
+		return price.GreaterThan(band)
+	}
+	return price.LessThan(band)
+}
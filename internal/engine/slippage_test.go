@@ -0,0 +1,61 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+// gappedMarketBuy sends a market buy for 3 into asks at 1000, 1050 and
+// then, past a 10% band, 1200
+func gappedMarketBuy(t *testing.T, bps int, tif domain.TimeInForce) (*enginetest.Harness, *domain.Order, []*domain.Trade) {
+	t.Helper()
+	h := enginetest.NewTestEngine() // 1000 bps default band
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+	h.MustLimit(maker, domain.SideSell, "1000", "1")
+	h.MustLimit(maker, domain.SideSell, "1050", "1")
+	h.MustLimit(maker, domain.SideSell, "1200", "5")
+
+	order := &domain.Order{TraderID: taker.ID, Side: domain.SideBuy, Type: domain.OrderTypeMarket,
+		Size: dec("3"), Leverage: 1, MaxSlippageBps: bps, TimeInForce: tif}
+	trades, err := h.Submit(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h, order, trades
+}
+
+func TestMarketOrderStopsAtSlippageBand(t *testing.T) {
+	h, order, trades := gappedMarketBuy(t, 0, "")
+	if len(trades) != 2 || !trades[1].Price.Equal(dec("1050")) {
+		t.Fatalf("trades = %d, want the 1000 and 1050 levels only", len(trades))
+	}
+	if !order.SlippageCapped || order.Status != domain.OrderStatusCancelled || !order.FilledSize.Equal(dec("2")) {
+		t.Fatalf("order = %s, %s filled, capped %v, want 2 filled and the rest cancelled at the band",
+			order.Status, order.FilledSize, order.SlippageCapped)
+	}
+	if asks := h.Asks(); len(asks) != 1 || !asks[0].Price.Equal(dec("1200")) || !asks[0].Size.Equal(dec("5")) {
+		t.Fatalf("asks = %+v, want the level past the band untouched", asks)
+	}
+
+	// The order's own band overrides the default
+	_, order, trades = gappedMarketBuy(t, 100, "")
+	if len(trades) != 1 || !order.SlippageCapped || !order.FilledSize.Equal(dec("1")) {
+		t.Fatalf("1%% band = %d trades, %s filled, capped %v, want only the 1000 level", len(trades), order.FilledSize, order.SlippageCapped)
+	}
+	_, order, trades = gappedMarketBuy(t, 10000, "")
+	if len(trades) != 3 || order.SlippageCapped || order.Status != domain.OrderStatusFilled {
+		t.Fatalf("100%% band = %d trades, %s, capped %v, want filled through the gap", len(trades), order.Status, order.SlippageCapped)
+	}
+
+	// A fill-or-kill that needs the level past the band is killed outright
+	h, order, trades = gappedMarketBuy(t, 0, domain.TimeInForceFOK)
+	if len(trades) != 0 || order.Status != domain.OrderStatusCancelled || !order.FilledSize.IsZero() {
+		t.Fatalf("FOK = %d trades, %s, want killed without trading", len(trades), order.Status)
+	}
+	if asks := h.Asks(); len(asks) != 3 {
+		t.Fatalf("asks = %+v, want the book untouched by the killed order", asks)
+	}
+}
@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// SLOHandler is called when a latency objective has been breached for its
+// full sustain period
+type SLOHandler func(alert *domain.SLOAlert)
+
+// sloCheckInterval is how often the rolling p99 is compared to the objective
+const sloCheckInterval = time.Second
+
+// latencyWatcher keeps a ring of recent match latencies. It has its own lock
+// so recording a sample never contends with readers of me.mu.
+type latencyWatcher struct {
+	mu            sync.Mutex
+	threshold     time.Duration
+	sustain       time.Duration
+	samples       []time.Duration
+	next          int
+	full          bool
+	breachedSince time.Time // Zero while p99 is within the objective
+	alerted       bool      // Set once the current breach has been reported
+	handlers      []SLOHandler
+}
+
+// SetSLOConfig sets the match latency objective. A zero threshold disables
+// sampling and alerting.
+func (me *MatchingEngine) SetSLOConfig(cfg *config.SLOConfig) {
+	if cfg == nil || cfg.MatchLatencyP99Ms <= 0 {
+		return
+	}
+	me.slo.mu.Lock()
+	defer me.slo.mu.Unlock()
+
+	me.slo.threshold = time.Duration(cfg.MatchLatencyP99Ms) * time.Millisecond
+	me.slo.sustain = time.Duration(cfg.SustainSeconds) * time.Second
+	me.slo.samples = make([]time.Duration, cfg.SampleWindow)
+	me.slo.next, me.slo.full = 0, false
+}
+
+// OnSLOBreach registers a handler for sustained SLO breaches
+func (me *MatchingEngine) OnSLOBreach(handler SLOHandler) {
+	me.slo.mu.Lock()
+	defer me.slo.mu.Unlock()
+	me.slo.handlers = append(me.slo.handlers, handler)
+}
+
+// observeMatchLatency records how long an order submission took, lock wait
+// included. Deferred at the top of each submission path.
+func (me *MatchingEngine) observeMatchLatency(start time.Time) {
+	me.slo.record(time.Since(start))
+}
+
+// StartSLOWatcher runs the periodic p99 check. It is stopped by Shutdown.
+func (me *MatchingEngine) StartSLOWatcher() {
+	if me.slo.samples == nil {
+		return
+	}
+
+	me.wg.Add(1)
+	go func() {
+		defer me.wg.Done()
+
+		ticker := time.NewTicker(sloCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-me.stopCh:
+				return
+			case now := <-ticker.C:
+				me.slo.check(now)
+			}
+		}
+	}()
+	slog.Info("Match latency SLO watcher started", "p99_objective", me.slo.threshold, "sustain", me.slo.sustain)
+}
+
+// record adds a latency sample, overwriting the oldest once the ring is full
+func (w *latencyWatcher) record(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.samples == nil {
+		return
+	}
+	w.samples[w.next] = d
+	w.next++
+	if w.next == len(w.samples) {
+		w.next, w.full = 0, true
+	}
+}
+
+// p99 returns the 99th percentile of the sampled latencies and how many
+// samples it was taken over. Caller must hold w.mu.
+func (w *latencyWatcher) p99() (time.Duration, int) {
+	n := w.next
+	if w.full {
+		n = len(w.samples)
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, w.samples[:n])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(n*99-1)/100], n
+}
+
+// check compares the rolling p99 to the objective. A breach is reported once
+// it has lasted the sustain period, and the watcher re-arms after p99 recovers.
+func (w *latencyWatcher) check(now time.Time) {
+	w.mu.Lock()
+
+	p99, n := w.p99()
+	if n == 0 || p99 <= w.threshold {
+		if w.alerted {
+			slog.Info("Match latency p99 recovered", "p99", p99, "objective", w.threshold)
+		}
+		w.breachedSince, w.alerted = time.Time{}, false
+		w.mu.Unlock()
+		return
+	}
+
+	if w.breachedSince.IsZero() {
+		w.breachedSince = now
+	}
+	if w.alerted || now.Sub(w.breachedSince) < w.sustain {
+		w.mu.Unlock()
+		return
+	}
+	w.alerted = true
+
+	alert := &domain.SLOAlert{
+		Timestamp:     now,
+		Metric:        "match_latency_p99",
+		ThresholdMs:   float64(w.threshold) / float64(time.Millisecond),
+		ValueMs:       float64(p99) / float64(time.Millisecond),
+		BreachedSince: w.breachedSince,
+		Samples:       n,
+	}
+	handlers := w.handlers
+	w.mu.Unlock()
+
+	slog.Warn("Match latency p99 has exceeded its objective",
+		"p99", p99, "objective", w.threshold, "since", alert.BreachedSince)
+	for _, handler := range handlers {
+		handler(alert)
+	}
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+func TestSLOBreachAlertsOncePerBreach(t *testing.T) {
+	me := NewMatchingEngine()
+	me.SetSLOConfig(&config.SLOConfig{MatchLatencyP99Ms: 50, SustainSeconds: 30, SampleWindow: 100})
+	var alerts []*domain.SLOAlert
+	me.OnSLOBreach(func(alert *domain.SLOAlert) { alerts = append(alerts, alert) })
+
+	feed := func(d time.Duration, n int) {
+		for i := 0; i < n; i++ {
+			me.slo.record(d)
+		}
+	}
+	t0 := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return t0.Add(time.Duration(seconds) * time.Second) }
+
+	// A single slow match is outside the p99 of 100 samples
+	feed(10*time.Millisecond, 99)
+	feed(200*time.Millisecond, 1)
+	me.slo.check(at(0))
+
+	// Every match slow: the breach must hold for the 30s sustain period
+	feed(80*time.Millisecond, 100)
+	for _, s := range []int{10, 20, 39} {
+		me.slo.check(at(s))
+	}
+	if len(alerts) != 0 {
+		t.Fatalf("%d alerts before the breach was sustained for 30s", len(alerts))
+	}
+	for _, s := range []int{40, 41, 60, 120} {
+		me.slo.check(at(s))
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("%d alerts for one sustained breach, want 1", len(alerts))
+	}
+	if a := alerts[0]; a.ValueMs != 80 || a.ThresholdMs != 50 || a.Samples != 100 || !a.BreachedSince.Equal(at(10)) {
+		t.Fatalf("alert = %+v, want p99 80ms over 50ms across 100 samples since %s", a, at(10))
+	}
+
+	// Recovery re-arms the watcher for the next breach
+	feed(10*time.Millisecond, 100)
+	me.slo.check(at(130))
+	feed(80*time.Millisecond, 100)
+	me.slo.check(at(140))
+	me.slo.check(at(170))
+	if len(alerts) != 2 {
+		t.Fatalf("%d alerts after a recovery and a second breach, want 2", len(alerts))
+	}
+}
@@ -0,0 +1,59 @@
+package engine_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestStaleMarketOrderRejected(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	h.Engine.SetClock(func() time.Time { return now })
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+	h.MustLimit(maker, domain.SideSell, "1000", "2")
+
+	market := func(sentAt *time.Time) error {
+		_, err := h.Submit(&domain.Order{
+			TraderID: taker.ID,
+			Side:     domain.SideBuy,
+			Type:     domain.OrderTypeMarket,
+			Size:     dec("0.5"),
+			Leverage: 1,
+			SentAt:   sentAt,
+		})
+		return err
+	}
+	at := func(age time.Duration) *time.Time {
+		sent := now.Add(-age)
+		return &sent
+	}
+
+	// The default maximum age is 2s
+	if err := market(at(1500 * time.Millisecond)); err != nil {
+		t.Fatalf("market order sent 1.5s ago rejected: %v", err)
+	}
+	if err := market(at(2 * time.Second)); err != nil {
+		t.Fatalf("market order sent exactly 2s ago rejected: %v", err)
+	}
+	if err := market(at(2500 * time.Millisecond)); err == nil || !strings.Contains(err.Error(), "stale") {
+		t.Fatalf("market order sent 2.5s ago: err = %v, want stale", err)
+	}
+	if got := h.PositionSize(taker); !got.Equal(dec("1")) {
+		t.Fatalf("taker position = %s, want 1 from the two fresh orders", got)
+	}
+
+	// Without a send time the order has not opted in
+	if err := market(nil); err != nil {
+		t.Fatalf("market order without a send time rejected: %v", err)
+	}
+	// Limit orders are never checked
+	if _, err := h.Submit(&domain.Order{TraderID: taker.ID, Side: domain.SideBuy, Type: domain.OrderTypeLimit,
+		Price: dec("990"), Size: dec("1"), Leverage: 1, SentAt: at(time.Minute)}); err != nil {
+		t.Fatalf("old limit order rejected: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package engine_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestFreshEngineQuotesConfiguredStartingPrice(t *testing.T) {
+	for _, tc := range []struct {
+		configured, want string
+	}{
+		{"50", "50"},
+		{"0.5", "0.5"},
+		{"0", "1000"}, // Non-positive prices fall back to the default
+		{"-5", "1000"},
+	} {
+		cfg := config.Default()
+		cfg.RIndex.StartingPrice = dec(tc.configured)
+		h := enginetest.NewTestEngineWithConfig(cfg)
+
+		stats := h.Engine.GetMarketStats(h.Instrument)
+		if !stats.LastPrice.Equal(dec(tc.want)) || !stats.MarkPrice.Equal(dec(tc.want)) {
+			t.Errorf("starting price %s: stats last %s mark %s, want %s", tc.configured, stats.LastPrice, stats.MarkPrice, tc.want)
+		}
+		if got := h.Engine.GetMarkPrice(h.Instrument); !got.Equal(dec(tc.want)) {
+			t.Errorf("starting price %s: mark price = %s, want %s", tc.configured, got, tc.want)
+		}
+		snap, err := h.Engine.GetHistoricalOI(h.Instrument, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !snap.Price.Equal(dec(tc.want)) {
+			t.Errorf("starting price %s: OI price = %s, want %s", tc.configured, snap.Price, tc.want)
+		}
+	}
+
+	// The first trade replaces it
+	cfg := config.Default()
+	cfg.RIndex.StartingPrice = dec("50")
+	h := enginetest.NewTestEngineWithConfig(cfg)
+	h.MustLimit(h.AddTrader("maker"), domain.SideSell, "55", "1")
+	h.MustMarket(h.AddTrader("taker"), domain.SideBuy, "1")
+	if got := h.Engine.GetMarkPrice(h.Instrument); !got.Equal(dec("55")) {
+		t.Fatalf("mark price after a trade at 55 = %s", got)
+	}
+}
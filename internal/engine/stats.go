@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/thatreguy/trade.re/internal/db"
+)
+
+// dayTradeStatsTTL is how long a 24h trade aggregate is reused before the
+// database is queried again
+const dayTradeStatsTTL = 5 * time.Second
+
+// dayTradeStats is an instrument's 24h trade aggregate as of a point in time
+type dayTradeStats struct {
+	db.TradeStats
+	at time.Time
+}
+
+// dayTradeStats returns the instrument's trade aggregate over the 24 hours
+// before now, reusing one read within dayTradeStatsTTL
+func (me *MatchingEngine) dayTradeStats(database *db.SQLiteDB, instrument string, now time.Time) (*dayTradeStats, error) {
+	me.statsCacheMu.Lock()
+	cached, ok := me.statsCache[instrument]
+	me.statsCacheMu.Unlock()
+	if ok && now.Sub(cached.at) < dayTradeStatsTTL {
+		return cached, nil
+	}
+
+	stats, err := database.GetTradeStatsSince(instrument, now.Add(-24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	day := &dayTradeStats{TradeStats: *stats, at: now}
+
+	me.statsCacheMu.Lock()
+	me.statsCache[instrument] = day
+	me.statsCacheMu.Unlock()
+	return day, nil
+}
@@ -0,0 +1,67 @@
+package engine_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thatreguy/trade.re/internal/db"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestDayStatsCountOnlyLast24hFromDatabase(t *testing.T) {
+	database, err := db.NewSQLite(filepath.Join(t.TempDir(), "stats.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	h := enginetest.NewTestEngine()
+	h.Engine.SetDatabase(database)
+	buyer := h.AddTrader("buyer")
+	seller := h.AddTrader("seller")
+
+	// 48h of trades, none of them in memory
+	now := time.Now()
+	save := func(price, size string, age time.Duration) {
+		trade := &domain.Trade{
+			ID:            uuid.New(),
+			Instrument:    h.Instrument,
+			Price:         dec(price),
+			Size:          dec(size),
+			Timestamp:     now.Add(-age),
+			BuyerID:       buyer.ID,
+			SellerID:      seller.ID,
+			AggressorSide: domain.SideBuy,
+		}
+		if err := database.SaveTrade(trade); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, seed := range []struct {
+		price, size string
+		age         time.Duration
+	}{
+		{"10", "5", 47 * time.Hour},
+		{"5000", "1", 25 * time.Hour},
+		{"1080", "2", 23 * time.Hour},
+		{"950", "1", 2 * time.Hour},
+	} {
+		save(seed.price, seed.size, seed.age)
+	}
+
+	stats := h.Engine.GetMarketStats(h.Instrument)
+	if !stats.High24h.Equal(dec("1080")) || !stats.Low24h.Equal(dec("950")) {
+		t.Fatalf("24h range = %s-%s, want 950-1080 from the last day only", stats.Low24h, stats.High24h)
+	}
+	if !stats.Volume24h.Equal(dec("3110")) || !stats.BuyVolume24h.Equal(dec("3110")) || !stats.SellVolume24h.IsZero() {
+		t.Fatalf("24h volume = %s (buy %s, sell %s), want 3110 all bought", stats.Volume24h, stats.BuyVolume24h, stats.SellVolume24h)
+	}
+
+	// The aggregate is cached for a few seconds rather than queried per call
+	save("2000", "1", time.Minute)
+	if stats := h.Engine.GetMarketStats(h.Instrument); !stats.High24h.Equal(dec("1080")) {
+		t.Fatalf("24h high = %s straight after the first query, want the cached 1080", stats.High24h)
+	}
+}
@@ -0,0 +1,194 @@
+package engine
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// SubmitOCO places two orders as a one-cancels-other pair: once either leg
+// trades or is cancelled, the other is cancelled. The legs must belong to the
+// same trader and instrument and be limit or stop orders. Returns the trades
+// produced by both legs.
+func (me *MatchingEngine) SubmitOCO(first, second *domain.Order) ([]*domain.Trade, error) {
+	defer me.observeMatchLatency(time.Now())
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	if first.TraderID != second.TraderID {
+		return nil, fmt.Errorf("OCO legs must belong to the same trader")
+	}
+	if first.Instrument != second.Instrument {
+		return nil, fmt.Errorf("OCO legs must be on the same instrument")
+	}
+	for _, leg := range []*domain.Order{first, second} {
+		if leg.Type == domain.OrderTypeMarket {
+			return nil, fmt.Errorf("OCO legs must be limit or stop orders")
+		}
+		if leg.TimeInForce.Immediate() {
+			return nil, fmt.Errorf("OCO legs cannot be %s", leg.TimeInForce)
+		}
+	}
+
+	// Validate both legs before placing either
+	book, err := me.validateOrderLocked(first)
+	if err != nil {
+		return nil, fmt.Errorf("first leg: %w", err)
+	}
+	if _, err := me.validateOrderLocked(second); err != nil {
+		return nil, fmt.Errorf("second leg: %w", err)
+	}
+
+	groupID := uuid.New()
+	first.ID, second.ID = uuid.New(), uuid.New()
+	first.OCOGroupID, second.OCOGroupID = &groupID, &groupID
+	me.linkOCO(first, second)
+
+	trades := me.placeOrderLocked(book, first)
+
+	// The first leg may already have traded or triggered, cancelling the pair
+	if _, linked := me.ocoSiblings[second.ID]; !linked {
+		second.Status = domain.OrderStatusCancelled
+		return trades, nil
+	}
+	trades = append(trades, me.placeOrderLocked(book, second)...)
+
+	return trades, nil
+}
+
+// linkOCO records two orders as each other's OCO sibling
+func (me *MatchingEngine) linkOCO(a, b *domain.Order) {
+	me.ocoSiblings[a.ID] = b
+	me.ocoSiblings[b.ID] = a
+}
+
+// unlinkOCO removes an order's OCO link and returns its sibling, or nil if it
+// has none
+func (me *MatchingEngine) unlinkOCO(order *domain.Order) *domain.Order {
+	sibling, exists := me.ocoSiblings[order.ID]
+	if !exists {
+		return nil
+	}
+	delete(me.ocoSiblings, order.ID)
+	delete(me.ocoSiblings, sibling.ID)
+	return sibling
+}
+
+// relinkOCO restores OCO links for orders loaded from the database
+func (me *MatchingEngine) relinkOCO(orders []*domain.Order) {
+	groups := make(map[uuid.UUID][]*domain.Order)
+	for _, order := range orders {
+		if order.OCOGroupID != nil {
+			groups[*order.OCOGroupID] = append(groups[*order.OCOGroupID], order)
+		}
+	}
+	for _, legs := range groups {
+		// A group with one open leg has already been resolved
+		if len(legs) == 2 {
+			me.linkOCO(legs[0], legs[1])
+		}
+	}
+}
+
+// cancelIfOpenLocked cancels an order if it is still resting or waiting for
+// its stop trigger. Caller must hold me.mu.
+func (me *MatchingEngine) cancelIfOpenLocked(order *domain.Order) {
+	book, exists := me.books[order.Instrument]
+	if !exists {
+		return
+	}
+	_, resting := book.GetOrder(order.ID)
+	_, pending := me.stopOrders[order.ID]
+	if !resting && !pending {
+		return
+	}
+	if err := me.cancelOrderLocked(order.ID, order.Instrument); err != nil {
+		slog.Error("Error cancelling OCO sibling", "order_id", order.ID, "error", err)
+	}
+}
+
+// addStopLocked parks a stop order until the last trade price reaches its
+// stop price. Caller must hold me.mu.
+func (me *MatchingEngine) addStopLocked(order *domain.Order) {
+	me.stopOrders[order.ID] = order
+
+	if me.db != nil {
+		if err := me.db.SaveOrder(order); err != nil {
+			slog.Error("Error saving stop order to database", "error", err)
+		}
+	}
+
+	for _, handler := range me.orderHandlers {
+		handler(order)
+	}
+}
+
+// traderStopsLocked returns a trader's untriggered stop orders on an
+// instrument. Caller must hold me.mu.
+func (me *MatchingEngine) traderStopsLocked(traderID uuid.UUID, instrument string) []*domain.Order {
+	var stops []*domain.Order
+	for _, order := range me.stopOrders {
+		if order.TraderID == traderID && order.Instrument == instrument {
+			stops = append(stops, order)
+		}
+	}
+	return stops
+}
+
+// processStopsLocked executes stop orders triggered by the last trade price
+// as market orders, oldest first. Trades from a triggered stop can trigger
+// further stops, so this repeats until none fire. Caller must hold me.mu.
+func (me *MatchingEngine) processStopsLocked(instrument string) {
+	book, exists := me.books[instrument]
+	if !exists {
+		return
+	}
+
+	for {
+		price, ok := me.lastTradePrice(instrument)
+		if !ok {
+			return
+		}
+
+		var triggered []*domain.Order
+		for _, order := range me.stopOrders {
+			if order.Instrument == instrument && order.StopTriggered(price) {
+				triggered = append(triggered, order)
+			}
+		}
+		if len(triggered) == 0 {
+			return
+		}
+		sort.Slice(triggered, func(i, j int) bool {
+			return triggered[i].CreatedAt.Before(triggered[j].CreatedAt)
+		})
+
+		for _, order := range triggered {
+			// An earlier stop in this pass may have cancelled it as an OCO sibling
+			if _, pending := me.stopOrders[order.ID]; !pending {
+				continue
+			}
+			delete(me.stopOrders, order.ID)
+			order.Triggered = true
+
+			// Triggering counts as the leg executing, so its sibling goes first
+			if sibling := me.unlinkOCO(order); sibling != nil {
+				me.cancelIfOpenLocked(sibling)
+			}
+
+			trades := me.executeOrderLocked(book, order)
+			me.loggerLocked().Debug("Stop order triggered", "order_id", order.ID, "trader_id", order.TraderID,
+				"price", price, "trades", len(trades))
+
+			if me.db != nil {
+				if err := me.db.SaveOrder(order); err != nil {
+					slog.Error("Error saving triggered stop order to database", "error", err)
+				}
+			}
+		}
+	}
+}
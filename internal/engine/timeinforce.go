@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// validateTimeInForce checks an order's time in force. IOC and FOK orders
+// never rest, so they cannot be stops, post-only or icebergs.
+func validateTimeInForce(order *domain.Order) error {
+	if !order.TimeInForce.IsValid() {
+		return fmt.Errorf("invalid time_in_force: %s", order.TimeInForce)
+	}
+	if !order.TimeInForce.Immediate() {
+		return nil
+	}
+	if order.Type == domain.OrderTypeStop {
+		return fmt.Errorf("time_in_force %s is not supported on stop orders", order.TimeInForce)
+	}
+	if order.PostOnly {
+		return fmt.Errorf("post_only cannot be combined with time_in_force %s", order.TimeInForce)
+	}
+	if order.DisplaySize.IsPositive() {
+		return fmt.Errorf("display_size cannot be combined with time_in_force %s", order.TimeInForce)
+	}
+	return nil
+}
+
+// canFillLocked reports whether an order would fill in full right now.
+// Caller must hold me.mu.
+func (me *MatchingEngine) canFillLocked(book *OrderBook, order *domain.Order) bool {
+	return me.previewMatchLocked(book, order).filled.Equal(order.RemainingSize())
+}
@@ -0,0 +1,102 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+// watchStatuses records the status of each update for the trader's orders
+func watchStatuses(h *enginetest.Harness, trader *domain.Trader) map[uuid.UUID][]domain.OrderStatus {
+	statuses := make(map[uuid.UUID][]domain.OrderStatus)
+	h.Engine.OnOrderUpdate(func(order *domain.Order) {
+		if order.TraderID == trader.ID {
+			statuses[order.ID] = append(statuses[order.ID], order.Status)
+		}
+	})
+	return statuses
+}
+
+func immediateOrder(trader *domain.Trader, tif domain.TimeInForce, price, size string) *domain.Order {
+	return &domain.Order{
+		TraderID:    trader.ID,
+		Side:        domain.SideBuy,
+		Type:        domain.OrderTypeLimit,
+		Price:       dec(price),
+		Size:        dec(size),
+		Leverage:    1,
+		TimeInForce: tif,
+	}
+}
+
+func TestIOCCancelsUnfilledRemainder(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+	h.MustLimit(maker, domain.SideSell, "1000", "1")
+	h.MustLimit(maker, domain.SideSell, "1010", "1") // Beyond the limit price
+	statuses := watchStatuses(h, taker)
+
+	order := immediateOrder(taker, domain.TimeInForceIOC, "1005", "3")
+	trades, err := h.Submit(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 1 || !order.FilledSize.Equal(dec("1")) || order.Status != domain.OrderStatusCancelled {
+		t.Fatalf("IOC = %d trades, %s filled, %s, want 1 filled and the rest cancelled", len(trades), order.FilledSize, order.Status)
+	}
+	if bids := h.Bids(); len(bids) != 0 {
+		t.Fatalf("bids = %+v, want the remainder not resting", bids)
+	}
+	if orders := h.Engine.GetOpenOrders(taker.ID, h.Instrument); len(orders) != 0 {
+		t.Fatalf("%d open orders, want none", len(orders))
+	}
+	if got := statuses[order.ID]; len(got) == 0 || got[len(got)-1] != domain.OrderStatusCancelled {
+		t.Fatalf("order updates = %v, want the last one cancelled", got)
+	}
+}
+
+func TestFOKAlmostEnoughLiquidityDoesNothing(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+	h.MustLimit(maker, domain.SideSell, "1000", "1")
+	h.MustLimit(maker, domain.SideSell, "1001", "0.9")
+	h.MustLimit(taker, domain.SideSell, "1001", "5") // The taker's own order never matches
+	h.MustLimit(maker, domain.SideSell, "1002", "5") // Beyond the limit price
+	statuses := watchStatuses(h, taker)
+
+	// 1.9 is matchable, one lot short of the order
+	order := immediateOrder(taker, domain.TimeInForceFOK, "1001", "2")
+	trades, err := h.Submit(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 0 || !order.FilledSize.IsZero() || order.Status != domain.OrderStatusCancelled {
+		t.Fatalf("FOK = %d trades, %s filled, %s, want cancelled without trading", len(trades), order.FilledSize, order.Status)
+	}
+	if asks := h.Asks(); len(asks) != 3 || !asks[0].Size.Equal(dec("1")) || !asks[1].Size.Equal(dec("5.9")) {
+		t.Fatalf("asks = %+v, want the book untouched", asks)
+	}
+	if got := h.PositionSize(taker); !got.IsZero() {
+		t.Fatalf("taker position = %s, want nothing filled", got)
+	}
+	if got := statuses[order.ID]; len(got) != 1 || got[0] != domain.OrderStatusCancelled {
+		t.Fatalf("order updates = %v, want a single cancelled update", got)
+	}
+
+	// Exactly enough fills completely
+	order = immediateOrder(taker, domain.TimeInForceFOK, "1001", "1.9")
+	trades, err = h.Submit(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 2 || order.Status != domain.OrderStatusFilled || !h.PositionSize(taker).Equal(dec("1.9")) {
+		t.Fatalf("FOK = %d trades, %s, position %s, want filled in full", len(trades), order.Status, h.PositionSize(taker))
+	}
+	if got := statuses[order.ID]; len(got) == 0 || got[len(got)-1] != domain.OrderStatusFilled {
+		t.Fatalf("order updates = %v, want the last one filled", got)
+	}
+}
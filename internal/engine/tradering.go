@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// tradeRingVersion is bumped whenever the ring file layout changes
+const tradeRingVersion = 1
+
+// tradeRing is the on-disk form of the recent-trades ring
+type tradeRing struct {
+	Version int
+	SavedAt time.Time
+	Trades  []*domain.Trade // Newest first, as held in memory
+}
+
+// saveTradeRing writes the recent-trades ring to the configured file, if any.
+// The file is replaced atomically so a crash mid-write leaves the old one.
+func (me *MatchingEngine) saveTradeRing() error {
+	if me.engineConfig == nil || me.engineConfig.TradeRingFile == "" {
+		return nil
+	}
+	path := me.engineConfig.TradeRingFile
+
+	// Trades are never modified once recorded, and new ones replace the
+	// slice, so the ring can be encoded outside the lock
+	me.mu.RLock()
+	ring := tradeRing{
+		Version: tradeRingVersion,
+		SavedAt: time.Now(),
+		Trades:  me.recentTrades,
+	}
+	me.mu.RUnlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("creating trade ring file: %w", err)
+	}
+	err = gob.NewEncoder(tmp).Encode(&ring)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing trade ring: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("replacing trade ring: %w", err)
+	}
+	return nil
+}
+
+// loadTradeRingLocked reads the saved recent-trades ring. It returns false if
+// there is no file, it is unreadable or too old, or the database has trades
+// newer than the ring, in which case the caller should query the database.
+// Caller must hold me.mu.
+func (me *MatchingEngine) loadTradeRingLocked() ([]*domain.Trade, bool) {
+	if me.engineConfig == nil || me.engineConfig.TradeRingFile == "" {
+		return nil, false
+	}
+	path := me.engineConfig.TradeRingFile
+
+	f, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Error("Error opening trade ring", "path", path, "error", err)
+		}
+		return nil, false
+	}
+	defer f.Close()
+
+	var ring tradeRing
+	if err := gob.NewDecoder(f).Decode(&ring); err != nil {
+		slog.Warn("Ignoring unreadable trade ring", "path", path, "error", err)
+		return nil, false
+	}
+	if ring.Version != tradeRingVersion {
+		slog.Warn("Ignoring trade ring of another version", "path", path, "version", ring.Version, "want", tradeRingVersion)
+		return nil, false
+	}
+	if maxAge := me.engineConfig.TradeRingMaxAgeSeconds; maxAge > 0 && time.Since(ring.SavedAt) > time.Duration(maxAge)*time.Second {
+		slog.Warn("Ignoring stale trade ring", "path", path, "age", time.Since(ring.SavedAt).Round(time.Second))
+		return nil, false
+	}
+
+	// Trades recorded after the ring was saved (e.g. before a crash) make it stale
+	if me.db != nil {
+		var newest *domain.Trade
+		for _, instrument := range me.instruments {
+			trades, err := me.db.GetRecentTrades(instrument, 1)
+			if err != nil {
+				slog.Error("Error checking trade ring against database", "error", err)
+				return nil, false
+			}
+			if len(trades) > 0 && (newest == nil || trades[0].Timestamp.After(newest.Timestamp)) {
+				newest = trades[0]
+			}
+		}
+		switch {
+		case newest == nil && len(ring.Trades) == 0:
+		case newest == nil || len(ring.Trades) == 0 || newest.ID != ring.Trades[0].ID:
+			slog.Warn("Ignoring trade ring: database has newer trades", "path", path)
+			return nil, false
+		}
+	}
+
+	return ring.Trades, true
+}
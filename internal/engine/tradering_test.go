@@ -0,0 +1,104 @@
+package engine_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/db"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+// restartEngine opens the database at path and loads an engine from it,
+// returning what it logged about where recent trades came from
+func restartEngine(t *testing.T, cfg *config.Config, path string) (*enginetest.Harness, *db.SQLiteDB, string) {
+	t.Helper()
+	database, err := db.NewSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := enginetest.NewTestEngineWithConfig(cfg)
+	h.Engine.SetDatabase(database)
+
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	if err := h.Engine.LoadFromDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	return h, database, logs.String()
+}
+
+// candleSummary renders candles for comparison, independent of decimal exponents
+func candleSummary(candles []*domain.Candle) string {
+	var b strings.Builder
+	for _, c := range candles {
+		fmt.Fprintf(&b, "%s o=%s h=%s l=%s c=%s v=%s vwap=%s n=%d\n", c.OpenTime.UTC().Format(time.RFC3339),
+			c.Open, c.High, c.Low, c.Close, c.Volume, c.VWAP, c.TradeCount)
+	}
+	return b.String()
+}
+
+func TestTradeRingSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ring.db")
+	cfg := config.Default()
+	cfg.Engine.TradeRingFile = filepath.Join(dir, "trades.ring")
+
+	h, _, _ := restartEngine(t, cfg, path)
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+	for _, price := range []string{"1000", "1010", "995"} {
+		h.MustLimit(maker, domain.SideSell, price, "1")
+		h.MustMarket(taker, domain.SideBuy, "1")
+	}
+	before := candleSummary(h.Engine.GetCandles(h.Instrument, domain.CandleInterval1m, 10))
+	recent := h.Engine.GetRecentTrades(h.Instrument, 10)
+	if err := h.Engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cfg.Engine.TradeRingFile); err != nil {
+		t.Fatalf("trade ring not saved at shutdown: %v", err)
+	}
+
+	h, database, logs := restartEngine(t, cfg, path)
+	if !strings.Contains(logs, "from trade ring") {
+		t.Fatalf("trades not loaded from the ring:\n%s", logs)
+	}
+	if after := candleSummary(h.Engine.GetCandles(h.Instrument, domain.CandleInterval1m, 10)); after != before {
+		t.Fatalf("candles after restart:\n%s\nwant:\n%s", after, before)
+	}
+	loaded := h.Engine.GetRecentTrades(h.Instrument, 10)
+	if len(loaded) != len(recent) {
+		t.Fatalf("%d recent trades after restart, want %d", len(loaded), len(recent))
+	}
+	for i := range recent {
+		if loaded[i].ID != recent[i].ID || !loaded[i].Price.Equal(recent[i].Price) {
+			t.Fatalf("trade %d after restart = %s at %s, want %s at %s", i, loaded[i].ID, loaded[i].Price, recent[i].ID, recent[i].Price)
+		}
+	}
+
+	// A trade recorded after the ring was saved, as before a crash, makes
+	// the ring stale
+	h.MustLimit(maker, domain.SideSell, "1020", "1")
+	h.MustMarket(taker, domain.SideBuy, "1")
+	if err := database.Close(); err != nil {
+		t.Fatal(err)
+	}
+	h, database, logs = restartEngine(t, cfg, path)
+	defer database.Close()
+	if !strings.Contains(logs, "from database") {
+		t.Fatalf("stale ring was not ignored:\n%s", logs)
+	}
+	if trades := h.Engine.GetRecentTrades(h.Instrument, 10); len(trades) != 4 || !trades[0].Price.Equal(dec("1020")) {
+		t.Fatalf("recent trades = %d, want all 4 with the 1020 trade newest", len(trades))
+	}
+}
@@ -0,0 +1,57 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestTraderTypeLimits(t *testing.T) {
+	cfg := config.Default()
+	cfg.Game.TraderTypes = map[string]config.TraderTypeConfig{
+		"market_maker": {StartingBalance: dec("100000"), MaxLeverage: 50},
+		"human":        {MaxLeverage: 10},
+	}
+	h := enginetest.NewTestEngineWithConfig(cfg)
+	maker := h.AddTraderOfType("maker", domain.TraderTypeMarketMaker)
+	human := h.AddTraderOfType("human", domain.TraderTypeHuman)
+	bot := h.AddTraderOfType("bot", domain.TraderTypeBot)
+
+	if !maker.Balance.Equal(dec("100000")) || !human.Balance.Equal(cfg.Game.StartingBalance) || !bot.Balance.Equal(cfg.Game.StartingBalance) {
+		t.Fatalf("starting balances = %s maker, %s human, %s bot", maker.Balance, human.Balance, bot.Balance)
+	}
+
+	quote := func(trader *domain.Trader, leverage int) error {
+		_, err := h.Submit(&domain.Order{
+			TraderID: trader.ID,
+			Side:     domain.SideBuy,
+			Type:     domain.OrderTypeLimit,
+			Price:    dec("900"),
+			Size:     dec("1"),
+			Leverage: leverage,
+		})
+		return err
+	}
+	if err := quote(maker, 20); err != nil {
+		t.Fatalf("market maker at 20x rejected: %v", err)
+	}
+	if err := quote(human, 20); err == nil {
+		t.Fatal("human at 20x accepted over their 10x cap")
+	}
+	if err := quote(human, 10); err != nil {
+		t.Fatalf("human at their 10x cap rejected: %v", err)
+	}
+	if err := quote(maker, 51); err == nil {
+		t.Fatal("market maker at 51x accepted over their 50x cap")
+	}
+
+	// Bots have no override, so the instrument cap applies
+	if err := quote(bot, cfg.RIndex.MaxLeverage); err != nil {
+		t.Fatalf("bot at the instrument cap rejected: %v", err)
+	}
+	if err := quote(bot, cfg.RIndex.MaxLeverage+1); err == nil {
+		t.Fatal("bot over the instrument cap accepted")
+	}
+}
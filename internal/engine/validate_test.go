@@ -0,0 +1,119 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestLimitPriceTickBoundaries(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	trader := h.AddTrader("trader")
+
+	for _, tc := range []struct {
+		price string
+		ok    bool
+	}{
+		{"999.99", true},
+		{"1000.00", true},
+		{"1000.01", true},
+		{"1000.010000", true}, // Trailing zeros are still on the grid
+		{"1000.005", false},
+		{"1000.0099", false},
+		{"1000.0101", false},
+		{"0.01", true},
+		{"0.009", false},
+	} {
+		_, _, err := h.Limit(trader, domain.SideBuy, tc.price, "0.001")
+		if tc.ok && err != nil {
+			t.Errorf("limit at %s rejected: %v", tc.price, err)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("limit at %s accepted off the 0.01 tick", tc.price)
+		}
+	}
+}
+
+func TestStopPriceTickBoundaries(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	trader := h.AddTrader("trader")
+
+	stop := func(price string) error {
+		_, err := h.Submit(&domain.Order{
+			TraderID:  trader.ID,
+			Side:      domain.SideBuy,
+			Type:      domain.OrderTypeStop,
+			StopPrice: dec(price),
+			Size:      dec("0.001"),
+			Leverage:  1,
+		})
+		return err
+	}
+	if err := stop("1100.01"); err != nil {
+		t.Errorf("stop at 1100.01 rejected: %v", err)
+	}
+	if err := stop("1100.015"); err == nil {
+		t.Error("stop at 1100.015 accepted off the tick")
+	}
+}
+
+func TestMinOrderSizeBoundaries(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+
+	// 0.001 is both the minimum and the lot
+	if _, _, err := h.Limit(maker, domain.SideSell, "1000", "0.001"); err != nil {
+		t.Fatalf("order of exactly the minimum size rejected: %v", err)
+	}
+	if _, _, err := h.Limit(maker, domain.SideSell, "1000", "0.0009"); err == nil {
+		t.Fatal("order below the minimum size accepted")
+	}
+	if _, _, err := h.Limit(maker, domain.SideSell, "1000", "0.0019"); err != nil {
+		t.Fatalf("order rounding down to the minimum rejected: %v", err)
+	}
+	if asks := h.Asks(); len(asks) != 1 || !asks[0].Size.Equal(dec("0.002")) {
+		t.Fatalf("asks = %+v, want 0.002 after rounding 0.0019 down to the lot", asks)
+	}
+	if got := h.Engine.GetEffectiveConfig().Instrument.MinOrderSize; !got.Equal(dec("0.001")) {
+		t.Fatalf("effective min order size = %s, want 0.001", got)
+	}
+
+	h.MustMarket(taker, domain.SideBuy, "0.002")
+	if got := h.PositionSize(taker); !got.Equal(dec("0.002")) {
+		t.Fatalf("taker position = %s, want 0.002", got)
+	}
+}
+
+func TestAmendToSubTickPriceRejected(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	trader := h.AddTrader("trader")
+	order := h.MustLimit(trader, domain.SideBuy, "999", "1")
+
+	if _, _, err := h.Engine.AmendOrder(trader.ID, order.ID, h.Instrument, dec("999.005"), dec("0")); err == nil {
+		t.Fatal("amend to 999.005 accepted off the tick")
+	}
+	if bids := h.Bids(); len(bids) != 1 || !bids[0].Price.Equal(dec("999")) {
+		t.Fatalf("bids = %+v, want the order left at 999", bids)
+	}
+	if _, _, err := h.Engine.AmendOrder(trader.ID, order.ID, h.Instrument, dec("999.01"), dec("0")); err != nil {
+		t.Fatalf("amend to 999.01 rejected: %v", err)
+	}
+}
+
+func TestOCOLegOffTickRejected(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	trader := h.AddTrader("trader")
+
+	limit := &domain.Order{TraderID: trader.ID, Instrument: h.Instrument, Side: domain.SideSell, Type: domain.OrderTypeLimit,
+		Price: dec("1020"), Size: dec("1"), Leverage: 1}
+	stop := &domain.Order{TraderID: trader.ID, Instrument: h.Instrument, Side: domain.SideSell, Type: domain.OrderTypeStop,
+		StopPrice: dec("980.001"), Size: dec("1"), Leverage: 1}
+	if _, err := h.Engine.SubmitOCO(limit, stop); err == nil {
+		t.Fatal("OCO with an off-tick stop accepted")
+	}
+	if asks := h.Asks(); len(asks) != 0 {
+		t.Fatalf("asks = %+v, want neither leg placed", asks)
+	}
+}
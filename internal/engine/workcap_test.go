@@ -0,0 +1,79 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestOrderBeyondLevelBoundIsWorkCapped(t *testing.T) {
+	cfg := config.Default()
+	cfg.Engine.MaxMatchLevels = 3
+	h := enginetest.NewTestEngineWithConfig(cfg)
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+	for _, price := range []string{"1000", "1001", "1002", "1003", "1004"} {
+		h.MustLimit(maker, domain.SideSell, price, "1")
+	}
+
+	// A limit through every level must not rest its remainder, which still crosses
+	order, trades, err := h.Limit(taker, domain.SideBuy, "1004", "5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 3 {
+		t.Fatalf("trades = %d, want 3 levels' worth", len(trades))
+	}
+	if !order.WorkCapped || order.Status != domain.OrderStatusCancelled {
+		t.Fatalf("order work_capped = %v, status %s, want capped and cancelled", order.WorkCapped, order.Status)
+	}
+	if bids := h.Bids(); len(bids) != 0 {
+		t.Fatalf("bids = %+v, want the remainder cancelled", bids)
+	}
+	if asks := h.Asks(); len(asks) != 2 {
+		t.Fatalf("asks = %+v, want the two levels past the bound left", asks)
+	}
+}
+
+func TestOrderBeyondOrderBoundIsWorkCapped(t *testing.T) {
+	cfg := config.Default()
+	cfg.Engine.MaxMatchOrders = 2
+	h := enginetest.NewTestEngineWithConfig(cfg)
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+	for i := 0; i < 3; i++ {
+		h.MustLimit(maker, domain.SideSell, "1000", "1")
+	}
+
+	order, trades, err := h.Market(taker, domain.SideBuy, "3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 2 || !order.WorkCapped {
+		t.Fatalf("trades = %d, work_capped = %v, want 2 and capped", len(trades), order.WorkCapped)
+	}
+	if got := h.PositionSize(taker); !got.Equal(dec("2")) {
+		t.Fatalf("taker position = %s, want 2", got)
+	}
+}
+
+func TestOrderWithinBoundIsNotCapped(t *testing.T) {
+	cfg := config.Default()
+	cfg.Engine.MaxMatchLevels = 3
+	h := enginetest.NewTestEngineWithConfig(cfg)
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+	for _, price := range []string{"1000", "1001", "1002"} {
+		h.MustLimit(maker, domain.SideSell, price, "1")
+	}
+
+	order, trades, err := h.Market(taker, domain.SideBuy, "3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 3 || order.WorkCapped || order.Status != domain.OrderStatusFilled {
+		t.Fatalf("trades = %d, work_capped = %v, status %s, want 3 fills uncapped", len(trades), order.WorkCapped, order.Status)
+	}
+}
@@ -0,0 +1,138 @@
+// Package events fans engine events out to external pipelines. Sinks are
+// selected by the events section of the config; the engine depends only on
+// engine.EventSink, so other brokers can be added here without touching it.
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/engine"
+)
+
+// DefaultBufferSize is the Async queue length used when none is configured
+const DefaultBufferSize = 10000
+
+// New builds the sink selected by cfg, wrapped so publishing never blocks the
+// engine. Returns nil when no sink is configured, in which case nothing is
+// published.
+func New(cfg config.EventsConfig) (*Async, error) {
+	var sink engine.EventSink
+	switch cfg.Sink {
+	case "", "none":
+		return nil, nil
+	case "webhook":
+		sink = NewWebhook(cfg.WebhookURL)
+	default:
+		return nil, fmt.Errorf("unknown event sink: %s", cfg.Sink)
+	}
+
+	buffer := cfg.BufferSize
+	if buffer == 0 {
+		buffer = DefaultBufferSize
+	}
+	return NewAsync(sink, buffer), nil
+}
+
+// event is one queued publish
+type event struct {
+	topic   string
+	payload []byte
+}
+
+// Async publishes to another sink from a background goroutine. When the
+// queue is full new events are dropped rather than stalling the engine.
+type Async struct {
+	sink    engine.EventSink
+	queue   chan event
+	done    chan struct{}
+	dropped atomic.Uint64
+}
+
+// NewAsync starts publishing to sink through a queue of the given size
+func NewAsync(sink engine.EventSink, buffer int) *Async {
+	a := &Async{
+		sink:  sink,
+		queue: make(chan event, buffer),
+		done:  make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Publish queues an event without blocking
+func (a *Async) Publish(topic string, payload []byte) error {
+	select {
+	case a.queue <- event{topic: topic, payload: payload}:
+	default:
+		if n := a.dropped.Add(1); n == 1 || n%1000 == 0 {
+			slog.Warn("Event queue full, events dropped", "dropped", n)
+		}
+	}
+	return nil
+}
+
+// Dropped returns how many events were discarded because the queue was full
+func (a *Async) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Close publishes any queued events and stops the background goroutine.
+// Publish must not be called after Close.
+func (a *Async) Close() {
+	close(a.queue)
+	<-a.done
+}
+
+func (a *Async) run() {
+	defer close(a.done)
+	for e := range a.queue {
+		if err := a.sink.Publish(e.topic, e.payload); err != nil {
+			slog.Error("Error publishing event", "topic", e.topic, "error", err)
+		}
+	}
+}
+
+// Webhook POSTs each event to a URL as {"topic": ..., "payload": ...}
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook creates a sink posting to url
+func NewWebhook(url string) *Webhook {
+	return &Webhook{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Publish implements engine.EventSink
+func (w *Webhook) Publish(topic string, payload []byte) error {
+	body, err := json.Marshal(struct {
+		Topic   string          `json:"topic"`
+		Payload json.RawMessage `json:"payload"`
+	}{topic, payload})
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
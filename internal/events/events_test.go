@@ -0,0 +1,105 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+// fakeSink records every published event by topic
+type fakeSink struct {
+	mu      sync.Mutex
+	events  map[string][][]byte
+	release chan struct{} // When set, Publish waits on it
+}
+
+func newFakeSink() *fakeSink {
+	return &fakeSink{events: make(map[string][][]byte)}
+}
+
+func (s *fakeSink) Publish(topic string, payload []byte) error {
+	if s.release != nil {
+		<-s.release
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events[topic] = append(s.events[topic], payload)
+	return nil
+}
+
+func (s *fakeSink) count(topic string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events[topic])
+}
+
+func TestSinkReceivesEachEventType(t *testing.T) {
+	h := enginetest.NewTestEngine()
+	sink := newFakeSink()
+	async := NewAsync(sink, 100)
+	h.Engine.SetEventSink(async)
+
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+	h.MustLimit(maker, domain.SideSell, "1000", "1")
+	h.MustMarket(taker, domain.SideBuy, "1")
+	h.Engine.AddLiquidation(&domain.Liquidation{
+		ID:         uuid.New(),
+		TraderID:   taker.ID,
+		Instrument: h.Instrument,
+		Side:       domain.SideBuy,
+		Size:       decimal.NewFromInt(1),
+		Timestamp:  time.Now(),
+	})
+	async.Close()
+
+	for _, topic := range []string{engine.TopicTrades, engine.TopicOrders, engine.TopicPositions, engine.TopicLiquidations} {
+		if sink.count(topic) == 0 {
+			t.Errorf("no %s events published", topic)
+		}
+	}
+
+	var trade domain.Trade
+	if err := json.Unmarshal(sink.events[engine.TopicTrades][0], &trade); err != nil {
+		t.Fatal(err)
+	}
+	if trade.BuyerID != taker.ID || !trade.Price.Equal(decimal.NewFromInt(1000)) {
+		t.Fatalf("trade event = %+v, want the taker buying at 1000", trade)
+	}
+}
+
+func TestAsyncDropsRatherThanBlocks(t *testing.T) {
+	sink := newFakeSink()
+	sink.release = make(chan struct{})
+	async := NewAsync(sink, 2)
+
+	// The first event is taken by the stalled sink, two more fill the queue
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			async.Publish(engine.TopicTrades, []byte(`{}`))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Publish blocked on a stalled sink")
+	}
+	if dropped := async.Dropped(); dropped < 7 {
+		t.Fatalf("dropped %d of 10 events past a queue of 2, want at least 7", dropped)
+	}
+
+	close(sink.release)
+	async.Close()
+	if got := sink.count(engine.TopicTrades) + int(async.Dropped()); got != 10 {
+		t.Fatalf("%d events published or dropped, want all 10", got)
+	}
+}
@@ -0,0 +1,47 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// CandleSource supplies saved candles. *db.SQLiteDB implements it.
+type CandleSource interface {
+	EachCandle(instrument string, interval domain.CandleInterval, start, end time.Time, limit int, fn func(*domain.Candle) error) error
+}
+
+var candleHeader = []string{"open_time", "open", "high", "low", "close", "volume", "trade_count", "vwap"}
+
+// WriteCandlesCSV writes up to limit candles opening within the range to w as
+// CSV, oldest first, streaming rows from the source as they are read
+func WriteCandlesCSV(w io.Writer, src CandleSource, instrument string, interval domain.CandleInterval, start, end time.Time, limit int) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(candleHeader); err != nil {
+		return err
+	}
+	err := src.EachCandle(instrument, interval, start, end, limit, func(c *domain.Candle) error {
+		return cw.Write(candleRow(c))
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func candleRow(c *domain.Candle) []string {
+	return []string{
+		formatTime(c.OpenTime),
+		c.Open.String(),
+		c.High.String(),
+		c.Low.String(),
+		c.Close.String(),
+		c.Volume.String(),
+		strconv.FormatInt(c.TradeCount, 10),
+		c.VWAP.String(),
+	}
+}
@@ -0,0 +1,157 @@
+// Package export writes the public market dataset as a ZIP of CSV files for
+// research. Rows are streamed from the source straight into the archive, so a
+// large period never has to be held in memory.
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// Source supplies the rows for an export. *db.SQLiteDB implements it.
+type Source interface {
+	EachTrader(fn func(*domain.Trader) error) error
+	EachTrade(start, end time.Time, fn func(*domain.Trade) error) error
+	EachLiquidation(start, end time.Time, fn func(*domain.Liquidation) error) error
+	EachPosition(start, end time.Time, fn func(*domain.Position) error) error
+}
+
+// WriteZIP writes traders.csv, trades.csv, liquidations.csv and positions.csv
+// to w. Every trader is included, with public fields only; trades and
+// liquidations are those within the range, and positions those last updated
+// within it.
+func WriteZIP(w io.Writer, src Source, start, end time.Time) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name   string
+		header []string
+		write  func(*csv.Writer) error
+	}{
+		{"traders.csv", traderHeader, func(cw *csv.Writer) error {
+			return src.EachTrader(func(t *domain.Trader) error { return cw.Write(traderRow(t)) })
+		}},
+		{"trades.csv", tradeHeader, func(cw *csv.Writer) error {
+			return src.EachTrade(start, end, func(t *domain.Trade) error { return cw.Write(tradeRow(t)) })
+		}},
+		{"liquidations.csv", liquidationHeader, func(cw *csv.Writer) error {
+			return src.EachLiquidation(start, end, func(l *domain.Liquidation) error { return cw.Write(liquidationRow(l)) })
+		}},
+		{"positions.csv", positionHeader, func(cw *csv.Writer) error {
+			return src.EachPosition(start, end, func(p *domain.Position) error { return cw.Write(positionRow(p)) })
+		}},
+	}
+
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return fmt.Errorf("creating %s: %w", f.name, err)
+		}
+		cw := csv.NewWriter(fw)
+		if err := cw.Write(f.header); err != nil {
+			return fmt.Errorf("writing %s: %w", f.name, err)
+		}
+		if err := f.write(cw); err != nil {
+			return fmt.Errorf("writing %s: %w", f.name, err)
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return fmt.Errorf("writing %s: %w", f.name, err)
+		}
+	}
+
+	return zw.Close()
+}
+
+var traderHeader = []string{"id", "username", "type", "balance", "total_pnl", "trade_count", "max_leverage_used", "created_at"}
+
+func traderRow(t *domain.Trader) []string {
+	return []string{
+		t.ID.String(),
+		t.Username,
+		string(t.Type),
+		t.Balance.String(),
+		t.TotalPnL.String(),
+		strconv.FormatInt(t.TradeCount, 10),
+		strconv.Itoa(t.MaxLeverageUsed),
+		formatTime(t.CreatedAt),
+	}
+}
+
+var tradeHeader = []string{
+	"id", "timestamp", "instrument", "price", "size", "aggressor_side",
+	"buyer_id", "buyer_order_id", "buyer_leverage", "buyer_effect", "buyer_new_position", "buyer_fee", "buyer_realized_pnl",
+	"seller_id", "seller_order_id", "seller_leverage", "seller_effect", "seller_new_position", "seller_fee", "seller_realized_pnl",
+}
+
+func tradeRow(t *domain.Trade) []string {
+	return []string{
+		t.ID.String(),
+		formatTime(t.Timestamp),
+		t.Instrument,
+		t.Price.String(),
+		t.Size.String(),
+		string(t.AggressorSide),
+		t.BuyerID.String(),
+		t.BuyerOrderID.String(),
+		strconv.Itoa(t.BuyerLeverage),
+		string(t.BuyerEffect),
+		t.BuyerNewPosition.String(),
+		t.BuyerFee.String(),
+		t.BuyerRealizedPnL.String(),
+		t.SellerID.String(),
+		t.SellerOrderID.String(),
+		strconv.Itoa(t.SellerLeverage),
+		string(t.SellerEffect),
+		t.SellerNewPosition.String(),
+		t.SellerFee.String(),
+		t.SellerRealizedPnL.String(),
+	}
+}
+
+var liquidationHeader = []string{"id", "timestamp", "trader_id", "instrument", "side", "size", "entry_price", "liquidation_price", "mark_price", "leverage", "loss", "insurance_fund_hit"}
+
+func liquidationRow(l *domain.Liquidation) []string {
+	return []string{
+		l.ID.String(),
+		formatTime(l.Timestamp),
+		l.TraderID.String(),
+		l.Instrument,
+		string(l.Side),
+		l.Size.String(),
+		l.EntryPrice.String(),
+		l.LiquidationPrice.String(),
+		l.MarkPrice.String(),
+		strconv.Itoa(l.Leverage),
+		l.Loss.String(),
+		strconv.FormatBool(l.InsuranceFundHit),
+	}
+}
+
+var positionHeader = []string{"trader_id", "instrument", "size", "entry_price", "leverage", "margin", "unrealized_pnl", "realized_pnl", "liquidation_price", "updated_at"}
+
+func positionRow(p *domain.Position) []string {
+	return []string{
+		p.TraderID.String(),
+		p.Instrument,
+		p.Size.String(),
+		p.EntryPrice.String(),
+		strconv.Itoa(p.Leverage),
+		p.Margin.String(),
+		p.UnrealizedPnL.String(),
+		p.RealizedPnL.String(),
+		p.LiquidationPrice.String(),
+		formatTime(p.UpdatedAt),
+	}
+}
+
+// formatTime renders timestamps in UTC, like everything else on the exchange
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,96 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/db"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+func TestWriteZIPFromDatabase(t *testing.T) {
+	database, err := db.NewSQLite(filepath.Join(t.TempDir(), "export.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	h := enginetest.NewTestEngine()
+	h.Engine.SetDatabase(database)
+
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+	h.AddTrader("idle")
+	h.MustLimit(maker, domain.SideSell, "1000", "2")
+	h.MustMarket(taker, domain.SideBuy, "1") // Before the range
+	time.Sleep(5 * time.Millisecond)
+	start := time.Now()
+	time.Sleep(5 * time.Millisecond)
+	h.MustMarket(taker, domain.SideBuy, "1")
+	h.Engine.AddLiquidation(&domain.Liquidation{
+		ID:         uuid.New(),
+		TraderID:   taker.ID,
+		Instrument: h.Instrument,
+		Side:       domain.SideBuy,
+		Size:       decimal.NewFromInt(2),
+		MarkPrice:  decimal.NewFromInt(900),
+		Leverage:   1,
+		Timestamp:  time.Now(),
+	})
+	end := time.Now().Add(time.Second)
+
+	var buf bytes.Buffer
+	if err := WriteZIP(&buf, database, start, end); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		name   string
+		header []string
+		rows   int
+	}{
+		{"traders.csv", traderHeader, 3}, // Every trader, whatever the range
+		{"trades.csv", tradeHeader, 1},   // Only the second fill
+		{"liquidations.csv", liquidationHeader, 1},
+		{"positions.csv", positionHeader, 2}, // Both updated by the second fill
+	}
+	if len(zr.File) != len(want) {
+		t.Fatalf("%d files in the export, want %d", len(zr.File), len(want))
+	}
+	for i, w := range want {
+		f := zr.File[i]
+		if f.Name != w.name {
+			t.Fatalf("file %d = %s, want %s", i, f.Name, w.name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(rc).ReadAll()
+		rc.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", f.Name, err)
+		}
+		if len(records) == 0 || len(records[0]) != len(w.header) || records[0][0] != w.header[0] {
+			t.Fatalf("%s header = %v, want %v", f.Name, records, w.header)
+		}
+		if rows := len(records) - 1; rows != w.rows {
+			t.Errorf("%s has %d rows, want %d", f.Name, rows, w.rows)
+		}
+		for _, record := range records[1:] {
+			if len(record) != len(w.header) {
+				t.Errorf("%s row has %d fields, want %d", f.Name, len(record), len(w.header))
+			}
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Package funding settles periodic funding payments between longs and shorts.
+// The rate follows the premium of the mark price over an anchor price (the
+// index price when there is one, otherwise a trade TWAP), so holding the
+// crowded side of the market costs money.
+package funding
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// checkInterval is how often the predicted rate is refreshed and the
+// settlement time checked
+const checkInterval = time.Second
+
+// PriceProvider gives the mark price and the anchors it may be compared to
+type PriceProvider interface {
+	GetMarkPrice(instrument string) decimal.Decimal
+	GetIndexPrice(instrument string) (decimal.Decimal, bool)
+	GetTWAP(instrument string, window time.Duration) (decimal.Decimal, bool)
+}
+
+// PositionStore applies funding to open positions
+type PositionStore interface {
+	ApplyFunding(instrument string, rate, markPrice decimal.Decimal) *domain.FundingSettlement
+}
+
+// MarketPhaser moves the market in and out of the pre-settlement freeze
+type MarketPhaser interface {
+	BeginFundingFreeze(reason string)
+	EndFundingFreeze(reason string)
+}
+
+// SettlementHandler is called after each funding settlement
+type SettlementHandler func(settlement *domain.FundingSettlement)
+
+// FreezeHandler is called when the freeze before a settlement is scheduled
+type FreezeHandler func(freeze *domain.FundingFreeze)
+
+// Engine computes the funding rate and settles it on schedule
+type Engine struct {
+	cfg            config.FundingConfig
+	priceProvider  PriceProvider
+	positionStore  PositionStore
+	mu             sync.RWMutex
+	rates          map[string]decimal.Decimal // Predicted rate for the next settlement
+	nextFunding    time.Time
+	frozen         bool // Market is in the freeze before nextFunding
+	phaser         MarketPhaser
+	now            func() time.Time
+	handlers       []SettlementHandler
+	freezeHandlers []FreezeHandler
+	stopCh         chan struct{}
+	wg             sync.WaitGroup
+}
+
+// NewEngine creates a new funding engine
+func NewEngine(cfg config.FundingConfig, pp PriceProvider, ps PositionStore) *Engine {
+	e := &Engine{
+		cfg:           cfg,
+		priceProvider: pp,
+		positionStore: ps,
+		rates:         make(map[string]decimal.Decimal),
+		now:           time.Now,
+		stopCh:        make(chan struct{}),
+	}
+	e.nextFunding = e.nextSettlement(time.Now())
+	return e
+}
+
+// SetClock replaces the clock settlements and freezes are timed by. Call it
+// before Start.
+func (e *Engine) SetClock(now func() time.Time) {
+	e.now = now
+	e.nextFunding = e.nextSettlement(now())
+}
+
+// SetMarketPhaser sets what the pre-settlement freeze moves in and out of
+// its phase. Without one, or with freeze_seconds unset, nothing is frozen.
+func (e *Engine) SetMarketPhaser(phaser MarketPhaser) {
+	e.phaser = phaser
+}
+
+// OnSettlement registers a settlement handler
+func (e *Engine) OnSettlement(handler SettlementHandler) {
+	e.handlers = append(e.handlers, handler)
+}
+
+// OnFreezeScheduled registers a handler told of each upcoming freeze: on
+// Start and after every settlement
+func (e *Engine) OnFreezeScheduled(handler FreezeHandler) {
+	e.freezeHandlers = append(e.freezeHandlers, handler)
+}
+
+// Start begins the funding loop
+func (e *Engine) Start() {
+	e.wg.Add(1)
+	go e.runLoop()
+	slog.Info("Funding engine started", "interval_minutes", e.cfg.IntervalMinutes, "next", e.NextFundingTime())
+	e.notifyFreezeScheduled()
+}
+
+// Stop halts the funding engine
+func (e *Engine) Stop() {
+	close(e.stopCh)
+	e.wg.Wait()
+	slog.Info("Funding engine stopped")
+}
+
+// GetCurrentRate returns the rate the next settlement would apply at current prices
+func (e *Engine) GetCurrentRate(instrument string) decimal.Decimal {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.rates[instrument]
+}
+
+// NextFundingTime returns when funding is next settled
+func (e *Engine) NextFundingTime() time.Time {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.nextFunding
+}
+
+// NextFundingFreeze returns the freeze before the next settlement, or nil if
+// none is configured
+func (e *Engine) NextFundingFreeze() *domain.FundingFreeze {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.freezeLocked()
+}
+
+// freezeLocked returns the freeze before nextFunding, or nil if none is
+// configured. Caller must hold e.mu.
+func (e *Engine) freezeLocked() *domain.FundingFreeze {
+	if e.cfg.FreezeSeconds <= 0 || e.phaser == nil || e.nextFunding.IsZero() {
+		return nil
+	}
+	return &domain.FundingFreeze{
+		StartsAt:       e.nextFunding.Add(-time.Duration(e.cfg.FreezeSeconds) * time.Second),
+		SettlementTime: e.nextFunding,
+	}
+}
+
+// notifyFreezeScheduled tells the freeze handlers of the next freeze, if any
+func (e *Engine) notifyFreezeScheduled() {
+	freeze := e.NextFundingFreeze()
+	if freeze == nil {
+		return
+	}
+	for _, handler := range e.freezeHandlers {
+		handler(freeze)
+	}
+}
+
+// runLoop refreshes the predicted rate and settles when the time comes
+func (e *Engine) runLoop() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case <-ticker.C:
+			e.tick(e.now())
+		}
+	}
+}
+
+// tick updates the predicted rate, enters the freeze once its window opens,
+// and once the settlement time has passed settles it, lifts the freeze and
+// schedules the next one
+func (e *Engine) tick(now time.Time) {
+	instrument := domain.RIndexSymbol
+	rate, mark := e.ComputeRate(instrument)
+
+	e.mu.Lock()
+	e.rates[instrument] = rate
+	due := !now.Before(e.nextFunding)
+	settlementTime := e.nextFunding
+	freeze := e.freezeLocked()
+	freezing := !due && !e.frozen && freeze != nil && !now.Before(freeze.StartsAt)
+	if freezing {
+		e.frozen = true
+	}
+	thawing := due && e.frozen
+	if due {
+		e.frozen = false
+		e.nextFunding = e.nextSettlement(now)
+	}
+	e.mu.Unlock()
+
+	if freezing {
+		e.phaser.BeginFundingFreeze(fmt.Sprintf("funding settles at %s", settlementTime.Format(time.RFC3339)))
+	}
+	if due {
+		e.settle(instrument, rate, mark)
+		if thawing {
+			e.phaser.EndFundingFreeze("funding settled")
+		}
+		e.notifyFreezeScheduled()
+	}
+}
+
+// ComputeRate returns the funding rate at current prices and the mark price
+// it was computed from: the mark's premium over the index price, or over the
+// anchor TWAP without an index, capped at MaxRate either way. Positive means
+// longs pay shorts.
+func (e *Engine) ComputeRate(instrument string) (rate, mark decimal.Decimal) {
+	mark = e.priceProvider.GetMarkPrice(instrument)
+	anchor, ok := e.priceProvider.GetIndexPrice(instrument)
+	if !ok {
+		anchor, ok = e.priceProvider.GetTWAP(instrument, time.Duration(e.cfg.AnchorWindowMinutes)*time.Minute)
+	}
+	if !ok || !anchor.IsPositive() || !mark.IsPositive() {
+		return decimal.Zero, mark
+	}
+
+	rate = mark.Sub(anchor).Div(anchor)
+	if rate.GreaterThan(e.cfg.MaxRate) {
+		rate = e.cfg.MaxRate
+	} else if rate.LessThan(e.cfg.MaxRate.Neg()) {
+		rate = e.cfg.MaxRate.Neg()
+	}
+	return rate, mark
+}
+
+// settle applies one funding payment to every open position
+func (e *Engine) settle(instrument string, rate, mark decimal.Decimal) {
+	if rate.IsZero() || !mark.IsPositive() {
+		slog.Info("Funding settled, no payments", "instrument", instrument, "rate", decimal.Zero)
+		return
+	}
+
+	settlement := e.positionStore.ApplyFunding(instrument, rate, mark)
+	slog.Info("Funding settled", "instrument", instrument, "rate", rate,
+		"positions", settlement.Positions, "paid", settlement.TotalPaid)
+
+	for _, handler := range e.handlers {
+		handler(settlement)
+	}
+}
+
+// nextSettlement returns the first settlement time after now. Settlements
+// are aligned to the UTC day, so an 8h interval settles at 00:00, 08:00 and
+// 16:00 UTC whenever the server was started.
+func (e *Engine) nextSettlement(now time.Time) time.Time {
+	interval := time.Duration(e.cfg.IntervalMinutes) * time.Minute
+	if interval <= 0 {
+		return time.Time{}
+	}
+	return now.UTC().Truncate(interval).Add(interval)
+}
@@ -0,0 +1,132 @@
+package funding
+
+import (
+	"testing"
+	"time"
+
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+)
+
+// settlement is the first settlement after the injected clock's start
+var settlement = time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+
+// newFrozenEngine wires a funding engine that freezes freezeSeconds before
+// each settlement to a test engine, on a clock 10 minutes before the first
+func newFrozenEngine(t *testing.T, freezeSeconds int) (*Engine, *enginetest.Harness) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Funding.Enabled = true
+	cfg.Funding.FreezeSeconds = freezeSeconds
+	h := enginetest.NewTestEngineWithConfig(cfg)
+
+	e := NewEngine(cfg.Funding, h.Engine, h.Engine)
+	e.SetMarketPhaser(h.Engine)
+	e.SetClock(func() time.Time { return settlement.Add(-10 * time.Minute) })
+	h.Engine.SetFundingSource(e)
+	return e, h
+}
+
+func marketState(h *enginetest.Harness) domain.MarketState {
+	state, _ := h.Engine.GetMarketState()
+	return state
+}
+
+func TestFundingFreezeRejectsOpeningOrders(t *testing.T) {
+	e, h := newFrozenEngine(t, 120)
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+
+	// Before the window opens, orders are accepted as usual
+	e.tick(settlement.Add(-3 * time.Minute))
+	if state := marketState(h); state != domain.MarketStateOpen {
+		t.Fatalf("state = %s before the freeze, want open", state)
+	}
+	h.MustLimit(maker, domain.SideSell, "1000", "1")
+	h.MustMarket(taker, domain.SideBuy, "1")
+
+	e.tick(settlement.Add(-90 * time.Second))
+	if state := marketState(h); state != domain.MarketStateFundingFreeze {
+		t.Fatalf("state = %s inside the freeze, want funding_freeze", state)
+	}
+	if _, _, err := h.Limit(taker, domain.SideBuy, "990", "1"); err == nil {
+		t.Fatal("order growing a position accepted during the freeze")
+	}
+	if _, _, err := h.Limit(h.AddTrader("newcomer"), domain.SideSell, "1010", "1"); err == nil {
+		t.Fatal("order opening a position accepted during the freeze")
+	}
+	// Closing the long and resting liquidity still work
+	if _, _, err := h.Limit(taker, domain.SideSell, "1001", "1"); err != nil {
+		t.Fatalf("reducing order rejected during the freeze: %v", err)
+	}
+	h.MustMarket(maker, domain.SideBuy, "1")
+	if got := h.PositionSize(taker); !got.IsZero() {
+		t.Fatalf("taker position = %s after closing, want 0", got)
+	}
+
+	e.tick(settlement)
+	if state := marketState(h); state != domain.MarketStateOpen {
+		t.Fatalf("state = %s after settlement, want open", state)
+	}
+	h.MustLimit(taker, domain.SideBuy, "990", "1")
+
+	next := e.NextFundingFreeze()
+	if next == nil || !next.SettlementTime.Equal(settlement.Add(8*time.Hour)) {
+		t.Fatalf("next freeze = %+v, want one before %s", next, settlement.Add(8*time.Hour))
+	}
+}
+
+func TestFundingFreezeLeavesHaltedMarket(t *testing.T) {
+	e, h := newFrozenEngine(t, 120)
+
+	e.tick(settlement.Add(-time.Minute))
+	if err := h.Engine.SetMarketState(domain.MarketStateHalted, "incident"); err != nil {
+		t.Fatal(err)
+	}
+	e.tick(settlement)
+	if state := marketState(h); state != domain.MarketStateHalted {
+		t.Fatalf("state = %s after settlement, want halted", state)
+	}
+
+	// Nor does the next freeze start in a market that is not open
+	e.tick(settlement.Add(8*time.Hour - time.Minute))
+	if state := marketState(h); state != domain.MarketStateHalted {
+		t.Fatalf("state = %s in the next freeze, want halted", state)
+	}
+}
+
+func TestFundingFreezeOffByDefault(t *testing.T) {
+	e, h := newFrozenEngine(t, 0)
+	trader := h.AddTrader("trader")
+
+	e.tick(settlement.Add(-time.Second))
+	if state := marketState(h); state != domain.MarketStateOpen {
+		t.Fatalf("state = %s, want open", state)
+	}
+	h.MustLimit(trader, domain.SideBuy, "990", "1")
+	if freeze := h.Engine.GetMarketStats(h.Instrument).NextFundingFreeze; freeze != nil {
+		t.Fatalf("next freeze = %+v, want none", freeze)
+	}
+}
+
+func TestFundingFreezeAnnounced(t *testing.T) {
+	e, h := newFrozenEngine(t, 120)
+	var announced []*domain.FundingFreeze
+	e.OnFreezeScheduled(func(freeze *domain.FundingFreeze) {
+		announced = append(announced, freeze)
+	})
+
+	e.notifyFreezeScheduled()
+	e.tick(settlement)
+	if len(announced) != 2 {
+		t.Fatalf("announced %d freezes, want 2", len(announced))
+	}
+	if want := settlement.Add(-2 * time.Minute); !announced[0].StartsAt.Equal(want) {
+		t.Fatalf("first freeze starts at %s, want %s", announced[0].StartsAt, want)
+	}
+	if stats := h.Engine.GetMarketStats(h.Instrument); stats.NextFundingFreeze == nil ||
+		!stats.NextFundingFreeze.SettlementTime.Equal(settlement.Add(8*time.Hour)) {
+		t.Fatalf("stats next freeze = %+v", stats.NextFundingFreeze)
+	}
+}
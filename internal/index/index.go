@@ -0,0 +1,87 @@
+// Package index supplies the reference index prices trading is measured
+// against. Sources are selected by the index section of the config; the
+// engine depends only on engine.IndexSource.
+package index
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/engine"
+)
+
+// New builds the index source selected by cfg, starting from the
+// instrument's starting price. Returns nil when none is configured, in which
+// case the index price follows the mark.
+func New(cfg config.IndexConfig, instrument config.RIndexConfig) (engine.IndexSource, error) {
+	switch cfg.Source {
+	case "", "none":
+		return nil, nil
+	case "random_walk":
+		return NewRandomWalk(cfg.Seed, time.Duration(cfg.StepSeconds)*time.Second, cfg.Volatility, instrument.StartingPrice, instrument.TickSize, time.Now()), nil
+	default:
+		return nil, fmt.Errorf("unknown index source: %s", cfg.Source)
+	}
+}
+
+// RandomWalk is an index that moves once per step by a random fraction of
+// at most volatility either way. The moves are drawn from a seeded source,
+// so the same seed gives the same path from each start; the walk is the same
+// for every instrument.
+type RandomWalk struct {
+	mu         sync.Mutex
+	rng        *rand.Rand
+	start      time.Time
+	step       time.Duration
+	volatility decimal.Decimal
+	tick       decimal.Decimal
+	steps      int64 // Steps taken since start
+	price      decimal.Decimal
+}
+
+// NewRandomWalk starts a walk at price from start, rounding to tick
+func NewRandomWalk(seed int64, step time.Duration, volatility, price, tick decimal.Decimal, start time.Time) *RandomWalk {
+	return &RandomWalk{
+		rng:        rand.New(rand.NewSource(seed)),
+		start:      start,
+		step:       step,
+		volatility: volatility,
+		tick:       tick,
+		price:      price,
+	}
+}
+
+// CurrentIndex returns the index now (implements engine.IndexSource)
+func (w *RandomWalk) CurrentIndex(instrument string) decimal.Decimal {
+	return w.At(time.Now())
+}
+
+// At returns the index at a time, taking any steps due since the last call.
+// The walk never goes back, so an earlier time returns the latest price.
+func (w *RandomWalk) At(t time.Time) decimal.Decimal {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.step <= 0 {
+		return w.price
+	}
+	due := int64(t.Sub(w.start) / w.step)
+	for ; w.steps < due; w.steps++ {
+		move := decimal.NewFromFloat(w.rng.Float64()*2 - 1).Mul(w.volatility)
+		w.price = w.roundToTick(w.price.Mul(decimal.NewFromInt(1).Add(move)))
+	}
+	return w.price
+}
+
+// roundToTick rounds a price to the nearest tick, never below one tick
+func (w *RandomWalk) roundToTick(price decimal.Decimal) decimal.Decimal {
+	if !w.tick.IsPositive() {
+		return price
+	}
+	price = price.Div(w.tick).Round(0).Mul(w.tick)
+	return decimal.Max(price, w.tick)
+}
@@ -1,7 +1,8 @@
 package liquidation
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 	"sync"
 	"time"
 
@@ -18,14 +19,22 @@ type PriceProvider interface {
 
 // PositionStore manages positions
 type PositionStore interface {
+	Instruments() []string
 	GetAllPositions(instrument string) []*domain.Position
 	GetPosition(traderID uuid.UUID, instrument string) *domain.Position
-	ClosePosition(traderID uuid.UUID, instrument string, markPrice decimal.Decimal) error
+	ClosePosition(traderID uuid.UUID, instrument string, markPrice decimal.Decimal) (decimal.Decimal, error)
+	CancelAllOrders(traderID uuid.UUID) []*domain.Order
+	GetADLQueue(instrument string) []*domain.ADLQueueEntry
+	DeleveragePosition(traderID uuid.UUID, instrument string, size, markPrice, cover decimal.Decimal) (decimal.Decimal, decimal.Decimal, error)
+	GetCrossMarginAccount(traderID uuid.UUID) *domain.CrossMarginAccount
 }
 
 // LiquidationHandler is called when a liquidation occurs
 type LiquidationHandler func(liq *domain.Liquidation)
 
+// InsuranceFundHandler is called after each insurance fund balance change
+type InsuranceFundHandler func(event *domain.InsuranceFundEvent)
+
 // Engine monitors positions and triggers liquidations
 type Engine struct {
 	cfg              config.LiquidationConfig
@@ -34,6 +43,7 @@ type Engine struct {
 	insuranceFund    decimal.Decimal
 	insuranceFundMu  sync.RWMutex
 	handlers         []LiquidationHandler
+	fundHandlers     []InsuranceFundHandler
 	stopCh           chan struct{}
 	wg               sync.WaitGroup
 }
@@ -54,18 +64,23 @@ func (e *Engine) OnLiquidation(handler LiquidationHandler) {
 	e.handlers = append(e.handlers, handler)
 }
 
+// OnInsuranceFundChange registers a handler for insurance fund balance changes
+func (e *Engine) OnInsuranceFundChange(handler InsuranceFundHandler) {
+	e.fundHandlers = append(e.fundHandlers, handler)
+}
+
 // Start begins the liquidation monitoring loop
 func (e *Engine) Start() {
 	e.wg.Add(1)
 	go e.monitorLoop()
-	log.Printf("Liquidation engine started (interval: %dms)", e.cfg.CheckIntervalMs)
+	slog.Info("Liquidation engine started", "interval_ms", e.cfg.CheckIntervalMs)
 }
 
 // Stop halts the liquidation engine
 func (e *Engine) Stop() {
 	close(e.stopCh)
 	e.wg.Wait()
-	log.Println("Liquidation engine stopped")
+	slog.Info("Liquidation engine stopped")
 }
 
 // GetInsuranceFund returns current insurance fund balance
@@ -75,6 +90,46 @@ func (e *Engine) GetInsuranceFund() decimal.Decimal {
 	return e.insuranceFund
 }
 
+// RestoreInsuranceFund sets the balance carried over from before a restart,
+// without recording a change. Call it before Start.
+func (e *Engine) RestoreInsuranceFund(balance decimal.Decimal) {
+	e.insuranceFundMu.Lock()
+	defer e.insuranceFundMu.Unlock()
+	e.insuranceFund = balance
+}
+
+// ApplyInsuranceFundChange adds event.Delta to the fund and records the change.
+// ID, Timestamp and Balance are filled in. A change that would take the balance
+// below zero is rejected.
+func (e *Engine) ApplyInsuranceFundChange(event *domain.InsuranceFundEvent) error {
+	e.insuranceFundMu.Lock()
+	if e.insuranceFund.Add(event.Delta).IsNegative() {
+		balance := e.insuranceFund
+		e.insuranceFundMu.Unlock()
+		return fmt.Errorf("insurance fund balance %s cannot cover %s", balance, event.Delta)
+	}
+	e.applyFundChangeLocked(event)
+	e.insuranceFundMu.Unlock()
+
+	e.notifyFundChange(event)
+	return nil
+}
+
+// applyFundChangeLocked updates the balance. Caller must hold insuranceFundMu.
+func (e *Engine) applyFundChangeLocked(event *domain.InsuranceFundEvent) {
+	e.insuranceFund = e.insuranceFund.Add(event.Delta)
+	event.ID = uuid.New()
+	event.Timestamp = time.Now()
+	event.Balance = e.insuranceFund
+}
+
+// notifyFundChange passes a recorded change to the fund handlers
+func (e *Engine) notifyFundChange(event *domain.InsuranceFundEvent) {
+	for _, handler := range e.fundHandlers {
+		handler(event)
+	}
+}
+
 // monitorLoop continuously checks for liquidatable positions
 func (e *Engine) monitorLoop() {
 	defer e.wg.Done()
@@ -92,28 +147,38 @@ func (e *Engine) monitorLoop() {
 	}
 }
 
-// checkPositions scans all positions for liquidations
+// checkPositions scans all positions of every instrument for liquidations
 func (e *Engine) checkPositions() {
-	markPrice := e.priceProvider.GetMarkPrice(domain.RIndexSymbol)
-	if markPrice.IsZero() {
-		return // No price available yet
-	}
+	for _, instrument := range e.positionStore.Instruments() {
+		markPrice := e.priceProvider.GetMarkPrice(instrument)
+		if markPrice.IsZero() {
+			continue // No price available yet
+		}
 
-	positions := e.positionStore.GetAllPositions(domain.RIndexSymbol)
+		positions := e.positionStore.GetAllPositions(instrument)
 
-	for _, pos := range positions {
-		if e.shouldLiquidate(pos, markPrice) {
-			e.liquidatePosition(pos, markPrice)
+		for _, pos := range positions {
+			if e.shouldLiquidate(pos, markPrice) {
+				e.liquidatePosition(pos, markPrice)
+			}
 		}
 	}
 }
 
-// shouldLiquidate determines if a position should be liquidated
+// shouldLiquidate determines if a position should be liquidated. A cross
+// margin position is liquidated once its trader's total equity no longer
+// covers the maintenance margin of all their positions.
 func (e *Engine) shouldLiquidate(pos *domain.Position, markPrice decimal.Decimal) bool {
 	if pos.Size.IsZero() {
 		return false
 	}
 
+	if pos.MarginMode == domain.MarginModeCross {
+		if account := e.positionStore.GetCrossMarginAccount(pos.TraderID); account != nil {
+			return account.Equity.LessThanOrEqual(account.Maintenance)
+		}
+	}
+
 	if pos.IsLong() {
 		// Long position: liquidate if mark price <= liquidation price
 		return markPrice.LessThanOrEqual(pos.LiquidationPrice)
@@ -156,58 +221,159 @@ func (e *Engine) liquidatePosition(pos *domain.Position, markPrice decimal.Decim
 		Leverage:         pos.Leverage,
 		Loss:             loss,
 		Timestamp:        time.Now(),
+		Effect:           domain.EffectLiquidation,
+	}
+
+	// A cross position is also backed by the trader's free balance, and
+	// whatever the loss leaves of it stays with the trader
+	backing := pos.Margin
+	var cross bool
+	if pos.MarginMode == domain.MarginModeCross {
+		if account := e.positionStore.GetCrossMarginAccount(pos.TraderID); account != nil {
+			backing = backing.Add(decimal.Max(account.Balance, decimal.Zero))
+			cross = true
+		}
 	}
 
 	// Handle insurance fund
+	var unbacked decimal.Decimal
+	fundEvent := &domain.InsuranceFundEvent{LiquidationID: &liq.ID}
 	e.insuranceFundMu.Lock()
-	if loss.GreaterThan(pos.Margin) {
-		// Loss exceeds margin, insurance fund covers the difference
-		shortfall := loss.Sub(pos.Margin)
+	if loss.GreaterThan(backing) {
+		// Loss exceeds what backs the position, insurance fund covers the difference
+		shortfall := loss.Sub(backing)
+		fundEvent.Cause = domain.InsuranceFundShortfallCover
 		if e.insuranceFund.GreaterThanOrEqual(shortfall) {
-			e.insuranceFund = e.insuranceFund.Sub(shortfall)
+			fundEvent.Delta = shortfall.Neg()
 			liq.InsuranceFundHit = true
 		} else {
-			// Insurance fund depleted - would trigger ADL
-			// For now, just use what's available
-			e.insuranceFund = decimal.Zero
+			// Insurance fund depleted - the rest is covered by ADL below
+			fundEvent.Delta = e.insuranceFund.Neg()
 			liq.InsuranceFundHit = true
-			log.Printf("WARNING: Insurance fund depleted during liquidation of %s", pos.TraderID)
+			unbacked = shortfall.Sub(e.insuranceFund)
+			slog.Warn("Insurance fund depleted during liquidation, remainder left to auto-deleverage", "trader_id", pos.TraderID, "unbacked", unbacked)
 		}
-	} else {
-		// Margin covers the loss, excess goes to insurance fund
-		surplus := pos.Margin.Sub(loss)
-		e.insuranceFund = e.insuranceFund.Add(surplus)
+	}
+	recorded := !fundEvent.Delta.IsZero()
+	if recorded {
+		e.applyFundChangeLocked(fundEvent)
 	}
 	e.insuranceFundMu.Unlock()
+	if recorded {
+		e.notifyFundChange(fundEvent)
+	}
+
+	// Pull the trader's resting orders first, so none can re-open exposure
+	if e.cfg.CancelOrdersOnLiquidation {
+		if cancelled := e.positionStore.CancelAllOrders(pos.TraderID); len(cancelled) > 0 {
+			slog.Info("Cancelled orders of liquidated trader", "trader_id", pos.TraderID, "count", len(cancelled))
+		}
+	}
 
 	// Close the position
-	if err := e.positionStore.ClosePosition(pos.TraderID, pos.Instrument, markPrice); err != nil {
-		log.Printf("Error closing liquidated position: %v", err)
+	fee, err := e.positionStore.ClosePosition(pos.TraderID, pos.Instrument, markPrice)
+	if err != nil {
+		slog.Error("Error closing liquidated position", "trader_id", pos.TraderID, "error", err)
 		return
 	}
 
+	// An isolated trader forfeits their margin, so what the loss and the
+	// liquidation fee leave of it goes to the insurance fund
+	if surplus := pos.Margin.Sub(loss).Sub(fee); !cross && surplus.IsPositive() {
+		if err := e.ApplyInsuranceFundChange(&domain.InsuranceFundEvent{
+			LiquidationID: &liq.ID,
+			Cause:         domain.InsuranceFundLiquidationSurplus,
+			Delta:         surplus,
+		}); err != nil {
+			slog.Error("Error recording liquidation surplus", "liquidation_id", liq.ID, "error", err)
+		}
+	}
+
 	// Notify handlers
 	for _, handler := range e.handlers {
 		handler(liq)
 	}
 
-	log.Printf("LIQUIDATION: %s %s %s @ %s (leverage: %dx, loss: %s)",
-		pos.TraderID.String()[:8],
-		side,
-		pos.Size.Abs().String(),
-		markPrice.String(),
-		pos.Leverage,
-		loss.String(),
+	slog.Info("Liquidation",
+		"liquidation_id", liq.ID,
+		"trader_id", pos.TraderID,
+		"side", side,
+		"size", pos.Size.Abs(),
+		"price", markPrice,
+		"leverage", pos.Leverage,
+		"loss", loss,
 	)
+
+	if unbacked.IsPositive() {
+		e.autoDeleverage(pos, markPrice, unbacked)
+	}
+}
+
+// autoDeleverage covers a shortfall the insurance fund could not by closing
+// profitable positions on the other side at the mark price, top of the ADL
+// queue first, until their profit pays for it. Each is recorded as an ADL
+// liquidation.
+func (e *Engine) autoDeleverage(liquidated *domain.Position, markPrice, shortfall decimal.Decimal) {
+	remaining := shortfall
+	for _, entry := range e.positionStore.GetADLQueue(liquidated.Instrument) {
+		if !remaining.IsPositive() {
+			break
+		}
+		// Only positions on the other side profit from the same move
+		if (entry.Side == domain.SideBuy) == liquidated.IsLong() {
+			continue
+		}
+
+		size := entry.Size
+		if entry.UnrealizedPnL.GreaterThan(remaining) {
+			size = entry.Size.Mul(remaining).Div(entry.UnrealizedPnL)
+		}
+		pos := e.positionStore.GetPosition(entry.TraderID, liquidated.Instrument)
+		closed, covered, err := e.positionStore.DeleveragePosition(entry.TraderID, liquidated.Instrument, size, markPrice, remaining)
+		if err != nil {
+			slog.Error("Error auto-deleveraging", "trader_id", entry.TraderID, "error", err)
+			continue
+		}
+		remaining = remaining.Sub(covered)
+
+		liq := &domain.Liquidation{
+			ID:         uuid.New(),
+			TraderID:   entry.TraderID,
+			Instrument: liquidated.Instrument,
+			Side:       entry.Side,
+			Size:       closed,
+			EntryPrice: entry.EntryPrice,
+			MarkPrice:  markPrice,
+			Leverage:   entry.Leverage,
+			Loss:       covered.Sub(entry.UnrealizedPnL.Mul(closed).Div(entry.Size)),
+			Timestamp:  time.Now(),
+			Effect:     domain.EffectADL,
+		}
+		if pos != nil {
+			liq.LiquidationPrice = pos.LiquidationPrice
+		}
+		for _, handler := range e.handlers {
+			handler(liq)
+		}
+
+		slog.Info("Auto-deleveraged", "trader_id", entry.TraderID, "side", entry.Side, "size", closed,
+			"price", markPrice, "covered", covered, "liquidated_trader_id", liquidated.TraderID)
+	}
+
+	if remaining.IsPositive() {
+		slog.Warn("Shortfall unbacked after ADL", "trader_id", liquidated.TraderID, "unbacked", remaining)
+	}
 }
 
-// CalculateLiquidationPrice computes the liquidation price for a position
-func CalculateLiquidationPrice(entryPrice decimal.Decimal, leverage int, isLong bool, margins config.MaintenanceMargins) decimal.Decimal {
+// CalculateLiquidationPrice computes the liquidation price for a position,
+// rounded to places decimal places
+func CalculateLiquidationPrice(entryPrice decimal.Decimal, leverage int, isLong bool, margins config.MaintenanceMargins, places int32) decimal.Decimal {
+	leverage = domain.NormalizeLeverage(leverage)
 	maintMargin := margins.GetMarginForLeverage(leverage)
 	leverageDecimal := decimal.NewFromInt(int64(leverage))
 
 	// Liquidation distance = entry / leverage * (1 - maintenance margin)
-	distance := entryPrice.Div(leverageDecimal).Mul(decimal.NewFromInt(1).Sub(maintMargin))
+	distance := entryPrice.DivRound(leverageDecimal, places).Mul(decimal.NewFromInt(1).Sub(maintMargin))
 
 	if isLong {
 		// Long: liquidation price = entry - distance
@@ -218,10 +384,12 @@ func CalculateLiquidationPrice(entryPrice decimal.Decimal, leverage int, isLong
 	}
 }
 
-// CalculateRequiredMargin computes margin needed for a position
-func CalculateRequiredMargin(size, price decimal.Decimal, leverage int) decimal.Decimal {
+// CalculateRequiredMargin computes margin needed for a position, rounded to
+// places decimal places (leverage below 1x is treated as 1x, i.e. fully
+// collateralized)
+func CalculateRequiredMargin(size, price decimal.Decimal, leverage int, places int32) decimal.Decimal {
 	notional := size.Abs().Mul(price)
-	return notional.Div(decimal.NewFromInt(int64(leverage)))
+	return notional.DivRound(decimal.NewFromInt(int64(domain.NormalizeLeverage(leverage))), places)
 }
 
 // ValidateLeverage checks if leverage is within allowed range
@@ -0,0 +1,325 @@
+package liquidation_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/config"
+	"github.com/thatreguy/trade.re/internal/db"
+	"github.com/thatreguy/trade.re/internal/domain"
+	"github.com/thatreguy/trade.re/internal/engine/enginetest"
+	"github.com/thatreguy/trade.re/internal/liquidation"
+)
+
+// order submits an order at the given leverage, failing the test on error
+func order(t *testing.T, h *enginetest.Harness, trader *domain.Trader, side domain.Side, typ domain.OrderType, price, size string, leverage int) {
+	t.Helper()
+	o := &domain.Order{
+		TraderID: trader.ID,
+		Side:     side,
+		Type:     typ,
+		Size:     decimal.RequireFromString(size),
+		Leverage: leverage,
+	}
+	if price != "" {
+		o.Price = decimal.RequireFromString(price)
+	}
+	if _, err := h.Submit(o); err != nil {
+		t.Fatalf("%s %s %s@%s: %v", trader.Username, side, size, price, err)
+	}
+}
+
+// systemEquity is everything the traders, the fund and the exchange hold:
+// balances, position margins and unrealized P&L at the mark, the fund, and
+// kept fees. Liquidation only moves money between them.
+func systemEquity(h *enginetest.Harness, fund *liquidation.Engine, traders ...*domain.Trader) decimal.Decimal {
+	mark := h.Engine.GetMarkPrice(h.Instrument)
+	total := fund.GetInsuranceFund().Add(h.Engine.GetInsuranceFundStatus().FeeRevenue)
+	for _, trader := range traders {
+		total = total.Add(h.Trader(trader).Balance)
+		if pos := h.Position(trader); pos != nil {
+			total = total.Add(pos.Margin).Add(pos.ComputeUnrealizedPnL(mark))
+		}
+	}
+	return total
+}
+
+// liquidate starts the liquidation engine and waits for the trader's position to close
+func liquidate(t *testing.T, h *enginetest.Harness, liq *liquidation.Engine, trader *domain.Trader) {
+	t.Helper()
+	liq.Start()
+	defer liq.Stop()
+	deadline := time.Now().Add(2 * time.Second)
+	for h.Position(trader) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("position was not liquidated")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// setup opens a 10x long for the victim against a 1x short, then prints a
+// trade at price between two other traders to move the mark
+func setup(t *testing.T, cfg *config.Config, price string) (h *enginetest.Harness, liq *liquidation.Engine, victim, winner *domain.Trader, all []*domain.Trader) {
+	t.Helper()
+	return setupWithDB(t, cfg, nil, price)
+}
+
+// setupWithDB is setup persisting to database, if not nil
+func setupWithDB(t *testing.T, cfg *config.Config, database *db.SQLiteDB, price string) (h *enginetest.Harness, liq *liquidation.Engine, victim, winner *domain.Trader, all []*domain.Trader) {
+	t.Helper()
+	cfg.Liquidation.CheckIntervalMs = 5
+	h = enginetest.NewTestEngineWithConfig(cfg)
+	liq = liquidation.NewEngine(cfg.Liquidation, h.Engine, h.Engine)
+	h.Engine.SetInsuranceFund(liq)
+	if database != nil {
+		h.Engine.SetDatabase(database)
+		liq.OnInsuranceFundChange(h.Engine.RecordInsuranceFundEvent)
+	}
+
+	victim = h.AddTrader("victim")
+	winner = h.AddTrader("winner")
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+
+	order(t, h, winner, domain.SideSell, domain.OrderTypeLimit, "1000", "1", 1)
+	order(t, h, victim, domain.SideBuy, domain.OrderTypeMarket, "", "1", 10)
+	order(t, h, maker, domain.SideSell, domain.OrderTypeLimit, price, "0.1", 1)
+	order(t, h, taker, domain.SideBuy, domain.OrderTypeMarket, "", "0.1", 1)
+	return h, liq, victim, winner, []*domain.Trader{victim, winner, maker, taker}
+}
+
+func TestIsolatedShortfallCoveredByFundAndADLConservesEquity(t *testing.T) {
+	cfg := config.Default()
+	cfg.Liquidation.InsuranceFundInitial = decimal.NewFromInt(20)
+	h, liq, victim, winner, all := setup(t, cfg, "850")
+
+	before := systemEquity(h, liq, all...)
+	victimBalance := h.Trader(victim).Balance
+	liquidate(t, h, liq, victim)
+
+	// The 150 loss overruns the 100 margin: the fund pays 20 and ADL the rest
+	if got := h.Trader(victim).Balance; !got.Equal(victimBalance) {
+		t.Fatalf("victim balance = %s, want %s: a covered shortfall was also charged to the trader", got, victimBalance)
+	}
+	if fund := liq.GetInsuranceFund(); !fund.IsZero() {
+		t.Fatalf("insurance fund = %s, want 0", fund)
+	}
+	if pos := h.Position(winner); pos != nil && pos.Size.Abs().GreaterThanOrEqual(decimal.NewFromInt(1)) {
+		t.Fatalf("winner was not deleveraged: %s", pos.Size)
+	}
+	if after := systemEquity(h, liq, all...); !after.Equal(before) {
+		t.Fatalf("system equity = %s after liquidation, want %s", after, before)
+	}
+}
+
+func TestIsolatedSurplusGoesOnlyToFund(t *testing.T) {
+	cfg := config.Default()
+	cfg.Fees.LiquidationRate = decimal.NewFromFloat(0.0001)
+	h, liq, victim, _, all := setup(t, cfg, "900.5")
+
+	before := systemEquity(h, liq, all...)
+	fundBefore := liq.GetInsuranceFund()
+	victimBalance := h.Trader(victim).Balance
+	liquidate(t, h, liq, victim)
+
+	// The 99.5 loss leaves 0.5 of the margin; the fee takes 0.09005 and the fund the rest
+	if got := h.Trader(victim).Balance; !got.Equal(victimBalance) {
+		t.Fatalf("victim balance = %s, want %s: the surplus was returned to the trader", got, victimBalance)
+	}
+	if !liq.GetInsuranceFund().GreaterThan(fundBefore) {
+		t.Fatalf("insurance fund = %s, want more than %s", liq.GetInsuranceFund(), fundBefore)
+	}
+	if after := systemEquity(h, liq, all...); !after.Equal(before) {
+		t.Fatalf("system equity = %s after liquidation, want %s", after, before)
+	}
+}
+
+func TestZeroLeverageIsTreatedAsFullyCollateralized(t *testing.T) {
+	margins := config.Default().Liquidation.MaintenanceMargins
+	entry := decimal.NewFromInt(1000)
+
+	// Leverage 0 (as old rows load) used to divide by zero
+	if got, want := liquidation.CalculateLiquidationPrice(entry, 0, true, margins, 16), liquidation.CalculateLiquidationPrice(entry, 1, true, margins, 16); !got.Equal(want) {
+		t.Fatalf("liquidation price at 0x = %s, want the 1x price %s", got, want)
+	}
+	if got := liquidation.CalculateRequiredMargin(decimal.NewFromInt(2), entry, 0, 16); !got.Equal(decimal.NewFromInt(2000)) {
+		t.Fatalf("required margin at 0x = %s, want the full 2000 notional", got)
+	}
+}
+
+func TestFundHistoryRecordsFeesAndLiquidation(t *testing.T) {
+	database, err := db.NewSQLite(filepath.Join(t.TempDir(), "fund.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	start := time.Now()
+	cfg := config.Default()
+	cfg.Fees.LiquidationRate = decimal.NewFromFloat(0.0001)
+	h, liq, victim, _, _ := setupWithDB(t, cfg, database, "900.5")
+	liquidate(t, h, liq, victim)
+
+	events, err := h.Engine.GetInsuranceFundHistory(start, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Half of each trade's fees, then half the liquidation fee, then the
+	// margin the loss and fee leave behind
+	want := []struct {
+		cause domain.InsuranceFundCause
+		delta string
+	}{
+		{domain.InsuranceFundFee, "0.4"},                    // (0.2 + 0.6) / 2 on 1 at 1000
+		{domain.InsuranceFundFee, "0.03602"},                // (0.01801 + 0.05403) / 2 on 0.1 at 900.5
+		{domain.InsuranceFundFee, "0.045025"},               // 0.09005 / 2
+		{domain.InsuranceFundLiquidationSurplus, "0.40995"}, // 100 - 99.5 - 0.09005
+	}
+	if len(events) != len(want) {
+		t.Fatalf("%d fund events, want %d: %+v", len(events), len(want), events)
+	}
+	balance := cfg.Liquidation.InsuranceFundInitial
+	for i, w := range want {
+		event := events[i]
+		balance = balance.Add(event.Delta)
+		if event.Cause != w.cause || !event.Delta.Equal(decimal.RequireFromString(w.delta)) {
+			t.Errorf("event %d = %s %s, want %s %s", i, event.Cause, event.Delta, w.cause, w.delta)
+		}
+		if !event.Balance.Equal(balance) {
+			t.Errorf("event %d balance = %s, want %s", i, event.Balance, balance)
+		}
+	}
+	if events[0].TradeID == nil || events[1].TradeID == nil {
+		t.Error("trade fee events do not name their trade")
+	}
+	if events[3].LiquidationID == nil {
+		t.Error("surplus event does not name its liquidation")
+	}
+	if !balance.Equal(liq.GetInsuranceFund()) {
+		t.Errorf("history ends at %s, fund holds %s", balance, liq.GetInsuranceFund())
+	}
+}
+
+func TestFeesDivertToFundUntilTarget(t *testing.T) {
+	cfg := config.Default()
+	cfg.Fees.InsuranceFundShare = decimal.NewFromFloat(0.5)
+	cfg.Fees.InsuranceFundTarget = cfg.Liquidation.InsuranceFundInitial.Add(decimal.NewFromFloat(0.6))
+	h := enginetest.NewTestEngineWithConfig(cfg)
+	liq := liquidation.NewEngine(cfg.Liquidation, h.Engine, h.Engine)
+	h.Engine.SetInsuranceFund(liq)
+	maker := h.AddTrader("maker")
+	taker := h.AddTrader("taker")
+
+	if !h.Engine.GetInsuranceFundStatus().Diverting {
+		t.Fatal("fund below its target is not diverting fees")
+	}
+
+	// Each trade of 1 at 1000 collects 0.2 from the maker and 0.6 from the
+	// taker, half of which is diverted while there is room under the target
+	for i, w := range []struct{ fund, revenue string }{
+		{"0.4", "0.4"}, // Half of 0.8
+		{"0.6", "1"},   // Only 0.2 of room left
+		{"0.6", "1.8"}, // At the target, everything is revenue
+	} {
+		order(t, h, maker, domain.SideSell, domain.OrderTypeLimit, "1000", "1", 1)
+		order(t, h, taker, domain.SideBuy, domain.OrderTypeMarket, "", "1", 1)
+
+		status := h.Engine.GetInsuranceFundStatus()
+		if got := liq.GetInsuranceFund().Sub(cfg.Liquidation.InsuranceFundInitial); !got.Equal(decimal.RequireFromString(w.fund)) {
+			t.Errorf("trade %d: fund up %s, want %s", i+1, got, w.fund)
+		}
+		if !status.FeeRevenue.Equal(decimal.RequireFromString(w.revenue)) {
+			t.Errorf("trade %d: fee revenue = %s, want %s", i+1, status.FeeRevenue, w.revenue)
+		}
+	}
+
+	status := h.Engine.GetInsuranceFundStatus()
+	if status.Diverting || !status.Balance.Equal(cfg.Fees.InsuranceFundTarget) || !status.Target.Equal(cfg.Fees.InsuranceFundTarget) {
+		t.Fatalf("status = %+v, want stopped at the target", status)
+	}
+}
+
+func TestLiquidationCancelsRestingOrders(t *testing.T) {
+	for _, cancel := range []bool{true, false} {
+		t.Run(fmt.Sprintf("cancel=%v", cancel), func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Liquidation.CancelOrdersOnLiquidation = cancel
+			h, liq, victim, _, _ := setup(t, cfg, "850")
+
+			var mu sync.Mutex
+			cancelled := make(map[uuid.UUID]bool)
+			h.Engine.OnOrderUpdate(func(o *domain.Order) {
+				if o.Status == domain.OrderStatusCancelled {
+					mu.Lock()
+					cancelled[o.ID] = true
+					mu.Unlock()
+				}
+			})
+
+			// A bid that would re-lever the victim, and a protective stop
+			order(t, h, victim, domain.SideBuy, domain.OrderTypeLimit, "800", "0.1", 10)
+			stop := &domain.Order{TraderID: victim.ID, Side: domain.SideSell, Type: domain.OrderTypeStop,
+				StopPrice: decimal.NewFromInt(700), Size: decimal.NewFromInt(1), Leverage: 10}
+			if _, err := h.Submit(stop); err != nil {
+				t.Fatal(err)
+			}
+			open := h.Engine.GetOpenOrders(victim.ID, h.Instrument)
+			if len(open) != 2 {
+				t.Fatalf("%d open orders before liquidation, want 2", len(open))
+			}
+			liquidate(t, h, liq, victim)
+
+			left := h.Engine.GetOpenOrders(victim.ID, h.Instrument)
+			mu.Lock()
+			defer mu.Unlock()
+			if !cancel {
+				if len(left) != 2 || len(cancelled) != 0 {
+					t.Fatalf("%d orders left and %d cancelled with the policy off, want all kept", len(left), len(cancelled))
+				}
+				return
+			}
+			if len(left) != 0 {
+				t.Fatalf("%d orders left after liquidation, want none", len(left))
+			}
+			for _, o := range open {
+				if !cancelled[o.ID] {
+					t.Errorf("no cancellation event for %s %s", o.Type, o.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestOneLotWickDoesNotLiquidateAtAverageMark(t *testing.T) {
+	cfg := config.Default()
+	cfg.Engine.MarkPriceMethod = "average"
+	h, liq, victim, _, _ := setup(t, cfg, "850")
+
+	// The wick pulls the last trade under the 10x long's liquidation price,
+	// but the average of the open at 1000 and the wick stays above it
+	liqPrice := liquidation.CalculateLiquidationPrice(decimal.NewFromInt(1000), 10, true, cfg.Liquidation.MaintenanceMargins, 16)
+	mark := h.Engine.GetMarkPrice(h.Instrument)
+	if !mark.Equal(decimal.NewFromInt(925)) || !mark.GreaterThan(liqPrice) {
+		t.Fatalf("mark = %s, want the 925 average above the %s liquidation price", mark, liqPrice)
+	}
+	liq.Start()
+	time.Sleep(20 * time.Duration(cfg.Liquidation.CheckIntervalMs) * time.Millisecond)
+	liq.Stop()
+	if h.Position(victim) == nil {
+		t.Fatal("victim liquidated by a single wick at the average mark")
+	}
+
+	// The same wick at the last-trade mark does liquidate
+	h, liq, victim, _, _ = setup(t, config.Default(), "850")
+	if mark := h.Engine.GetMarkPrice(h.Instrument); !mark.Equal(decimal.NewFromInt(850)) {
+		t.Fatalf("last-trade mark = %s, want the 850 wick", mark)
+	}
+	liquidate(t, h, liq, victim)
+}
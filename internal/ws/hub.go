@@ -2,8 +2,10 @@ package ws
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -20,14 +22,22 @@ const (
 type MessageType string
 
 const (
-	TypeTrade        MessageType = "trade"
-	TypeOrderBook    MessageType = "orderbook"
-	TypePosition     MessageType = "position"
-	TypeOrder        MessageType = "order"
-	TypeOI           MessageType = "oi"
-	TypeLiquidation  MessageType = "liquidation"
-	TypeSubscribe    MessageType = "subscribe"
-	TypeUnsubscribe  MessageType = "unsubscribe"
+	TypeTrade         MessageType = "trade"
+	TypeOrderBook     MessageType = "orderbook"
+	TypeBookDelta     MessageType = "orderbook_delta"
+	TypePosition      MessageType = "position"
+	TypeOrder         MessageType = "order"
+	TypeOI            MessageType = "oi"
+	TypeLiquidation   MessageType = "liquidation"
+	TypeMarketState   MessageType = "market_state"
+	TypeFundingFreeze MessageType = "funding_freeze"
+	TypeWelcome       MessageType = "welcome"
+	TypeHeartbeat     MessageType = "heartbeat"
+	TypeMMPTriggered  MessageType = "mmp_triggered"
+	TypeSubscribe     MessageType = "subscribe"
+	TypeUnsubscribe   MessageType = "unsubscribe"
+	TypeAuth          MessageType = "auth"
+	TypeError         MessageType = "error"
 )
 
 // Message is the WebSocket message envelope
@@ -36,6 +46,16 @@ type Message struct {
 	Channel    string      `json:"channel,omitempty"`
 	Data       interface{} `json:"data"`
 	Timestamp  int64       `json:"timestamp"`
+	Seq        uint64      `json:"seq,omitempty"` // Set on messages broadcast to all clients
+}
+
+// HeartbeatChannel is subscribed by default; unsubscribe from it to opt out
+const HeartbeatChannel = "heartbeat"
+
+// Heartbeat is the payload of a server heartbeat
+type Heartbeat struct {
+	ServerTime int64  `json:"server_time"` // Unix milliseconds
+	Seq        uint64 `json:"seq"`         // Seq of the last broadcast message
 }
 
 // Client represents a WebSocket connection
@@ -43,29 +63,141 @@ type Client struct {
 	hub           *Hub
 	conn          *websocket.Conn
 	send          chan []byte
+	encoding      Encoding // Fixed at connect; JSON unless msgpack was requested
 	subscriptions map[string]bool
+	traderID      string // Set once authenticated; empty for anonymous clients
 	mu            sync.RWMutex
+	slow          atomic.Bool // Set once the send buffer overflowed and the client is being dropped
+	closed        bool        // send has been closed; guarded by hub.mu
 }
 
 // Hub manages all WebSocket clients and broadcasts
 type Hub struct {
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	broadcast  chan *payload
 	register   chan *Client
 	unregister chan *Client
 	mu         sync.RWMutex
+	closed     bool // Set by Close; clients connecting afterwards are turned away
+
+	seq               atomic.Uint64 // Last sequence number assigned by Broadcast
+	seqMu             sync.Mutex    // Keeps broadcasts enqueued in sequence order
+	heartbeatInterval time.Duration // Zero disables heartbeats
+
+	fillDelay time.Duration  // Simulated latency on fill broadcasts; zero disables
+	delayed   chan delayedFn // FIFO of delayed fill broadcasts
+
+	bookSnapshot func(instrument string) (interface{}, error) // Sent on orderbook subscribe; nil disables
+	authenticate func(token string) (string, error)           // Resolves a token to a trader ID; nil disables auth
+}
+
+// delayedFn is a broadcast held back until its due time
+type delayedFn struct {
+	due time.Time
+	fn  func()
 }
 
 // NewHub creates a new WebSocket hub
 func NewHub() *Hub {
 	return &Hub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte, 256),
+		broadcast:  make(chan *payload, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 	}
 }
 
+// SetHeartbeatInterval enables application-level heartbeats. Must be called before Run.
+func (h *Hub) SetHeartbeatInterval(d time.Duration) {
+	h.heartbeatInterval = d
+}
+
+// SetFillBroadcastDelay holds back fill broadcasts by d to simulate feed
+// latency for client testing. Fills keep their relative order. Must be called
+// before Run.
+func (h *Hub) SetFillBroadcastDelay(d time.Duration) {
+	h.fillDelay = d
+	if d > 0 {
+		h.delayed = make(chan delayedFn, 1024)
+	}
+}
+
+// afterFillDelay runs fn once the simulated fill latency has passed, or
+// immediately when it is disabled
+func (h *Hub) afterFillDelay(fn func()) {
+	if h.fillDelay <= 0 {
+		fn()
+		return
+	}
+	h.delayed <- delayedFn{due: time.Now().Add(h.fillDelay), fn: fn}
+}
+
+// runDelayed delivers delayed broadcasts in the order they were queued
+func (h *Hub) runDelayed() {
+	for d := range h.delayed {
+		time.Sleep(time.Until(d.due))
+		d.fn()
+	}
+}
+
+// SetOrderBookSnapshot sets how to build the snapshot a client receives when
+// it subscribes to an orderbook channel. Deltas that follow continue from the
+// snapshot's seq. Must be called before clients connect.
+func (h *Hub) SetOrderBookSnapshot(snapshot func(instrument string) (interface{}, error)) {
+	h.bookSnapshot = snapshot
+}
+
+// SetAuthenticator sets how a token sent in an auth message is resolved to a
+// trader ID. Must be called before clients connect.
+func (h *Hub) SetAuthenticator(authenticate func(token string) (string, error)) {
+	h.authenticate = authenticate
+}
+
+// HeartbeatInterval returns the configured heartbeat interval (zero if disabled)
+func (h *Hub) HeartbeatInterval() time.Duration {
+	return h.heartbeatInterval
+}
+
+// Seq returns the sequence number of the last message broadcast to all clients
+func (h *Hub) Seq() uint64 {
+	return h.seq.Load()
+}
+
+// payload is a message to send, encoded once per encoding on first use so a
+// broadcast costs one encoding however many clients receive it. Not safe for
+// concurrent use; each payload is encoded from a single goroutine.
+type payload struct {
+	msg     Message
+	json    []byte
+	msgpack []byte
+}
+
+// newPayload encodes msg as JSON up front, so marshaling errors surface at
+// the call site
+func newPayload(msg Message) (*payload, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return &payload{msg: msg, json: data}, nil
+}
+
+// encode returns the message in the given encoding
+func (p *payload) encode(encoding Encoding) ([]byte, error) {
+	if encoding != EncodingMsgpack {
+		return p.json, nil
+	}
+	if p.msgpack == nil {
+		data, err := encodeMsgpack(p.msg)
+		if err != nil {
+			slog.Error("Error encoding message as msgpack", "type", p.msg.Type, "error", err)
+			return nil, err
+		}
+		p.msgpack = data
+	}
+	return p.msgpack, nil
+}
+
 // Register adds a client to the hub
 func (h *Hub) Register(client *Client) {
 	h.register <- client
@@ -73,34 +205,134 @@ func (h *Hub) Register(client *Client) {
 
 // Run starts the hub's main loop
 func (h *Hub) Run() {
+	var heartbeat <-chan time.Time
+	if h.heartbeatInterval > 0 {
+		ticker := time.NewTicker(h.heartbeatInterval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	if h.delayed != nil {
+		go h.runDelayed()
+	}
+
 	for {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
+			if h.closed {
+				client.closeSend()
+				h.mu.Unlock()
+				continue
+			}
 			h.clients[client] = true
 			h.mu.Unlock()
-			log.Printf("Client connected. Total: %d", len(h.clients))
+			slog.Debug("Client connected", "clients", len(h.clients))
 
 		case client := <-h.unregister:
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
-				close(client.send)
+				client.closeSend()
 			}
 			h.mu.Unlock()
-			log.Printf("Client disconnected. Total: %d", len(h.clients))
+			slog.Debug("Client disconnected", "clients", len(h.clients))
 
 		case message := <-h.broadcast:
 			h.mu.RLock()
 			for client := range h.clients {
+				data, err := message.encode(client.encoding)
+				if err != nil {
+					continue
+				}
 				select {
-				case client.send <- message:
+				case client.send <- data:
 				default:
-					close(client.send)
-					delete(h.clients, client)
+					h.dropSlow(client)
 				}
 			}
 			h.mu.RUnlock()
+
+		case now := <-heartbeat:
+			h.sendHeartbeat(now)
+		}
+	}
+}
+
+// Close disconnects every client with a going-away close frame and turns
+// away any that connect afterwards. It is safe to call more than once.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for client := range h.clients {
+		delete(h.clients, client)
+		client.closeSend()
+	}
+	slog.Info("WebSocket hub closed")
+}
+
+// dropSlow disconnects a client whose send buffer is full rather than let it
+// silently miss messages. It is unregistered through the hub loop like any
+// other disconnect, from its own goroutine since callers may be holding h.mu
+// or be the loop itself. Safe to call repeatedly; only the first call counts.
+func (h *Hub) dropSlow(client *Client) {
+	if !client.slow.CompareAndSwap(false, true) {
+		return
+	}
+	if traderID := client.TraderID(); traderID != "" {
+		slog.Warn("Dropping slow WebSocket client: send buffer full", "trader_id", traderID)
+	} else {
+		slog.Warn("Dropping slow WebSocket client: send buffer full")
+	}
+	go func() { h.unregister <- client }()
+}
+
+// isClosed reports whether Close has been called
+func (h *Hub) isClosed() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.closed
+}
+
+// sendHeartbeat tells subscribed clients the server is alive and how far the
+// broadcast sequence has advanced, so they can detect gaps in quiet periods
+func (h *Hub) sendHeartbeat(now time.Time) {
+	msg := Message{
+		Type:    TypeHeartbeat,
+		Channel: HeartbeatChannel,
+		Data: Heartbeat{
+			ServerTime: now.UnixMilli(),
+			Seq:        h.seq.Load(),
+		},
+		Timestamp: now.UnixMilli(),
+	}
+	p, err := newPayload(msg)
+	if err != nil {
+		slog.Error("Error marshaling heartbeat", "error", err)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.clients {
+		client.mu.RLock()
+		subscribed := client.subscriptions[HeartbeatChannel]
+		client.mu.RUnlock()
+
+		if subscribed {
+			data, err := p.encode(client.encoding)
+			if err != nil {
+				continue
+			}
+			select {
+			case client.send <- data:
+			default:
+				h.dropSlow(client)
+			}
 		}
 	}
 }
@@ -108,9 +340,9 @@ func (h *Hub) Run() {
 // BroadcastToChannel sends a message to clients subscribed to a channel
 func (h *Hub) BroadcastToChannel(channel string, msg Message) {
 	msg.Timestamp = time.Now().UnixMilli()
-	data, err := json.Marshal(msg)
+	p, err := newPayload(msg)
 	if err != nil {
-		log.Printf("Error marshaling message: %v", err)
+		slog.Error("Error marshaling message", "error", err)
 		return
 	}
 
@@ -123,43 +355,115 @@ func (h *Hub) BroadcastToChannel(channel string, msg Message) {
 		client.mu.RUnlock()
 
 		if subscribed {
+			data, err := p.encode(client.encoding)
+			if err != nil {
+				continue
+			}
 			select {
 			case client.send <- data:
 			default:
-				// Client buffer full, skip
+				h.dropSlow(client)
 			}
 		}
 	}
 }
 
-// Broadcast sends a message to all clients
+// Broadcast sends a message to all clients, stamping it with the next sequence number
 func (h *Hub) Broadcast(msg Message) {
+	h.seqMu.Lock()
+	defer h.seqMu.Unlock()
+
 	msg.Timestamp = time.Now().UnixMilli()
-	data, err := json.Marshal(msg)
+	msg.Seq = h.seq.Load() + 1
+	p, err := newPayload(msg)
 	if err != nil {
-		log.Printf("Error marshaling message: %v", err)
+		slog.Error("Error marshaling message", "error", err)
 		return
 	}
-	h.broadcast <- data
+	h.seq.Store(msg.Seq)
+	h.broadcast <- p
 }
 
 // BroadcastTrade sends a trade to all clients (trades are always public)
 func (h *Hub) BroadcastTrade(trade interface{}) {
-	h.Broadcast(Message{
-		Type: TypeTrade,
-		Data: trade,
+	h.afterFillDelay(func() {
+		h.Broadcast(Message{
+			Type: TypeTrade,
+			Data: trade,
+		})
 	})
 }
 
 // BroadcastOrderBook sends order book update
 func (h *Hub) BroadcastOrderBook(instrument string, book interface{}) {
-	h.BroadcastToChannel("orderbook:"+instrument, Message{
+	channel := OrderBookChannel(instrument)
+	h.BroadcastToChannel(channel, Message{
 		Type:    TypeOrderBook,
-		Channel: "orderbook:" + instrument,
+		Channel: channel,
 		Data:    book,
 	})
 }
 
+// BroadcastOrderBookDelta sends a price level change to the instrument's
+// orderbook channel
+func (h *Hub) BroadcastOrderBookDelta(instrument string, delta interface{}) {
+	channel := OrderBookChannel(instrument)
+	h.BroadcastToChannel(channel, Message{
+		Type:    TypeBookDelta,
+		Channel: channel,
+		Data:    delta,
+	})
+}
+
+// OrderBookChannel is the channel carrying an instrument's order book
+func OrderBookChannel(instrument string) string {
+	return "orderbook:" + instrument
+}
+
+// RunOrderBookSnapshots broadcasts a full order book snapshot on the
+// instrument's orderbook channel every interval and whenever refresh fires
+// (e.g. after a trade), so clients can simply keep the latest one. Ticks with
+// no subscribers skip building the snapshot. Blocks until stop is closed.
+func (h *Hub) RunOrderBookSnapshots(instrument string, interval time.Duration, snapshot func() (interface{}, error), refresh <-chan struct{}, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	channel := OrderBookChannel(instrument)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-refresh:
+		}
+		if !h.hasSubscribers(channel) {
+			continue
+		}
+		book, err := snapshot()
+		if err != nil {
+			slog.Error("Error building order book snapshot", "error", err)
+			continue
+		}
+		h.BroadcastOrderBook(instrument, book)
+	}
+}
+
+// hasSubscribers reports whether any client is subscribed to a channel
+func (h *Hub) hasSubscribers(channel string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.clients {
+		client.mu.RLock()
+		subscribed := client.subscriptions[channel]
+		client.mu.RUnlock()
+		if subscribed {
+			return true
+		}
+	}
+	return false
+}
+
 // BroadcastPosition sends position update (positions are public)
 func (h *Hub) BroadcastPosition(position interface{}) {
 	h.Broadcast(Message{
@@ -168,16 +472,199 @@ func (h *Hub) BroadcastPosition(position interface{}) {
 	})
 }
 
-// NewClient creates a new client
+// BroadcastMarketState sends a market phase transition to all clients
+func (h *Hub) BroadcastMarketState(change interface{}) {
+	h.Broadcast(Message{
+		Type: TypeMarketState,
+		Data: change,
+	})
+}
+
+// BroadcastFundingFreeze announces the freeze before the next funding
+// settlement to all clients
+func (h *Hub) BroadcastFundingFreeze(freeze interface{}) {
+	h.Broadcast(Message{
+		Type: TypeFundingFreeze,
+		Data: freeze,
+	})
+}
+
+// PrivateChannel is the channel for events addressed to a single trader.
+// Only that trader, once authenticated, may subscribe.
+func PrivateChannel(traderID string) string {
+	return "private:" + traderID
+}
+
+// SendToTrader sends an event on a trader's private channel
+func (h *Hub) SendToTrader(traderID string, msgType MessageType, data interface{}) {
+	channel := PrivateChannel(traderID)
+	h.BroadcastToChannel(channel, Message{
+		Type:    msgType,
+		Channel: channel,
+		Data:    data,
+	})
+}
+
+// FillsChannel is the private channel carrying a trader's own fills, order
+// updates and liquidations. Only that trader, once authenticated, may
+// subscribe.
+func FillsChannel(traderID string) string {
+	return "fills:" + traderID
+}
+
+// SendFill sends an event on a trader's fills channel. Fills are held back
+// with the public feed when fill latency is simulated.
+func (h *Hub) SendFill(traderID string, msgType MessageType, data interface{}) {
+	channel := FillsChannel(traderID)
+	send := func() {
+		h.BroadcastToChannel(channel, Message{
+			Type:    msgType,
+			Channel: channel,
+			Data:    data,
+		})
+	}
+	if msgType == TypeTrade {
+		h.afterFillDelay(send)
+		return
+	}
+	send()
+}
+
+// channelOwner returns the trader a private channel belongs to, or false for
+// a public channel
+func channelOwner(channel string) (string, bool) {
+	for _, prefix := range []string{PrivateChannel(""), FillsChannel("")} {
+		if owner, ok := strings.CutPrefix(channel, prefix); ok {
+			return owner, true
+		}
+	}
+	return "", false
+}
+
+// TraderChannel is the public channel following one trader's fills,
+// position changes and liquidations. Anyone may subscribe.
+func TraderChannel(traderID string) string {
+	return "trader:" + traderID
+}
+
+// BroadcastTraderActivity sends an event on a trader's public activity channel
+func (h *Hub) BroadcastTraderActivity(traderID string, msgType MessageType, data interface{}) {
+	channel := TraderChannel(traderID)
+	send := func() {
+		h.BroadcastToChannel(channel, Message{
+			Type:    msgType,
+			Channel: channel,
+			Data:    data,
+		})
+	}
+	if msgType == TypeTrade {
+		h.afterFillDelay(send)
+		return
+	}
+	send()
+}
+
+// NewClient creates a new client receiving JSON text frames
 func NewClient(hub *Hub, conn *websocket.Conn) *Client {
+	return NewClientWithEncoding(hub, conn, EncodingJSON)
+}
+
+// NewClientWithEncoding creates a new client receiving messages in the given
+// encoding. Msgpack clients get binary frames; their own control messages
+// (subscribe, unsubscribe) are still read as JSON.
+func NewClientWithEncoding(hub *Hub, conn *websocket.Conn, encoding Encoding) *Client {
 	return &Client{
 		hub:           hub,
 		conn:          conn,
 		send:          make(chan []byte, 256),
-		subscriptions: make(map[string]bool),
+		encoding:      encoding,
+		subscriptions: map[string]bool{HeartbeatChannel: true},
 	}
 }
 
+// closeSend closes the client's send buffer, ending its WritePump. Caller
+// must hold hub.mu for writing.
+func (c *Client) closeSend() {
+	c.closed = true
+	close(c.send)
+}
+
+// Send queues a message for this client only. It is a no-op once the client
+// has been disconnected, since its ReadPump may still be answering requests
+// after the hub closed its send buffer.
+func (c *Client) Send(msg Message) {
+	msg.Timestamp = time.Now().UnixMilli()
+	p, err := newPayload(msg)
+	if err != nil {
+		slog.Error("Error marshaling message", "error", err)
+		return
+	}
+	data, err := p.encode(c.encoding)
+	if err != nil {
+		slog.Error("Error marshaling message", "error", err)
+		return
+	}
+
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+		c.hub.dropSlow(c)
+	}
+}
+
+// TraderID returns the trader the client authenticated as, or "" if it has not
+func (c *Client) TraderID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.traderID
+}
+
+// SetTraderID marks the client as authenticated as a trader, dropping any
+// private subscriptions that belonged to a previous one
+func (c *Client) SetTraderID(traderID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.traderID = traderID
+	for channel := range c.subscriptions {
+		if owner, private := channelOwner(channel); private && owner != traderID {
+			delete(c.subscriptions, channel)
+		}
+	}
+}
+
+// authenticate handles an auth message, replying with the trader ID on
+// success or an error
+func (c *Client) authenticate(token string) {
+	if c.hub.authenticate == nil {
+		c.sendError("", "authentication is not available")
+		return
+	}
+	traderID, err := c.hub.authenticate(token)
+	if err != nil {
+		c.sendError("", "invalid token")
+		return
+	}
+	c.SetTraderID(traderID)
+	c.Send(Message{
+		Type: TypeAuth,
+		Data: map[string]string{"trader_id": traderID},
+	})
+}
+
+// sendError tells the client a request it made was refused
+func (c *Client) sendError(channel, reason string) {
+	c.Send(Message{
+		Type:    TypeError,
+		Channel: channel,
+		Data:    reason,
+	})
+}
+
 // Subscribe adds a channel subscription
 func (c *Client) Subscribe(channel string) {
 	c.mu.Lock()
@@ -185,6 +672,32 @@ func (c *Client) Subscribe(channel string) {
 	c.mu.Unlock()
 }
 
+// subscribe adds a channel subscription requested by the client. Private
+// channels are refused unless the client authenticated as their owner. An
+// orderbook channel starts with a full snapshot; deltas count on from its seq.
+func (c *Client) subscribe(channel string) {
+	if owner, private := channelOwner(channel); private && owner != c.TraderID() {
+		c.sendError(channel, "not authorized for this channel")
+		return
+	}
+	c.Subscribe(channel)
+
+	instrument, ok := strings.CutPrefix(channel, OrderBookChannel(""))
+	if !ok || c.hub.bookSnapshot == nil {
+		return
+	}
+	book, err := c.hub.bookSnapshot(instrument)
+	if err != nil {
+		slog.Error("Error building order book snapshot", "error", err)
+		return
+	}
+	c.Send(Message{
+		Type:    TypeOrderBook,
+		Channel: channel,
+		Data:    book,
+	})
+}
+
 // Unsubscribe removes a channel subscription
 func (c *Client) Unsubscribe(channel string) {
 	c.mu.Lock()
@@ -210,7 +723,7 @@ func (c *Client) ReadPump() {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error: %v", err)
+				slog.Warn("WebSocket error", "error", err)
 			}
 			break
 		}
@@ -223,13 +736,31 @@ func (c *Client) ReadPump() {
 
 		switch msg.Type {
 		case TypeSubscribe:
-			if channel, ok := msg.Data.(string); ok {
-				c.Subscribe(channel)
+			switch data := msg.Data.(type) {
+			case string:
+				c.subscribe(data)
+			case map[string]interface{}:
+				// {"channel": "...", "heartbeat": false}
+				if channel, ok := data["channel"].(string); ok && channel != "" {
+					c.subscribe(channel)
+				}
+				if heartbeat, ok := data["heartbeat"].(bool); ok {
+					if heartbeat {
+						c.Subscribe(HeartbeatChannel)
+					} else {
+						c.Unsubscribe(HeartbeatChannel)
+					}
+				}
 			}
 		case TypeUnsubscribe:
 			if channel, ok := msg.Data.(string); ok {
 				c.Unsubscribe(channel)
 			}
+		case TypeAuth:
+			// {"type": "auth", "data": "<token or API key>"}
+			if token, ok := msg.Data.(string); ok {
+				c.authenticate(token)
+			}
 		}
 	}
 }
@@ -247,20 +778,33 @@ func (c *Client) WritePump() {
 		case message, ok := <-c.send:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				closing := []byte{}
+				if c.hub.isClosed() {
+					closing = websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+				} else if c.slow.Load() {
+					closing = websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "client too slow")
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, closing)
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
+			frameType := websocket.TextMessage
+			if c.encoding == EncodingMsgpack {
+				frameType = websocket.BinaryMessage
+			}
+			w, err := c.conn.NextWriter(frameType)
 			if err != nil {
 				return
 			}
 			w.Write(message)
 
-			// Batch pending messages
+			// Batch pending messages. JSON messages are newline separated;
+			// msgpack values are self-delimiting, so they are written back to back.
 			n := len(c.send)
 			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
+				if frameType == websocket.TextMessage {
+					w.Write([]byte{'\n'})
+				}
 				w.Write(<-c.send)
 			}
 
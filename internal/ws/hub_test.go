@@ -0,0 +1,268 @@
+package ws
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient registers a client with no connection; tests read what the
+// hub queues for it straight off its send buffer
+func newTestClient(t *testing.T, hub *Hub, encoding Encoding) *Client {
+	t.Helper()
+	client := NewClientWithEncoding(hub, nil, encoding)
+	hub.Register(client)
+	return client
+}
+
+// recv decodes the next JSON message queued for the client
+func recv(t *testing.T, client *Client) Message {
+	t.Helper()
+	select {
+	case data, ok := <-client.send:
+		if !ok {
+			t.Fatal("client was disconnected")
+		}
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message within 5s")
+	}
+	return Message{}
+}
+
+func TestHeartbeatsArriveAtConfiguredCadence(t *testing.T) {
+	const interval = 40 * time.Millisecond
+	hub := NewHub()
+	hub.SetHeartbeatInterval(interval)
+	go hub.Run()
+
+	client := newTestClient(t, hub, EncodingJSON)
+	optedOut := newTestClient(t, hub, EncodingJSON)
+	optedOut.Unsubscribe(HeartbeatChannel)
+
+	var times []int64
+	for len(times) < 5 {
+		msg := recv(t, client)
+		if msg.Type != TypeHeartbeat || msg.Channel != HeartbeatChannel {
+			t.Fatalf("got %s on %q, want a heartbeat", msg.Type, msg.Channel)
+		}
+		beat := msg.Data.(map[string]interface{})
+		if seq := beat["seq"].(float64); seq != 0 {
+			t.Fatalf("heartbeat seq = %v before any broadcast, want 0", seq)
+		}
+		times = append(times, int64(beat["server_time"].(float64)))
+	}
+	// Four intervals apart, give or take scheduling
+	if span := time.Duration(times[4]-times[0]) * time.Millisecond; span < 3*interval || span > 10*interval {
+		t.Fatalf("5 heartbeats spanned %s, want about %s", span, 4*interval)
+	}
+
+	// Heartbeats carry the last broadcast seq so quiet clients can spot gaps
+	hub.Broadcast(Message{Type: TypeTrade})
+	if msg := recv(t, client); msg.Type != TypeTrade || msg.Seq != 1 {
+		t.Fatalf("got %s seq %d, want the trade as seq 1", msg.Type, msg.Seq)
+	}
+	if beat := recv(t, client).Data.(map[string]interface{}); beat["seq"].(float64) != 1 {
+		t.Fatalf("heartbeat seq = %v after one broadcast, want 1", beat["seq"])
+	}
+
+	if msg := recv(t, optedOut); msg.Type != TypeTrade {
+		t.Fatalf("opted-out client got %s, want only the trade", msg.Type)
+	}
+	select {
+	case data := <-optedOut.send:
+		t.Fatalf("opted-out client got %s", data)
+	default:
+	}
+}
+
+func TestTraderChannelCarriesOnlyThatTrader(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	// Anonymous clients may follow anyone
+	spectator := newTestClient(t, hub, EncodingJSON)
+	spectator.subscribe(TraderChannel("alice"))
+
+	hub.BroadcastTraderActivity("bob", TypeTrade, map[string]string{"id": "bob-trade"})
+	hub.BroadcastTraderActivity("alice", TypeTrade, map[string]string{"id": "alice-trade"})
+	hub.BroadcastTraderActivity("alice", TypePosition, map[string]string{"trader_id": "alice"})
+
+	msg := recv(t, spectator)
+	if msg.Type != TypeTrade || msg.Channel != TraderChannel("alice") || msg.Data.(map[string]interface{})["id"] != "alice-trade" {
+		t.Fatalf("got %s on %q: %v, want alice's trade", msg.Type, msg.Channel, msg.Data)
+	}
+	if msg := recv(t, spectator); msg.Type != TypePosition {
+		t.Fatalf("got %s, want alice's position", msg.Type)
+	}
+	select {
+	case data := <-spectator.send:
+		t.Fatalf("unexpected message %s", data)
+	default:
+	}
+}
+
+func TestFillBroadcastDelay(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	hub := NewHub()
+	hub.SetFillBroadcastDelay(delay)
+	go hub.Run()
+	client := newTestClient(t, hub, EncodingJSON)
+
+	started := time.Now()
+	hub.BroadcastTrade(map[string]string{"id": "first"})
+	hub.BroadcastTrade(map[string]string{"id": "second"})
+	for _, want := range []string{"first", "second"} {
+		msg := recv(t, client)
+		if got := msg.Data.(map[string]interface{})["id"]; got != want {
+			t.Fatalf("got trade %v, want %s: delayed fills must keep their order", got, want)
+		}
+	}
+	if elapsed := time.Since(started); elapsed < delay {
+		t.Fatalf("fills arrived after %s, want at least %s", elapsed, delay)
+	}
+}
+
+func TestOrderBookSnapshotsArriveAtCadenceAlongsideDeltas(t *testing.T) {
+	const interval = 40 * time.Millisecond
+	hub := NewHub()
+	go hub.Run()
+
+	var builds atomic.Int64
+	snapshot := func() (interface{}, error) {
+		return map[string]int64{"build": builds.Add(1)}, nil
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go hub.RunOrderBookSnapshots("R.index", interval, snapshot, nil, stop)
+
+	// Nobody is subscribed, so no snapshot is built
+	client := newTestClient(t, hub, EncodingJSON)
+	time.Sleep(3 * interval)
+	if n := builds.Load(); n != 0 {
+		t.Fatalf("%d snapshots built with no subscribers", n)
+	}
+
+	client.Subscribe(OrderBookChannel("R.index"))
+	var snapshots []time.Time
+	deltas := 0
+	for len(snapshots) < 5 {
+		if len(snapshots) == 2 && deltas == 0 {
+			hub.BroadcastOrderBookDelta("R.index", map[string]string{"price": "1000"})
+		}
+		msg := recv(t, client)
+		if msg.Channel != OrderBookChannel("R.index") {
+			t.Fatalf("got %s on %q, want the orderbook channel", msg.Type, msg.Channel)
+		}
+		switch msg.Type {
+		case TypeOrderBook:
+			snapshots = append(snapshots, time.Now())
+		case TypeBookDelta:
+			deltas++
+		default:
+			t.Fatalf("got %s, want a snapshot or delta", msg.Type)
+		}
+	}
+	if deltas != 1 {
+		t.Fatalf("%d deltas between the snapshots, want 1", deltas)
+	}
+	// Four intervals apart, give or take scheduling
+	if span := snapshots[4].Sub(snapshots[0]); span < 3*interval || span > 10*interval {
+		t.Fatalf("5 snapshots spanned %s, want about %s", span, 4*interval)
+	}
+}
+
+func TestStalledClientIsDisconnectedWhenFlooded(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	stalled := newTestClient(t, hub, EncodingJSON)
+	healthy := newTestClient(t, hub, EncodingJSON)
+	stalled.subscribe(TraderChannel("alice"))
+
+	// Flood the stalled client from several goroutines at once, through both
+	// the hub loop and channel broadcasts, while the healthy one gets few
+	// enough to fit its buffer
+	const broadcasts = 100
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				hub.BroadcastToChannel(TraderChannel("alice"), Message{Type: TypePosition})
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < broadcasts; i++ {
+			hub.Broadcast(Message{Type: TypeMarketState})
+		}
+	}()
+	wg.Wait()
+
+	// The stalled client's buffer drains and then closes
+	deadline := time.After(5 * time.Second)
+	for open := true; open; {
+		select {
+		case _, open = <-stalled.send:
+		case <-deadline:
+			t.Fatal("stalled client was not disconnected")
+		}
+	}
+	if !stalled.slow.Load() {
+		t.Fatal("stalled client was disconnected without being marked slow")
+	}
+
+	hub.mu.RLock()
+	_, stillStalled := hub.clients[stalled]
+	_, stillHealthy := hub.clients[healthy]
+	hub.mu.RUnlock()
+	if stillStalled || !stillHealthy {
+		t.Fatalf("registered: stalled %v, healthy %v, want only the healthy client", stillStalled, stillHealthy)
+	}
+	for i := 0; i < broadcasts; i++ {
+		if msg := recv(t, healthy); msg.Type != TypeMarketState {
+			t.Fatalf("healthy client got %s, want every market state broadcast", msg.Type)
+		}
+	}
+	if healthy.slow.Load() {
+		t.Fatal("healthy client marked slow")
+	}
+}
+
+func TestDisconnectedClientRequestsAreIgnored(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	dropped := newTestClient(t, hub, EncodingJSON)
+	for i := 0; i < cap(dropped.send)+1; i++ {
+		hub.BroadcastToChannel(HeartbeatChannel, Message{Type: TypeHeartbeat})
+	}
+	for range dropped.send {
+		// Drain until the hub closes the buffer
+	}
+
+	// Its ReadPump may still be handling requests, each of which replies
+	dropped.subscribe(TraderChannel("bob"))
+	dropped.authenticate("token")
+	if !dropped.slow.Load() {
+		t.Fatal("flooded client was not dropped as slow")
+	}
+
+	// Likewise for clients still reading when the hub shuts down
+	open := newTestClient(t, hub, EncodingJSON)
+	hub.Broadcast(Message{Type: TypeMarketState})
+	recv(t, open) // Registered
+	hub.Close()
+	open.subscribe(TraderChannel("bob"))
+	if _, ok := <-open.send; ok {
+		t.Fatal("reply queued after the hub closed")
+	}
+}
@@ -0,0 +1,267 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+	"github.com/thatreguy/trade.re/internal/domain"
+)
+
+// readMsgpack decodes the next value the encoder can write. Numbers come back
+// as float64 so the tree compares equal to one decoded from JSON.
+func readMsgpack(r *bytes.Reader) (interface{}, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case b <= 0x7f:
+		return float64(b), nil
+	case b >= 0xe0:
+		return float64(int8(b)), nil
+	case b&0xe0 == 0xa0:
+		return readMsgpackString(r, int(b&0x1f))
+	case b&0xf0 == 0x90:
+		return readMsgpackArray(r, int(b&0x0f))
+	case b&0xf0 == 0x80:
+		return readMsgpackMap(r, int(b&0x0f))
+	}
+
+	var n int
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xd0:
+		var i int8
+		err = binary.Read(r, binary.BigEndian, &i)
+		return float64(i), err
+	case 0xd1:
+		var i int16
+		err = binary.Read(r, binary.BigEndian, &i)
+		return float64(i), err
+	case 0xd2:
+		var i int32
+		err = binary.Read(r, binary.BigEndian, &i)
+		return float64(i), err
+	case 0xd3:
+		var i int64
+		err = binary.Read(r, binary.BigEndian, &i)
+		return float64(i), err
+	case 0xcb:
+		var bits uint64
+		err = binary.Read(r, binary.BigEndian, &bits)
+		return math.Float64frombits(bits), err
+	case 0xd9:
+		var l uint8
+		err = binary.Read(r, binary.BigEndian, &l)
+		n = int(l)
+	case 0xda, 0xdc, 0xde:
+		var l uint16
+		err = binary.Read(r, binary.BigEndian, &l)
+		n = int(l)
+	case 0xdb, 0xdd, 0xdf:
+		var l uint32
+		err = binary.Read(r, binary.BigEndian, &l)
+		n = int(l)
+	default:
+		return nil, fmt.Errorf("unexpected msgpack type 0x%02x", b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch b {
+	case 0xd9, 0xda, 0xdb:
+		return readMsgpackString(r, n)
+	case 0xdc, 0xdd:
+		return readMsgpackArray(r, n)
+	}
+	return readMsgpackMap(r, n)
+}
+
+func readMsgpackString(r *bytes.Reader, n int) (interface{}, error) {
+	s := make([]byte, n)
+	if _, err := io.ReadFull(r, s); err != nil {
+		return nil, err
+	}
+	return string(s), nil
+}
+
+func readMsgpackArray(r *bytes.Reader, n int) (interface{}, error) {
+	items := make([]interface{}, n)
+	for i := range items {
+		item, err := readMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+func readMsgpackMap(r *bytes.Reader, n int) (interface{}, error) {
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := readMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("map key %v is not a string", k)
+		}
+		if m[key], err = readMsgpack(r); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func testTrade(price string) *domain.Trade {
+	return &domain.Trade{
+		ID:               uuid.New(),
+		Instrument:       domain.RIndexSymbol,
+		Price:            decimal.RequireFromString(price),
+		Size:             decimal.RequireFromString("0.25"),
+		Timestamp:        time.Now(),
+		BuyerID:          uuid.New(),
+		SellerID:         uuid.New(),
+		BuyerOrderID:     uuid.New(),
+		SellerOrderID:    uuid.New(),
+		BuyerLeverage:    10,
+		SellerLeverage:   150,
+		BuyerNewPosition: decimal.RequireFromString("0.25"),
+	}
+}
+
+// dialEncoding connects a client in the given encoding to a server over hub
+func dialEncoding(t *testing.T, hub *Hub, encoding Encoding) *websocket.Conn {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		client := NewClientWithEncoding(hub, conn, encoding)
+		hub.Register(client)
+		client.Send(Message{Type: TypeWelcome})
+		go client.WritePump()
+		go client.ReadPump()
+	}))
+	t.Cleanup(server.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func TestMsgpackClientDecodesTrade(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	jsonConn := dialEncoding(t, hub, EncodingJSON)
+	packConn := dialEncoding(t, hub, EncodingMsgpack)
+
+	// Registration has completed once each welcome arrives
+	var welcome Message
+	if err := jsonConn.ReadJSON(&welcome); err != nil || welcome.Type != TypeWelcome {
+		t.Fatalf("json welcome = %+v, %v", welcome, err)
+	}
+	frameType, frame, err := packConn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frameType != websocket.BinaryMessage {
+		t.Fatalf("msgpack client got frame type %d, want binary", frameType)
+	}
+	if v, err := readMsgpack(bytes.NewReader(frame)); err != nil || v.(map[string]interface{})["type"] != string(TypeWelcome) {
+		t.Fatalf("msgpack welcome = %v, %v", v, err)
+	}
+
+	hub.BroadcastTrade(testTrade("1000.5"))
+	hub.BroadcastTrade(testTrade("1001"))
+
+	// Both trades as the JSON client sees them, batched or not
+	var want []interface{}
+	for len(want) < 2 {
+		_, data, err := jsonConn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range bytes.Split(data, []byte{'\n'}) {
+			var v interface{}
+			if err := json.Unmarshal(line, &v); err != nil {
+				t.Fatal(err)
+			}
+			want = append(want, v)
+		}
+	}
+
+	// Batched msgpack values are written back to back in one frame
+	var got []interface{}
+	for len(got) < 2 {
+		frameType, frame, err := packConn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frameType != websocket.BinaryMessage {
+			t.Fatalf("msgpack client got frame type %d, want binary", frameType)
+		}
+		r := bytes.NewReader(frame)
+		for r.Len() > 0 {
+			v, err := readMsgpack(r)
+			if err != nil {
+				t.Fatalf("decoding frame % x: %v", frame, err)
+			}
+			got = append(got, v)
+		}
+	}
+
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Fatalf("msgpack trade %d = %v, want the JSON schema %v", i, got[i], want[i])
+		}
+	}
+	data := got[0].(map[string]interface{})["data"].(map[string]interface{})
+	if data["price"] != "1000.5" || data["seller_leverage"] != float64(150) {
+		t.Fatalf("trade data = %v, want price as a string and leverage as a number", data)
+	}
+}
+
+func BenchmarkEncoding(b *testing.B) {
+	msg := Message{Type: TypeTrade, Data: testTrade("1000.5"), Timestamp: time.Now().UnixMilli()}
+	b.Run("json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := json.Marshal(msg); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("msgpack", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := encodeMsgpack(msg); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
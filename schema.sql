@@ -104,6 +104,7 @@ CREATE TABLE liquidations (
     loss DECIMAL(20, 8) NOT NULL,
     counterparty_id UUID REFERENCES traders(id),
     insurance_fund_hit BOOLEAN NOT NULL DEFAULT FALSE,
+    effect VARCHAR(20) NOT NULL DEFAULT 'liquidation' CHECK (effect IN ('liquidation', 'adl')),
     timestamp TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
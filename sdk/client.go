@@ -0,0 +1,217 @@
+// Package sdk is a Go client for the Trade.re REST API.
+//
+// A Client is safe for concurrent use. Bots issuing many requests per second
+// should share a single Client (or a single transport across Clients) so
+// connections are reused instead of re-dialed:
+//
+//	transport := sdk.NewTransport(sdk.TransportConfig{
+//		MaxIdleConns:        200,
+//		MaxIdleConnsPerHost: 100,
+//		MaxConnsPerHost:     100,
+//		IdleConnTimeout:     90 * time.Second,
+//		KeepAlive:           30 * time.Second,
+//	})
+//	mm := sdk.NewClient(url, sdk.WithTransport(transport), sdk.WithAPIKey(mmKey))
+//	taker := sdk.NewClient(url, sdk.WithTransport(transport), sdk.WithAPIKey(takerKey))
+//
+// The Go default of 2 idle connections per host is the usual bottleneck: with
+// more concurrent requests than that, the excess connections are closed after
+// each response and must be re-established. Set MaxIdleConnsPerHost to at
+// least the number of requests you expect to have in flight.
+//
+// All prices, sizes and balances are decimal.Decimal rather than float64, so
+// responses decode without precision loss whether the server encodes them as
+// JSON strings ("1000.25") or numbers (1000.25).
+//
+// Client.Stream opens the real-time WebSocket feed. Tape UIs can pass
+// WithTradeBatching to receive trades in ordered slices rather than one
+// callback per trade.
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is the per-request timeout used when none is configured
+const DefaultTimeout = 10 * time.Second
+
+// Client talks to a Trade.re server
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	apiKey     string
+	token      string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithAPIKey authenticates requests with an API key (X-API-Key header)
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithToken authenticates requests with a JWT bearer token
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient replaces the underlying HTTP client entirely. The Client
+// keeps its own copy, so transport and timeout options applied after this one
+// leave the given client as it was; the copy still shares its transport.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		copied := *hc
+		c.httpClient = &copied
+	}
+}
+
+// WithTransport sets the HTTP transport. Pass the same transport to several
+// Clients to share one connection pool between them.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.httpClient.Transport = rt
+	}
+}
+
+// WithTimeout sets the overall per-request timeout
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.httpClient.Timeout = d
+	}
+}
+
+// WithTransportConfig builds a dedicated transport from the given settings
+func WithTransportConfig(cfg TransportConfig) Option {
+	return func(c *Client) {
+		c.httpClient.Transport = NewTransport(cfg)
+	}
+}
+
+// TransportConfig holds connection pool settings for NewTransport.
+// Zero values fall back to the net/http defaults.
+type TransportConfig struct {
+	MaxIdleConns        int           // Idle connections kept across all hosts
+	MaxIdleConnsPerHost int           // Idle connections kept per host (Go default: 2)
+	MaxConnsPerHost     int           // Hard cap on connections per host (0 = unlimited)
+	IdleConnTimeout     time.Duration // How long an idle connection is kept
+	KeepAlive           time.Duration // TCP keep-alive period
+	DisableKeepAlives   bool          // Use a new connection for every request
+}
+
+// NewTransport creates an HTTP transport tuned with the given settings
+func NewTransport(cfg TransportConfig) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.MaxIdleConns > 0 {
+		t.MaxIdleConns = cfg.MaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.MaxConnsPerHost > 0 {
+		t.MaxConnsPerHost = cfg.MaxConnsPerHost
+	}
+	if cfg.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	if cfg.KeepAlive > 0 {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: cfg.KeepAlive,
+		}
+		t.DialContext = dialer.DialContext
+	}
+	t.DisableKeepAlives = cfg.DisableKeepAlives
+
+	return t
+}
+
+// NewClient creates a new API client for the server at baseURL
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is returned when the server responds with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("trade.re API error (%d): %s", e.StatusCode, e.Message)
+}
+
+// do sends a request and decodes the JSON response into out (if non-nil)
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = strings.TrimSpace(string(data))
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: apiErr.Error}
+	}
+
+	if out == nil {
+		// Drain so the connection can be reused
+		_, err := io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	// Drain any trailing bytes so the connection returns to the pool
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
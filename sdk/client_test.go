@@ -0,0 +1,78 @@
+package sdk
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithHTTPClientLeavesCallerClientUnchanged(t *testing.T) {
+	transport := &http.Transport{}
+	hc := &http.Client{Timeout: 5 * time.Second, Transport: transport}
+
+	c := NewClient("http://localhost", WithHTTPClient(hc), WithTimeout(time.Second), WithTransport(http.DefaultTransport))
+	if hc.Timeout != 5*time.Second || hc.Transport != transport {
+		t.Fatalf("caller's client changed to timeout %s, transport %v", hc.Timeout, hc.Transport)
+	}
+	if c.httpClient.Timeout != time.Second || c.httpClient.Transport != http.DefaultTransport {
+		t.Fatalf("client options not applied: timeout %s", c.httpClient.Timeout)
+	}
+
+	// Two Clients built from one http.Client share its transport but not each other's options
+	a := NewClient("http://localhost", WithHTTPClient(hc), WithTimeout(time.Second))
+	b := NewClient("http://localhost", WithHTTPClient(hc), WithTimeout(2*time.Second))
+	if a.httpClient.Timeout == b.httpClient.Timeout || a.httpClient.Transport != transport || b.httpClient.Transport != transport {
+		t.Fatal("clients built from one http.Client interfere")
+	}
+}
+
+func TestSharedTransportReusesConnectionsUnderConcurrency(t *testing.T) {
+	var conns atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"instrument": "R.index"}`))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	const workers, requests = 8, 25
+	transport := NewTransport(TransportConfig{MaxIdleConnsPerHost: workers})
+	defer transport.CloseIdleConnections()
+	// Several Clients over one transport draw on one pool
+	clients := []*Client{
+		NewClient(server.URL, WithTransport(transport)),
+		NewClient(server.URL, WithHTTPClient(&http.Client{}), WithTransport(transport)),
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(c *Client) {
+			defer wg.Done()
+			for j := 0; j < requests; j++ {
+				if _, err := c.GetMarketStats(context.Background()); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(clients[i%len(clients)])
+	}
+	wg.Wait()
+
+	// Without reuse every request dials. A dial can race a connection on its
+	// way back to the pool, so allow some slack over one per worker.
+	if n := conns.Load(); n > 2*workers {
+		t.Fatalf("%d connections opened for %d requests from %d workers, want at most %d", n, workers*requests, workers, 2*workers)
+	}
+}
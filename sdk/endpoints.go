@@ -0,0 +1,232 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/shopspring/decimal"
+)
+
+// GetInstruments lists the instruments the server trades
+func (c *Client) GetInstruments(ctx context.Context) ([]Instrument, error) {
+	var instruments []Instrument
+	if err := c.do(ctx, http.MethodGet, "/api/v1/instruments", nil, &instruments); err != nil {
+		return nil, err
+	}
+	return instruments, nil
+}
+
+// GetMarketStats returns current R.index statistics
+func (c *Client) GetMarketStats(ctx context.Context) (*MarketStats, error) {
+	var stats MarketStats
+	if err := c.do(ctx, http.MethodGet, "/api/v1/market/stats", nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetOrderBook returns the R.index order book to the given depth
+func (c *Client) GetOrderBook(ctx context.Context, depth int) (*OrderBook, error) {
+	var book OrderBook
+	path := fmt.Sprintf("/api/v1/market/orderbook?depth=%d", depth)
+	if err := c.do(ctx, http.MethodGet, path, nil, &book); err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
+
+// GetRecentTrades returns the most recent R.index trades
+func (c *Client) GetRecentTrades(ctx context.Context, limit int) ([]Trade, error) {
+	var page struct {
+		Trades []Trade `json:"trades"`
+	}
+	path := fmt.Sprintf("/api/v1/market/trades?limit=%d", limit)
+	if err := c.do(ctx, http.MethodGet, path, nil, &page); err != nil {
+		return nil, err
+	}
+	return page.Trades, nil
+}
+
+// GetLargestLiquidations returns the biggest R.index liquidations ever,
+// ranked by "loss" or "notional"
+func (c *Client) GetLargestLiquidations(ctx context.Context, by string, limit int) ([]LargestLiquidation, error) {
+	var largest []LargestLiquidation
+	path := fmt.Sprintf("/api/v1/market/liquidations/largest?by=%s&limit=%d", url.QueryEscape(by), limit)
+	if err := c.do(ctx, http.MethodGet, path, nil, &largest); err != nil {
+		return nil, err
+	}
+	return largest, nil
+}
+
+// GetLeaderboard returns traders ranked by "pnl", "roi", "volume" or
+// "max_leverage", best first
+func (c *Client) GetLeaderboard(ctx context.Context, sort string, limit int) ([]LeaderboardEntry, error) {
+	var board []LeaderboardEntry
+	path := fmt.Sprintf("/api/v1/leaderboard?sort=%s&limit=%d", url.QueryEscape(sort), limit)
+	if err := c.do(ctx, http.MethodGet, path, nil, &board); err != nil {
+		return nil, err
+	}
+	return board, nil
+}
+
+// GetPositions returns every open R.index position
+func (c *Client) GetPositions(ctx context.Context) ([]Position, error) {
+	var positions []Position
+	if err := c.do(ctx, http.MethodGet, "/api/v1/market/positions", nil, &positions); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+// GetLiquidationRisk returns the R.index positions whose liquidation price is
+// within threshold (a fraction of the mark, e.g. 0.02) of the mark, closest first
+func (c *Client) GetLiquidationRisk(ctx context.Context, threshold decimal.Decimal) ([]Position, error) {
+	var positions []Position
+	path := "/api/v1/market/liquidation-risk?threshold=" + url.QueryEscape(threshold.String())
+	if err := c.do(ctx, http.MethodGet, path, nil, &positions); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+// GetTrader returns a single trader
+func (c *Client) GetTrader(ctx context.Context, traderID string) (*Trader, error) {
+	var trader Trader
+	path := "/api/v1/traders/" + url.PathEscape(traderID)
+	if err := c.do(ctx, http.MethodGet, path, nil, &trader); err != nil {
+		return nil, err
+	}
+	return &trader, nil
+}
+
+// GetTraderPositions returns a trader's open positions
+func (c *Client) GetTraderPositions(ctx context.Context, traderID string) ([]Position, error) {
+	var positions []Position
+	path := "/api/v1/traders/" + url.PathEscape(traderID) + "/positions"
+	if err := c.do(ctx, http.MethodGet, path, nil, &positions); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+// GetTraderOrders returns a trader's open R.index orders. A non-empty status
+// ("pending", "partial", "filled" or "cancelled") returns only orders in that
+// status instead; filled and cancelled orders come newest first.
+func (c *Client) GetTraderOrders(ctx context.Context, traderID, status string) ([]Order, error) {
+	var orders []Order
+	path := "/api/v1/traders/" + url.PathEscape(traderID) + "/orders"
+	if status != "" {
+		path += "?status=" + url.QueryEscape(status)
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// GetTraderExposure returns a trader's notional, margin and unrealized P&L
+// summed across all instruments
+func (c *Client) GetTraderExposure(ctx context.Context, traderID string) (*Exposure, error) {
+	var exposure Exposure
+	path := "/api/v1/traders/" + url.PathEscape(traderID) + "/exposure"
+	if err := c.do(ctx, http.MethodGet, path, nil, &exposure); err != nil {
+		return nil, err
+	}
+	return &exposure, nil
+}
+
+// PlaceOrder submits a new order
+func (c *Client) PlaceOrder(ctx context.Context, req PlaceOrderRequest) (*PlaceOrderResponse, error) {
+	var resp PlaceOrderResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/orders", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// PlaceOCO submits two limit or stop orders as a one-cancels-other pair
+func (c *Client) PlaceOCO(ctx context.Context, first, second PlaceOrderRequest) (*PlaceOCOResponse, error) {
+	body := map[string][]PlaceOrderRequest{"orders": {first, second}}
+	var resp PlaceOCOResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/orders/oco", body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// PlaceOrders submits up to 100 orders in one engine pass. Results are in
+// request order; an order that was rejected has Error set.
+func (c *Client) PlaceOrders(ctx context.Context, orders []PlaceOrderRequest) ([]BatchOrderResult, error) {
+	body := map[string][]PlaceOrderRequest{"orders": orders}
+	var resp struct {
+		Results []BatchOrderResult `json:"results"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/orders/batch", body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// SimulateOrder previews the fills, fees and resulting position an order
+// would have if placed now, without placing it
+func (c *Client) SimulateOrder(ctx context.Context, req PlaceOrderRequest) (*OrderSimulation, error) {
+	var sim OrderSimulation
+	if err := c.do(ctx, http.MethodPost, "/api/v1/orders/simulate", req, &sim); err != nil {
+		return nil, err
+	}
+	return &sim, nil
+}
+
+// GetOrder returns an R.index order's current fill state and status
+func (c *Client) GetOrder(ctx context.Context, orderID string) (*Order, error) {
+	var order Order
+	path := "/api/v1/orders/" + url.PathEscape(orderID)
+	if err := c.do(ctx, http.MethodGet, path, nil, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// CancelOrder cancels a resting order
+func (c *Client) CancelOrder(ctx context.Context, orderID, instrument string) error {
+	path := "/api/v1/orders/" + url.PathEscape(orderID) + "?instrument=" + url.QueryEscape(instrument)
+	return c.do(ctx, http.MethodDelete, path, nil, nil)
+}
+
+// AmendOrder changes a resting order's price and/or total size; a zero value
+// leaves that field unchanged. A size decrease alone keeps queue priority.
+func (c *Client) AmendOrder(ctx context.Context, orderID, instrument string, price, size decimal.Decimal) (*PlaceOrderResponse, error) {
+	body := map[string]string{"instrument": instrument}
+	if !price.IsZero() {
+		body["price"] = price.String()
+	}
+	if !size.IsZero() {
+		body["size"] = size.String()
+	}
+	var resp PlaceOrderResponse
+	if err := c.do(ctx, http.MethodPut, "/api/v1/orders/"+url.PathEscape(orderID), body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SetMarginMode switches the authenticated trader to "isolated" or "cross"
+// margin. The server refuses while the trader has open positions.
+func (c *Client) SetMarginMode(ctx context.Context, mode string) error {
+	return c.do(ctx, http.MethodPut, "/api/v1/traders/me/margin-mode", map[string]string{"margin_mode": mode}, nil)
+}
+
+// CreateAPIKey issues a new API key for the authenticated trader, revoking
+// any previous one. The server never returns the key again, so store it;
+// pass it to WithAPIKey for later clients.
+func (c *Client) CreateAPIKey(ctx context.Context) (string, error) {
+	var resp struct {
+		APIKey string `json:"api_key"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/apikey", nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.APIKey, nil
+}
@@ -0,0 +1,222 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// StreamMessage is a raw message from the real-time feed
+type StreamMessage struct {
+	Type      string          `json:"type"`
+	Channel   string          `json:"channel,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	Timestamp int64           `json:"timestamp"`
+	Seq       uint64          `json:"seq,omitempty"`
+}
+
+// StreamHandlers receives stream events. Nil handlers are skipped.
+// OnTrade, OnTrades and OnMessage are called from a single goroutine, so
+// trades are delivered in the order the server sent them.
+type StreamHandlers struct {
+	OnTrade   func(Trade)         // Each trade, when batching is off
+	OnTrades  func([]Trade)       // Trade batches, when batching is on
+	OnMessage func(StreamMessage) // Every non-trade message
+	OnError   func(error)         // Read or decode errors; a read error ends the stream
+}
+
+// StreamOption configures a Stream
+type StreamOption func(*Stream)
+
+// WithTradeBatching delivers trades to OnTrades in slices of up to maxSize,
+// flushing a partial batch every interval. Use this for UIs rendering a
+// tape, where one callback per trade causes layout thrash.
+func WithTradeBatching(maxSize int, interval time.Duration) StreamOption {
+	return func(s *Stream) {
+		s.batchSize = maxSize
+		s.batchInterval = interval
+	}
+}
+
+// Stream is a connection to the real-time WebSocket feed
+type Stream struct {
+	conn     *websocket.Conn
+	handlers StreamHandlers
+
+	batchSize     int
+	batchInterval time.Duration
+
+	events  chan StreamMessage
+	writeMu sync.Mutex
+	done    chan struct{}
+	closeMu sync.Once
+}
+
+// Stream connects to the server's WebSocket feed. A client with a token or
+// API key authenticates the connection, so it may subscribe to its own
+// "fills:{trader_id}" channel.
+func (c *Client) Stream(ctx context.Context, handlers StreamHandlers, opts ...StreamOption) (*Stream, error) {
+	wsURL := c.baseURL + "/ws"
+	switch {
+	case strings.HasPrefix(wsURL, "https://"):
+		wsURL = "wss://" + strings.TrimPrefix(wsURL, "https://")
+	case strings.HasPrefix(wsURL, "http://"):
+		wsURL = "ws://" + strings.TrimPrefix(wsURL, "http://")
+	}
+	token := c.token
+	if token == "" {
+		token = c.apiKey
+	}
+	if token != "" {
+		wsURL += "?token=" + url.QueryEscape(token)
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to stream: %w", err)
+	}
+
+	s := &Stream{
+		conn:     conn,
+		handlers: handlers,
+		events:   make(chan StreamMessage, 1024),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	go s.readLoop()
+	go s.dispatchLoop()
+	return s, nil
+}
+
+// Subscribe adds a channel subscription (e.g. "orderbook:R.index")
+func (s *Stream) Subscribe(channel string) error {
+	return s.send("subscribe", channel)
+}
+
+// Unsubscribe removes a channel subscription
+func (s *Stream) Unsubscribe(channel string) error {
+	return s.send("unsubscribe", channel)
+}
+
+func (s *Stream) send(msgType, channel string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.conn.WriteJSON(map[string]string{"type": msgType, "data": channel})
+}
+
+// Close disconnects and waits until every received trade has been delivered,
+// including a final partial batch
+func (s *Stream) Close() error {
+	var err error
+	s.closeMu.Do(func() {
+		s.writeMu.Lock()
+		s.conn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		s.writeMu.Unlock()
+		err = s.conn.Close()
+	})
+	<-s.done
+	return err
+}
+
+// readLoop decodes frames into events until the connection ends
+func (s *Stream) readLoop() {
+	defer close(s.events)
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure) && !errors.Is(err, net.ErrClosed) {
+				s.reportError(err)
+			}
+			return
+		}
+
+		// The server may pack several messages into one frame, newline separated
+		for _, line := range bytes.Split(data, []byte{'\n'}) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			var msg StreamMessage
+			if err := json.Unmarshal(line, &msg); err != nil {
+				s.reportError(fmt.Errorf("decoding stream message: %w", err))
+				continue
+			}
+			s.events <- msg
+		}
+	}
+}
+
+// dispatchLoop delivers events to handlers, batching trades if configured
+func (s *Stream) dispatchLoop() {
+	defer close(s.done)
+
+	batching := s.batchSize > 0 && s.handlers.OnTrades != nil
+	var batch []Trade
+	var tick <-chan time.Time
+	if batching && s.batchInterval > 0 {
+		ticker := time.NewTicker(s.batchInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	flush := func() {
+		if len(batch) > 0 {
+			s.handlers.OnTrades(batch)
+			batch = nil
+		}
+	}
+
+	for {
+		select {
+		case msg, ok := <-s.events:
+			if !ok {
+				flush()
+				return
+			}
+			if msg.Type != "trade" {
+				if s.handlers.OnMessage != nil {
+					s.handlers.OnMessage(msg)
+				}
+				continue
+			}
+
+			var trade Trade
+			if err := json.Unmarshal(msg.Data, &trade); err != nil {
+				s.reportError(fmt.Errorf("decoding trade: %w", err))
+				continue
+			}
+			if !batching {
+				if s.handlers.OnTrade != nil {
+					s.handlers.OnTrade(trade)
+				}
+				continue
+			}
+			batch = append(batch, trade)
+			if len(batch) >= s.batchSize {
+				flush()
+			}
+
+		case <-tick:
+			flush()
+		}
+	}
+}
+
+func (s *Stream) reportError(err error) {
+	if s.handlers.OnError != nil {
+		s.handlers.OnError(err)
+	}
+}
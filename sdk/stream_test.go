@@ -0,0 +1,124 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// tapeServer sends n trades with IDs 0 to n-1, then a "done" message
+func tapeServer(t *testing.T, n int) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for i := 0; i < n; i++ {
+			msg := fmt.Sprintf(`{"type": "trade", "data": {"id": "%d", "instrument": "R.index"}}`, i)
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+				return
+			}
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "done"}`))
+		// Hold the connection until the client closes it
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTradeBatchingDeliversFullBatchesInOrder(t *testing.T) {
+	const trades, batchSize = 257, 50
+	server := tapeServer(t, trades)
+
+	var mu sync.Mutex
+	var batches [][]Trade
+	done := make(chan struct{})
+	stream, err := NewClient(server.URL).Stream(context.Background(), StreamHandlers{
+		OnTrades: func(batch []Trade) {
+			mu.Lock()
+			batches = append(batches, batch)
+			mu.Unlock()
+		},
+		OnTrade: func(Trade) { t.Error("OnTrade called with batching on") },
+		OnMessage: func(msg StreamMessage) {
+			if msg.Type == "done" {
+				close(done)
+			}
+		},
+	}, WithTradeBatching(batchSize, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not finish")
+	}
+	mu.Lock()
+	full := len(batches)
+	mu.Unlock()
+	if full != trades/batchSize {
+		t.Fatalf("%d batches before close, want %d full ones", full, trades/batchSize)
+	}
+
+	// Close flushes the 7 left over
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != trades/batchSize+1 {
+		t.Fatalf("%d batches after close, want the partial one added", len(batches))
+	}
+	next := 0
+	for i, batch := range batches {
+		if want := batchSize; i < len(batches)-1 && len(batch) != want {
+			t.Fatalf("batch %d has %d trades, want %d", i, len(batch), want)
+		}
+		for _, trade := range batch {
+			if trade.ID != fmt.Sprint(next) {
+				t.Fatalf("trade %s delivered where %d was expected", trade.ID, next)
+			}
+			next++
+		}
+	}
+	if next != trades {
+		t.Fatalf("%d trades delivered, want %d", next, trades)
+	}
+}
+
+func TestTradeBatchingFlushesOnInterval(t *testing.T) {
+	server := tapeServer(t, 3)
+
+	got := make(chan []Trade, 3)
+	stream, err := NewClient(server.URL).Stream(context.Background(), StreamHandlers{
+		OnTrades: func(batch []Trade) { got <- batch },
+	}, WithTradeBatching(100, 20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	// None of the batches fill up; the ticker delivers them all before close
+	for delivered := 0; delivered < 3; {
+		select {
+		case batch := <-got:
+			delivered += len(batch)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d of 3 trades flushed", delivered)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package sdk
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Trader is a market participant (all fields public)
+type Trader struct {
+	ID              string          `json:"id"`
+	Username        string          `json:"username"`
+	Type            string          `json:"type"`
+	CreatedAt       time.Time       `json:"created_at"`
+	Balance         decimal.Decimal `json:"balance"`
+	TotalPnL        decimal.Decimal `json:"total_pnl"`
+	TradeCount      int64           `json:"trade_count"`
+	MaxLeverageUsed int             `json:"max_leverage_used"`
+	MarginMode      string          `json:"margin_mode"` // "isolated" or "cross"; empty means isolated
+}
+
+// Order is a trading order as reported by the server
+type Order struct {
+	ID          string          `json:"id"`
+	TraderID    string          `json:"trader_id"`
+	Instrument  string          `json:"instrument"`
+	Side        string          `json:"side"`
+	Type        string          `json:"type"`
+	Price       decimal.Decimal `json:"price"`
+	StopPrice   decimal.Decimal `json:"stop_price"`
+	Size        decimal.Decimal `json:"size"`
+	DisplaySize decimal.Decimal `json:"display_size"` // Iceberg slice shown on the book; zero = all
+	PostOnly    bool            `json:"post_only,omitempty"`
+	ReduceOnly  bool            `json:"reduce_only,omitempty"`
+	TimeInForce string          `json:"time_in_force,omitempty"`
+	STP         string          `json:"self_trade_prevention,omitempty"`
+	FilledSize  decimal.Decimal `json:"filled_size"`
+	Leverage    int             `json:"leverage"`
+	Status      string          `json:"status"`
+	WorkCapped  bool            `json:"work_capped,omitempty"`
+	Slipped     bool            `json:"slippage_capped,omitempty"` // Market remainder cancelled at the slippage band
+	Triggered   bool            `json:"triggered,omitempty"`
+	OCOGroupID  string          `json:"oco_group_id,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// Trade is an executed trade with both sides visible
+type Trade struct {
+	ID                string          `json:"id"`
+	Instrument        string          `json:"instrument"`
+	Price             decimal.Decimal `json:"price"`
+	Size              decimal.Decimal `json:"size"`
+	Timestamp         time.Time       `json:"timestamp"`
+	BuyerID           string          `json:"buyer_id"`
+	SellerID          string          `json:"seller_id"`
+	BuyerOrderID      string          `json:"buyer_order_id"`
+	SellerOrderID     string          `json:"seller_order_id"`
+	BuyerLeverage     int             `json:"buyer_leverage"`
+	SellerLeverage    int             `json:"seller_leverage"`
+	BuyerEffect       string          `json:"buyer_effect"`
+	SellerEffect      string          `json:"seller_effect"`
+	BuyerNewPosition  decimal.Decimal `json:"buyer_new_position"`
+	SellerNewPosition decimal.Decimal `json:"seller_new_position"`
+	AggressorSide     string          `json:"aggressor_side"`
+	BuyerFee          decimal.Decimal `json:"buyer_fee"`
+	SellerFee         decimal.Decimal `json:"seller_fee"`
+	BuyerFeeRate      decimal.Decimal `json:"buyer_fee_rate"`
+	SellerFeeRate     decimal.Decimal `json:"seller_fee_rate"`
+	BuyerRealizedPnL  decimal.Decimal `json:"buyer_realized_pnl"`
+	SellerRealizedPnL decimal.Decimal `json:"seller_realized_pnl"`
+}
+
+// Position is a trader's open position
+type Position struct {
+	TraderID         string          `json:"trader_id"`
+	Instrument       string          `json:"instrument"`
+	Size             decimal.Decimal `json:"size"`
+	EntryPrice       decimal.Decimal `json:"entry_price"`
+	Leverage         int             `json:"leverage"`
+	Margin           decimal.Decimal `json:"margin"`
+	UnrealizedPnL    decimal.Decimal `json:"unrealized_pnl"`
+	RealizedPnL      decimal.Decimal `json:"realized_pnl"`
+	LiquidationPrice decimal.Decimal `json:"liquidation_price"`
+	MarginMode       string          `json:"margin_mode"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+
+	// Set by the position endpoints only
+	MarginRatio              *decimal.Decimal `json:"margin_ratio,omitempty"`
+	DistanceToLiquidationBps *decimal.Decimal `json:"distance_to_liquidation_bps,omitempty"`
+}
+
+// Exposure aggregates a trader's positions across every instrument
+type Exposure struct {
+	TraderID      string               `json:"trader_id"`
+	Timestamp     time.Time            `json:"timestamp"`
+	LongNotional  decimal.Decimal      `json:"long_notional"`
+	ShortNotional decimal.Decimal      `json:"short_notional"`
+	NetNotional   decimal.Decimal      `json:"net_notional"`
+	MarginUsed    decimal.Decimal      `json:"margin_used"`
+	UnrealizedPnL decimal.Decimal      `json:"unrealized_pnl"`
+	Positions     []InstrumentExposure `json:"positions"`
+}
+
+// InstrumentExposure is one position's share of an Exposure
+type InstrumentExposure struct {
+	Instrument    string          `json:"instrument"`
+	Size          decimal.Decimal `json:"size"`
+	MarkPrice     decimal.Decimal `json:"mark_price"`
+	Notional      decimal.Decimal `json:"notional"`
+	Margin        decimal.Decimal `json:"margin"`
+	UnrealizedPnL decimal.Decimal `json:"unrealized_pnl"`
+}
+
+// Liquidation is a forced position closure
+type Liquidation struct {
+	ID               string          `json:"id"`
+	TraderID         string          `json:"trader_id"`
+	Instrument       string          `json:"instrument"`
+	Side             string          `json:"side"`
+	Size             decimal.Decimal `json:"size"`
+	EntryPrice       decimal.Decimal `json:"entry_price"`
+	LiquidationPrice decimal.Decimal `json:"liquidation_price"`
+	MarkPrice        decimal.Decimal `json:"mark_price"`
+	Leverage         int             `json:"leverage"`
+	Loss             decimal.Decimal `json:"loss"`
+	Timestamp        time.Time       `json:"timestamp"`
+	InsuranceFundHit bool            `json:"insurance_fund_hit"`
+	Effect           string          `json:"effect"` // "liquidation", or "adl" for an auto-deleveraged position
+}
+
+// LargestLiquidation is one entry in the all-time largest liquidations
+type LargestLiquidation struct {
+	Rank     int             `json:"rank"`
+	Username string          `json:"username"`
+	Notional decimal.Decimal `json:"notional"`
+	Liquidation
+}
+
+// LeaderboardEntry is one trader's place on the all-time leaderboard.
+// Metric is the value the board was sorted by.
+type LeaderboardEntry struct {
+	Rank            int             `json:"rank"`
+	TraderID        string          `json:"trader_id"`
+	Username        string          `json:"username"`
+	Type            string          `json:"type"`
+	TotalPnL        decimal.Decimal `json:"total_pnl"`
+	ROI             decimal.Decimal `json:"roi"`
+	TradeCount      int64           `json:"trade_count"`
+	MaxLeverageUsed int             `json:"max_leverage_used"`
+	Metric          decimal.Decimal `json:"metric"`
+}
+
+// OrderBookLevel is one aggregated price level
+type OrderBookLevel struct {
+	Price      decimal.Decimal `json:"price"`
+	Size       decimal.Decimal `json:"size"`
+	OrderCount int             `json:"order_count"`
+}
+
+// OrderBook is an order book snapshot
+type OrderBook struct {
+	Instrument string           `json:"instrument"`
+	Bids       []OrderBookLevel `json:"bids"`
+	Asks       []OrderBookLevel `json:"asks"`
+	Seq        uint64           `json:"seq"` // Seq of the last level update included
+	Timestamp  time.Time        `json:"timestamp"`
+}
+
+// OrderBookDelta is one price level change from the orderbook stream channel.
+// A zero Size removes the level.
+type OrderBookDelta struct {
+	Instrument string          `json:"instrument"`
+	Side       string          `json:"side"`
+	Price      decimal.Decimal `json:"price"`
+	Size       decimal.Decimal `json:"size"`
+	Seq        uint64          `json:"seq"`
+}
+
+// MarketStats holds current market statistics
+type MarketStats struct {
+	Instrument        string          `json:"instrument"`
+	LastPrice         decimal.Decimal `json:"last_price"`
+	MarkPrice         decimal.Decimal `json:"mark_price"`
+	IndexPrice        decimal.Decimal `json:"index_price"`
+	High24h           decimal.Decimal `json:"high_24h"`
+	Low24h            decimal.Decimal `json:"low_24h"`
+	Volume24h         decimal.Decimal `json:"volume_24h"`
+	BuyVolume24h      decimal.Decimal `json:"buy_volume_24h"`  // Taken by buyers
+	SellVolume24h     decimal.Decimal `json:"sell_volume_24h"` // Taken by sellers
+	OpenInterest      decimal.Decimal `json:"open_interest"`
+	FundingRate       decimal.Decimal `json:"funding_rate"`
+	NextFundingTime   time.Time       `json:"next_funding_time"`
+	NextFundingFreeze *FundingFreeze  `json:"next_funding_freeze,omitempty"` // Set if funding.freeze_seconds is
+	InsuranceFund     decimal.Decimal `json:"insurance_fund"`
+	MarketState       string          `json:"market_state"`
+	Timestamp         time.Time       `json:"timestamp"`
+}
+
+// FundingFreeze is the window before a funding settlement in which only
+// orders reducing a position are accepted
+type FundingFreeze struct {
+	StartsAt       time.Time `json:"starts_at"`
+	SettlementTime time.Time `json:"settlement_time"`
+}
+
+// Instrument is a registered instrument's contract specification and current prices
+type Instrument struct {
+	Symbol       string          `json:"symbol"`
+	TickSize     decimal.Decimal `json:"tick_size"`
+	LotSize      decimal.Decimal `json:"lot_size"`
+	MinOrderSize decimal.Decimal `json:"min_order_size"`
+	MaxLeverage  int             `json:"max_leverage"`
+	LastPrice    decimal.Decimal `json:"last_price"`
+	MarkPrice    decimal.Decimal `json:"mark_price"`
+	MarketState  string          `json:"market_state"`
+}
+
+// PlaceOrderRequest describes a new order
+type PlaceOrderRequest struct {
+	TraderID    string          `json:"trader_id"` // Ignored by the server: orders are placed for the authenticated trader
+	Instrument  string          `json:"instrument"`
+	Side        string          `json:"side"` // "buy" or "sell"
+	Type        string          `json:"type"` // "limit", "market" or "stop"
+	Price       decimal.Decimal `json:"price"`
+	StopPrice   decimal.Decimal `json:"stop_price"` // Trigger price for stop orders
+	Size        decimal.Decimal `json:"size"`
+	DisplaySize decimal.Decimal `json:"display_size"`                    // Iceberg: size to show on the book (limit orders; zero = all)
+	PostOnly    bool            `json:"post_only,omitempty"`             // Limit orders: rejected instead of taking liquidity
+	ReduceOnly  bool            `json:"reduce_only,omitempty"`           // Only shrink the position: capped at its size, rejected without one
+	TimeInForce string          `json:"time_in_force,omitempty"`         // "GTC" (default), "IOC" or "FOK"
+	STP         string          `json:"self_trade_prevention,omitempty"` // "skip", "cancel-resting" or "cancel-aggressor"; empty = server default
+	Slippage    int             `json:"max_slippage_bps,omitempty"`      // Market and stop orders: band past the best price; zero = server default
+	Leverage    int             `json:"leverage"`
+	SentAt      int64           `json:"sent_at,omitempty"` // Unix ms; set to have a stale market order rejected
+}
+
+// MarshalJSON always encodes prices and size as strings, which is what the server
+// expects, regardless of decimal.MarshalJSONWithoutQuotes
+func (r PlaceOrderRequest) MarshalJSON() ([]byte, error) {
+	type alias PlaceOrderRequest
+	var displaySize string
+	if !r.DisplaySize.IsZero() {
+		displaySize = r.DisplaySize.String()
+	}
+	return json.Marshal(struct {
+		alias
+		Price       string `json:"price"`
+		StopPrice   string `json:"stop_price"`
+		Size        string `json:"size"`
+		DisplaySize string `json:"display_size,omitempty"`
+	}{
+		alias:       alias(r),
+		Price:       r.Price.String(),
+		StopPrice:   r.StopPrice.String(),
+		Size:        r.Size.String(),
+		DisplaySize: displaySize,
+	})
+}
+
+// PlaceOrderResponse is the server's reply to an order submission
+type PlaceOrderResponse struct {
+	Order  Order   `json:"order"`
+	Trades []Trade `json:"trades"`
+}
+
+// BatchOrderResult is the outcome of one order in a batch submission
+type BatchOrderResult struct {
+	Order  *Order  `json:"order,omitempty"` // Nil if the order was rejected
+	Trades []Trade `json:"trades,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// SimulatedFill is one fill a simulated order would make
+type SimulatedFill struct {
+	Price        decimal.Decimal `json:"price"`
+	Size         decimal.Decimal `json:"size"`
+	MakerID      string          `json:"maker_id"`
+	MakerOrderID string          `json:"maker_order_id"`
+}
+
+// OrderSimulation is the server's preview of an order that was not placed
+type OrderSimulation struct {
+	Instrument    string          `json:"instrument"`
+	Side          string          `json:"side"`
+	Size          decimal.Decimal `json:"size"`
+	FilledSize    decimal.Decimal `json:"filled_size"`
+	AveragePrice  decimal.Decimal `json:"average_price"`
+	Notional      decimal.Decimal `json:"notional"`
+	Fee           decimal.Decimal `json:"fee"`
+	Fills         []SimulatedFill `json:"fills"`
+	Rests         decimal.Decimal `json:"rests"`
+	Cancels       decimal.Decimal `json:"cancels"`
+	StopReason    string          `json:"stop_reason,omitempty"` // "killed", "self_trade", "work_capped" or "slippage_capped"
+	Position      decimal.Decimal `json:"position"`
+	NewPosition   decimal.Decimal `json:"new_position"`
+	NewEntryPrice decimal.Decimal `json:"new_entry_price"`
+	Timestamp     time.Time       `json:"timestamp"`
+}
+
+// PlaceOCOResponse is the server's reply to an OCO pair submission
+type PlaceOCOResponse struct {
+	GroupID string  `json:"group_id"`
+	Orders  []Order `json:"orders"`
+	Trades  []Trade `json:"trades"`
+}
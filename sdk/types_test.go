@@ -0,0 +1,59 @@
+package sdk
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestDecodePricesAsStringsOrNumbers(t *testing.T) {
+	want := decimal.RequireFromString("1000.25")
+	for _, price := range []string{`"1000.25"`, `1000.25`} {
+		var order Order
+		if err := json.Unmarshal([]byte(`{"id":"o1","price":`+price+`,"size":"2","filled_size":0.5}`), &order); err != nil {
+			t.Fatalf("order with price %s: %v", price, err)
+		}
+		if !order.Price.Equal(want) || !order.Size.Equal(decimal.NewFromInt(2)) || !order.FilledSize.Equal(decimal.RequireFromString("0.5")) {
+			t.Fatalf("order with price %s = %s x %s (%s filled)", price, order.Price, order.Size, order.FilledSize)
+		}
+
+		var trade Trade
+		if err := json.Unmarshal([]byte(`{"id":"t1","price":`+price+`,"size":1.5,"timestamp":"2024-01-02T03:04:05Z"}`), &trade); err != nil {
+			t.Fatalf("trade with price %s: %v", price, err)
+		}
+		if !trade.Price.Equal(want) || !trade.Size.Equal(decimal.RequireFromString("1.5")) {
+			t.Fatalf("trade with price %s = %s x %s", price, trade.Price, trade.Size)
+		}
+	}
+}
+
+func TestPlaceOrderRequestEncodesDecimalsAsStrings(t *testing.T) {
+	defer func(without bool) { decimal.MarshalJSONWithoutQuotes = without }(decimal.MarshalJSONWithoutQuotes)
+	decimal.MarshalJSONWithoutQuotes = true
+
+	data, err := json.Marshal(PlaceOrderRequest{
+		Instrument:  "R.index",
+		Side:        "buy",
+		Type:        "limit",
+		Price:       decimal.RequireFromString("1000.25"),
+		Size:        decimal.RequireFromString("2"),
+		DisplaySize: decimal.RequireFromString("0.5"),
+		Leverage:    10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for field, want := range map[string]string{"price": "1000.25", "stop_price": "0", "size": "2", "display_size": "0.5"} {
+		if got, ok := fields[field].(string); !ok || got != want {
+			t.Errorf("%s = %#v, want the string %q in %s", field, fields[field], want, data)
+		}
+	}
+	if fields["leverage"] != float64(10) {
+		t.Errorf("leverage = %#v, want the number 10", fields["leverage"])
+	}
+}
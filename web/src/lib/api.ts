@@ -230,11 +230,13 @@ class ApiClient {
   }
 
   async getRecentTrades(limit = 50): Promise<Trade[]> {
-    return this.request(`/api/v1/market/trades?limit=${limit}`)
+    const page = await this.request<{ trades: Trade[] }>(`/api/v1/market/trades?limit=${limit}`)
+    return page.trades
   }
 
   async getRecentLiquidations(limit = 50): Promise<Liquidation[]> {
-    return this.request(`/api/v1/market/liquidations?limit=${limit}`)
+    const page = await this.request<{ liquidations: Liquidation[] }>(`/api/v1/market/liquidations?limit=${limit}`)
+    return page.liquidations
   }
 
   async getMarketStats(): Promise<MarketStats> {